@@ -2,11 +2,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log" // Standard log for initial messages
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
 
 	"wgMicro_api/internal/config"
+	"wgMicro_api/internal/domain"
 	"wgMicro_api/internal/handler"
 	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/metrics"
 	"wgMicro_api/internal/repository"
 	"wgMicro_api/internal/server"
 	"wgMicro_api/internal/service"
@@ -15,8 +27,6 @@ import (
 
 	_ "wgMicro_api/docs" // Swagger docs
 
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
 )
 
@@ -33,6 +43,11 @@ import (
 // @BasePath /
 
 // @schemes http https
+
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Admin token as "Bearer <ADMIN_TOKEN>".
 func main() {
 	// Load configuration using Viper
 	// Note: logger.Init should ideally be called after config is loaded if logger itself needs config.
@@ -50,6 +65,10 @@ func main() {
 		}
 	}()
 
+	// Background tasks run until SIGINT/SIGTERM, which also triggers the HTTP server's shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	logger.Logger.Info("Application starting with loaded configuration...",
 		zap.String("version", "1.0"), // Consider making this a build-time variable
 		zap.String("environment", appConfig.AppEnv),
@@ -58,7 +77,66 @@ func main() {
 
 	// ServerKeyManager is no longer needed, server's public key is in appConfig.Server.PublicKey
 
-	repo := repository.NewWGRepository(appConfig.WGInterface, appConfig.DerivedWgCmdTimeout)
+	if appConfig.Server.ValidateEndpoint != config.EndpointValidationOff {
+		if err := config.ValidateEndpointHost(appConfig.Server.EndpointHost, net.DefaultResolver); err != nil {
+			if appConfig.Server.ValidateEndpoint == config.EndpointValidationStrict {
+				logger.Logger.Fatal("SERVER_ENDPOINT_HOST is not a valid IP or resolvable hostname", zap.Error(err))
+			}
+			logger.Logger.Warn("!!! SERVER_ENDPOINT_HOST is not a valid IP or resolvable hostname: generated client configs will not connect !!!",
+				zap.String("endpointHost", appConfig.Server.EndpointHost), zap.Error(err))
+		}
+	}
+
+	runner := appConfig.CommandRunner() // Every 'wg'/'ip' invocation gets only the CMD_ENV_ALLOWLIST environment
+	repo := repository.NewWGRepository(appConfig.WGInterface, appConfig.DerivedWgCmdTimeout,
+		repository.WithCommandRunner(runner),
+		repository.WithSlowCommandThreshold(appConfig.DerivedSlowCmdWarn),
+		repository.WithPersistChanges(appConfig.PersistChanges, appConfig.WGConfigPath),
+		repository.WithWriteBatchWindow(appConfig.DerivedWriteBatchWindow),
+	)
+
+	if appConfig.AutoCreateInterface {
+		if _, err := repo.EnsureInterfaceUp(appConfig.WGConfigPath); err != nil {
+			logger.Logger.Fatal("AUTO_CREATE_INTERFACE is enabled but the WireGuard interface could not be brought up",
+				zap.String("interface", appConfig.WGInterface),
+				zap.String("configPath", appConfig.WGConfigPath),
+				zap.Error(err))
+		}
+	}
+
+	keyGen, err := service.NewKeyGenerator(appConfig.KeyGenBackend, runner, appConfig.DerivedKeyGenTimeout)
+	if err != nil {
+		logger.Logger.Fatal("Failed to initialize key generator", zap.String("backend", appConfig.KeyGenBackend), zap.Error(err))
+	}
+
+	metadataStore, err := repository.NewFileMetadataStore(appConfig.MetadataFile)
+	if err != nil {
+		logger.Logger.Fatal("Failed to load peer metadata store", zap.String("path", appConfig.MetadataFile), zap.Error(err))
+	}
+
+	interfaceOpts := []service.Option{ // Shared with the additional interfaces of WG_INTERFACES
+		service.WithCommandRunner(runner),
+		service.WithKeyGenerator(keyGen),
+		service.WithExportMaxBytes(int64(appConfig.ExportMaxBytes)),
+		service.WithBareIPNormalization(appConfig.NormalizeBareIPs),
+		service.WithClientDefaultKeepalive(appConfig.ClientConfig.DefaultKeepalive),
+		service.WithMaxClientDNS(appConfig.ClientConfig.MaxDNS),
+		service.WithTrafficStats(appConfig.ExposeTrafficStats),
+		service.WithClientKeyCheck(appConfig.CheckClientPrivateKey),
+		service.WithOnlineThreshold(appConfig.DerivedOnlineThreshold),
+		service.WithAllowedIPRanges(appConfig.AllowedIPRanges),
+	}
+	serviceOpts := append(slices.Clip(interfaceOpts), service.WithMetadataStore(metadataStore))
+	if appConfig.AddressPool != "" {
+		// The server's own interface addresses are never handed out to peers.
+		var reserved []netip.Addr
+		for _, raw := range appConfig.Server.InterfaceAddresses {
+			if prefix, err := netip.ParsePrefix(raw); err == nil {
+				reserved = append(reserved, prefix.Addr())
+			}
+		}
+		serviceOpts = append(serviceOpts, service.WithAddressPool(netip.MustParsePrefix(appConfig.AddressPool), reserved...)) // Validated by config.LoadConfig
+	}
 
 	svc := service.NewConfigService(
 		repo,
@@ -67,11 +145,98 @@ func main() {
 		appConfig.DerivedKeyGenTimeout,    // Pass derived key gen timeout
 		appConfig.ClientConfig.DNSServers, // Pass client DNS servers
 		appConfig.ClientConfig.MTU,        // Pass client MTU
+		serviceOpts...,
 	)
+	if appConfig.DerivedMetadataReconcile > 0 {
+		go service.NewMetadataReconciler(svc, appConfig.DerivedMetadataReconcile).Run(ctx)
+	}
+
+	// Startup check: warn loudly if the configured interface addresses differ from the live interface.
+	addressDrift := service.DetectAddressDrift(appConfig.Server.InterfaceAddresses, repo)
+	serverHandler := handler.NewServerHandler(domain.ServerInfo{
+		Interface:          appConfig.WGInterface,
+		Name:               appConfig.Server.Name,
+		Description:        appConfig.Server.Description,
+		PublicKey:          appConfig.Server.PublicKey,
+		Endpoint:           appConfig.DerivedServerEndpoint,
+		ListenPort:         appConfig.Server.ListenPort,
+		SigningPublicKey:   appConfig.SigningPublicKey(),
+		InterfaceAddresses: appConfig.Server.InterfaceAddresses,
+		AddressDrift:       addressDrift,
+	}, handler.WithListenPortChanger(svc), handler.WithServerErrorSanitization(appConfig.SanitizeErrors))
 
-	cfgHandler := handler.NewConfigHandler(svc)
-	router := server.NewRouter(cfgHandler, repo) // repo is passed for readiness probe
+	interfaceServices := map[string]handler.ServiceInterface{appConfig.WGInterface: svc}
+	interfaceRepos := make(map[string]repository.Repo, len(appConfig.WGInterfaces)-1) // For the write pre-flight; WG_INTERFACE uses repo
+	for _, name := range appConfig.WGInterfaces[1:] {
+		ifaceSvc, ifaceRepo := newInterfaceService(ctx, appConfig, name, runner, interfaceOpts)
+		interfaceServices[name], interfaceRepos[name] = ifaceSvc, ifaceRepo
+	}
 
+	cfgHandler := handler.NewConfigHandler(svc,
+		handler.WithInterfaceServices(interfaceServices),
+		handler.WithFilenameOptions(handler.FilenameOptions{
+			MaxLength: appConfig.ClientConfig.FilenameMaxLength,
+			NonASCII:  handler.NonASCIIMode(appConfig.ClientConfig.FilenameNonASCII),
+		}),
+		handler.WithErrorSanitization(appConfig.SanitizeErrors),
+		handler.WithClientFileCacheControl(appConfig.ClientConfig.FileCacheControl),
+		handler.WithEmptyAllowedIPsUpdate(appConfig.AllowEmptyAllowedIPsUpdate),
+		handler.WithConfigSigningKey(appConfig.ConfigSigningKey),
+	)
+	routerOpts := []server.Option{
+		server.WithServerHandler(serverHandler),
+		server.WithGzip(appConfig.GzipMinBytes),
+		server.WithStrictJSON(appConfig.StrictJSON),
+		server.WithProbePaths(appConfig.LivenessPath, appConfig.ReadinessPath),
+		server.WithInterfaceRepos(interfaceRepos),
+		server.WithReadinessOptions(
+			server.RequirePeers(appConfig.ReadyRequiresPeers),
+			server.AllowWriteCheck(appConfig.ReadyAllowWriteCheck),
+			server.CacheReadiness(appConfig.DerivedReadinessCache),
+			server.RejectWritesWhenNotReady(appConfig.ReadyRejectWrites),
+		),
+	}
+	if appConfig.Server.NameHeader {
+		routerOpts = append(routerOpts, server.WithServerNameHeader(appConfig.Server.Name))
+	}
+	if appConfig.ReadyCheckListenPort {
+		routerOpts = append(routerOpts, server.WithReadinessOptions(server.CheckListenPort(serverHandler.ListenPort)))
+	}
+	var statsRefresher handler.StatsRefresher // Stays nil while the collector is disabled
+	var statsCollector *service.StatsCollector
+	if appConfig.DerivedStatsInterval > 0 {
+		statsCollector = service.NewStatsCollector(repo, appConfig.DerivedStatsInterval)
+		go statsCollector.Run(ctx)
+		routerOpts = append(routerOpts, server.WithStatsHandler(handler.NewStatsHandler(statsCollector, handler.WithTrafficExposed(appConfig.ExposeTrafficStats))))
+		statsRefresher = statsCollector
+	}
+	adminOpts := []handler.AdminOption{
+		handler.WithLogBuffer(logger.Ring), handler.WithInactivePruner(svc),
+		handler.WithAdminErrorSanitization(appConfig.SanitizeErrors),
+	}
+	if peerGauges, err := metrics.RegisterPeerGauges(repo, appConfig.DerivedMetricsPeerCache, appConfig.ExposeTrafficStats); err != nil {
+		logger.Logger.Error("Prometheus peer gauges disabled", zap.Error(err))
+	} else {
+		adminOpts = append(adminOpts, handler.WithCacheResetters(peerGauges))
+	}
+	if appConfig.StatsDAddr != "" {
+		statsd, err := metrics.NewStatsD(appConfig.StatsDAddr)
+		if err != nil {
+			logger.Logger.Error("StatsD disabled", zap.Error(err))
+		} else {
+			defer statsd.Close()
+			routerOpts = append(routerOpts, server.WithStatsD(statsd))
+			if statsCollector != nil {
+				go statsd.Run(ctx, appConfig.DerivedStatsInterval, statsCollector, appConfig.ExposeTrafficStats)
+			} else {
+				logger.Logger.Warn("StatsD gauges disabled: the stats collector is off (STATS_INTERVAL_SECONDS=0); only request timers are sent")
+			}
+		}
+	}
+	maintenance := server.NewMaintenance(appConfig.MaintenanceMode)
+	routerOpts = append(routerOpts, server.WithMaintenance(maintenance))
+	adminOpts = append(adminOpts, handler.WithMaintenanceSwitch(maintenance))
+	routerOpts = append(routerOpts, server.WithAdminHandler(handler.NewAdminHandler(repo, metadataStore, statsRefresher, adminOpts...), appConfig.AdminToken))
 	// Swagger UI
 	// Update @host in annotations if it needs to be dynamic based on config
 	// For now, localhost:8080 is hardcoded in Swaggo annotations.
@@ -80,8 +245,8 @@ func main() {
 	// but that needs to be done before `ginSwagger.WrapHandler` is called or by re-registering.
 	// For now, we assume the @host annotation is sufficient for typical use.
 	// Example: docs.SwaggerInfo.Host = fmt.Sprintf("localhost:%s", appConfig.Port)
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	logger.Logger.Info("Swagger UI available at /swagger/index.html")
+	routerOpts = append(routerOpts, server.WithSwagger(appConfig.EnableSwagger))
+	router := server.NewRouter(cfgHandler, repo, routerOpts...) // repo is passed for readiness probe
 
 	serverAddress := ":" + appConfig.Port
 	logger.Logger.Info("Starting HTTP server...",
@@ -89,10 +254,54 @@ func main() {
 		zap.String("port", appConfig.Port),
 	)
 
-	if err := router.Run(serverAddress); err != nil {
-		logger.Logger.Fatal("Failed to start HTTP server",
-			zap.String("address", serverAddress),
-			zap.Error(err),
-		)
+	srv := &http.Server{Addr: serverAddress, Handler: router}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Logger.Fatal("Failed to start HTTP server",
+				zap.String("address", serverAddress),
+				zap.Error(err),
+			)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Logger.Info("Shutdown signal received, stopping HTTP server", zap.Duration("timeout", appConfig.DerivedShutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), appConfig.DerivedShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Logger.Warn("HTTP server did not shut down cleanly", zap.Error(err))
+	}
+	logger.Logger.Info("HTTP server stopped")
+}
+
+// newInterfaceService builds the service of an additional interface from WG_INTERFACES and returns it with its repository.
+// Its server public key and listen port are read from the running interface; the endpoint host
+// and client settings are those of WG_INTERFACE. Peer metadata goes to its own file next to
+// PEER_METADATA_FILE (see config.InterfaceMetadataFile); the address pool stays with WG_INTERFACE.
+func newInterfaceService(ctx context.Context, appConfig *config.Config, name string, runner repository.CommandRunner, opts []service.Option) (*service.ConfigService, repository.Repo) {
+	repo := repository.NewWGRepository(name, appConfig.DerivedWgCmdTimeout,
+		repository.WithCommandRunner(runner),
+		repository.WithSlowCommandThreshold(appConfig.DerivedSlowCmdWarn),
+		repository.WithPersistChanges(appConfig.PersistChanges, ""), // WG_CONFIG_PATH belongs to WG_INTERFACE
+		repository.WithWriteBatchWindow(appConfig.DerivedWriteBatchWindow),
+	)
+	info, err := repo.GetInterfaceInfo(ctx)
+	if err != nil {
+		logger.Logger.Fatal("Failed to read an interface listed in WG_INTERFACES", zap.String("interface", name), zap.Error(err))
+	}
+	metadataFile := appConfig.InterfaceMetadataFile(name)
+	metadataStore, err := repository.NewFileMetadataStore(metadataFile)
+	if err != nil {
+		logger.Logger.Fatal("Failed to load peer metadata store", zap.String("interface", name), zap.String("path", metadataFile), zap.Error(err))
+	}
+	endpoint := ""
+	if appConfig.Server.EndpointHost != "" {
+		endpoint = domain.JoinEndpoint(appConfig.Server.EndpointHost, strconv.Itoa(info.ListenPort))
 	}
+	logger.Logger.Info("Serving additional WireGuard interface",
+		zap.String("interface", name), zap.String("endpoint", endpoint), zap.Int("listenPort", info.ListenPort),
+		zap.String("metadataFile", metadataFile))
+	svc := service.NewConfigService(repo, info.PublicKey, endpoint, appConfig.DerivedKeyGenTimeout,
+		appConfig.ClientConfig.DNSServers, appConfig.ClientConfig.MTU, append(slices.Clip(opts), service.WithMetadataStore(metadataStore))...)
+	return svc, repo
 }
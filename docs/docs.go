@@ -20,42 +20,51 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/configs": {
+        "/admin/logs/stream": {
             "get": {
-                "description": "Retrieves a list of all currently configured WireGuard peers. Private keys of peers are not included.",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Streams the service's log lines as Server-Sent Events (` + "`" + `event: log` + "`" + `, one JSON-encoded entry per ` + "`" + `data:` + "`" + ` line).\nThe last lines kept in memory are sent first, followed by new lines as they are written. Lines are dropped for a client that cannot keep up.\nRequires ` + "`" + `Authorization: Bearer \u003cADMIN_TOKEN\u003e` + "`" + `; the endpoint is not registered when ADMIN_TOKEN is unset.",
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "configs"
+                    "admin"
                 ],
-                "summary": "List all peer configurations",
+                "summary": "Stream recent logs",
                 "responses": {
                     "200": {
-                        "description": "A list of peer configurations.",
+                        "description": "SSE stream of log lines.",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
-                            }
+                            "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error.",
+                    "401": {
+                        "description": "Missing or wrong admin token.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
                     "503": {
-                        "description": "Service unavailable (WireGuard timeout).",
+                        "description": "Log streaming is not enabled.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/maintenance": {
             "post": {
-                "description": "Adds a new peer. The server generates cryptographic keys for the peer.\nThe request body should specify AllowedIPs and optionally PreSharedKey and PersistentKeepalive.\nThe response includes the full peer configuration, including the server-generated PrivateKey, which the client must securely store.",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "While maintenance mode is on, every mutating /configs endpoint and /admin/prune-inactive answer 503 with a maintenance message; reads, health checks and /admin keep working.\nThe startup state comes from MAINTENANCE_MODE. The state is not persisted across restarts.\nRequires ` + "`" + `Authorization: Bearer \u003cADMIN_TOKEN\u003e` + "`" + `; the endpoint is not registered when ADMIN_TOKEN is unset.",
                 "consumes": [
                     "application/json"
                 ],
@@ -63,41 +72,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "configs"
+                    "admin"
                 ],
-                "summary": "Create new peer with server-generated keys",
+                "summary": "Toggle maintenance mode",
                 "parameters": [
                     {
-                        "description": "Peer settings for creation (keys will be generated by server).",
-                        "name": "peerRequest",
+                        "description": "Desired maintenance state.",
+                        "name": "maintenanceRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.CreatePeerRequest"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.MaintenanceRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Peer created successfully. The response includes the generated private key.",
+                    "200": {
+                        "description": "Maintenance mode after the change.",
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.MaintenanceResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid input if the request body is malformed or contains invalid data.",
+                        "description": "Malformed body or missing enabled.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error if peer creation or key generation fails.",
+                    "401": {
+                        "description": "Missing or wrong admin token.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
                     "503": {
-                        "description": "Service unavailable if a WireGuard command times out.",
+                        "description": "Maintenance mode is not available.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
@@ -105,57 +114,69 @@ const docTemplate = `{
                 }
             }
         },
-        "/configs/client-file": {
+        "/admin/prune-inactive": {
             "post": {
-                "description": "Generates a WireGuard .conf file for a client.\nThe request body must contain the client's existing public key (to identify the peer on the server) and the client's corresponding private key.\nThe API uses these keys along with server configuration (server public key, endpoint) and the specific peer's details (AllowedIPs, PSK from server, Keepalive) to construct the .conf file.\nThe provided client private key is inserted directly into the .conf file. The API does not store this client-provided private key.",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Deletes peers that never completed a handshake and whose metadata createdAt is older than ` + "`" + `olderThan` + "`" + ` (a positive age, e.g. \"30d\", \"72h\").\nUnless ` + "`" + `requireZeroTraffic` + "`" + ` is false, peers with any received or transmitted bytes are kept. Peers without a recorded createdAt are never pruned.\nThis is destructive and requires ` + "`" + `?confirm=true` + "`" + `. The response lists the pruned public keys.\nRequires ` + "`" + `Authorization: Bearer \u003cADMIN_TOKEN\u003e` + "`" + `; the endpoint is not registered when ADMIN_TOKEN is unset.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "configs"
+                    "admin"
                 ],
-                "summary": "Generate client .conf file (client provides keys)",
+                "summary": "Prune peers that never connected",
                 "parameters": [
                     {
-                        "description": "Client's public and private keys needed for .conf generation.",
-                        "name": "clientKeysRequest",
+                        "description": "Minimum age and traffic requirement.",
+                        "name": "pruneRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.ClientFileRequest"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.PruneInactiveRequest"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Must be true to delete anything.",
+                        "name": "confirm",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "The WireGuard .conf file content as plain text.",
+                        "description": "Pruned peers, plus any that matched but could not be removed.",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.PruneInactiveResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid input if the request body is malformed or required keys are missing.",
+                        "description": "Malformed body, invalid olderThan, or missing confirm=true.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Peer not found if no peer matches the provided client_public_key.",
+                    "401": {
+                        "description": "Missing or wrong admin token.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error if .conf file generation fails for other reasons.",
+                        "description": "Peer listing failed.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
                     "503": {
-                        "description": "Service unavailable if a WireGuard command (e.g., during peer data fetch) times out.",
+                        "description": "Pruning is not enabled, maintenance mode is on, or a WireGuard command timed out.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
@@ -163,45 +184,131 @@ const docTemplate = `{
                 }
             }
         },
-        "/configs/delete": {
+        "/admin/refresh": {
             "post": {
-                "description": "Removes a peer from the WireGuard interface using its public key.",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Drops the cached /readyz result and Prometheus peer gauges, reloads peer metadata from PEER_METADATA_FILE and recomputes the /stats snapshot,\nso out-of-band changes made with ` + "`" + `wg` + "`" + ` or by editing the metadata file become visible immediately.\nRequires ` + "`" + `Authorization: Bearer \u003cADMIN_TOKEN\u003e` + "`" + `; the endpoint is not registered when ADMIN_TOKEN is unset.",
                 "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Force-refresh all caches",
+                "responses": {
+                    "200": {
+                        "description": "Caches refreshed; includes the current peer count.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.RefreshResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Metadata reload or peer listing failed.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs": {
+            "get": {
+                "description": "Retrieves a list of all currently configured WireGuard peers. Private keys of peers are not included.\nWith ` + "`" + `?name=\u003cprefix\u003e` + "`" + `, only peers whose metadata name starts with the prefix (case-insensitive) are returned.\nWith one or more ` + "`" + `?tag=` + "`" + `, only peers whose metadata carries every listed tag are returned (e.g. ` + "`" + `?tag=env:staging\u0026tag=team:ops` + "`" + `).\nWith ` + "`" + `?fields=publicKey,allowedIps` + "`" + `, each object contains only the listed fields.\nWith ` + "`" + `?createdAfter=` + "`" + ` and/or ` + "`" + `?createdBefore=` + "`" + ` (RFC 3339), only peers whose metadata createdAt lies in [createdAfter, createdBefore) are returned, oldest first.\nWith ` + "`" + `?maxAllowedIps=N` + "`" + `, each peer's allowedIps is cut to the first N entries and ` + "`" + `allowedIpsMore` + "`" + ` counts the rest; fetch the peer itself for the full list.\nWith ` + "`" + `?format=csv` + "`" + `, the peers are returned as ` + "`" + `text/csv` + "`" + ` with the columns publicKey, allowedIps, endpoint, latestHandshake, rx, tx and keepalive; preshared keys are never included.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
                 "tags": [
                     "configs"
                 ],
-                "summary": "Delete a peer configuration",
+                "summary": "List all peer configurations",
                 "parameters": [
                     {
-                        "description": "Public key of the peer to delete.",
-                        "name": "deleteRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.DeleteConfigRequest"
-                        }
+                        "type": "string",
+                        "description": "Metadata name prefix to filter by (case-insensitive).",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Metadata tag the peer must carry; repeat for several (AND).",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated JSON field names to include (e.g. publicKey,allowedIps,latestHandshake).",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only peers created at or after this RFC 3339 time.",
+                        "name": "createdAfter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only peers created before this RFC 3339 time.",
+                        "name": "createdBefore",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of allowedIps entries per peer (positive).",
+                        "name": "maxAllowedIps",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Response format.",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Peer deleted successfully (No Content).",
+                    "200": {
+                        "description": "A list of peer configurations.",
                         "schema": {
-                            "type": "null"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid input (e.g., empty public key or malformed JSON).",
-                        "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Peer not found (only if service layer can reliably detect this for delete operations).",
+                        "description": "Unknown field name in 'fields', invalid 'maxAllowedIps', an invalid creation window, a combination of 'name', 'tag' and the creation window, an unknown 'format', or 'fields' with format=csv.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
@@ -219,11 +326,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/configs/get": {
+            },
             "post": {
-                "description": "Retrieves detailed configuration for a specific peer identified by its public key. The peer's private key is not included.",
+                "description": "Adds a new peer. The server generates cryptographic keys for the peer.\nThe request body should specify AllowedIPs and optionally PreSharedKey and PersistentKeepalive.\nBare addresses in AllowedIPs become /32 (IPv4) or /128 (IPv6) unless NORMALIZE_BARE_IPS=false, in which case they are rejected.\nIf ADDRESS_POOL_CIDR is set and AllowedIPs is empty, the next free pool address is allocated and returned in ` + "`" + `assignedAddress` + "`" + `.\nThe response includes the full peer configuration, including the server-generated PrivateKey, which the client must securely store. It is sent with ` + "`" + `Cache-Control: no-store` + "`" + `.\nIt also carries ` + "`" + `server: {publicKey, endpoint}` + "`" + `, so the client config can be built without another request.\nTo import an existing peer instead (e.g. when migrating), pass public_key and optionally the matching private_key.\nAn imported private key is verified against the public key, echoed in the response and never stored.\nSet generate_preshared_key to have the server generate a preshared key when none is given; it is returned in ` + "`" + `preSharedKey` + "`" + `.",
                 "consumes": [
                     "application/json"
                 ],
@@ -233,45 +338,57 @@ const docTemplate = `{
                 "tags": [
                     "configs"
                 ],
-                "summary": "Get configuration by public key",
+                "summary": "Create new peer with server-generated keys",
                 "parameters": [
                     {
-                        "description": "Public key to retrieve configuration for.",
-                        "name": "getRequest",
+                        "description": "Peer settings for creation (keys will be generated by server unless public_key is given).",
+                        "name": "peerRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.GetConfigRequest"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.CreatePeerRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Peer's configuration.",
+                    "201": {
+                        "description": "Peer created successfully. The response includes the generated or imported private key.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
                         }
                     },
                     "400": {
-                        "description": "Invalid input (e.g., empty public key or malformed JSON).",
+                        "description": "Invalid input if the request body is malformed or contains invalid data.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Peer not found.",
+                    "409": {
+                        "description": "A peer with the imported public key already exists, or the address pool is exhausted.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "An imported key pair does not match, or an AllowedIPs entry lies outside ALLOWED_IP_RANGES.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error.",
+                        "description": "Internal server error if peer creation or key generation fails.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
                     "503": {
-                        "description": "Service unavailable (WireGuard timeout).",
+                        "description": "Service unavailable if a WireGuard command times out.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
@@ -279,9 +396,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/configs/rotate": {
+        "/configs/apply": {
             "post": {
-                "description": "Rotates peer's keys. Server generates new keys. Old peer removed, new one created preserving AllowedIPs \u0026 Keepalive. Response includes new PrivateKey (client must store it).",
+                "description": "Reconciles the interface with a full desired-state document: missing peers are created and changed peers are updated.\nPeers not in the document are deleted only with ` + "`" + `?prune=true` + "`" + `; otherwise they are left untouched.\nAn empty presharedKey or zero keepalive leaves the existing value of a peer unchanged.",
                 "consumes": [
                     "application/json"
                 ],
@@ -291,39 +408,54 @@ const docTemplate = `{
                 "tags": [
                     "configs"
                 ],
-                "summary": "Rotate peer key",
+                "summary": "Apply a desired peer set",
                 "parameters": [
                     {
-                        "description": "Public key of the peer to rotate.",
-                        "name": "rotateRequest",
+                        "description": "Complete desired peer set.",
+                        "name": "desiredState",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.RotatePeerRequest"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/wgMicro_api_internal_domain.DesiredPeer"
+                            }
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Delete peers that are not in the document.",
+                        "name": "prune",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "New peer configuration including new PrivateKey.",
+                        "description": "Summary of the applied changes.",
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ApplyResult"
                         }
                     },
                     "400": {
-                        "description": "Invalid input (e.g., empty public key or malformed JSON).",
+                        "description": "Malformed JSON.",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Peer not found.",
+                    "422": {
+                        "description": "Invalid desired state (missing or duplicate publicKey, overlapping AllowedIPs or AllowedIPs outside ALLOWED_IP_RANGES).",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error (key rotation fails).",
+                        "description": "Internal server error (changes made before the failure are kept).",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
@@ -337,9 +469,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/configs/update-allowed-ips": {
+        "/configs/bulk-rotate": {
             "post": {
-                "description": "Replaces the list of allowed IP addresses for an existing peer, identified by its public key.",
+                "description": "Responds 200 if all rotations succeed and 207 if at least one fails. A result whose old peer could not be removed carries both the error and the new peer.\nResponds 200 if all rotations succeed and 207 if at least one fails.\nThe response contains new private keys: treat it as sensitive. It is never logged and is sent with Cache-Control: no-store.",
                 "consumes": [
                     "application/json"
                 ],
@@ -349,32 +481,75 @@ const docTemplate = `{
                 "tags": [
                     "configs"
                 ],
-                "summary": "Update allowed IPs for a peer",
+                "summary": "Rotate keys of several peers",
                 "parameters": [
                     {
-                        "description": "Public key and new list of allowed IPs for the peer.",
-                        "name": "updateRequest",
+                        "description": "Public keys of the peers to rotate.",
+                        "name": "bulkRotateRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.UpdateAllowedIpsRequest"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.BulkRotateRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Allowed IPs updated successfully (No body content in response)."
+                        "description": "All peers rotated.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.BulkRotateResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Some rotations failed; see per-key errors.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.BulkRotateResponse"
+                        }
                     },
                     "400": {
-                        "description": "Invalid input (e.g., missing public key or malformed body).",
+                        "description": "Invalid input (e.g., empty key list or malformed JSON).",
                         "schema": {
                             "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
+                    }
+                }
+            }
+        },
+        "/configs/bulk-rotate-psk": {
+            "post": {
+                "description": "Generates a fresh preshared key for every configured peer and applies it, e.g. after a PSK compromise.\nEach peer is rotated as by a single preshared key rotation; public keys, AllowedIPs and keepalive are unchanged.\nResponds 200 if all rotations succeed and 207 if at least one fails.\nThe response maps each public key to its new preshared key: treat it as sensitive. It is never logged and is sent with Cache-Control: no-store.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Rotate the preshared keys of all peers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "All preshared keys rotated.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.BulkRotatePSKResponse"
+                        }
                     },
-                    "404": {
-                        "description": "Peer not found.",
+                    "207": {
+                        "description": "Some rotations failed; see failed.",
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.BulkRotatePSKResponse"
                         }
                     },
                     "500": {
@@ -392,176 +567,2553 @@ const docTemplate = `{
                 }
             }
         },
-        "/healthz": {
+        "/configs/by-id/{peerId}": {
             "get": {
-                "description": "Indicates if the application process is running and responsive.\nA 200 OK response means the service is live.",
+                "description": "Same as GET /configs/{publicKey}, looked up by the short ` + "`" + `peerId` + "`" + ` included in peer responses\n(16 base32 characters of the SHA-256 of the public key) instead of the base64 key.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "configs"
+                ],
+                "summary": "Get configuration by peer ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Peer ID, e.g. h4zcuwakognvtzdi (case-insensitive).",
+                        "name": "peerId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated JSON field names to include (e.g. publicKey,allowedIps).",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
                 ],
-                "summary": "Liveness probe for the service",
                 "responses": {
                     "200": {
-                        "description": "Service is live.",
+                        "description": "Peer's configuration.",
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.HealthResponse"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed peer ID or unknown field name.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No peer has this ID.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/readyz": {
+        "/configs/by-ip": {
             "get": {
-                "description": "Indicates if the application is ready to accept and process new requests.\nThis typically involves checking dependencies like database connections or, in this case, WireGuard utility accessibility.",
+                "description": "Returns the peer whose AllowedIPs contain the given IP address, for when a client's address is known but not its public key.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "configs"
+                ],
+                "summary": "Get configuration by assigned IP",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "IP address to look up, e.g. 10.0.0.5.",
+                        "name": "ip",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
                 ],
-                "summary": "Readiness probe for the service",
                 "responses": {
                     "200": {
-                        "description": "Service is ready to handle requests.",
+                        "description": "Peer whose AllowedIPs contain the address.",
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.ReadinessResponse"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or invalid IP address.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No peer's AllowedIPs contain the address.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "More than one peer's AllowedIPs contain the address.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     },
                     "503": {
-                        "description": "Service is not ready, e.g., WireGuard is inaccessible or command timed out.",
+                        "description": "Service unavailable (WireGuard timeout).",
                         "schema": {
-                            "$ref": "#/definitions/wgMicro_api_internal_domain.ReadinessResponse"
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/client-file": {
+            "post": {
+                "description": "Generates a WireGuard .conf file for a client.\nThe request body must contain the client's existing public key (to identify the peer on the server) and the client's corresponding private key.\nThe API uses these keys along with server configuration (server public key, endpoint) and the specific peer's details (AllowedIPs, PSK from server, Keepalive) to construct the .conf file.\nThe provided client private key is inserted directly into the .conf file. The API does not store this client-provided private key.\nWith ` + "`" + `?encoding=base64` + "`" + `, the file is returned as JSON ` + "`" + `{filename, contentBase64}` + "`" + ` instead of plain text.\nWith ` + "`" + `?encoding=datauri` + "`" + `, it is returned as JSON ` + "`" + `{filename, dataUri}` + "`" + ` where dataUri is a ` + "`" + `data:text/plain;base64,...` + "`" + ` URI usable as a download link.\nAn optional ` + "`" + `persistent_keepalive` + "`" + ` (0-65535) overrides the client's PersistentKeepalive; 0 omits it. The server-side peer is not changed.\nAn optional ` + "`" + `allowed_ips` + "`" + ` list of CIDR prefixes replaces the full-tunnel ` + "`" + `0.0.0.0/0, ::/0` + "`" + ` of the ` + "`" + `[Peer]` + "`" + ` section for split tunneling.\nThe response carries the client's private key and is sent with ` + "`" + `Cache-Control: no-store` + "`" + ` unless CLIENT_FILE_CACHE_CONTROL overrides it.\nUnless CHECK_CLIENT_PRIVATE_KEY=false, a private key that is not 32 base64-encoded bytes is rejected with 400 and an all-zero one with 422.\nWith CONFIG_SIGNING_KEY set, the base64 Ed25519 signature of the .conf content is sent in ` + "`" + `X-Config-Signature` + "`" + ` and, for JSON encodings, in ` + "`" + `signature` + "`" + `.\nWith ` + "`" + `?explain=true` + "`" + ` (JSON encodings only), ` + "`" + `explain` + "`" + ` tells whether DNS, MTU, endpoint and keepalive came from the request, the peer, a server default or the interface.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Generate client .conf file (client provides keys)",
+                "parameters": [
+                    {
+                        "description": "Client's public and private keys needed for .conf generation.",
+                        "name": "clientKeysRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ClientFileRequest"
+                        }
+                    },
+                    {
+                        "enum": [
+                            "raw",
+                            "base64",
+                            "datauri"
+                        ],
+                        "type": "string",
+                        "description": "Response encoding: raw (default), base64 or datauri.",
+                        "name": "encoding",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Annotate defaulted values with their source (base64 and datauri encodings).",
+                        "name": "explain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The WireGuard .conf file content as plain text, domain.ClientFileBase64Response with ?encoding=base64, or domain.ClientFileDataURIResponse with ?encoding=datauri.",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input if the request body is malformed, required keys are missing, the private key or an allowed_ips entry is malformed, the encoding is unknown, or explain is combined with the raw encoding.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found if no peer matches the provided client_public_key.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "The private key is well-formed but weak.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error if .conf file generation fails for other reasons.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable if a WireGuard command (e.g., during peer data fetch) times out.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/delete": {
+            "post": {
+                "description": "Removes a peer from the WireGuard interface using its public key.\nWith ` + "`" + `?verbose=true` + "`" + `, responds with 200 and reports whether the peer existed and was deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Delete a peer configuration",
+                "parameters": [
+                    {
+                        "description": "Public key of the peer to delete.",
+                        "name": "deleteRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.DeleteConfigRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return {deleted, existed} instead of 204.",
+                        "name": "verbose",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deletion result (verbose mode only).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.DeleteConfigResponse"
+                        }
+                    },
+                    "204": {
+                        "description": "Peer deleted successfully (No Content).",
+                        "schema": {
+                            "type": "null"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input (e.g., empty public key or malformed JSON).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found (only if service layer can reliably detect this for delete operations).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/export": {
+            "get": {
+                "description": "Streams all peers as server-side WireGuard ` + "`" + `[Peer]` + "`" + ` blocks, suitable for backups or migrating to another server.\nBlocks are written one peer at a time, so memory use does not grow with the number of peers.\nIf EXPORT_MAX_BYTES is set and reached, the document ends with a truncation comment.\nThe export includes preshared keys and must be handled as a secret.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Export all peers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WireGuard configuration fragment with one [Peer] block per peer.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/get": {
+            "post": {
+                "description": "Retrieves detailed configuration for a specific peer identified by its public key. The peer's private key is not included.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Get configuration by public key",
+                "parameters": [
+                    {
+                        "description": "Public key to retrieve configuration for.",
+                        "name": "getRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.GetConfigRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated JSON field names to include (e.g. publicKey,allowedIps).",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Peer's configuration.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input (e.g., empty public key, malformed JSON or unknown field name).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "wgMicro_api_internal_domain.ClientFileRequest": {
+        },
+        "/configs/plan": {
+            "post": {
+                "description": "Computes the same diff as /configs/apply for the given document and ` + "`" + `prune` + "`" + ` flag, but changes nothing.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Preview applying a desired peer set",
+                "parameters": [
+                    {
+                        "description": "Complete desired peer set.",
+                        "name": "desiredState",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/wgMicro_api_internal_domain.DesiredPeer"
+                            }
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Plan deletion of peers that are not in the document.",
+                        "name": "prune",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Planned changes.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ReconcilePlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed JSON.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid desired state (missing or duplicate publicKey, overlapping AllowedIPs or AllowedIPs outside ALLOWED_IP_RANGES).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/preview": {
+            "post": {
+                "description": "Builds the .conf POST /configs/client-file would return once a peer with the supplied values exists,\nusing the server's public key and endpoint. Nothing is read from or written to the WireGuard interface.\n` + "`" + `dns` + "`" + ` and ` + "`" + `mtu` + "`" + ` override the configured client values; AllowedIPs and keys are validated as on creation.\n` + "`" + `dns` + "`" + ` may have at most MAX_CLIENT_DNS comma-separated entries, each an IP address or a host name.\nThe response carries the private key and is sent with ` + "`" + `Cache-Control: no-store` + "`" + ` unless CLIENT_FILE_CACHE_CONTROL overrides it.\nWith ` + "`" + `?explain=true` + "`" + `, ` + "`" + `explain` + "`" + ` tells whether DNS, MTU, endpoint and keepalive came from the request, the peer, a server default or the interface.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Preview a client .conf for a peer that is not registered yet",
+                "parameters": [
+                    {
+                        "description": "Would-be peer values and the client's private key.",
+                        "name": "previewRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.PreviewConfigRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Annotate defaulted values with their source.",
+                        "name": "explain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Generated .conf content.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.PreviewConfigResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body, key, AllowedIPs entry or DNS list.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "The private key is well-formed but weak.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/rotate": {
+            "post": {
+                "description": "Rotates peer's keys. Server generates new keys. Old peer removed, new one created preserving AllowedIPs \u0026 Keepalive. Response includes new PrivateKey (client must store it).\nPeer metadata (name, description, createdAt) moves to the new public key; an optional ` + "`" + `name` + "`" + ` replaces the stored name.\nLike the create response, it carries ` + "`" + `server: {publicKey, endpoint}` + "`" + ` for building the new client config.\nAn optional ` + "`" + `expectedPublicKey` + "`" + ` makes the rotation conditional: it must equal ` + "`" + `public_key` + "`" + ` (422 otherwise),\nand if the peer no longer has that key, e.g. after a concurrent rotation, 409 is returned instead of 404 and nothing changes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Rotate peer key",
+                "parameters": [
+                    {
+                        "description": "Public key of the peer to rotate and an optional new name.",
+                        "name": "rotateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.RotatePeerRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "New peer configuration including new PrivateKey.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input (e.g., empty public key or malformed JSON).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The peer no longer has expectedPublicKey.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "expectedPublicKey differs from public_key.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error (key rotation fails).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/stale": {
+            "get": {
+                "description": "Returns peers that have never completed a handshake or whose latest handshake is older than ` + "`" + `olderThan` + "`" + `.\nUseful for finding provisioned-but-unused peers. Durations accept Go syntax plus days, e.g. ` + "`" + `7d` + "`" + `, ` + "`" + `24h` + "`" + `, ` + "`" + `30m` + "`" + `, ` + "`" + `1d12h` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "List stale peer configurations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "7d",
+                        "description": "Minimum handshake age for a peer to count as stale.",
+                        "name": "olderThan",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stale peer configurations.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid olderThan duration.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/stats": {
+            "post": {
+                "description": "Returns only the peer's latest handshake, received and transmitted bytes and whether it is online\n(handshake within ONLINE_THRESHOLD_SECONDS), without keys or AllowedIPs, so dashboards can poll it cheaply.\nAnswers 403 when EXPOSE_TRAFFIC_STATS=false, like /stats.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Get live traffic statistics of a peer",
+                "parameters": [
+                    {
+                        "description": "Public key of the peer.",
+                        "name": "getRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.GetConfigRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Peer traffic statistics.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.PeerStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input (e.g., empty or malformed public key).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Traffic statistics are disabled (EXPOSE_TRAFFIC_STATS=false).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/summary": {
+            "get": {
+                "description": "Returns how many peers are online (latest handshake within ONLINE_THRESHOLD_SECONDS), offline, or have never connected,\nso dashboards need not fetch and classify the full list.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Count peers by connection state",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Peer counts.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.PeerSummary"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/top": {
+            "get": {
+                "description": "Returns the ` + "`" + `limit` + "`" + ` peers with the most received (` + "`" + `by=rx` + "`" + `) or transmitted (` + "`" + `by=tx` + "`" + `) bytes, busiest first,\nfor capacity planning. Peers with equal counters are ordered by public key.\nAnswers 403 when EXPOSE_TRAFFIC_STATS=false, like /stats.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "List the peers with the most traffic",
+                "parameters": [
+                    {
+                        "enum": [
+                            "rx",
+                            "tx"
+                        ],
+                        "type": "string",
+                        "default": "rx",
+                        "description": "Traffic direction to rank by.",
+                        "name": "by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Maximum number of peers.",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Peers ordered by traffic.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid by or limit.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Traffic statistics are disabled (EXPOSE_TRAFFIC_STATS=false).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/update-allowed-ips": {
+            "post": {
+                "description": "Replaces the list of allowed IP addresses for an existing peer, identified by its public key.\nBare addresses become /32 (IPv4) or /128 (IPv6) unless NORMALIZE_BARE_IPS=false, in which case they are rejected.\nAn empty list clears all AllowedIPs and requires ` + "`" + `?confirm=true` + "`" + ` unless ALLOW_EMPTY_ALLOWED_IPS_UPDATE=true.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Update allowed IPs for a peer",
+                "parameters": [
+                    {
+                        "description": "Public key and new list of allowed IPs for the peer.",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.UpdateAllowedIpsRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Confirm clearing all AllowedIPs with an empty list.",
+                        "name": "confirm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Allowed IPs updated successfully (No body content in response)."
+                    },
+                    "400": {
+                        "description": "Invalid input (e.g., missing public key, malformed body or unconfirmed empty list).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "An AllowedIPs entry lies outside ALLOWED_IP_RANGES.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/verify-key": {
+            "post": {
+                "description": "Derives the public key from the supplied private key (via 'wg pubkey') and reports whether it matches the supplied public key.\nUseful for a UI to confirm a private key belongs to a registered peer before generating a config.\nThe private key is only used for the derivation; it is neither stored nor logged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Verify a client key pair",
+                "parameters": [
+                    {
+                        "description": "Public key and the private key to check against it.",
+                        "name": "verifyRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.VerifyKeyRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result of the comparison.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.VerifyKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input (e.g., missing keys, malformed JSON or a malformed private key).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (key derivation timed out).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/{publicKey}": {
+            "get": {
+                "description": "Same as POST /configs/get, with the URL-encoded public key in the path.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Get configuration by public key (path)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL-encoded public key of the peer.",
+                        "name": "publicKey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated JSON field names to include (e.g. publicKey,allowedIps).",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Peer's configuration.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed public key or unknown field name.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the peer identified by the URL-encoded public key in the path.\nUnlike POST /configs/delete, a well-formed key that is not configured yields 404.\nWith ` + "`" + `?verbose=true` + "`" + `, responds with 200 and reports whether the peer existed and was deleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Delete a peer configuration (path)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL-encoded public key of the peer.",
+                        "name": "publicKey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return {deleted, existed} instead of 204.",
+                        "name": "verbose",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deletion result (verbose mode only).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.DeleteConfigResponse"
+                        }
+                    },
+                    "204": {
+                        "description": "Peer deleted successfully (No Content).",
+                        "schema": {
+                            "type": "null"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed public key.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Applies an RFC 6902 JSON Patch to the peer identified by the URL-encoded public key and writes only the result with a single ` + "`" + `wg set` + "`" + `.\nPatchable paths are ` + "`" + `/allowedIps` + "`" + ` (also ` + "`" + `/allowedIps/\u003cindex\u003e` + "`" + `, and ` + "`" + `/allowedIps/-` + "`" + ` to append), ` + "`" + `/presharedKey` + "`" + ` and ` + "`" + `/persistentKeepalive` + "`" + `;\nsupported operations are add, remove, replace and test. Removing ` + "`" + `/presharedKey` + "`" + ` or ` + "`" + `/persistentKeepalive` + "`" + ` turns it off.\nRead-only fields such as ` + "`" + `/publicKey` + "`" + `, ` + "`" + `/endpoint` + "`" + ` or the traffic counters are rejected with 422.\nThe patch is all-or-nothing. A result without AllowedIPs requires ` + "`" + `?confirm=true` + "`" + ` unless ALLOW_EMPTY_ALLOWED_IPS_UPDATE=true.",
+                "consumes": [
+                    "application/json-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Patch a peer (JSON Patch)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL-encoded public key of the peer.",
+                        "name": "publicKey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON Patch operations, applied in order.",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/wgMicro_api_internal_domain.PatchOperation"
+                            }
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Confirm a patch that removes all AllowedIPs.",
+                        "name": "confirm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Peer after the patch.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed key, patch or value, or an unconfirmed empty AllowedIPs list.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A test operation did not match.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "The patch touches a read-only field, or an AllowedIPs entry lies outside ALLOWED_IP_RANGES.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/{publicKey}/allowed-ips": {
+            "put": {
+                "description": "Same as POST /configs/update-allowed-ips, with the URL-encoded public key in the path.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Update allowed IPs for a peer (path)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL-encoded public key of the peer.",
+                        "name": "publicKey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New list of allowed IPs for the peer.",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.AllowedIpsUpdate"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Confirm clearing all AllowedIPs with an empty list.",
+                        "name": "confirm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Allowed IPs updated successfully (No body content in response)."
+                    },
+                    "400": {
+                        "description": "Malformed public key or body, or unconfirmed empty list.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "An AllowedIPs entry lies outside ALLOWED_IP_RANGES.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/{publicKey}/full": {
+            "post": {
+                "description": "Returns the peer identified by the URL-encoded public key together with its generated client .conf,\nas POST /configs/client-file would produce it for the supplied private key.\nThe private key is only inserted into the .conf; it is neither stored nor logged.\nThe response is sent with ` + "`" + `Cache-Control: no-store` + "`" + ` unless CLIENT_FILE_CACHE_CONTROL overrides it.\nWith CONFIG_SIGNING_KEY set, the Ed25519 signature of the .conf is returned in ` + "`" + `X-Config-Signature` + "`" + ` and ` + "`" + `signature` + "`" + `.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Get a peer's configuration and client .conf in one call",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL-encoded public key of the peer.",
+                        "name": "publicKey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Client's private key and an optional keepalive override.",
+                        "name": "fullRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.FullConfigRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Peer configuration and .conf content.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.FullConfigResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed public key, body or private key.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "The private key is well-formed but weak.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/configs/{publicKey}/rotate": {
+            "post": {
+                "description": "Same as POST /configs/rotate, with the URL-encoded public key in the path. An optional ` + "`" + `name` + "`" + ` query parameter replaces the stored metadata name.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configs"
+                ],
+                "summary": "Rotate peer key (path)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL-encoded public key of the peer to rotate.",
+                        "name": "publicKey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "New metadata name for the rotated peer.",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404.",
+                        "name": "X-WG-Interface",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "New peer configuration including new PrivateKey.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed public key.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Peer not found.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error (key rotation fails).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Indicates if the application process is running and responsive.\nA 200 OK response means the service is live.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe for the service",
+                "responses": {
+                    "200": {
+                        "description": "Service is live.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/keys/fingerprint": {
+            "post": {
+                "description": "Returns a short, stable fingerprint of a WireGuard public key for display in UIs and logs:\nthe first 8 bytes of the SHA-256 of the decoded key, as colon-separated hex groups.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Fingerprint a public key",
+                "parameters": [
+                    {
+                        "description": "Public key to fingerprint.",
+                        "name": "fingerprintRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.KeyFingerprintRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Fingerprint of the key.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.KeyFingerprintResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input (e.g., missing or malformed public key).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Indicates if the application is ready to accept and process new requests.\nThis typically involves checking dependencies like database connections or, in this case, WireGuard utility accessibility.\nIf READY_REQUIRES_PEERS is enabled, an interface without any peers is reported as not ready.\nResults are reused for READINESS_CACHE_MS (a quarter of it after a failure), so rapid probes may see a slightly old result.\nWith READY_REJECT_WRITES, a cached failure also makes changes to /configs answer 503 with Retry-After until it expires.\nWith ` + "`" + `?verbose=true` + "`" + `, the response also includes the ` + "`" + `wg` + "`" + ` version and the interface name and,\nif READY_CHECK_LISTEN_PORT is enabled, whether the WireGuard UDP listen port is bound.\nWith ` + "`" + `?checkWrite=true` + "`" + ` and READY_ALLOW_WRITE_CHECK enabled, the probe also adds and removes a throwaway peer\nwith a random key to verify that the interface can be modified (e.g. CAP_NET_ADMIN is present).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe for the service",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include wgVersion, interface and listen port state in the response.",
+                        "name": "verbose",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also verify write capability (requires READY_ALLOW_WRITE_CHECK).",
+                        "name": "checkWrite",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Service is ready to handle requests.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ReadinessResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service is not ready, e.g., WireGuard is inaccessible or command timed out.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ReadinessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/server": {
+            "get": {
+                "description": "Returns the instance name and description (SERVER_NAME, SERVER_DESCRIPTION), the server's public key, endpoint, listen port, configured interface addresses\nand the result of the startup check comparing them with the live interface addresses.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "Get server interface information",
+                "responses": {
+                    "200": {
+                        "description": "Server interface information.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ServerInfo"
+                        }
+                    }
+                }
+            }
+        },
+        "/server/collector": {
+            "get": {
+                "description": "Reports the background stats collector's last success time, last error and consecutive failures,\nso operators can tell whether /stats is stale because collection keeps failing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "Get stats collector status",
+                "responses": {
+                    "200": {
+                        "description": "Collector status.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.CollectorStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/server/listen-port": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Runs ` + "`" + `wg set \u003cinterface\u003e listen-port \u003cport\u003e` + "`" + ` and uses the new port in the endpoint of client configs generated from then on.\nExisting client configs keep the old endpoint and must be regenerated. The change is lost when the interface is recreated unless WG_PERSIST_CHANGES saves it; SERVER_LISTEN_PORT is not updated.\nRequires ` + "`" + `Authorization: Bearer \u003cADMIN_TOKEN\u003e` + "`" + `; the endpoint is not registered when ADMIN_TOKEN is unset.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "Change the interface listen port",
+                "parameters": [
+                    {
+                        "description": "New listen port.",
+                        "name": "listenPortRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ListenPortRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Listen port changed.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ListenPortResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body or missing port.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Port outside 1-65535.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "The interface rejected the change.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable (WireGuard timeout), or changing the port is not available.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/server/publickey": {
+            "get": {
+                "description": "Returns only the server's public key as plain text, without JSON or a trailing newline,\nso it can be piped straight into client config scripts.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "Get server public key",
+                "responses": {
+                    "200": {
+                        "description": "Server public key.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "description": "Returns the peer count and total traffic from the latest background collection.\nThe data may be up to one collection interval old; see /server/collector for collector health.\nrxDelta/txDelta (total and per peer) are the bytes moved between the previous collection (deltaSince) and this one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "Get aggregate interface statistics",
+                "responses": {
+                    "200": {
+                        "description": "Latest collected statistics.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.InterfaceStats"
+                        }
+                    },
+                    "403": {
+                        "description": "Traffic statistics are disabled (EXPOSE_TRAFFIC_STATS=false).",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "No successful collection yet.",
+                        "schema": {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "wgMicro_api_internal_domain.AddressDrift": {
+            "type": "object",
+            "properties": {
+                "checked": {
+                    "description": "Checked is false when the live addresses could not be obtained; the other fields are then incomplete.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "configured": {
+                    "description": "Configured lists the addresses from SERVER_INTERFACE_ADDRESSES / WG_ACTUAL_INTERFACE_ADDRESSES.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "description": "Error explains why the live addresses could not be obtained, if Checked is false.",
+                    "type": "string"
+                },
+                "inSync": {
+                    "description": "InSync is true when configured and live addresses are the same set.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "live": {
+                    "description": "Live lists the addresses currently assigned to the interface.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "missing": {
+                    "description": "Missing lists configured addresses that are not present on the interface.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "unexpected": {
+                    "description": "Unexpected lists live addresses that are not in the configuration.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.AllowedIpsUpdate": {
+            "type": "object",
+            "properties": {
+                "allowedIps": {
+                    "description": "AllowedIps is the new list of IP networks (CIDR notation) to set for the peer.\nThis will replace the existing list. An empty list might remove all allowed IPs.\nExample: [\"10.0.0.3/32\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.0.0.2/32"
+                    ]
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ApplyResult": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "deleted": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "unchanged": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.BulkRotatePSKResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "description": "Failed maps public keys of peers whose preshared key could not be rotated to the error.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "presharedKeys": {
+                    "description": "PresharedKeys maps the public key of every rotated peer to its new preshared key.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.BulkRotateRequest": {
+            "type": "object",
+            "required": [
+                "publicKeys"
+            ],
+            "properties": {
+                "publicKeys": {
+                    "description": "PublicKeys are the current public keys of the peers to rotate.",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.BulkRotateResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/wgMicro_api_internal_domain.BulkRotateResult"
+                    }
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.BulkRotateResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error describes why rotation failed for this peer.",
+                    "type": "string"
+                },
+                "newConfig": {
+                    "description": "NewConfig is the rotated peer configuration, including the new PrivateKey. It is also set next to\nError when the new peer was created but the old one could not be removed.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    ]
+                },
+                "publicKey": {
+                    "description": "PublicKey is the peer's public key before rotation, as given in the request.",
+                    "type": "string"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ClientConfigExplanation": {
+            "type": "object",
+            "properties": {
+                "dns": {
+                    "$ref": "#/definitions/wgMicro_api_internal_domain.ExplainedString"
+                },
+                "endpoint": {
+                    "$ref": "#/definitions/wgMicro_api_internal_domain.ExplainedString"
+                },
+                "mtu": {
+                    "$ref": "#/definitions/wgMicro_api_internal_domain.ExplainedInt"
+                },
+                "persistentKeepalive": {
+                    "$ref": "#/definitions/wgMicro_api_internal_domain.ExplainedInt"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ClientFileRequest": {
+            "type": "object",
+            "required": [
+                "client_private_key",
+                "client_public_key"
+            ],
+            "properties": {
+                "allowed_ips": {
+                    "description": "AllowedIps optionally replaces the full-tunnel ` + "`" + `0.0.0.0/0, ::/0` + "`" + ` routed through the VPN by the client,\ne.g. only the VPN subnet for split tunneling. Each entry must be a CIDR prefix.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.8.0.0/24"
+                    ]
+                },
+                "client_private_key": {
+                    "description": "Client's private key, base64 encoded",
+                    "type": "string"
+                },
+                "client_public_key": {
+                    "description": "Client's public key, base64 encoded",
+                    "type": "string"
+                },
+                "persistent_keepalive": {
+                    "description": "PersistentKeepalive optionally overrides the keepalive written to the client config (0 omits it),\nindependently of the server-side peer's value. The server peer is not changed.",
+                    "type": "integer",
+                    "maximum": 65535,
+                    "minimum": 0,
+                    "example": 25
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.CollectorStatus": {
+            "type": "object",
+            "properties": {
+                "consecutiveFailures": {
+                    "description": "ConsecutiveFailures counts failed collections since the last success.",
+                    "type": "integer"
+                },
+                "intervalSeconds": {
+                    "description": "IntervalSeconds is the configured collection interval.",
+                    "type": "integer",
+                    "example": 30
+                },
+                "lastError": {
+                    "description": "LastError is the error of the most recent failed collection.",
+                    "type": "string"
+                },
+                "lastErrorAt": {
+                    "description": "LastErrorAt is when the most recent failed collection happened; absent if none failed.",
+                    "type": "string"
+                },
+                "lastSuccessAt": {
+                    "description": "LastSuccessAt is when stats were last collected successfully; absent if never.",
+                    "type": "string"
+                },
+                "stale": {
+                    "description": "Stale is true if there has been no successful collection within two intervals.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.Config": {
+            "type": "object",
+            "properties": {
+                "allowedIps": {
+                    "description": "AllowedIps is a list of IP networks (CIDR notation) from which this peer is allowed to send traffic\nand to which traffic will be routed through this peer.\nExample: [\"10.0.0.2/32\", \"192.168.2.0/24\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "allowedIpsMore": {
+                    "description": "AllowedIpsMore is how many AllowedIps entries were cut from this list response by ?maxAllowedIps.\nZero (omitted) means AllowedIps is complete.\nExample: 3",
+                    "type": "integer"
+                },
+                "assignedAddress": {
+                    "description": "AssignedAddress is the host address allocated from the address pool when the peer was created\nwithout AllowedIPs. It is only set in create responses; AllowedIps holds the same address.\nExample: \"10.0.0.2/32\"",
+                    "type": "string"
+                },
+                "endpoint": {
+                    "description": "Endpoint is the remote IP address and port to which this peer connects (if this config represents a client)\nor the public IP and port of this peer (if this config represents a remote peer from server's perspective).\nIf \"(none)\" is shown by 'wg show dump', this will be an empty string.\nomitempty is used as it might not always be set or known.\nExample: \"192.0.2.1:51820\"",
+                    "type": "string"
+                },
+                "latestHandshake": {
+                    "description": "LatestHandshake is the timestamp (UNIX seconds) of the most recent handshake with this peer.\nA value of 0 indicates no handshake has occurred.\nomitempty is used as it's state information.",
+                    "type": "integer"
+                },
+                "metadata": {
+                    "description": "Metadata is the peer's sidecar information from the metadata store, if any.\nIt is not part of 'wg show dump' output.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.PeerMetadata"
+                        }
+                    ]
+                },
+                "peerId": {
+                    "description": "PeerID is a short URL-safe identifier derived from PublicKey, for GET /configs/by-id/{peerId}.\nIt is not part of 'wg show dump' output and changes when the key is rotated.\nExample: \"h4zcuwakognvtzdi\"",
+                    "type": "string"
+                },
+                "persistentKeepalive": {
+                    "description": "PersistentKeepalive is the interval in seconds for sending keepalive packets to the peer.\n\"off\" from 'wg show dump' is represented as 0.\nomitempty is used as it might not be set.\nExample: 25",
+                    "type": "integer"
+                },
+                "preSharedKey": {
+                    "description": "PreSharedKey is an optional pre-shared key for an extra layer of security.\nIf \"(none)\" is shown by 'wg show dump', this will be an empty string.\nomitempty is used as it's optional.\nExample: \"s1t2u3v4...+Y9=\"",
+                    "type": "string"
+                },
+                "privateKey": {
+                    "description": "PrivateKey is the client's private key. This is NOT part of 'wg show dump' output\nbut is essential for generating a client .conf file.\nIt's populated by the service when new keys are generated (e.g., during Rotate)\nor expected from the user if they manage keys externally and provide it for config generation.\nomitempty is used so it's not shown in API responses unless explicitly set (e.g. on Rotate response).",
+                    "type": "string"
+                },
+                "publicKey": {
+                    "description": "PublicKey is the peer's public key. This is a mandatory field for identifying a peer.\nExample: \"a1b2c3d4...+Z0=\"",
+                    "type": "string"
+                },
+                "receiveBytes": {
+                    "description": "ReceiveBytes is the total number of bytes received from this peer.\nomitempty is used as it's state information.",
+                    "type": "integer"
+                },
+                "server": {
+                    "description": "Server is the server's public key and endpoint, for building the client config right away.\nIt is only set in create and rotate responses.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ServerIdentity"
+                        }
+                    ]
+                },
+                "transmitBytes": {
+                    "description": "TransmitBytes is the total number of bytes transmitted to this peer.\nomitempty is used as it's state information.",
+                    "type": "integer"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.CreatePeerRequest": {
+            "type": "object",
+            "properties": {
+                "allowed_ips": {
+                    "description": "AllowedIps is a list of IP networks (CIDR notation) for the new peer. Can be empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "description": "Description is an optional note stored in the metadata store (not a WireGuard field).",
+                    "type": "string"
+                },
+                "generate_preshared_key": {
+                    "description": "GeneratePresharedKey asks the server to generate a pre-shared key when PreSharedKey is empty.\nThe generated key is returned in the response. Only supported when the server generates the keys.",
+                    "type": "boolean"
+                },
+                "name": {
+                    "description": "Name is an optional friendly name stored in the metadata store (not a WireGuard field).",
+                    "type": "string"
+                },
+                "persistent_keepalive": {
+                    "description": "PersistentKeepalive is an optional interval in seconds for keepalive packets.",
+                    "type": "integer"
+                },
+                "preshared_key": {
+                    "description": "PreSharedKey is an optional pre-shared key for the new peer.",
+                    "type": "string"
+                },
+                "private_key": {
+                    "description": "PrivateKey optionally accompanies PublicKey. It must match PublicKey, is only echoed back\nin the response for immediate client config generation and is never stored.",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "PublicKey optionally imports an existing peer identity instead of generating new keys,\ne.g. when migrating peers from another server.\nRequired if PrivateKey is set.",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Tags are optional labels stored in the metadata store (not a WireGuard field).",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.DeleteConfigRequest": {
+            "type": "object",
+            "required": [
+                "public_key"
+            ],
+            "properties": {
+                "public_key": {
+                    "description": "PublicKey is the peer's public key to delete.",
+                    "type": "string"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.DeleteConfigResponse": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "existed": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.DesiredPeer": {
+            "type": "object",
+            "properties": {
+                "allowedIps": {
+                    "description": "AllowedIps is the complete desired list of allowed IP networks for the peer.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "keepalive": {
+                    "description": "Keepalive is the optional desired persistent keepalive interval in seconds.",
+                    "type": "integer"
+                },
+                "presharedKey": {
+                    "description": "PresharedKey is the optional desired pre-shared key.",
+                    "type": "string"
+                },
+                "publicKey": {
+                    "description": "PublicKey identifies the peer.",
+                    "type": "string"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is a stable, machine-readable error code. It is set for unexpected internal errors,\nwhose message may be replaced by a generic one when error sanitization is enabled.\nExample: \"internal_error\"",
+                    "type": "string",
+                    "example": "internal_error"
+                },
+                "error": {
+                    "description": "Error contains a human-readable message describing the error.\nThis message is intended for the API consumer.\nExample: \"Peer not found\" or \"Invalid input: public key is malformed\"",
+                    "type": "string",
+                    "example": "Peer not found"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ExplainedInt": {
+            "type": "object",
+            "properties": {
+                "source": {
+                    "type": "string",
+                    "example": "interface-fallback"
+                },
+                "value": {
+                    "type": "integer",
+                    "example": 1420
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ExplainedString": {
+            "type": "object",
+            "properties": {
+                "source": {
+                    "type": "string",
+                    "example": "server-default"
+                },
+                "value": {
+                    "type": "string",
+                    "example": "1.1.1.1"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.FullConfigRequest": {
+            "type": "object",
+            "required": [
+                "client_private_key"
+            ],
+            "properties": {
+                "client_private_key": {
+                    "description": "Client's private key, base64 encoded",
+                    "type": "string"
+                },
+                "persistent_keepalive": {
+                    "description": "PersistentKeepalive optionally overrides the keepalive written to the .conf, as in ClientFileRequest.",
+                    "type": "integer",
+                    "maximum": 65535,
+                    "minimum": 0,
+                    "example": 25
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.FullConfigResponse": {
+            "type": "object",
+            "properties": {
+                "conf": {
+                    "description": "Conf is the .conf file content, including the supplied private key.",
+                    "type": "string"
+                },
+                "config": {
+                    "description": "Config is the peer as returned by GET /configs/{publicKey}.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.Config"
+                        }
+                    ]
+                },
+                "filename": {
+                    "description": "Filename is the sanitized file name /configs/client-file would send in Content-Disposition.",
+                    "type": "string",
+                    "example": "peer.conf"
+                },
+                "signature": {
+                    "description": "Signature is the base64 Ed25519 signature of Conf, present when CONFIG_SIGNING_KEY is set.",
+                    "type": "string"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.GetConfigRequest": {
+            "type": "object",
+            "required": [
+                "public_key"
+            ],
+            "properties": {
+                "public_key": {
+                    "description": "PublicKey is the peer's public key to retrieve configuration for.",
+                    "type": "string"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "Status indicates the health of the service.\nExpected value is \"ok\" for a healthy service.\nExample: \"ok\"",
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.InterfaceStats": {
+            "type": "object",
+            "properties": {
+                "collectedAt": {
+                    "description": "CollectedAt is when the snapshot was taken.",
+                    "type": "string"
+                },
+                "deltaSince": {
+                    "description": "DeltaSince is when the previous snapshot, which the deltas are measured against, was taken.\nIt is absent until two snapshots exist.",
+                    "type": "string"
+                },
+                "peerCount": {
+                    "description": "PeerCount is the number of peers on the interface.",
+                    "type": "integer",
+                    "example": 12
+                },
+                "peers": {
+                    "description": "Peers lists the per-peer traffic between the previous snapshot and this one.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/wgMicro_api_internal_domain.PeerTrafficDelta"
+                    }
+                },
+                "receiveBytes": {
+                    "description": "ReceiveBytes is the sum of bytes received from all peers.",
+                    "type": "integer"
+                },
+                "rxDelta": {
+                    "description": "RxDelta is the sum of the peers' RxDelta.",
+                    "type": "integer"
+                },
+                "transmitBytes": {
+                    "description": "TransmitBytes is the sum of bytes sent to all peers.",
+                    "type": "integer"
+                },
+                "txDelta": {
+                    "description": "TxDelta is the sum of the peers' TxDelta.",
+                    "type": "integer"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.KeyFingerprintRequest": {
             "type": "object",
             "required": [
-                "client_private_key",
-                "client_public_key"
+                "publicKey"
             ],
             "properties": {
-                "client_private_key": {
-                    "description": "Client's private key, base64 encoded",
+                "publicKey": {
+                    "description": "PublicKey is the base64-encoded WireGuard public key.",
                     "type": "string"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.KeyFingerprintResponse": {
+            "type": "object",
+            "properties": {
+                "fingerprint": {
+                    "description": "Fingerprint is a short, stable identifier for the key, suitable for logs and UIs.",
+                    "type": "string",
+                    "example": "3f2a:9c01:b7e4:5d68"
                 },
-                "client_public_key": {
-                    "description": "Client's public key, base64 encoded",
+                "publicKey": {
                     "type": "string"
                 }
             }
         },
-        "wgMicro_api_internal_domain.Config": {
+        "wgMicro_api_internal_domain.ListenPortRequest": {
+            "type": "object",
+            "required": [
+                "port"
+            ],
+            "properties": {
+                "port": {
+                    "description": "Port is the new UDP listen port, 1-65535.",
+                    "type": "integer",
+                    "example": 51821
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ListenPortResponse": {
             "type": "object",
             "properties": {
-                "allowedIps": {
-                    "description": "AllowedIps is a list of IP networks (CIDR notation) from which this peer is allowed to send traffic\nand to which traffic will be routed through this peer.\nExample: [\"10.0.0.2/32\", \"192.168.2.0/24\"]",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
                 "endpoint": {
-                    "description": "Endpoint is the remote IP address and port to which this peer connects (if this config represents a client)\nor the public IP and port of this peer (if this config represents a remote peer from server's perspective).\nIf \"(none)\" is shown by 'wg show dump', this will be an empty string.\nomitempty is used as it might not always be set or known.\nExample: \"192.0.2.1:51820\"",
-                    "type": "string"
+                    "description": "Endpoint is the host:port written into client configs from now on.",
+                    "type": "string",
+                    "example": "203.0.113.1:51821"
                 },
-                "latestHandshake": {
-                    "description": "LatestHandshake is the timestamp (UNIX seconds) of the most recent handshake with this peer.\nA value of 0 indicates no handshake has occurred.\nomitempty is used as it's state information.",
-                    "type": "integer"
+                "listenPort": {
+                    "type": "integer",
+                    "example": 51821
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.MaintenanceRequest": {
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "description": "Enabled turns maintenance mode on (true) or off (false).",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.MaintenanceResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "Enabled reports whether mutating /configs endpoints currently answer 503.",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.PatchOperation": {
+            "type": "object",
+            "required": [
+                "op",
+                "path"
+            ],
+            "properties": {
+                "op": {
+                    "description": "Op is \"add\", \"remove\", \"replace\" or \"test\".",
+                    "type": "string",
+                    "example": "replace"
                 },
-                "persistentKeepalive": {
-                    "description": "PersistentKeepalive is the interval in seconds for sending keepalive packets to the peer.\n\"off\" from 'wg show dump' is represented as 0.\nomitempty is used as it might not be set.\nExample: 25",
-                    "type": "integer"
+                "path": {
+                    "description": "Path is a JSON Pointer to a patchable field: /allowedIps, /allowedIps/\u003cindex\u003e, /allowedIps/- (append),\n/presharedKey or /persistentKeepalive.",
+                    "type": "string",
+                    "example": "/persistentKeepalive"
                 },
-                "preSharedKey": {
-                    "description": "PreSharedKey is an optional pre-shared key for an extra layer of security.\nIf \"(none)\" is shown by 'wg show dump', this will be an empty string.\nomitempty is used as it's optional.\nExample: \"s1t2u3v4...+Y9=\"",
+                "value": {
+                    "description": "Value is the new value for add and replace, or the expected one for test. It is ignored by remove.",
+                    "type": "object"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.PeerMetadata": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "description": "CreatedAt is when the peer was first created through this API.\nIt is kept across key rotations.",
                     "type": "string"
                 },
-                "privateKey": {
-                    "description": "PrivateKey is the client's private key. This is NOT part of 'wg show dump' output\nbut is essential for generating a client .conf file.\nIt's populated by the service when new keys are generated (e.g., during Rotate)\nor expected from the user if they manage keys externally and provide it for config generation.\nomitempty is used so it's not shown in API responses unless explicitly set (e.g. on Rotate response).",
+                "description": {
+                    "description": "Description is an optional free-form note about the peer.",
+                    "type": "string"
+                },
+                "lastRotatedAt": {
+                    "description": "LastRotatedAt is when the peer's keys were last rotated; absent if they never were.",
                     "type": "string"
                 },
+                "name": {
+                    "description": "Name is an optional human-friendly name for the peer.",
+                    "type": "string",
+                    "example": "alice-laptop"
+                },
+                "rotationCount": {
+                    "description": "RotationCount is how many times the peer's keys have been rotated through this API.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "tags": {
+                    "description": "Tags are optional free-form labels such as \"team:ops\" or \"env:staging\".",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "env:staging",
+                        "team:ops"
+                    ]
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.PeerStats": {
+            "type": "object",
+            "properties": {
+                "latestHandshake": {
+                    "description": "LatestHandshake is the time of the last completed handshake; omitted if the peer never connected.",
+                    "type": "string",
+                    "example": "2024-05-01T12:00:00Z"
+                },
+                "online": {
+                    "description": "Online reports whether the latest handshake is within ONLINE_THRESHOLD_SECONDS.",
+                    "type": "boolean",
+                    "example": true
+                },
                 "publicKey": {
-                    "description": "PublicKey is the peer's public key. This is a mandatory field for identifying a peer.\nExample: \"a1b2c3d4...+Z0=\"",
                     "type": "string"
                 },
                 "receiveBytes": {
-                    "description": "ReceiveBytes is the total number of bytes received from this peer.\nomitempty is used as it's state information.",
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 1048576
                 },
                 "transmitBytes": {
-                    "description": "TransmitBytes is the total number of bytes transmitted to this peer.\nomitempty is used as it's state information.",
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 524288
                 }
             }
         },
-        "wgMicro_api_internal_domain.CreatePeerRequest": {
+        "wgMicro_api_internal_domain.PeerSummary": {
             "type": "object",
             "properties": {
-                "allowed_ips": {
-                    "description": "AllowedIps is a list of IP networks (CIDR notation) for the new peer. Can be empty.",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "neverConnected": {
+                    "description": "NeverConnected counts peers that never completed a handshake.",
+                    "type": "integer",
+                    "example": 2
                 },
-                "persistent_keepalive": {
-                    "description": "PersistentKeepalive is an optional interval in seconds for keepalive packets.",
-                    "type": "integer"
+                "offline": {
+                    "description": "Offline counts peers that completed a handshake, but not within the threshold.",
+                    "type": "integer",
+                    "example": 3
                 },
-                "preshared_key": {
-                    "description": "PreSharedKey is an optional pre-shared key for the new peer.",
+                "online": {
+                    "description": "Online counts peers whose latest handshake is within ONLINE_THRESHOLD_SECONDS.",
+                    "type": "integer",
+                    "example": 7
+                },
+                "total": {
+                    "description": "Total is the number of peers on the interface; the other three counts add up to it.",
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.PeerTrafficDelta": {
+            "type": "object",
+            "properties": {
+                "publicKey": {
                     "type": "string"
+                },
+                "rxDelta": {
+                    "type": "integer"
+                },
+                "txDelta": {
+                    "type": "integer"
                 }
             }
         },
-        "wgMicro_api_internal_domain.DeleteConfigRequest": {
+        "wgMicro_api_internal_domain.PreviewConfigRequest": {
             "type": "object",
             "required": [
+                "private_key",
                 "public_key"
             ],
             "properties": {
+                "allowed_ips": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.0.0.2/32"
+                    ]
+                },
+                "dns": {
+                    "description": "DNS overrides the configured client DNS servers; omit to use them, \"\" to leave DNS out.",
+                    "type": "string",
+                    "example": "1.1.1.1"
+                },
+                "mtu": {
+                    "description": "MTU overrides the configured client MTU; omit to use it, 0 to leave MTU out.",
+                    "type": "integer",
+                    "maximum": 65535,
+                    "minimum": 0,
+                    "example": 1420
+                },
+                "persistent_keepalive": {
+                    "type": "integer",
+                    "maximum": 65535,
+                    "minimum": 0,
+                    "example": 25
+                },
+                "preshared_key": {
+                    "type": "string"
+                },
+                "private_key": {
+                    "type": "string"
+                },
                 "public_key": {
-                    "description": "PublicKey is the peer's public key to delete.",
                     "type": "string"
                 }
             }
         },
-        "wgMicro_api_internal_domain.ErrorResponse": {
+        "wgMicro_api_internal_domain.PreviewConfigResponse": {
             "type": "object",
             "properties": {
-                "error": {
-                    "description": "Error contains a human-readable message describing the error.\nThis message is intended for the API consumer.\nExample: \"Peer not found\" or \"Invalid input: public key is malformed\"",
-                    "type": "string",
-                    "example": "Peer not found"
+                "conf": {
+                    "description": "Conf is the .conf content POST /configs/client-file would produce once the peer exists with these values.",
+                    "type": "string"
+                },
+                "explain": {
+                    "description": "Explain tells where each defaulted value came from; present with ?explain=true.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.ClientConfigExplanation"
+                        }
+                    ]
                 }
             }
         },
-        "wgMicro_api_internal_domain.GetConfigRequest": {
+        "wgMicro_api_internal_domain.PruneInactiveRequest": {
             "type": "object",
             "required": [
-                "public_key"
+                "olderThan"
             ],
             "properties": {
-                "public_key": {
-                    "description": "PublicKey is the peer's public key to retrieve configuration for.",
-                    "type": "string"
+                "olderThan": {
+                    "description": "OlderThan is the minimum age of a pruned peer and must be positive, e.g. \"30d\" or \"72h\", measured from its metadata createdAt.",
+                    "type": "string",
+                    "example": "30d"
+                },
+                "requireZeroTraffic": {
+                    "description": "RequireZeroTraffic additionally requires zero received and transmitted bytes. Defaults to true.",
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
-        "wgMicro_api_internal_domain.HealthResponse": {
+        "wgMicro_api_internal_domain.PruneInactiveResponse": {
             "type": "object",
             "properties": {
-                "status": {
-                    "description": "Status indicates the health of the service.\nExpected value is \"ok\" for a healthy service.\nExample: \"ok\"",
-                    "type": "string",
-                    "example": "ok"
+                "failed": {
+                    "description": "Failed maps public keys of matching peers that could not be removed to the error.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "pruned": {
+                    "description": "Pruned lists the public keys of the removed peers.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
@@ -573,10 +3125,73 @@ const docTemplate = `{
                     "type": "string",
                     "example": "wg command failed"
                 },
+                "interface": {
+                    "description": "Interface is the name of the managed WireGuard interface. Only included with ?verbose=true.\nExample: \"wg0\"",
+                    "type": "string",
+                    "example": "wg0"
+                },
+                "listenPort": {
+                    "description": "ListenPort is the WireGuard UDP listen port that was probed. Only included with ?verbose=true\nwhen READY_CHECK_LISTEN_PORT is enabled.\nExample: 51820",
+                    "type": "integer",
+                    "example": 51820
+                },
+                "listenPortBound": {
+                    "description": "ListenPortBound reports whether the UDP listen port is bound on this host, i.e. the VPN is listening,\nas opposed to the API process merely being up. Omitted if the port was not or could not be probed.",
+                    "type": "boolean",
+                    "example": true
+                },
                 "status": {
                     "description": "Status indicates the readiness of the service.\nExpected values: \"ready\" or \"not ready\".\nExample: \"ready\"",
                     "type": "string",
                     "example": "ready"
+                },
+                "wgVersion": {
+                    "description": "WgVersion is the output of 'wg --version'. Only included with ?verbose=true.\nExample: \"wireguard-tools v1.0.20210914 - https://git.zx2c4.com/wireguard-tools/\"",
+                    "type": "string",
+                    "example": "wireguard-tools v1.0.20210914 - https://git.zx2c4.com/wireguard-tools/"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ReconcilePlan": {
+            "type": "object",
+            "properties": {
+                "create": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "delete": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "update": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.RefreshResponse": {
+            "type": "object",
+            "properties": {
+                "metadataReloaded": {
+                    "description": "MetadataReloaded reports whether the metadata store was re-read from its backing file.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "peers": {
+                    "description": "Peers is the number of peers on the interface after the refresh.",
+                    "type": "integer",
+                    "example": 12
+                },
+                "statsRefreshed": {
+                    "description": "StatsRefreshed reports whether the cached /stats snapshot was recomputed.",
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
@@ -586,12 +3201,87 @@ const docTemplate = `{
                 "public_key"
             ],
             "properties": {
+                "expectedPublicKey": {
+                    "description": "ExpectedPublicKey optionally guards the rotation (optimistic concurrency): it must equal PublicKey,\nand if the peer has meanwhile been rotated away or deleted, 409 is returned instead of 404.",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name optionally replaces the peer's metadata name; other metadata is carried over to the new key.",
+                    "type": "string"
+                },
                 "public_key": {
                     "description": "PublicKey is the peer's current public key to rotate.",
                     "type": "string"
                 }
             }
         },
+        "wgMicro_api_internal_domain.ServerIdentity": {
+            "type": "object",
+            "properties": {
+                "endpoint": {
+                    "description": "Endpoint is the public host:port clients connect to.\nExample: \"203.0.113.1:51820\"",
+                    "type": "string",
+                    "example": "203.0.113.1:51820"
+                },
+                "publicKey": {
+                    "description": "PublicKey is the server's public key, the client's [Peer] PublicKey.",
+                    "type": "string"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.ServerInfo": {
+            "type": "object",
+            "properties": {
+                "addressDrift": {
+                    "description": "AddressDrift is the result of the address drift check performed at startup.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/wgMicro_api_internal_domain.AddressDrift"
+                        }
+                    ]
+                },
+                "description": {
+                    "description": "Description is a free-form description of this instance (SERVER_DESCRIPTION).",
+                    "type": "string",
+                    "example": "Frankfurt office gateway"
+                },
+                "endpoint": {
+                    "description": "Endpoint is the public host:port clients connect to.\nExample: \"203.0.113.1:51820\"",
+                    "type": "string",
+                    "example": "203.0.113.1:51820"
+                },
+                "interface": {
+                    "description": "Interface is the name of the managed WireGuard interface.\nExample: \"wg0\"",
+                    "type": "string",
+                    "example": "wg0"
+                },
+                "interfaceAddresses": {
+                    "description": "InterfaceAddresses are the configured interface addresses.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "listenPort": {
+                    "description": "ListenPort is the UDP port the interface listens on.",
+                    "type": "integer",
+                    "example": 51820
+                },
+                "name": {
+                    "description": "Name identifies this API instance among several (SERVER_NAME).",
+                    "type": "string",
+                    "example": "eu-west-1"
+                },
+                "publicKey": {
+                    "description": "PublicKey is the server's public key, as used in client configs.",
+                    "type": "string"
+                },
+                "signingPublicKey": {
+                    "description": "SigningPublicKey is the base64 Ed25519 public key verifying X-Config-Signature, present when signing is enabled.",
+                    "type": "string"
+                }
+            }
+        },
         "wgMicro_api_internal_domain.UpdateAllowedIpsRequest": {
             "type": "object",
             "required": [
@@ -610,6 +3300,41 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "wgMicro_api_internal_domain.VerifyKeyRequest": {
+            "type": "object",
+            "required": [
+                "private_key",
+                "public_key"
+            ],
+            "properties": {
+                "private_key": {
+                    "description": "PrivateKey is the client's private key. It is only used to derive a public key\nfor the comparison and is never stored or logged.",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "PublicKey is the peer's registered public key.",
+                    "type": "string"
+                }
+            }
+        },
+        "wgMicro_api_internal_domain.VerifyKeyResponse": {
+            "type": "object",
+            "properties": {
+                "matches": {
+                    "description": "Matches is true if the public key derived from the private key equals the supplied public key.",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Admin token as \"Bearer \u003cADMIN_TOKEN\u003e\".",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
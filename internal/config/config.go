@@ -3,15 +3,20 @@ package config
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"wgMicro_api/internal/domain"
 	"wgMicro_api/internal/repository"
 )
 
@@ -25,45 +30,166 @@ const (
 	DefaultWGInterface            = "wg0"
 	DefaultWgCmdTimeoutSeconds    = 5
 	DefaultKeyGenTimeoutSeconds   = 5
+	DefaultShutdownTimeoutSeconds = 10 // Grace period for in-flight requests after SIGINT/SIGTERM
 	DefaultServerEndpointPort     = "51820"
 	DefaultServerListenPort       = 51820 // Fallback if WG_ACTUAL_LISTEN_PORT is not set by entrypoint
 	DefaultClientConfigDNSServers = ""
 	DefaultClientConfigMTU        = 0 // Fallback if WG_ACTUAL_MTU is not set by entrypoint and CLIENT_CONFIG_MTU is not in .env
+	DefaultClientKeepalive        = 0 // 0 means client configs only carry the peer's own keepalive
+	DefaultKeyGenBackend          = "cli"
+	DefaultSlowCmdWarnMs          = 0  // 0 disables slow 'wg' command warnings
+	DefaultStatsIntervalSeconds   = 30 // 0 disables the background stats collector
+	DefaultMetricsPeerCacheSec    = 5  // 0 lists peers on every /metrics scrape
+	DefaultReadyRequiresPeers     = false
+	DefaultReadyAllowWriteCheck   = false
+	DefaultReadyCheckListenPort   = false
+	DefaultReadyRejectWrites      = false
+	DefaultReadinessCacheMs       = 1000 // 0 runs the readiness check on every probe
+	DefaultWriteBatchWindowMs     = 0    // 0 applies every peer write immediately
+	DefaultMetadataReconcileSec   = 0    // 0 disables background pruning of orphaned metadata
+	DefaultOnlineThresholdSec     = int(domain.DefaultOnlineThreshold / time.Second)
+	DefaultAutoCreateInterface    = false
+	DefaultPersistChanges         = false
+	DefaultNormalizeBareIPs       = true
+	DefaultAllowEmptyAllowedIPs   = false // Empty AllowedIPs updates, which cut the peer off, need ?confirm=true
+	DefaultCheckClientPrivateKey  = true
+	DefaultExportMaxBytes         = 0 // 0 means /configs/export is not size-capped
+	DefaultExposeTrafficStats     = true
+	DefaultGzipMinBytes           = 1024 // 0 disables response compression
+	DefaultValidateEndpointHost   = EndpointValidationOff
+	DefaultServerNameHeader       = false
+	DefaultStrictJSON             = false
+	DefaultMaintenanceMode        = false
+	DefaultClientFilenameMaxLen   = 64
+	DefaultClientFilenameNonASCII = "keep"
 )
 
 type Config struct {
-	AppEnv      string
-	Port        string
-	WGInterface string
+	AppEnv         string
+	Port           string
+	SanitizeErrors bool // If true, unexpected errors reach clients as a generic message; defaults to true in production
+	WGInterface    string
+	WGInterfaces   []string // Every interface served, selected per request with X-WG-Interface; always includes WGInterface
+
+	ExposeTrafficStats bool // If false, per-peer byte counters are stripped, /stats answers 403 and metrics omit traffic totals
+	GzipMinBytes       int  // Responses at least this large are gzip-compressed; 0 disables compression
+	StrictJSON         bool // If true, JSON bodies with unknown fields are rejected with 400
+	EnableSwagger      bool // If true, Swagger UI is served under /swagger; defaults to false in production
+	MaintenanceMode    bool // If true, the API starts with changes disabled; toggled at runtime via POST /admin/maintenance
 
 	Server struct {
+		Name               string // SERVER_NAME, identifies this instance in /server and optionally X-Server-Name
+		Description        string // SERVER_DESCRIPTION, shown in /server
+		NameHeader         bool   // SERVER_NAME_HEADER: send X-Server-Name on every response when Name is set
 		PrivateKey         string
 		PublicKey          string   // Derived
 		EndpointHost       string   // Always from .env
+		ValidateEndpoint   string   // VALIDATE_ENDPOINT_HOST: "false", "true" (warn) or "strict" (fatal); checked in main
 		EndpointPort       string   // Always from .env
 		ListenPort         int      // Potentially from WG_ACTUAL_LISTEN_PORT or .env
 		InterfaceAddresses []string // Potentially from WG_ACTUAL_INTERFACE_ADDRESSES or .env
 	}
 
 	ClientConfig struct {
-		DNSServers string // Always from .env
-		MTU        int    // Potentially from WG_ACTUAL_MTU or .env
+		DNSServers        string // Always from .env
+		MTU               int    // Potentially from WG_ACTUAL_MTU or .env
+		DefaultKeepalive  int    // PersistentKeepalive for client configs of peers without one (always from .env)
+		MaxDNS            int    // Maximum entries of a DNS list requested for a client config; 0 means unlimited
+		FilenameMaxLength int    // Max length of downloadable .conf filenames (always from .env)
+		FilenameNonASCII  string // "keep", "transliterate" or "drop" for non-ASCII filename characters (always from .env)
+		FileCacheControl  string // Cache-Control header of /configs/client-file responses (always from .env)
 	}
 
 	Timeouts struct {
-		WgCmdSeconds  int
-		KeyGenSeconds int
+		WgCmdSeconds       int
+		KeyGenSeconds      int
+		SlowCmdWarnMs      int // Log 'wg' commands slower than this many milliseconds; 0 disables
+		StatsInterval      int // Seconds between background stats collections; 0 disables the collector
+		ReadinessCacheMs   int // Milliseconds a /readyz result is reused; 0 disables caching
+		WriteBatchWindowMs int // Milliseconds peer creates and updates wait to be applied together; 0 disables batching
+		MetadataReconcile  int // Seconds between orphaned metadata pruning passes; 0 disables it
+		OnlineThreshold    int // Maximum handshake age in seconds for a peer to count as online
+		ShutdownSeconds    int // Seconds in-flight requests may finish after SIGINT/SIGTERM
+		MetricsPeerCache   int // Seconds the peer gauges of /metrics reuse one peer listing; 0 lists on every scrape
 	}
 
-	DerivedWgCmdTimeout   time.Duration
-	DerivedKeyGenTimeout  time.Duration
-	DerivedServerEndpoint string // Derived from Server.EndpointHost and Server.EndpointPort
+	KeyGenBackend string // "cli" (wg utility) or "native" (in-process curve25519)
+
+	CmdEnvAllowlist []string // Environment variables passed to 'wg' and 'ip'; empty inherits the full environment
+
+	ReadyRequiresPeers   bool // If true, /readyz reports not ready while the interface has no peers
+	ReadyAllowWriteCheck bool // If true, /readyz?checkWrite=true adds and removes a throwaway peer
+	ReadyCheckListenPort bool // If true, /readyz?verbose=true reports whether Server.ListenPort is bound
+	ReadyRejectWrites    bool // If true, mutating /configs requests get 503 while the cached readiness result is a failure
+
+	LivenessPath  string // Path of the liveness probe, e.g. /live
+	ReadinessPath string // Path of the readiness probe, e.g. /ready
+
+	NormalizeBareIPs bool // If true, bare AllowedIPs addresses get /32 or /128; if false, prefixes are required
+
+	AllowEmptyAllowedIPsUpdate bool // If false, clearing a peer's AllowedIPs requires ?confirm=true
+
+	CheckClientPrivateKey bool // If true, client files are refused for malformed or all-zero private keys
+
+	ExportMaxBytes int // Size cap for /configs/export documents; 0 means unlimited
+
+	MetadataFile string // JSON file for sidecar peer metadata (names, descriptions); empty keeps it in memory only; see InterfaceMetadataFile
+
+	AddressPool string // CIDR from which peers created without AllowedIPs get an address; empty disables allocation
+
+	AllowedIPRanges []netip.Prefix // Supernets requested AllowedIPs must lie in; empty means no restriction
+
+	AutoCreateInterface bool   // If true, bring the interface up with wg-quick at startup when it does not exist
+	WGConfigPath        string // Config file passed to 'wg-quick up'; empty means /etc/wireguard/<WG_INTERFACE>.conf
+	PersistChanges      bool   // If true, run 'wg-quick save' (on WGConfigPath when set) after every peer change
+
+	AdminToken string // Bearer token for /admin endpoints; empty disables them
+
+	StatsDAddr string // host:port of a StatsD server receiving gauges and request timers; empty disables StatsD
+
+	ConfigSigningKey ed25519.PrivateKey // Signs generated client .conf files (CONFIG_SIGNING_KEY); nil disables signing
+
+	DerivedWgCmdTimeout      time.Duration
+	DerivedSlowCmdWarn       time.Duration
+	DerivedStatsInterval     time.Duration
+	DerivedReadinessCache    time.Duration
+	DerivedWriteBatchWindow  time.Duration
+	DerivedMetadataReconcile time.Duration
+	DerivedOnlineThreshold   time.Duration
+	DerivedShutdownTimeout   time.Duration
+	DerivedMetricsPeerCache  time.Duration
+	DerivedKeyGenTimeout     time.Duration
+	DerivedServerEndpoint    string // Derived from Server.EndpointHost and Server.EndpointPort
+}
+
+// SigningPublicKey returns the base64 public half of ConfigSigningKey, or "" when signing is disabled.
+func (c *Config) SigningPublicKey() string {
+	if c.ConfigSigningKey == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(c.ConfigSigningKey.Public().(ed25519.PublicKey))
+}
+
+// InterfaceMetadataFile returns the peer metadata file of interface name: MetadataFile for WGInterface,
+// and for an additional interface the same path with the name before the extension, e.g. peers.wg1.json.
+// It returns "" (in-memory metadata) when MetadataFile is empty.
+func (c *Config) InterfaceMetadataFile(name string) string {
+	if c.MetadataFile == "" || name == c.WGInterface {
+		return c.MetadataFile
+	}
+	ext := filepath.Ext(c.MetadataFile)
+	return strings.TrimSuffix(c.MetadataFile, ext) + "." + name + ext
 }
 
 func (c *Config) IsDevelopment() bool {
 	return strings.ToLower(c.AppEnv) == EnvDevelopment
 }
 
+// CommandRunner returns the runner for 'wg' and 'ip' commands, restricted to CmdEnvAllowlist.
+func (c *Config) CommandRunner() repository.ExecRunner {
+	return repository.ExecRunner{Env: repository.AllowedEnv(c.CmdEnvAllowlist)}
+}
+
 // getEnvWithFallback first checks for a primary environment variable,
 // then a secondary (fallback) one, and finally returns a default value if neither is found.
 func getEnvWithFallback(primaryKey, secondaryKey, defaultValue string) string {
@@ -105,12 +231,60 @@ func getEnvIntWithFallback(primaryKey, secondaryKey string, defaultValue int) in
 	return defaultValue
 }
 
+// getEnvBool reads a boolean environment variable, returning defaultValue if it is unset or invalid.
+func getEnvBool(key string, defaultValue bool) bool {
+	valueStr, exists := os.LookupEnv(key)
+	if !exists || valueStr == "" {
+		log.Printf("INFO: Using default boolean value for %s: %t", key, defaultValue)
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Printf("WARNING: Invalid boolean value for env var %s: '%s'. Using default %t. Error: %v", key, valueStr, defaultValue, err)
+		return defaultValue
+	}
+	log.Printf("INFO: Using boolean value from env var %s: %t", key, value)
+	return value
+}
+
+// getProbePath reads a probe path, falling back to defaultValue if it does not start with '/'.
+// A path on or under a route the API already serves would make the router panic, so it is fatal.
+func getProbePath(key, defaultValue string) string {
+	path := getEnvWithFallback(key, "", defaultValue)
+	if !strings.HasPrefix(path, "/") {
+		log.Printf("WARNING: %s '%s' does not start with '/'. Using default '%s'.", key, path, defaultValue)
+		return defaultValue
+	}
+	for _, prefix := range domain.ReservedPathPrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			log.Fatalf("FATAL: %s '%s' collides with the API route '%s'", key, path, prefix)
+		}
+	}
+	return path
+}
+
 func LoadConfig() *Config {
 	cfg := Config{}
 
-	cfg.AppEnv = getEnvWithFallback("APP_ENV", "", DefaultAppEnv)                // No secondary for APP_ENV
+	cfg.AppEnv = getEnvWithFallback("APP_ENV", "", DefaultAppEnv) // No secondary for APP_ENV
+	cfg.SanitizeErrors = getEnvBool("SANITIZE_ERRORS", strings.ToLower(cfg.AppEnv) == EnvProduction)
+	cfg.EnableSwagger = getEnvBool("ENABLE_SWAGGER", strings.ToLower(cfg.AppEnv) != EnvProduction)
 	cfg.Port = getEnvWithFallback("PORT", "", DefaultPort)                       // No secondary for PORT
 	cfg.WGInterface = getEnvWithFallback("WG_INTERFACE", "", DefaultWGInterface) // No secondary for WG_INTERFACE
+	cfg.WGInterfaces = []string{cfg.WGInterface}
+	for _, name := range strings.Split(getEnvWithFallback("WG_INTERFACES", "", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" && !slices.Contains(cfg.WGInterfaces, name) {
+			cfg.WGInterfaces = append(cfg.WGInterfaces, name)
+		}
+	}
+	cfg.ExposeTrafficStats = getEnvBool("EXPOSE_TRAFFIC_STATS", DefaultExposeTrafficStats)
+	cfg.StrictJSON = getEnvBool("STRICT_JSON", DefaultStrictJSON)
+	cfg.MaintenanceMode = getEnvBool("MAINTENANCE_MODE", DefaultMaintenanceMode)
+	cfg.GzipMinBytes = getEnvIntWithFallback("GZIP_MIN_BYTES", "", DefaultGzipMinBytes)
+	if cfg.GzipMinBytes < 0 {
+		log.Printf("WARNING: GZIP_MIN_BYTES cannot be negative (%d). Using default %d.", cfg.GzipMinBytes, DefaultGzipMinBytes)
+		cfg.GzipMinBytes = DefaultGzipMinBytes
+	}
 
 	// --- Server Configurations ---
 	// SERVER_PRIVATE_KEY, SERVER_ENDPOINT_HOST, SERVER_ENDPOINT_PORT always come from the original .env or system env
@@ -124,6 +298,20 @@ func LoadConfig() *Config {
 		log.Println("WARNING: SERVER_ENDPOINT_HOST is not set. Client .conf files will not have an endpoint host.")
 	}
 	cfg.Server.EndpointPort = getEnvWithFallback("SERVER_ENDPOINT_PORT", "", DefaultServerEndpointPort)
+	cfg.Server.ValidateEndpoint = strings.ToLower(getEnvWithFallback("VALIDATE_ENDPOINT_HOST", "", DefaultValidateEndpointHost))
+	switch cfg.Server.ValidateEndpoint {
+	case EndpointValidationOff, EndpointValidationWarn, EndpointValidationStrict:
+	default:
+		log.Printf("WARNING: Invalid VALIDATE_ENDPOINT_HOST '%s' (expected false, true or strict). Using default '%s'.", cfg.Server.ValidateEndpoint, DefaultValidateEndpointHost)
+		cfg.Server.ValidateEndpoint = DefaultValidateEndpointHost
+	}
+
+	cfg.Server.Name = getEnvWithFallback("SERVER_NAME", "", "")
+	cfg.Server.Description = getEnvWithFallback("SERVER_DESCRIPTION", "", "")
+	cfg.Server.NameHeader = getEnvBool("SERVER_NAME_HEADER", DefaultServerNameHeader)
+	if cfg.Server.NameHeader && cfg.Server.Name == "" {
+		log.Println("WARNING: SERVER_NAME_HEADER is enabled but SERVER_NAME is not set. No X-Server-Name header will be sent.")
+	}
 
 	// ListenPort: Prefer WG_ACTUAL_LISTEN_PORT, fallback to SERVER_LISTEN_PORT, then default
 	cfg.Server.ListenPort = getEnvIntWithFallback(
@@ -171,9 +359,154 @@ func LoadConfig() *Config {
 		cfg.ClientConfig.MTU = DefaultClientConfigMTU
 	}
 
+	cfg.ClientConfig.DefaultKeepalive = getEnvIntWithFallback("CLIENT_DEFAULT_KEEPALIVE_IN_CONF", "", DefaultClientKeepalive)
+	if cfg.ClientConfig.DefaultKeepalive < 0 || cfg.ClientConfig.DefaultKeepalive > 65535 {
+		log.Printf("WARNING: CLIENT_DEFAULT_KEEPALIVE_IN_CONF must be between 0 and 65535 (%d). Using default %d.", cfg.ClientConfig.DefaultKeepalive, DefaultClientKeepalive)
+		cfg.ClientConfig.DefaultKeepalive = DefaultClientKeepalive
+	}
+
+	cfg.ClientConfig.MaxDNS = getEnvIntWithFallback("MAX_CLIENT_DNS", "", domain.DefaultMaxClientDNS)
+	if cfg.ClientConfig.MaxDNS < 0 {
+		log.Printf("WARNING: MAX_CLIENT_DNS cannot be negative (%d). Using default %d.", cfg.ClientConfig.MaxDNS, domain.DefaultMaxClientDNS)
+		cfg.ClientConfig.MaxDNS = domain.DefaultMaxClientDNS
+	}
+	if cfg.ClientConfig.MaxDNS > 0 && cfg.ClientConfig.DNSServers != "" {
+		if n := len(strings.Split(cfg.ClientConfig.DNSServers, ",")); n > cfg.ClientConfig.MaxDNS {
+			log.Printf("WARNING: CLIENT_CONFIG_DNS_SERVERS has %d entries, more than MAX_CLIENT_DNS (%d). Some clients may reject the generated configs.", n, cfg.ClientConfig.MaxDNS)
+		}
+	}
+
+	cfg.ClientConfig.FilenameMaxLength = getEnvIntWithFallback("CLIENT_CONFIG_FILENAME_MAX_LENGTH", "", DefaultClientFilenameMaxLen)
+	if cfg.ClientConfig.FilenameMaxLength <= 0 {
+		log.Printf("WARNING: CLIENT_CONFIG_FILENAME_MAX_LENGTH must be positive (%d). Using default %d.", cfg.ClientConfig.FilenameMaxLength, DefaultClientFilenameMaxLen)
+		cfg.ClientConfig.FilenameMaxLength = DefaultClientFilenameMaxLen
+	}
+	cfg.ClientConfig.FilenameNonASCII = strings.ToLower(getEnvWithFallback("CLIENT_CONFIG_FILENAME_NON_ASCII", "", DefaultClientFilenameNonASCII))
+	switch cfg.ClientConfig.FilenameNonASCII {
+	case "keep", "transliterate", "drop":
+	default:
+		log.Printf("WARNING: Invalid CLIENT_CONFIG_FILENAME_NON_ASCII '%s'. Using default '%s'.", cfg.ClientConfig.FilenameNonASCII, DefaultClientFilenameNonASCII)
+		cfg.ClientConfig.FilenameNonASCII = DefaultClientFilenameNonASCII
+	}
+	cfg.ClientConfig.FileCacheControl = getEnvWithFallback("CLIENT_FILE_CACHE_CONTROL", "", domain.DefaultClientFileCacheControl)
+
 	// --- Timeouts Configurations (always from .env) ---
 	cfg.Timeouts.WgCmdSeconds = getEnvIntWithFallback("WG_CMD_TIMEOUT_SECONDS", "", DefaultWgCmdTimeoutSeconds)
 	cfg.Timeouts.KeyGenSeconds = getEnvIntWithFallback("KEY_GEN_TIMEOUT_SECONDS", "", DefaultKeyGenTimeoutSeconds)
+	cfg.Timeouts.SlowCmdWarnMs = getEnvIntWithFallback("WG_SLOW_CMD_WARN_MS", "", DefaultSlowCmdWarnMs)
+	if cfg.Timeouts.SlowCmdWarnMs < 0 {
+		log.Printf("WARNING: WG_SLOW_CMD_WARN_MS is negative (%d). Disabling slow command warnings.", cfg.Timeouts.SlowCmdWarnMs)
+		cfg.Timeouts.SlowCmdWarnMs = 0
+	}
+
+	cfg.Timeouts.StatsInterval = getEnvIntWithFallback("STATS_INTERVAL_SECONDS", "", DefaultStatsIntervalSeconds)
+	if cfg.Timeouts.StatsInterval < 0 {
+		log.Printf("WARNING: STATS_INTERVAL_SECONDS is negative (%d). Disabling the stats collector.", cfg.Timeouts.StatsInterval)
+		cfg.Timeouts.StatsInterval = 0
+	}
+	cfg.Timeouts.ReadinessCacheMs = getEnvIntWithFallback("READINESS_CACHE_MS", "", DefaultReadinessCacheMs)
+	if cfg.Timeouts.ReadinessCacheMs < 0 {
+		log.Printf("WARNING: READINESS_CACHE_MS is negative (%d). Disabling the readiness cache.", cfg.Timeouts.ReadinessCacheMs)
+		cfg.Timeouts.ReadinessCacheMs = 0
+	}
+	cfg.Timeouts.WriteBatchWindowMs = getEnvIntWithFallback("WRITE_BATCH_WINDOW_MS", "", DefaultWriteBatchWindowMs)
+	if cfg.Timeouts.WriteBatchWindowMs < 0 {
+		log.Printf("WARNING: WRITE_BATCH_WINDOW_MS is negative (%d). Disabling write batching.", cfg.Timeouts.WriteBatchWindowMs)
+		cfg.Timeouts.WriteBatchWindowMs = 0
+	}
+	cfg.Timeouts.MetadataReconcile = getEnvIntWithFallback("METADATA_RECONCILE_INTERVAL", "", DefaultMetadataReconcileSec)
+	if cfg.Timeouts.MetadataReconcile < 0 {
+		log.Printf("WARNING: METADATA_RECONCILE_INTERVAL is negative (%d). Disabling metadata reconciliation.", cfg.Timeouts.MetadataReconcile)
+		cfg.Timeouts.MetadataReconcile = 0
+	}
+	cfg.Timeouts.OnlineThreshold = getEnvIntWithFallback("ONLINE_THRESHOLD_SECONDS", "", DefaultOnlineThresholdSec)
+	if cfg.Timeouts.OnlineThreshold <= 0 {
+		log.Printf("WARNING: ONLINE_THRESHOLD_SECONDS must be positive (%d). Using default %d.", cfg.Timeouts.OnlineThreshold, DefaultOnlineThresholdSec)
+		cfg.Timeouts.OnlineThreshold = DefaultOnlineThresholdSec
+	}
+	cfg.Timeouts.MetricsPeerCache = getEnvIntWithFallback("METRICS_PEER_CACHE_SECONDS", "", DefaultMetricsPeerCacheSec)
+	if cfg.Timeouts.MetricsPeerCache < 0 {
+		log.Printf("WARNING: METRICS_PEER_CACHE_SECONDS is negative (%d). Listing peers on every scrape.", cfg.Timeouts.MetricsPeerCache)
+		cfg.Timeouts.MetricsPeerCache = 0
+	}
+	cfg.Timeouts.ShutdownSeconds = getEnvIntWithFallback("SHUTDOWN_TIMEOUT_SECONDS", "", DefaultShutdownTimeoutSeconds)
+	if cfg.Timeouts.ShutdownSeconds < 0 {
+		log.Printf("WARNING: SHUTDOWN_TIMEOUT_SECONDS is negative (%d). Using default %d.", cfg.Timeouts.ShutdownSeconds, DefaultShutdownTimeoutSeconds)
+		cfg.Timeouts.ShutdownSeconds = DefaultShutdownTimeoutSeconds
+	}
+
+	// --- Client key generation backend (always from .env) ---
+	cfg.KeyGenBackend = strings.ToLower(getEnvWithFallback("KEYGEN_BACKEND", "", DefaultKeyGenBackend))
+	if cfg.KeyGenBackend != "cli" && cfg.KeyGenBackend != "native" {
+		log.Printf("WARNING: Invalid KEYGEN_BACKEND '%s'. Using default '%s'.", cfg.KeyGenBackend, DefaultKeyGenBackend)
+		cfg.KeyGenBackend = DefaultKeyGenBackend
+	}
+
+	cfg.NormalizeBareIPs = getEnvBool("NORMALIZE_BARE_IPS", DefaultNormalizeBareIPs)
+	cfg.AllowEmptyAllowedIPsUpdate = getEnvBool("ALLOW_EMPTY_ALLOWED_IPS_UPDATE", DefaultAllowEmptyAllowedIPs)
+	cfg.CheckClientPrivateKey = getEnvBool("CHECK_CLIENT_PRIVATE_KEY", DefaultCheckClientPrivateKey)
+
+	cfg.ExportMaxBytes = getEnvIntWithFallback("EXPORT_MAX_BYTES", "", DefaultExportMaxBytes)
+	if cfg.ExportMaxBytes < 0 {
+		log.Printf("WARNING: EXPORT_MAX_BYTES is negative (%d). Using default %d (unlimited).", cfg.ExportMaxBytes, DefaultExportMaxBytes)
+		cfg.ExportMaxBytes = DefaultExportMaxBytes
+	}
+
+	cfg.MetadataFile = getEnvWithFallback("PEER_METADATA_FILE", "", "")
+
+	cfg.AutoCreateInterface = getEnvBool("AUTO_CREATE_INTERFACE", DefaultAutoCreateInterface)
+	cfg.WGConfigPath = getEnvWithFallback("WG_CONFIG_PATH", "", "")
+	cfg.PersistChanges = getEnvBool("WG_PERSIST_CHANGES", DefaultPersistChanges)
+
+	cfg.AddressPool = getEnvWithFallback("ADDRESS_POOL_CIDR", "", "")
+	if cfg.AddressPool != "" {
+		if _, err := netip.ParsePrefix(cfg.AddressPool); err != nil {
+			log.Printf("WARNING: Invalid ADDRESS_POOL_CIDR '%s': %v. Disabling address allocation.", cfg.AddressPool, err)
+			cfg.AddressPool = ""
+		}
+	}
+
+	if ranges := getEnvWithFallback("ALLOWED_IP_RANGES", "", ""); ranges != "" {
+		for _, raw := range strings.Split(ranges, ",") {
+			if raw = strings.TrimSpace(raw); raw == "" {
+				continue
+			}
+			prefix, err := netip.ParsePrefix(raw)
+			if err != nil {
+				// Ignoring a bad entry would silently lift or narrow the restriction.
+				log.Fatalf("FATAL: Invalid ALLOWED_IP_RANGES entry '%s': %v", raw, err)
+			}
+			cfg.AllowedIPRanges = append(cfg.AllowedIPRanges, prefix)
+		}
+	}
+
+	cfg.AdminToken = getEnvWithFallback("ADMIN_TOKEN", "", "")
+	cfg.StatsDAddr = getEnvWithFallback("STATSD_ADDR", "", "")
+
+	signingKey, err := ParseSigningKey(os.Getenv("CONFIG_SIGNING_KEY")) // Read directly so the key is never logged
+	if err != nil {
+		log.Fatalf("FATAL: Invalid CONFIG_SIGNING_KEY: %v", err)
+	}
+	cfg.ConfigSigningKey = signingKey
+
+	cfg.ReadyRequiresPeers = getEnvBool("READY_REQUIRES_PEERS", DefaultReadyRequiresPeers)
+	cfg.ReadyAllowWriteCheck = getEnvBool("READY_ALLOW_WRITE_CHECK", DefaultReadyAllowWriteCheck)
+	cfg.ReadyCheckListenPort = getEnvBool("READY_CHECK_LISTEN_PORT", DefaultReadyCheckListenPort)
+	cfg.ReadyRejectWrites = getEnvBool("READY_REJECT_WRITES", DefaultReadyRejectWrites)
+
+	cfg.LivenessPath = getProbePath("LIVENESS_PATH", domain.DefaultLivenessPath)
+	cfg.ReadinessPath = getProbePath("READINESS_PATH", domain.DefaultReadinessPath)
+	if cfg.LivenessPath == cfg.ReadinessPath {
+		log.Fatalf("FATAL: LIVENESS_PATH and READINESS_PATH must differ, both are '%s'", cfg.LivenessPath)
+	}
+
+	if allowlist := getEnvWithFallback("CMD_ENV_ALLOWLIST", "", ""); allowlist != "" {
+		for _, name := range strings.Split(allowlist, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.CmdEnvAllowlist = append(cfg.CmdEnvAllowlist, name)
+			}
+		}
+	}
 
 	// --- Derive PublicKey from PrivateKey ---
 	var errDeriveKey error
@@ -182,7 +515,7 @@ func LoadConfig() *Config {
 		keyGenTimeout = time.Duration(DefaultKeyGenTimeoutSeconds) * time.Second
 		log.Printf("WARNING: KEY_GEN_TIMEOUT_SECONDS was invalid or zero, using default %d for key derivation.", DefaultKeyGenTimeoutSeconds)
 	}
-	cfg.Server.PublicKey, errDeriveKey = derivePublicKey(cfg.Server.PrivateKey, keyGenTimeout)
+	cfg.Server.PublicKey, errDeriveKey = derivePublicKey(cfg.CommandRunner(), cfg.Server.PrivateKey, keyGenTimeout)
 	if errDeriveKey != nil {
 		log.Fatalf("FATAL: Could not derive server public key from private key: %v", errDeriveKey)
 	}
@@ -191,6 +524,14 @@ func LoadConfig() *Config {
 	// --- Derive other fields ---
 	cfg.DerivedWgCmdTimeout = time.Duration(cfg.Timeouts.WgCmdSeconds) * time.Second
 	cfg.DerivedKeyGenTimeout = keyGenTimeout
+	cfg.DerivedSlowCmdWarn = time.Duration(cfg.Timeouts.SlowCmdWarnMs) * time.Millisecond
+	cfg.DerivedStatsInterval = time.Duration(cfg.Timeouts.StatsInterval) * time.Second
+	cfg.DerivedReadinessCache = time.Duration(cfg.Timeouts.ReadinessCacheMs) * time.Millisecond
+	cfg.DerivedWriteBatchWindow = time.Duration(cfg.Timeouts.WriteBatchWindowMs) * time.Millisecond
+	cfg.DerivedMetadataReconcile = time.Duration(cfg.Timeouts.MetadataReconcile) * time.Second
+	cfg.DerivedOnlineThreshold = time.Duration(cfg.Timeouts.OnlineThreshold) * time.Second
+	cfg.DerivedShutdownTimeout = time.Duration(cfg.Timeouts.ShutdownSeconds) * time.Second
+	cfg.DerivedMetricsPeerCache = time.Duration(cfg.Timeouts.MetricsPeerCache) * time.Second
 
 	if cfg.DerivedWgCmdTimeout <= 0 {
 		log.Printf("WARNING: WG_CMD_TIMEOUT_SECONDS is invalid, using default %d seconds.", DefaultWgCmdTimeoutSeconds)
@@ -198,27 +539,57 @@ func LoadConfig() *Config {
 	}
 
 	if cfg.Server.EndpointHost != "" && cfg.Server.EndpointPort != "" {
-		cfg.DerivedServerEndpoint = fmt.Sprintf("%s:%s", cfg.Server.EndpointHost, cfg.Server.EndpointPort)
+		cfg.DerivedServerEndpoint = domain.JoinEndpoint(cfg.Server.EndpointHost, cfg.Server.EndpointPort)
 	} else if cfg.Server.EndpointHost != "" {
 		cfg.DerivedServerEndpoint = cfg.Server.EndpointHost
 	}
 
 	log.Printf("--- Effective Configuration for Go App ---")
-	log.Printf("AppEnv: '%s', Port: '%s', WGInterface: '%s'", cfg.AppEnv, cfg.Port, cfg.WGInterface)
+	log.Printf("AppEnv: '%s', Port: '%s', WGInterface: '%s' (all interfaces: %v)", cfg.AppEnv, cfg.Port, cfg.WGInterface, cfg.WGInterfaces)
+	log.Printf("Sanitize Errors: %t", cfg.SanitizeErrors)
+	log.Printf("Swagger UI Enabled: %t", cfg.EnableSwagger)
+	log.Printf("Expose Traffic Stats: %t", cfg.ExposeTrafficStats)
+	log.Printf("Gzip Min Bytes: %d", cfg.GzipMinBytes)
+	log.Printf("Strict JSON: %t", cfg.StrictJSON)
+	log.Printf("Maintenance Mode at startup: %t", cfg.MaintenanceMode)
+	log.Printf("Server Name: '%s' (X-Server-Name header: %t), Description: '%s'", cfg.Server.Name, cfg.Server.NameHeader, cfg.Server.Description)
 	log.Printf("Server ListenPort: %d", cfg.Server.ListenPort)
 	log.Printf("Server InterfaceAddresses: %v", cfg.Server.InterfaceAddresses)
 	log.Printf("Server Endpoint: '%s' (Host: '%s', Port: '%s')", cfg.DerivedServerEndpoint, cfg.Server.EndpointHost, cfg.Server.EndpointPort)
+	log.Printf("Validate Endpoint Host: '%s'", cfg.Server.ValidateEndpoint)
 	log.Printf("Server PublicKey (derived): '%s...'", cfg.Server.PublicKey[:min(10, len(cfg.Server.PublicKey))])
-	log.Printf("Client DNS Servers: '%s'", cfg.ClientConfig.DNSServers)
+	log.Printf("Client DNS Servers: '%s' (requested lists at most %d entries, 0 means unlimited)", cfg.ClientConfig.DNSServers, cfg.ClientConfig.MaxDNS)
 	log.Printf("Client MTU: %d (0 means omit)", cfg.ClientConfig.MTU)
-	log.Printf("Timeouts: WG Cmd: %v, Key Gen: %v", cfg.DerivedWgCmdTimeout, cfg.DerivedKeyGenTimeout)
+	log.Printf("Client default keepalive: %d (0 means peer value only)", cfg.ClientConfig.DefaultKeepalive)
+	log.Printf("Client Filename: max length %d, non-ASCII '%s'", cfg.ClientConfig.FilenameMaxLength, cfg.ClientConfig.FilenameNonASCII)
+	log.Printf("Client File Cache-Control: '%s'", cfg.ClientConfig.FileCacheControl)
+	log.Printf("Timeouts: WG Cmd: %v, Key Gen: %v, Slow Cmd Warn: %v (0 means off), Stats Interval: %v (0 means off), Readiness Cache: %v (0 means off), Write Batch Window: %v (0 means off), Metadata Reconcile: %v (0 means off), Online Threshold: %v, Shutdown: %v, Metrics Peer Cache: %v (0 means off)", cfg.DerivedWgCmdTimeout, cfg.DerivedKeyGenTimeout, cfg.DerivedSlowCmdWarn, cfg.DerivedStatsInterval, cfg.DerivedReadinessCache, cfg.DerivedWriteBatchWindow, cfg.DerivedMetadataReconcile, cfg.DerivedOnlineThreshold, cfg.DerivedShutdownTimeout, cfg.DerivedMetricsPeerCache)
+	log.Printf("Key Gen Backend: '%s'", cfg.KeyGenBackend)
+	log.Printf("Command Env Allowlist: %v (empty means full environment)", cfg.CmdEnvAllowlist)
+	log.Printf("Ready Requires Peers: %t", cfg.ReadyRequiresPeers)
+	log.Printf("Ready Allow Write Check: %t", cfg.ReadyAllowWriteCheck)
+	log.Printf("Ready Check Listen Port: %t", cfg.ReadyCheckListenPort)
+	log.Printf("Ready Reject Writes: %t", cfg.ReadyRejectWrites)
+	log.Printf("Probe Paths: liveness '%s', readiness '%s'", cfg.LivenessPath, cfg.ReadinessPath)
+	log.Printf("Normalize Bare IPs: %t", cfg.NormalizeBareIPs)
+	log.Printf("Allow Empty AllowedIPs Update: %t (false requires ?confirm=true)", cfg.AllowEmptyAllowedIPsUpdate)
+	log.Printf("Check Client Private Key: %t", cfg.CheckClientPrivateKey)
+	log.Printf("Export Max Bytes: %d (0 means unlimited)", cfg.ExportMaxBytes)
+	log.Printf("Peer Metadata File: '%s' (empty means in-memory only)", cfg.MetadataFile)
+	log.Printf("Address Pool: '%s' (empty means no allocation)", cfg.AddressPool)
+	log.Printf("Allowed IP Ranges: %v (empty means no restriction)", cfg.AllowedIPRanges)
+	log.Printf("Auto Create Interface: %t (wg-quick config: '%s', empty means default)", cfg.AutoCreateInterface, cfg.WGConfigPath)
+	log.Printf("Persist Changes with wg-quick save: %t", cfg.PersistChanges)
+	log.Printf("Admin Endpoints Enabled: %t", cfg.AdminToken != "") // Never log the token itself
+	log.Printf("StatsD Address: '%s' (empty means disabled)", cfg.StatsDAddr)
+	log.Printf("Config Signing Public Key: '%s' (empty means signing is off)", cfg.SigningPublicKey())
 	log.Printf("-------------------------------------------")
 
 	return &cfg
 }
 
-// derivePublicKey uses 'wg pubkey' to derive a public key from a private key.
-func derivePublicKey(privateKey string, timeout time.Duration) (string, error) {
+// derivePublicKey uses 'wg pubkey', run through runner, to derive a public key from a private key.
+func derivePublicKey(runner repository.CommandRunner, privateKey string, timeout time.Duration) (string, error) {
 	if privateKey == "" {
 		return "", errors.New("private key is empty, cannot derive public key")
 	}
@@ -229,21 +600,16 @@ func derivePublicKey(privateKey string, timeout time.Duration) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "wg", "pubkey")
-	cmd.Stdin = strings.NewReader(privateKey)
-	pubKeyBytes, err := cmd.Output()
+	pubKeyBytes, stderr, err := runner.Run(ctx, privateKey, "wg", "pubkey")
 
 	if ctx.Err() == context.DeadlineExceeded {
 		return "", fmt.Errorf("wg pubkey timed out after %v: %w", timeout, repository.ErrWgTimeout)
 	}
 	if err != nil {
-		var execErr *exec.ExitError
 		var errMsgBuilder strings.Builder
 		errMsgBuilder.WriteString(fmt.Sprintf("wg pubkey command failed: %s.", err.Error()))
-		if errors.As(err, &execErr) {
-			if len(execErr.Stderr) > 0 {
-				errMsgBuilder.WriteString(fmt.Sprintf(" Stderr: %s", string(execErr.Stderr)))
-			}
+		if len(stderr) > 0 {
+			errMsgBuilder.WriteString(fmt.Sprintf(" Stderr: %s", string(stderr)))
 		}
 		return "", errors.New(errMsgBuilder.String())
 	}
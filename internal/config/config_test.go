@@ -0,0 +1,20 @@
+// internal/config/config_test.go
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterfaceMetadataFile(t *testing.T) {
+	cfg := &Config{WGInterface: "wg0", MetadataFile: "/data/peers.json"}
+	assert.Equal(t, "/data/peers.json", cfg.InterfaceMetadataFile("wg0"), "WG_INTERFACE keeps PEER_METADATA_FILE")
+	assert.Equal(t, "/data/peers.wg1.json", cfg.InterfaceMetadataFile("wg1"))
+
+	cfg.MetadataFile = "/data/peers"
+	assert.Equal(t, "/data/peers.wg1", cfg.InterfaceMetadataFile("wg1"), "A file without extension gets the name appended")
+
+	cfg.MetadataFile = ""
+	assert.Empty(t, cfg.InterfaceMetadataFile("wg1"), "In-memory metadata stays in memory for every interface")
+}
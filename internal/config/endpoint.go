@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Values of VALIDATE_ENDPOINT_HOST.
+const (
+	EndpointValidationOff    = "false"  // No check
+	EndpointValidationWarn   = "true"   // Warn at startup if the host is not usable
+	EndpointValidationStrict = "strict" // Refuse to start if the host is not usable
+)
+
+// endpointLookupTimeout bounds the DNS lookup done by ValidateEndpointHost.
+const endpointLookupTimeout = 5 * time.Second
+
+// HostResolver resolves host names; *net.Resolver implements it.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// ValidateEndpointHost checks that host, the SERVER_ENDPOINT_HOST written into client configs,
+// is a literal IP address (IPv6 optionally in brackets) or a name that resolver can resolve.
+func ValidateEndpointHost(host string, resolver HostResolver) error {
+	if host == "" {
+		return errors.New("endpoint host is empty")
+	}
+	if _, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")); err == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), endpointLookupTimeout)
+	defer cancel()
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("endpoint host %q does not resolve: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("endpoint host %q resolves to no addresses", host)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// internal/config/endpoint_test.go
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubResolver resolves only the names in addrs and records every lookup.
+type stubResolver struct {
+	addrs   map[string][]string
+	lookups []string
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups = append(r.lookups, host)
+	if addrs, ok := r.addrs[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestValidateEndpointHost(t *testing.T) {
+	resolver := &stubResolver{addrs: map[string][]string{"vpn.example.com": {"203.0.113.1"}}}
+
+	t.Run("Literal_IP_needs_no_lookup", func(t *testing.T) {
+		for _, host := range []string{"203.0.113.1", "2001:db8::1", "[2001:db8::1]"} {
+			assert.NoError(t, ValidateEndpointHost(host, resolver), host)
+		}
+		assert.Empty(t, resolver.lookups)
+	})
+
+	t.Run("Resolvable_host", func(t *testing.T) {
+		assert.NoError(t, ValidateEndpointHost("vpn.example.com", resolver))
+		assert.Equal(t, []string{"vpn.example.com"}, resolver.lookups)
+	})
+
+	t.Run("Invalid_host", func(t *testing.T) {
+		assert.Error(t, ValidateEndpointHost("vpn.exmaple.con", resolver))
+		assert.Error(t, ValidateEndpointHost("", resolver))
+	})
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// ParseSigningKey decodes CONFIG_SIGNING_KEY: a standard base64 Ed25519 seed (32 bytes)
+// or full private key (64 bytes). An empty value returns nil, which disables signing.
+func ParseSigningKey(value string) (ed25519.PrivateKey, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("signing key is not valid base64: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		key := ed25519.PrivateKey(raw)
+		// The second half of a private key is its public key; reject keys where it does not match the seed.
+		if !ed25519.NewKeyFromSeed(key.Seed()).Equal(key) {
+			return nil, fmt.Errorf("signing key is inconsistent: public half does not match the seed")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("signing key must decode to %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+	}
+}
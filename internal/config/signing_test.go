@@ -0,0 +1,38 @@
+// internal/config/signing_test.go
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSigningKey(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	want := ed25519.NewKeyFromSeed(seed)
+
+	key, err := ParseSigningKey("")
+	require.NoError(t, err)
+	assert.Nil(t, key, "An empty value disables signing")
+
+	key, err = ParseSigningKey(base64.StdEncoding.EncodeToString(seed))
+	require.NoError(t, err)
+	assert.True(t, want.Equal(key))
+
+	key, err = ParseSigningKey(" " + base64.StdEncoding.EncodeToString(want) + "\n")
+	require.NoError(t, err)
+	assert.True(t, want.Equal(key))
+
+	tampered := append(ed25519.PrivateKey(nil), want...)
+	tampered[len(tampered)-1] ^= 1
+	for _, value := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short")), base64.StdEncoding.EncodeToString(tampered)} {
+		_, err := ParseSigningKey(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,39 @@
+package domain
+
+// RefreshResponse is the JSON response for POST /admin/refresh.
+type RefreshResponse struct {
+	// Peers is the number of peers on the interface after the refresh.
+	Peers int `json:"peers" example:"12"`
+	// MetadataReloaded reports whether the metadata store was re-read from its backing file.
+	MetadataReloaded bool `json:"metadataReloaded" example:"true"`
+	// StatsRefreshed reports whether the cached /stats snapshot was recomputed.
+	StatsRefreshed bool `json:"statsRefreshed" example:"true"`
+}
+
+// PruneInactiveRequest is the JSON body of POST /admin/prune-inactive.
+type PruneInactiveRequest struct {
+	// OlderThan is the minimum age of a pruned peer and must be positive, e.g. "30d" or "72h", measured from its metadata createdAt.
+	OlderThan string `json:"olderThan" binding:"required" example:"30d"`
+	// RequireZeroTraffic additionally requires zero received and transmitted bytes. Defaults to true.
+	RequireZeroTraffic *bool `json:"requireZeroTraffic,omitempty" example:"true"`
+}
+
+// PruneInactiveResponse is the JSON response of POST /admin/prune-inactive.
+type PruneInactiveResponse struct {
+	// Pruned lists the public keys of the removed peers.
+	Pruned []string `json:"pruned"`
+	// Failed maps public keys of matching peers that could not be removed to the error.
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// MaintenanceRequest is the JSON body of POST /admin/maintenance.
+type MaintenanceRequest struct {
+	// Enabled turns maintenance mode on (true) or off (false).
+	Enabled *bool `json:"enabled" binding:"required" example:"true"`
+}
+
+// MaintenanceResponse reports the maintenance mode after POST /admin/maintenance.
+type MaintenanceResponse struct {
+	// Enabled reports whether mutating /configs endpoints currently answer 503.
+	Enabled bool `json:"enabled" example:"true"`
+}
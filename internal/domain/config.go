@@ -14,6 +14,11 @@ type Config struct {
 	// Example: "a1b2c3d4...+Z0="
 	PublicKey string `json:"publicKey"` // Mandatory
 
+	// PeerID is a short URL-safe identifier derived from PublicKey, for GET /configs/by-id/{peerId}.
+	// It is not part of 'wg show dump' output and changes when the key is rotated.
+	// Example: "h4zcuwakognvtzdi"
+	PeerID string `json:"peerId,omitempty"`
+
 	// PreSharedKey is an optional pre-shared key for an extra layer of security.
 	// If "(none)" is shown by 'wg show dump', this will be an empty string.
 	// omitempty is used as it's optional.
@@ -50,6 +55,24 @@ type Config struct {
 	// omitempty is used as it might not be set.
 	// Example: 25
 	PersistentKeepalive int `json:"persistentKeepalive,omitempty"`
+
+	// Metadata is the peer's sidecar information from the metadata store, if any.
+	// It is not part of 'wg show dump' output.
+	Metadata *PeerMetadata `json:"metadata,omitempty"`
+
+	// AssignedAddress is the host address allocated from the address pool when the peer was created
+	// without AllowedIPs. It is only set in create responses; AllowedIps holds the same address.
+	// Example: "10.0.0.2/32"
+	AssignedAddress string `json:"assignedAddress,omitempty"`
+
+	// Server is the server's public key and endpoint, for building the client config right away.
+	// It is only set in create and rotate responses.
+	Server *ServerIdentity `json:"server,omitempty"`
+
+	// AllowedIpsMore is how many AllowedIps entries were cut from this list response by ?maxAllowedIps.
+	// Zero (omitted) means AllowedIps is complete.
+	// Example: 3
+	AllowedIpsMore int `json:"allowedIpsMore,omitempty"`
 }
 
 // AllowedIpsUpdate represents the request body for updating a peer's allowed IPs.
@@ -66,19 +89,148 @@ type AllowedIpsUpdate struct {
 type ClientFileRequest struct {
 	ClientPublicKey  string `json:"client_public_key" binding:"required"`  // Client's public key, base64 encoded
 	ClientPrivateKey string `json:"client_private_key" binding:"required"` // Client's private key, base64 encoded
+	// PersistentKeepalive optionally overrides the keepalive written to the client config (0 omits it),
+	// independently of the server-side peer's value. The server peer is not changed.
+	PersistentKeepalive *int `json:"persistent_keepalive,omitempty" binding:"omitempty,min=0,max=65535" example:"25"`
+	// AllowedIps optionally replaces the full-tunnel `0.0.0.0/0, ::/0` routed through the VPN by the client,
+	// e.g. only the VPN subnet for split tunneling. Each entry must be a CIDR prefix.
+	AllowedIps []string `json:"allowed_ips,omitempty" example:"10.8.0.0/24"`
+}
+
+// FullConfigRequest is the body of POST /configs/{publicKey}/full.
+type FullConfigRequest struct {
+	ClientPrivateKey string `json:"client_private_key" binding:"required"` // Client's private key, base64 encoded
+	// PersistentKeepalive optionally overrides the keepalive written to the .conf, as in ClientFileRequest.
+	PersistentKeepalive *int `json:"persistent_keepalive,omitempty" binding:"omitempty,min=0,max=65535" example:"25"`
+}
+
+// FullConfigResponse combines a peer's server-side configuration with its generated client .conf file.
+type FullConfigResponse struct {
+	// Config is the peer as returned by GET /configs/{publicKey}.
+	Config Config `json:"config"`
+	// Filename is the sanitized file name /configs/client-file would send in Content-Disposition.
+	Filename string `json:"filename" example:"peer.conf"`
+	// Conf is the .conf file content, including the supplied private key.
+	Conf string `json:"conf"`
+	// Signature is the base64 Ed25519 signature of Conf, present when CONFIG_SIGNING_KEY is set.
+	Signature string `json:"signature,omitempty"`
+}
+
+// ClientConfigOptions customizes a generated client .conf file.
+type ClientConfigOptions struct {
+	// PersistentKeepalive, if set, replaces the keepalive that would otherwise be written; 0 omits the line.
+	PersistentKeepalive *int
+	// DNS, if set, replaces the configured DNS servers; "" omits the line.
+	DNS *string
+	// MTU, if set, replaces the configured or interface MTU; 0 omits the line.
+	MTU *int
+	// AllowedIPs, if non-empty, replaces the full-tunnel routes of the [Peer] section. Entries must be CIDR prefixes.
+	AllowedIPs []string
+}
+
+// Sources of a ClientConfigExplanation value.
+const (
+	SourceRequest           = "request"            // Override supplied with the request
+	SourceServerDefault     = "server-default"     // Configured server value, e.g. CLIENT_CONFIG_DNS_SERVERS
+	SourcePeer              = "peer"               // The peer's own setting
+	SourceInterfaceFallback = "interface-fallback" // Read from the WireGuard interface because nothing is configured
+)
+
+// ExplainedString is a resolved client config value with where it came from.
+type ExplainedString struct {
+	Value  string `json:"value" example:"1.1.1.1"`
+	Source string `json:"source" example:"server-default"`
+}
+
+// ExplainedInt is ExplainedString for numeric values; 0 means the line is omitted.
+type ExplainedInt struct {
+	Value  int    `json:"value" example:"1420"`
+	Source string `json:"source" example:"interface-fallback"`
+}
+
+// ClientConfigExplanation annotates the defaulted values of a generated client .conf with their source,
+// returned with ?explain=true to debug configs picking up unexpected values.
+type ClientConfigExplanation struct {
+	DNS                 ExplainedString `json:"dns"`
+	MTU                 ExplainedInt    `json:"mtu"`
+	Endpoint            ExplainedString `json:"endpoint"`
+	PersistentKeepalive ExplainedInt    `json:"persistentKeepalive"`
+}
+
+// PreviewConfigRequest is the body of POST /configs/preview: every value of the would-be peer,
+// supplied by the client, so a .conf can be shown before the peer is registered.
+// Field names follow CreatePeerRequest.
+type PreviewConfigRequest struct {
+	PublicKey           string   `json:"public_key" binding:"required"`
+	PrivateKey          string   `json:"private_key" binding:"required"`
+	AllowedIps          []string `json:"allowed_ips" example:"10.0.0.2/32"`
+	PresharedKey        string   `json:"preshared_key,omitempty"`
+	PersistentKeepalive int      `json:"persistent_keepalive,omitempty" binding:"omitempty,min=0,max=65535" example:"25"`
+	// DNS overrides the configured client DNS servers; omit to use them, "" to leave DNS out.
+	DNS *string `json:"dns,omitempty" example:"1.1.1.1"`
+	// MTU overrides the configured client MTU; omit to use it, 0 to leave MTU out.
+	MTU *int `json:"mtu,omitempty" binding:"omitempty,min=0,max=65535" example:"1420"`
+}
+
+// PreviewConfigResponse is the JSON response of POST /configs/preview.
+type PreviewConfigResponse struct {
+	// Conf is the .conf content POST /configs/client-file would produce once the peer exists with these values.
+	Conf string `json:"conf"`
+	// Explain tells where each defaulted value came from; present with ?explain=true.
+	Explain *ClientConfigExplanation `json:"explain,omitempty"`
+}
+
+// ClientFileBase64Response is the JSON form of a generated client .conf file,
+// returned by /configs/client-file?encoding=base64 for embedding in other JSON payloads.
+type ClientFileBase64Response struct {
+	// Filename is the sanitized file name the raw variant sends in Content-Disposition.
+	Filename string `json:"filename" example:"peer.conf"`
+	// ContentBase64 is the .conf file content, standard base64-encoded.
+	ContentBase64 string `json:"contentBase64" example:"W0ludGVyZmFjZV0K..."`
+	// Signature is the base64 Ed25519 signature of the decoded content, present when CONFIG_SIGNING_KEY is set.
+	Signature string `json:"signature,omitempty"`
+	// Explain tells where each defaulted value came from; present with ?explain=true.
+	Explain *ClientConfigExplanation `json:"explain,omitempty"`
+}
+
+// ClientFileDataURIResponse is the data: URI form of a generated client .conf file,
+// returned by /configs/client-file?encoding=datauri so a web UI can use it directly as a download link.
+type ClientFileDataURIResponse struct {
+	// Filename is the sanitized file name, suitable for the anchor's download attribute.
+	Filename string `json:"filename" example:"peer.conf"`
+	// DataURI is the .conf file content as a base64 data: URI.
+	DataURI string `json:"dataUri" example:"data:text/plain;base64,W0ludGVyZmFjZV0K..."`
+	// Signature is the base64 Ed25519 signature of the decoded content, present when CONFIG_SIGNING_KEY is set.
+	Signature string `json:"signature,omitempty"`
+	// Explain tells where each defaulted value came from; present with ?explain=true.
+	Explain *ClientConfigExplanation `json:"explain,omitempty"`
 }
 
 // CreatePeerRequest represents the request body for creating a new peer
 // where the server generates the cryptographic keys.
 type CreatePeerRequest struct {
+	// PublicKey optionally imports an existing peer identity instead of generating new keys,
+	// e.g. when migrating peers from another server.
+	// Required if PrivateKey is set.
+	PublicKey string `json:"public_key,omitempty" binding:"required_with=PrivateKey"`
+	// PrivateKey optionally accompanies PublicKey. It must match PublicKey, is only echoed back
+	// in the response for immediate client config generation and is never stored.
+	PrivateKey string `json:"private_key,omitempty"`
 	// AllowedIps is a list of IP networks (CIDR notation) for the new peer. Can be empty.
 	AllowedIps []string `json:"allowed_ips"`
 	// PreSharedKey is an optional pre-shared key for the new peer.
 	PreSharedKey string `json:"preshared_key,omitempty"`
+	// GeneratePresharedKey asks the server to generate a pre-shared key when PreSharedKey is empty.
+	// The generated key is returned in the response. Only supported when the server generates the keys.
+	GeneratePresharedKey bool `json:"generate_preshared_key,omitempty"`
 	// PersistentKeepalive is an optional interval in seconds for keepalive packets.
 	PersistentKeepalive int `json:"persistent_keepalive,omitempty"`
-	// You_can_add_other_fields_here_if_needed_by_client_for_new_peer_creation,
-	// e.g., a friendly name or description for the peer, though these are not standard WG fields.
+	// Name is an optional friendly name stored in the metadata store (not a WireGuard field).
+	Name string `json:"name,omitempty"`
+	// Description is an optional note stored in the metadata store (not a WireGuard field).
+	Description string `json:"description,omitempty"`
+	// Tags are optional labels stored in the metadata store (not a WireGuard field).
+	Tags []string `json:"tags,omitempty"`
 }
 
 // GetConfigRequest represents the request body for getting a peer configuration by public key.
@@ -93,10 +245,22 @@ type DeleteConfigRequest struct {
 	PublicKey string `json:"public_key" binding:"required"`
 }
 
+// DeleteConfigResponse is returned by the delete endpoint in verbose mode.
+// It reports whether the peer existed before the call and whether it was actually removed.
+type DeleteConfigResponse struct {
+	Deleted bool `json:"deleted" example:"true"`
+	Existed bool `json:"existed" example:"true"`
+}
+
 // RotatePeerRequest represents the request body for rotating a peer's keys.
 type RotatePeerRequest struct {
 	// PublicKey is the peer's current public key to rotate.
 	PublicKey string `json:"public_key" binding:"required"`
+	// Name optionally replaces the peer's metadata name; other metadata is carried over to the new key.
+	Name string `json:"name,omitempty"`
+	// ExpectedPublicKey optionally guards the rotation (optimistic concurrency): it must equal PublicKey,
+	// and if the peer has meanwhile been rotated away or deleted, 409 is returned instead of 404.
+	ExpectedPublicKey string `json:"expectedPublicKey,omitempty"`
 }
 
 // UpdateAllowedIpsRequest represents the request body for updating a peer's allowed IPs.
@@ -107,3 +271,49 @@ type UpdateAllowedIpsRequest struct {
 	// This will replace the existing list. An empty list might remove all allowed IPs.
 	AllowedIps []string `json:"allowed_ips"`
 }
+
+// BulkRotateRequest represents the request body for rotating keys of several peers at once.
+type BulkRotateRequest struct {
+	// PublicKeys are the current public keys of the peers to rotate.
+	PublicKeys []string `json:"publicKeys" binding:"required,min=1"`
+}
+
+// BulkRotateResult is the outcome of rotating a single peer within a bulk rotation.
+// Exactly one of NewConfig and Error is set.
+type BulkRotateResult struct {
+	// PublicKey is the peer's public key before rotation, as given in the request.
+	PublicKey string `json:"publicKey"`
+	// NewConfig is the rotated peer configuration, including the new PrivateKey. It is also set next to
+	// Error when the new peer was created but the old one could not be removed.
+	NewConfig *Config `json:"newConfig,omitempty"`
+	// Error describes why rotation failed for this peer.
+	Error string `json:"error,omitempty"`
+}
+
+// BulkRotateResponse is returned by the bulk rotate endpoint, with one result per requested key.
+type BulkRotateResponse struct {
+	Results []BulkRotateResult `json:"results"`
+}
+
+// BulkRotatePSKResponse is returned by the bulk preshared key rotation endpoint.
+type BulkRotatePSKResponse struct {
+	// PresharedKeys maps the public key of every rotated peer to its new preshared key.
+	PresharedKeys map[string]string `json:"presharedKeys"`
+	// Failed maps public keys of peers whose preshared key could not be rotated to the error.
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// VerifyKeyRequest represents the request body for checking that a private key corresponds to a public key.
+type VerifyKeyRequest struct {
+	// PublicKey is the peer's registered public key.
+	PublicKey string `json:"public_key" binding:"required"`
+	// PrivateKey is the client's private key. It is only used to derive a public key
+	// for the comparison and is never stored or logged.
+	PrivateKey string `json:"private_key" binding:"required"`
+}
+
+// VerifyKeyResponse is the response body for a key pair verification.
+type VerifyKeyResponse struct {
+	// Matches is true if the public key derived from the private key equals the supplied public key.
+	Matches bool `json:"matches" example:"true"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Defaults shared by the configuration loader and the packages that apply them,
+// so each value is defined once.
+const (
+	// DefaultClientFileCacheControl keeps generated client files, which embed private keys, out of every cache.
+	DefaultClientFileCacheControl = "no-store"
+
+	// DefaultOnlineThreshold is the maximum handshake age for a peer to count as online.
+	// WireGuard re-handshakes every two minutes while traffic flows, so three minutes leaves some slack.
+	DefaultOnlineThreshold = 3 * time.Minute
+
+	// DefaultMaxClientDNS is the maximum number of DNS entries a requested client config DNS list may have.
+	DefaultMaxClientDNS = 4
+
+	// Default probe paths, used unless LIVENESS_PATH/READINESS_PATH override them.
+	DefaultLivenessPath  = "/healthz"
+	DefaultReadinessPath = "/readyz"
+)
+
+// ReservedPathPrefixes are the top-level paths the API router serves.
+// A probe path on or under one of them would be registered twice, so the configuration loader rejects it.
+var ReservedPathPrefixes = []string{"/configs", "/keys", "/server", "/stats", "/metrics", "/admin", "/swagger"}
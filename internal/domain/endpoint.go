@@ -0,0 +1,13 @@
+package domain
+
+import (
+	"net"
+	"strings"
+)
+
+// JoinEndpoint forms the host:port endpoint written into client configs.
+// An IPv6 literal host, with or without brackets, is bracketed as in [2001:db8::1]:51820;
+// IPv4 addresses and host names are joined unchanged.
+func JoinEndpoint(host, port string) string {
+	return net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), port)
+}
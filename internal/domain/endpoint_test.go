@@ -0,0 +1,22 @@
+// internal/domain/endpoint_test.go
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinEndpoint(t *testing.T) {
+	for _, tc := range []struct {
+		host, port, expected string
+	}{
+		{"2001:db8::1", "51820", "[2001:db8::1]:51820"},
+		{"[2001:db8::1]", "51820", "[2001:db8::1]:51820"},
+		{"fe80::1%eth0", "51820", "[fe80::1%eth0]:51820"},
+		{"203.0.113.1", "51820", "203.0.113.1:51820"},
+		{"vpn.example.com", "51820", "vpn.example.com:51820"},
+	} {
+		assert.Equal(t, tc.expected, JoinEndpoint(tc.host, tc.port), tc.host)
+	}
+}
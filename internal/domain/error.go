@@ -1,5 +1,7 @@
 package domain
 
+import "errors"
+
 // ErrorResponse represents a generic JSON error response body for API errors.
 // It provides a simple structure with a single "error" field containing a message.
 type ErrorResponse struct {
@@ -7,4 +9,49 @@ type ErrorResponse struct {
 	// This message is intended for the API consumer.
 	// Example: "Peer not found" or "Invalid input: public key is malformed"
 	Error string `json:"error" example:"Peer not found"`
+	// Code is a stable, machine-readable error code. It is set for unexpected internal errors,
+	// whose message may be replaced by a generic one when error sanitization is enabled.
+	// Example: "internal_error"
+	Code string `json:"code,omitempty" example:"internal_error"`
+}
+
+// ErrorCodeInternal is the ErrorResponse code of unexpected internal errors.
+const ErrorCodeInternal = "internal_error"
+
+// ErrorCategory classifies a validation or state error by the kind of problem, which decides its HTTP status.
+type ErrorCategory int
+
+const (
+	// CategoryMalformed is input that cannot be parsed, such as a key that is not base64 (400 Bad Request).
+	CategoryMalformed ErrorCategory = iota + 1
+	// CategoryInvalid is well-formed input that is semantically invalid, such as overlapping AllowedIPs (422 Unprocessable Entity).
+	CategoryInvalid
+	// CategoryConflict is valid input that conflicts with the current state, such as an existing peer (409 Conflict).
+	CategoryConflict
+	// CategoryForbidden is a request the server's configuration does not allow, such as per-peer traffic
+	// statistics with EXPOSE_TRAFFIC_STATS=false (403 Forbidden). Retrying does not help.
+	CategoryForbidden
+)
+
+// CategorizedError is a sentinel error carrying its ErrorCategory.
+// Sentinels are compared with errors.Is as usual; CategoryOf finds the category through wrapping.
+type CategorizedError struct {
+	Category ErrorCategory
+	msg      string
+}
+
+// NewCategorizedError creates a sentinel error of the given category.
+func NewCategorizedError(category ErrorCategory, msg string) *CategorizedError {
+	return &CategorizedError{Category: category, msg: msg}
+}
+
+func (e *CategorizedError) Error() string { return e.msg }
+
+// CategoryOf returns the category of the first CategorizedError in err's chain, or 0 if there is none.
+func CategoryOf(err error) ErrorCategory {
+	var ce *CategorizedError
+	if errors.As(err, &ce) {
+		return ce.Category
+	}
+	return 0
 }
@@ -0,0 +1,11 @@
+package domain
+
+// ExportStats summarizes a streamed peer export.
+type ExportStats struct {
+	// Peers is the number of [Peer] blocks written.
+	Peers int
+	// Bytes is the total number of bytes written, including the header and any truncation notice.
+	Bytes int64
+	// Truncated is true if the export stopped early because it reached the configured size cap.
+	Truncated bool
+}
@@ -20,4 +20,17 @@ type ReadinessResponse struct {
 	// This field is omitted if the status is "ready".
 	// Example: "wg command failed: wireguard command timed out"
 	Error string `json:"error,omitempty" example:"wg command failed"`
+	// WgVersion is the output of 'wg --version'. Only included with ?verbose=true.
+	// Example: "wireguard-tools v1.0.20210914 - https://git.zx2c4.com/wireguard-tools/"
+	WgVersion string `json:"wgVersion,omitempty" example:"wireguard-tools v1.0.20210914 - https://git.zx2c4.com/wireguard-tools/"`
+	// Interface is the name of the managed WireGuard interface. Only included with ?verbose=true.
+	// Example: "wg0"
+	Interface string `json:"interface,omitempty" example:"wg0"`
+	// ListenPort is the WireGuard UDP listen port that was probed. Only included with ?verbose=true
+	// when READY_CHECK_LISTEN_PORT is enabled.
+	// Example: 51820
+	ListenPort int `json:"listenPort,omitempty" example:"51820"`
+	// ListenPortBound reports whether the UDP listen port is bound on this host, i.e. the VPN is listening,
+	// as opposed to the API process merely being up. Omitted if the port was not or could not be probed.
+	ListenPortBound *bool `json:"listenPortBound,omitempty" example:"true"`
 }
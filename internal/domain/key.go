@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// KeyLen is the length in bytes of a decoded WireGuard key (public, private or preshared).
+const KeyLen = 32
+
+// ErrInvalidKeyFormat is returned when a string is not a base64-encoded 32-byte WireGuard key.
+var ErrInvalidKeyFormat = NewCategorizedError(CategoryMalformed, "invalid key format")
+
+// ParseKey decodes a base64-encoded WireGuard key, as printed by the 'wg' utility.
+func ParseKey(key string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not valid base64", ErrInvalidKeyFormat)
+	}
+	if len(raw) != KeyLen {
+		return nil, fmt.Errorf("%w: decoded key is %d bytes, expected %d", ErrInvalidKeyFormat, len(raw), KeyLen)
+	}
+	return raw, nil
+}
+
+// IsValidKey reports whether key is a base64-encoded 32-byte WireGuard key.
+func IsValidKey(key string) bool {
+	_, err := ParseKey(key)
+	return err == nil
+}
+
+// KeyFingerprintRequest represents the request body for computing a public key fingerprint.
+type KeyFingerprintRequest struct {
+	// PublicKey is the base64-encoded WireGuard public key.
+	PublicKey string `json:"publicKey" binding:"required"`
+}
+
+// KeyFingerprintResponse is the response body for a public key fingerprint.
+type KeyFingerprintResponse struct {
+	PublicKey string `json:"publicKey"`
+	// Fingerprint is a short, stable identifier for the key, suitable for logs and UIs.
+	Fingerprint string `json:"fingerprint" example:"3f2a:9c01:b7e4:5d68"`
+}
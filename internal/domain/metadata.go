@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// PeerMetadata is sidecar information about a peer that WireGuard itself does not store.
+// It is kept in the metadata store, keyed by the peer's public key.
+type PeerMetadata struct {
+	// Name is an optional human-friendly name for the peer.
+	Name string `json:"name,omitempty" example:"alice-laptop"`
+	// Description is an optional free-form note about the peer.
+	Description string `json:"description,omitempty"`
+	// Tags are optional free-form labels such as "team:ops" or "env:staging".
+	Tags []string `json:"tags,omitempty" example:"env:staging,team:ops"`
+	// CreatedAt is when the peer was first created through this API.
+	// It is kept across key rotations.
+	CreatedAt time.Time `json:"createdAt"`
+	// RotationCount is how many times the peer's keys have been rotated through this API.
+	RotationCount int `json:"rotationCount,omitempty" example:"2"`
+	// LastRotatedAt is when the peer's keys were last rotated; absent if they never were.
+	LastRotatedAt *time.Time `json:"lastRotatedAt,omitempty"`
+}
+
+// HasTags reports whether md carries every tag in tags. An empty tags list always matches.
+func (md *PeerMetadata) HasTags(tags []string) bool {
+	for _, want := range tags {
+		if !slices.Contains(md.Tags, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// RotateOptions customizes a key rotation.
+type RotateOptions struct {
+	// Name, if set, replaces the peer's metadata name on the rotated key.
+	Name string
+	// ExpectedPublicKey, if set, must equal the key being rotated; if the peer no longer has it,
+	// for example because a concurrent rotation won, the rotation fails with a conflict.
+	ExpectedPublicKey string
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "encoding/json"
+
+// PatchOperation is one RFC 6902 JSON Patch operation on a peer,
+// e.g. {"op": "add", "path": "/allowedIps/-", "value": "10.0.0.7/32"}.
+type PatchOperation struct {
+	// Op is "add", "remove", "replace" or "test".
+	Op string `json:"op" binding:"required" example:"replace"`
+	// Path is a JSON Pointer to a patchable field: /allowedIps, /allowedIps/<index>, /allowedIps/- (append),
+	// /presharedKey or /persistentKeepalive.
+	Path string `json:"path" binding:"required" example:"/persistentKeepalive"`
+	// Value is the new value for add and replace, or the expected one for test. It is ignored by remove.
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
+}
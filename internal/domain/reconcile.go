@@ -0,0 +1,30 @@
+package domain
+
+// DesiredPeer is one entry of a desired-state document used by /configs/apply.
+// An empty PresharedKey or a zero Keepalive means "leave as is" for an existing peer,
+// since 'wg set' cannot clear them without extra commands.
+type DesiredPeer struct {
+	// PublicKey identifies the peer.
+	PublicKey string `json:"publicKey"`
+	// AllowedIps is the complete desired list of allowed IP networks for the peer.
+	AllowedIps []string `json:"allowedIps"`
+	// PresharedKey is the optional desired pre-shared key.
+	PresharedKey string `json:"presharedKey,omitempty"`
+	// Keepalive is the optional desired persistent keepalive interval in seconds.
+	Keepalive int `json:"keepalive,omitempty"`
+}
+
+// ApplyResult summarizes what /configs/apply did, as lists of public keys.
+type ApplyResult struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Deleted   []string `json:"deleted"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// ReconcilePlan lists, by public key, the changes /configs/apply would make for a desired-state document.
+type ReconcilePlan struct {
+	Create []string `json:"create"`
+	Update []string `json:"update"`
+	Delete []string `json:"delete"`
+}
@@ -0,0 +1,82 @@
+package domain
+
+// AddressDrift describes the result of comparing the interface addresses from the application
+// configuration with the addresses actually assigned to the WireGuard interface.
+// A drift means generated client configs may not match the live server.
+type AddressDrift struct {
+	// Checked is false when the live addresses could not be obtained; the other fields are then incomplete.
+	Checked bool `json:"checked" example:"true"`
+	// InSync is true when configured and live addresses are the same set.
+	InSync bool `json:"inSync" example:"true"`
+	// Configured lists the addresses from SERVER_INTERFACE_ADDRESSES / WG_ACTUAL_INTERFACE_ADDRESSES.
+	Configured []string `json:"configured"`
+	// Live lists the addresses currently assigned to the interface.
+	Live []string `json:"live,omitempty"`
+	// Missing lists configured addresses that are not present on the interface.
+	Missing []string `json:"missing,omitempty"`
+	// Unexpected lists live addresses that are not in the configuration.
+	Unexpected []string `json:"unexpected,omitempty"`
+	// Error explains why the live addresses could not be obtained, if Checked is false.
+	Error string `json:"error,omitempty"`
+}
+
+// ServerIdentity is what a client needs to know about the server to build its config.
+// It is embedded in create and rotate responses so the client needs no second request.
+type ServerIdentity struct {
+	// PublicKey is the server's public key, the client's [Peer] PublicKey.
+	PublicKey string `json:"publicKey"`
+	// Endpoint is the public host:port clients connect to.
+	// Example: "203.0.113.1:51820"
+	Endpoint string `json:"endpoint,omitempty" example:"203.0.113.1:51820"`
+}
+
+// InterfaceInfo is the interface line of 'wg show <iface> dump', describing the live interface
+// rather than the values configured through environment variables.
+type InterfaceInfo struct {
+	// PrivateKey is the interface private key. It is never serialized.
+	PrivateKey string `json:"-"`
+	// PublicKey is the interface public key.
+	PublicKey string `json:"publicKey"`
+	// ListenPort is the UDP port the interface listens on.
+	ListenPort int `json:"listenPort" example:"51820"`
+	// FwMark is the firewall mark set on outgoing packets; 0 means off.
+	FwMark uint32 `json:"fwmark,omitempty" example:"0"`
+}
+
+// ServerInfo is the JSON response for the /server endpoint.
+// It describes this server's WireGuard interface as seen by the API.
+type ServerInfo struct {
+	// Interface is the name of the managed WireGuard interface.
+	// Example: "wg0"
+	Interface string `json:"interface" example:"wg0"`
+	// Name identifies this API instance among several (SERVER_NAME).
+	Name string `json:"name,omitempty" example:"eu-west-1"`
+	// Description is a free-form description of this instance (SERVER_DESCRIPTION).
+	Description string `json:"description,omitempty" example:"Frankfurt office gateway"`
+	// PublicKey is the server's public key, as used in client configs.
+	PublicKey string `json:"publicKey"`
+	// Endpoint is the public host:port clients connect to.
+	// Example: "203.0.113.1:51820"
+	Endpoint string `json:"endpoint,omitempty" example:"203.0.113.1:51820"`
+	// ListenPort is the UDP port the interface listens on.
+	ListenPort int `json:"listenPort" example:"51820"`
+	// SigningPublicKey is the base64 Ed25519 public key verifying X-Config-Signature, present when signing is enabled.
+	SigningPublicKey string `json:"signingPublicKey,omitempty"`
+	// InterfaceAddresses are the configured interface addresses.
+	InterfaceAddresses []string `json:"interfaceAddresses"`
+	// AddressDrift is the result of the address drift check performed at startup.
+	AddressDrift AddressDrift `json:"addressDrift"`
+}
+
+// ListenPortRequest is the JSON body of POST /server/listen-port.
+type ListenPortRequest struct {
+	// Port is the new UDP listen port, 1-65535.
+	Port int `json:"port" binding:"required" example:"51821"`
+}
+
+// ListenPortResponse reports the listen port and client endpoint after POST /server/listen-port.
+type ListenPortResponse struct {
+	ListenPort int `json:"listenPort" example:"51821"`
+	// Endpoint is the host:port written into client configs from now on.
+	Endpoint string `json:"endpoint,omitempty" example:"203.0.113.1:51821"`
+}
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// InterfaceStats is an aggregate snapshot of the interface's peers, as cached by the stats collector.
+type InterfaceStats struct {
+	// PeerCount is the number of peers on the interface.
+	PeerCount int `json:"peerCount" example:"12"`
+	// ReceiveBytes is the sum of bytes received from all peers.
+	ReceiveBytes uint64 `json:"receiveBytes"`
+	// TransmitBytes is the sum of bytes sent to all peers.
+	TransmitBytes uint64 `json:"transmitBytes"`
+	// CollectedAt is when the snapshot was taken.
+	CollectedAt time.Time `json:"collectedAt"`
+	// DeltaSince is when the previous snapshot, which the deltas are measured against, was taken.
+	// It is absent until two snapshots exist.
+	DeltaSince *time.Time `json:"deltaSince,omitempty"`
+	// RxDelta is the sum of the peers' RxDelta.
+	RxDelta uint64 `json:"rxDelta"`
+	// TxDelta is the sum of the peers' TxDelta.
+	TxDelta uint64 `json:"txDelta"`
+	// Peers lists the per-peer traffic between the previous snapshot and this one.
+	Peers []PeerTrafficDelta `json:"peers,omitempty"`
+}
+
+// PeerTrafficDelta is one peer's traffic between two stats snapshots.
+// After a counter reset (e.g. the interface was recreated) the new counter value is reported.
+// A peer missing from the previous snapshot reports its full counters.
+type PeerTrafficDelta struct {
+	PublicKey string `json:"publicKey"`
+	RxDelta   uint64 `json:"rxDelta"`
+	TxDelta   uint64 `json:"txDelta"`
+}
+
+// CollectorStatus reports the health of the background stats collector,
+// so operators can tell whether cached stats are stale because collection keeps failing.
+type CollectorStatus struct {
+	// IntervalSeconds is the configured collection interval.
+	IntervalSeconds int `json:"intervalSeconds" example:"30"`
+	// LastSuccessAt is when stats were last collected successfully; absent if never.
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	// LastErrorAt is when the most recent failed collection happened; absent if none failed.
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+	// LastError is the error of the most recent failed collection.
+	LastError string `json:"lastError,omitempty"`
+	// ConsecutiveFailures counts failed collections since the last success.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// Stale is true if there has been no successful collection within two intervals.
+	Stale bool `json:"stale"`
+}
+
+// PeerStats is the JSON response of POST /configs/stats: a peer's live traffic counters without
+// key material, for dashboards and monitoring systems.
+type PeerStats struct {
+	PublicKey string `json:"publicKey"`
+	// LatestHandshake is the time of the last completed handshake; omitted if the peer never connected.
+	LatestHandshake *time.Time `json:"latestHandshake,omitempty" example:"2024-05-01T12:00:00Z"`
+	ReceiveBytes    uint64     `json:"receiveBytes" example:"1048576"`
+	TransmitBytes   uint64     `json:"transmitBytes" example:"524288"`
+	// Online reports whether the latest handshake is within ONLINE_THRESHOLD_SECONDS.
+	Online bool `json:"online" example:"true"`
+}
+
+// PeerSummary is the JSON response of GET /configs/summary.
+type PeerSummary struct {
+	// Total is the number of peers on the interface; the other three counts add up to it.
+	Total int `json:"total" example:"12"`
+	// Online counts peers whose latest handshake is within ONLINE_THRESHOLD_SECONDS.
+	Online int `json:"online" example:"7"`
+	// Offline counts peers that completed a handshake, but not within the threshold.
+	Offline int `json:"offline" example:"3"`
+	// NeverConnected counts peers that never completed a handshake.
+	NeverConnected int `json:"neverConnected" example:"2"`
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StatsRefresher recomputes a cached statistics snapshot on demand.
+type StatsRefresher interface {
+	Collect(ctx context.Context) error
+}
+
+// InactivePruner deletes peers that never connected.
+type InactivePruner interface {
+	PruneInactive(ctx context.Context, olderThan time.Duration, requireZeroTraffic bool) (*domain.PruneInactiveResponse, error)
+}
+
+// CacheResetter drops a cached result so the next read recomputes it.
+type CacheResetter interface {
+	Reset()
+}
+
+// MaintenanceSwitch turns maintenance mode on and off.
+type MaintenanceSwitch interface {
+	Enabled() bool
+	SetEnabled(enabled bool)
+}
+
+// AdminHandler serves operator endpoints under /admin.
+type AdminHandler struct {
+	repo     repository.Repo
+	metadata repository.MetadataStore
+	stats    StatsRefresher     // nil when the stats collector is disabled
+	logs     *logger.RingBuffer // nil disables GET /admin/logs/stream
+	pruner   InactivePruner     // nil disables POST /admin/prune-inactive
+	maint    MaintenanceSwitch  // nil disables POST /admin/maintenance
+	caches   []CacheResetter    // Reset by POST /admin/refresh
+
+	sanitizeErrs bool // Hide internal error details from clients, as WithErrorSanitization does
+}
+
+// AdminOption customizes an AdminHandler.
+type AdminOption func(*AdminHandler)
+
+// WithLogBuffer enables GET /admin/logs/stream, serving lines from the given buffer.
+func WithLogBuffer(buf *logger.RingBuffer) AdminOption {
+	return func(h *AdminHandler) {
+		h.logs = buf
+	}
+}
+
+// WithInactivePruner enables POST /admin/prune-inactive, deleting peers through p.
+func WithInactivePruner(p InactivePruner) AdminOption {
+	return func(h *AdminHandler) {
+		h.pruner = p
+	}
+}
+
+// WithCacheResetters makes POST /admin/refresh reset caches, such as the Prometheus peer gauges.
+func WithCacheResetters(caches ...CacheResetter) AdminOption {
+	return func(h *AdminHandler) {
+		h.caches = append(h.caches, caches...)
+	}
+}
+
+// WithAdminErrorSanitization is WithErrorSanitization for the admin endpoints.
+func WithAdminErrorSanitization(enabled bool) AdminOption {
+	return func(h *AdminHandler) {
+		h.sanitizeErrs = enabled
+	}
+}
+
+// WithMaintenanceSwitch enables POST /admin/maintenance, toggling m.
+func WithMaintenanceSwitch(m MaintenanceSwitch) AdminOption {
+	return func(h *AdminHandler) {
+		h.maint = m
+	}
+}
+
+// logStreamHeartbeat is how often an idle log stream sends an SSE comment to keep proxies from closing it.
+const logStreamHeartbeat = 15 * time.Second
+
+// NewAdminHandler creates a new AdminHandler. metadata and stats may be nil.
+func NewAdminHandler(repo repository.Repo, metadata repository.MetadataStore, stats StatsRefresher, opts ...AdminOption) *AdminHandler {
+	if repo == nil {
+		logger.Logger.Fatal("Repository cannot be nil for AdminHandler")
+	}
+	h := &AdminHandler{repo: repo, metadata: metadata, stats: stats}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Refresh godoc
+// @Summary      Force-refresh all caches
+// @Description  Drops the cached /readyz result and Prometheus peer gauges, reloads peer metadata from PEER_METADATA_FILE and recomputes the /stats snapshot,
+// @Description  so out-of-band changes made with `wg` or by editing the metadata file become visible immediately.
+// @Description  Requires `Authorization: Bearer <ADMIN_TOKEN>`; the endpoint is not registered when ADMIN_TOKEN is unset.
+// @Tags         admin
+// @Produce      json
+// @Security     AdminToken
+// @Success      200  {object}  domain.RefreshResponse  "Caches refreshed; includes the current peer count."
+// @Failure      401  {object}  domain.ErrorResponse    "Missing or wrong admin token."
+// @Failure      500  {object}  domain.ErrorResponse    "Metadata reload or peer listing failed."
+// @Failure      503  {object}  domain.ErrorResponse    "Service unavailable (WireGuard timeout)."
+// @Router       /admin/refresh [post]
+func (h *AdminHandler) Refresh(c *gin.Context) {
+	logger.Logger.Info("Admin refresh requested")
+
+	for _, cache := range h.caches {
+		cache.Reset()
+	}
+
+	resp := domain.RefreshResponse{}
+	if reloader, ok := h.metadata.(repository.MetadataReloader); ok {
+		if err := reloader.Reload(); err != nil {
+			logger.Logger.Error("Admin refresh: failed to reload peer metadata", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, internalErrorResponse("Failed to reload peer metadata", err, h.sanitizeErrs))
+			return
+		}
+		resp.MetadataReloaded = true
+	}
+
+	peers, err := h.repo.ListConfigs(c.Request.Context())
+	if err != nil {
+		logger.Logger.Error("Admin refresh: failed to list peers", zap.Error(err))
+		if errors.Is(err, repository.ErrWgTimeout) {
+			c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "WireGuard operation timed out."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, internalErrorResponse("Failed to list peers", err, h.sanitizeErrs))
+		return
+	}
+	resp.Peers = len(peers)
+
+	if h.stats != nil {
+		// A failure is recorded by the collector and visible on /server/collector.
+		resp.StatsRefreshed = h.stats.Collect(c.Request.Context()) == nil
+	}
+
+	logger.Logger.Info("Admin refresh completed",
+		zap.Int("peers", resp.Peers),
+		zap.Bool("metadataReloaded", resp.MetadataReloaded),
+		zap.Bool("statsRefreshed", resp.StatsRefreshed))
+	c.JSON(http.StatusOK, resp)
+}
+
+// PruneInactive godoc
+// @Summary      Prune peers that never connected
+// @Description  Deletes peers that never completed a handshake and whose metadata createdAt is older than `olderThan` (a positive age, e.g. "30d", "72h").
+// @Description  Unless `requireZeroTraffic` is false, peers with any received or transmitted bytes are kept. Peers without a recorded createdAt are never pruned.
+// @Description  This is destructive and requires `?confirm=true`. The response lists the pruned public keys.
+// @Description  Requires `Authorization: Bearer <ADMIN_TOKEN>`; the endpoint is not registered when ADMIN_TOKEN is unset.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        pruneRequest  body      domain.PruneInactiveRequest   true   "Minimum age and traffic requirement."
+// @Param        confirm       query     bool                          true   "Must be true to delete anything."
+// @Success      200           {object}  domain.PruneInactiveResponse  "Pruned peers, plus any that matched but could not be removed."
+// @Failure      400           {object}  domain.ErrorResponse          "Malformed body, invalid olderThan, or missing confirm=true."
+// @Failure      401           {object}  domain.ErrorResponse          "Missing or wrong admin token."
+// @Failure      500           {object}  domain.ErrorResponse          "Peer listing failed."
+// @Failure      503           {object}  domain.ErrorResponse          "Pruning is not enabled, maintenance mode is on, or a WireGuard command timed out."
+// @Router       /admin/prune-inactive [post]
+func (h *AdminHandler) PruneInactive(c *gin.Context) {
+	if h.pruner == nil {
+		c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "Pruning is not enabled."})
+		return
+	}
+	var req domain.PruneInactiveRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+	olderThan, err := ParseAgeDuration(req.OlderThan)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid olderThan: " + err.Error()})
+		return
+	}
+	if olderThan <= 0 {
+		// A zero age would match every never-connected peer, including ones created a moment ago.
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid olderThan: must be a positive duration"})
+		return
+	}
+	if confirmed, _ := strconv.ParseBool(c.Query("confirm")); !confirmed {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Pruning deletes peers; repeat the request with ?confirm=true."})
+		return
+	}
+	requireZeroTraffic := req.RequireZeroTraffic == nil || *req.RequireZeroTraffic
+
+	logger.Logger.Info("Admin prune of inactive peers requested",
+		zap.Duration("olderThan", olderThan), zap.Bool("requireZeroTraffic", requireZeroTraffic))
+	resp, err := h.pruner.PruneInactive(c.Request.Context(), olderThan, requireZeroTraffic)
+	if err != nil {
+		logger.Logger.Error("Admin prune: failed to list peers", zap.Error(err))
+		if errors.Is(err, repository.ErrWgTimeout) {
+			c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "WireGuard operation timed out."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, internalErrorResponse("Failed to list peers", err, h.sanitizeErrs))
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SetMaintenance godoc
+// @Summary      Toggle maintenance mode
+// @Description  While maintenance mode is on, every mutating /configs endpoint and /admin/prune-inactive answer 503 with a maintenance message; reads, health checks and /admin keep working.
+// @Description  The startup state comes from MAINTENANCE_MODE. The state is not persisted across restarts.
+// @Description  Requires `Authorization: Bearer <ADMIN_TOKEN>`; the endpoint is not registered when ADMIN_TOKEN is unset.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        maintenanceRequest  body      domain.MaintenanceRequest   true  "Desired maintenance state."
+// @Success      200                 {object}  domain.MaintenanceResponse  "Maintenance mode after the change."
+// @Failure      400                 {object}  domain.ErrorResponse        "Malformed body or missing enabled."
+// @Failure      401                 {object}  domain.ErrorResponse        "Missing or wrong admin token."
+// @Failure      503                 {object}  domain.ErrorResponse        "Maintenance mode is not available."
+// @Router       /admin/maintenance [post]
+func (h *AdminHandler) SetMaintenance(c *gin.Context) {
+	if h.maint == nil {
+		c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "Maintenance mode is not available."})
+		return
+	}
+	var req domain.MaintenanceRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+	h.maint.SetEnabled(*req.Enabled)
+	logger.Logger.Warn("Maintenance mode changed", zap.Bool("enabled", *req.Enabled), zap.String("clientIP", c.ClientIP()))
+	c.JSON(http.StatusOK, domain.MaintenanceResponse{Enabled: h.maint.Enabled()})
+}
+
+// StreamLogs godoc
+// @Summary      Stream recent logs
+// @Description  Streams the service's log lines as Server-Sent Events (`event: log`, one JSON-encoded entry per `data:` line).
+// @Description  The last lines kept in memory are sent first, followed by new lines as they are written. Lines are dropped for a client that cannot keep up.
+// @Description  Requires `Authorization: Bearer <ADMIN_TOKEN>`; the endpoint is not registered when ADMIN_TOKEN is unset.
+// @Tags         admin
+// @Produce      text/event-stream
+// @Security     AdminToken
+// @Success      200  {string}  string                "SSE stream of log lines."
+// @Failure      401  {object}  domain.ErrorResponse  "Missing or wrong admin token."
+// @Failure      503  {object}  domain.ErrorResponse  "Log streaming is not enabled."
+// @Router       /admin/logs/stream [get]
+func (h *AdminHandler) StreamLogs(c *gin.Context) {
+	if h.logs == nil {
+		c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "Log streaming is not enabled."})
+		return
+	}
+
+	logger.Logger.Info("Admin log stream opened", zap.String("clientIP", c.ClientIP()))
+	recent, lines, cancel := h.logs.Subscribe()
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	c.Status(http.StatusOK)
+
+	for _, line := range recent {
+		writeLogEvent(c, line)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case line := <-lines:
+			writeLogEvent(c, line)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": keepalive\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeLogEvent writes one log line as an SSE "log" event.
+func writeLogEvent(c *gin.Context, line string) {
+	// Encoded entries are single-line JSON, but guard against embedded newlines breaking the framing.
+	line = strings.ReplaceAll(line, "\n", "\\n")
+	fmt.Fprintf(c.Writer, "event: log\ndata: %s\n\n", line)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// strictJSONKey is the gin context key under which StrictJSON marks a request for strict decoding.
+const strictJSONKey = "strictJSON"
+
+// StrictJSON makes the handlers of the requests it wraps reject JSON bodies with fields the target
+// struct does not have, instead of ignoring them. Unlike gin's EnableDecoderDisallowUnknownFields
+// it applies per router, not to the whole process.
+func StrictJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(strictJSONKey, true)
+		c.Next()
+	}
+}
+
+// bindJSON is c.ShouldBindJSON, decoding strictly on requests marked by StrictJSON.
+func bindJSON(c *gin.Context, obj any) error {
+	if !c.GetBool(strictJSONKey) {
+		return c.ShouldBindJSON(obj)
+	}
+	return c.ShouldBindWith(obj, strictJSONBinding{})
+}
+
+// strictJSONBinding is binding.JSON with unknown fields rejected.
+type strictJSONBinding struct{}
+
+func (strictJSONBinding) Name() string { return "json" }
+
+func (strictJSONBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
@@ -1,14 +1,20 @@
 package handler
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"net/http" // Standard HTTP status codes
-	"strings"
+	"strconv"
+	"time"
 
 	"wgMicro_api/internal/domain"
 	"wgMicro_api/internal/logger"
 	"wgMicro_api/internal/repository" // Needed for checking repository.ErrPeerNotFound and repository.ErrWgTimeout
+	"wgMicro_api/internal/service"    // Needed for checking service-level validation errors
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -16,30 +22,122 @@ import (
 
 // ServiceInterface defines the operations that the handler can request from the service layer.
 type ServiceInterface interface {
-	GetAll() ([]domain.Config, error)
-	Get(publicKey string) (*domain.Config, error)
-	CreateWithNewKeys(allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) // For server-side key generation
+	GetAll(ctx context.Context) ([]domain.Config, error)
+	FindByNamePrefix(ctx context.Context, prefix string) ([]domain.Config, error)
+	FindByTags(ctx context.Context, tags []string) ([]domain.Config, error)
+	ListCreatedBetween(ctx context.Context, after, before time.Time) ([]domain.Config, error)
+	FindByAllowedIP(ctx context.Context, ip string) (*domain.Config, error)
+	ListStale(ctx context.Context, olderThan time.Duration) ([]domain.Config, error)
+	Summary(ctx context.Context) (*domain.PeerSummary, error)
+	TopTalkers(ctx context.Context, by string, limit int) ([]domain.Config, error)
+	PeerStats(ctx context.Context, publicKey string) (*domain.PeerStats, error)
+	Get(ctx context.Context, publicKey string) (*domain.Config, error)
+	GetByPeerID(ctx context.Context, peerID string) (*domain.Config, error)
+	CreateWithNewKeys(ctx context.Context, allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error)                  // For server-side key generation
+	CreateWithExistingKeys(ctx context.Context, publicKey, privateKey string, allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) // For importing existing peers
 	// Create(cfg domain.Config) error // If clients provide their own PublicKey, this might be needed. Based on current decision, CreateWithNewKeys is primary.
-	UpdateAllowedIPs(publicKey string, ips []string) error
-	Delete(publicKey string) error
+	UpdateAllowedIPs(ctx context.Context, publicKey string, ips []string) error
+	PatchPeer(ctx context.Context, publicKey string, ops []domain.PatchOperation, allowEmptyAllowedIPs bool) (*domain.Config, error)
+	Delete(ctx context.Context, publicKey string) error
+	DeleteVerbose(ctx context.Context, publicKey string) (*domain.DeleteConfigResponse, error)
 	BuildClientConfig(peerCfg *domain.Config, clientPrivateKey string) (string, error) // Takes client's private key
-	RotatePeerKey(oldPublicKey string) (*domain.Config, error)
+	BuildClientConfigWithOptions(peerCfg *domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error)
+	ServerIdentity() *domain.ServerIdentity
+	PreviewClientConfig(peer domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error)
+	ExplainClientConfig(peer domain.Config, opts domain.ClientConfigOptions) *domain.ClientConfigExplanation
+	RotatePeerKey(ctx context.Context, oldPublicKey string) (*domain.Config, error)
+	RotatePeerKeyWithOptions(ctx context.Context, oldPublicKey string, opts domain.RotateOptions) (*domain.Config, error)
+	RotatePresharedKey(ctx context.Context, publicKey string) (string, error)
+	SetPeerMetadata(publicKey, name, description string) (*domain.PeerMetadata, error)
+	SetPeerTags(publicKey string, tags []string) (*domain.PeerMetadata, error)
+	VerifyKeyPair(publicKey, privateKey string) (bool, error)
+	ApplyDesiredState(ctx context.Context, desired []domain.DesiredPeer, prune bool) (*domain.ApplyResult, error)
+	PlanDesiredState(ctx context.Context, desired []domain.DesiredPeer, prune bool) (*domain.ReconcilePlan, error)
+	ExportPeers(ctx context.Context, w io.Writer) (domain.ExportStats, error)
 }
 
 // ConfigHandler orchestrates request handling for WireGuard configurations.
 type ConfigHandler struct {
-	svc ServiceInterface
+	svc          ServiceInterface
+	filenameOpts FilenameOptions             // Sanitization rules for downloadable .conf filenames
+	sanitizeErrs bool                        // Hide internal error details (paths, command lines, stderr) from clients
+	fileCache    string                      // Cache-Control of generated client files
+	allowEmptyIP bool                        // Accept AllowedIPs updates with an empty list without ?confirm=true
+	signingKey   ed25519.PrivateKey          // Signs generated .conf content; nil disables signing
+	interfaces   map[string]ServiceInterface // Services of the interfaces selectable with InterfaceHeader
+}
+
+// DefaultTopTalkersLimit is the number of peers returned by TopTalkers when limit is not given.
+const DefaultTopTalkersLimit = 10
+
+// Option customizes a ConfigHandler created by NewConfigHandler.
+type Option func(*ConfigHandler)
+
+// WithFilenameOptions overrides DefaultFilenameOptions for generated .conf filenames.
+func WithFilenameOptions(opts FilenameOptions) Option {
+	return func(h *ConfigHandler) {
+		h.filenameOpts = opts
+	}
+}
+
+// WithErrorSanitization makes unexpected errors return a generic message and error code instead of
+// the error text, which may contain internal paths, command lines and stderr. The full error is still logged.
+func WithErrorSanitization(enabled bool) Option {
+	return func(h *ConfigHandler) {
+		h.sanitizeErrs = enabled
+	}
+}
+
+// WithClientFileCacheControl overrides the Cache-Control header of client-file responses.
+// An empty value keeps domain.DefaultClientFileCacheControl.
+func WithClientFileCacheControl(value string) Option {
+	return func(h *ConfigHandler) {
+		if value != "" {
+			h.fileCache = value
+		}
+	}
+}
+
+// WithEmptyAllowedIPsUpdate lets AllowedIPs updates with an empty list, which leave the peer
+// unable to route any traffic, through without ?confirm=true.
+func WithEmptyAllowedIPsUpdate(allowed bool) Option {
+	return func(h *ConfigHandler) {
+		h.allowEmptyIP = allowed
+	}
+}
+
+// ConfigSignatureHeader carries the base64 Ed25519 signature of a generated .conf file.
+const ConfigSignatureHeader = "X-Config-Signature"
+
+// WithConfigSigningKey signs every generated client .conf with key. The signature is sent in
+// ConfigSignatureHeader and in the signature field of JSON responses; a nil key disables signing.
+func WithConfigSigningKey(key ed25519.PrivateKey) Option {
+	return func(h *ConfigHandler) {
+		h.signingKey = key
+	}
 }
 
 // NewConfigHandler creates a new ConfigHandler.
-func NewConfigHandler(svc ServiceInterface) *ConfigHandler {
+func NewConfigHandler(svc ServiceInterface, opts ...Option) *ConfigHandler {
 	if svc == nil {
 		logger.Logger.Fatal("Service interface cannot be nil for ConfigHandler")
 	}
-	return &ConfigHandler{svc: svc}
+	h := &ConfigHandler{svc: svc, filenameOpts: DefaultFilenameOptions, fileCache: domain.DefaultClientFileCacheControl}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // handleError standardizes error responses.
+// Errors from the service carry a domain.ErrorCategory that decides the status:
+//   - 400 Bad Request: malformed input that cannot be parsed (bad base64 key, unparseable AllowedIPs entry).
+//   - 422 Unprocessable Entity: well-formed but semantically invalid input (weak key, mismatched key pair,
+//     invalid desired-state document such as overlapping AllowedIPs).
+//   - 409 Conflict: valid input that conflicts with the current state (peer exists, key changed, pool exhausted).
+//   - 403 Forbidden: a request the server's configuration does not allow (hidden traffic statistics).
+//
+// Missing peers map to 404, WireGuard timeouts to 503 and anything else to 500.
 func (h *ConfigHandler) handleError(c *gin.Context, operation string, key string, err error) {
 	logFields := []zap.Field{zap.Error(err), zap.String("operation", operation)}
 	if key != "" {
@@ -47,75 +145,423 @@ func (h *ConfigHandler) handleError(c *gin.Context, operation string, key string
 	}
 	logger.Logger.Error("Handler error", logFields...)
 
-	var statusCode int
+	c.JSON(h.errorResponse(key, err))
+}
+
+// errorResponse is the status and body handleError answers err with.
+func (h *ConfigHandler) errorResponse(key string, err error) (int, domain.ErrorResponse) {
 	var errMsg string = "An unexpected error occurred."
 
 	switch {
 	case errors.Is(err, repository.ErrPeerNotFound):
-		statusCode = http.StatusNotFound
-		errMsg = fmt.Sprintf("Peer with public key '%s' not found.", key)
+		return http.StatusNotFound, domain.ErrorResponse{Error: fmt.Sprintf("Peer with public key '%s' not found.", key)}
 	case errors.Is(err, repository.ErrWgTimeout):
-		statusCode = http.StatusServiceUnavailable
-		errMsg = "WireGuard operation timed out. The service might be temporarily unavailable or under heavy load."
+		return http.StatusServiceUnavailable, domain.ErrorResponse{Error: "WireGuard operation timed out. The service might be temporarily unavailable or under heavy load."}
+	case errors.Is(err, service.ErrInvalidPrivateKey):
+		errMsg = "The supplied private key is not a valid WireGuard key."
+	case errors.Is(err, service.ErrInvalidPublicKey):
+		errMsg = "The supplied public key is not a valid WireGuard key (expected 32 bytes, base64-encoded)."
+	case errors.Is(err, service.ErrWeakPrivateKey):
+		errMsg = "The supplied private key is all zeros or otherwise degenerate; generate a new one, e.g. with 'wg genkey'."
+	case errors.Is(err, service.ErrKeyPairMismatch):
+		errMsg = "The supplied private key does not match the public key."
+	case errors.Is(err, service.ErrPeerAlreadyExists):
+		errMsg = fmt.Sprintf("Peer with public key '%s' already exists.", key)
+	case errors.Is(err, service.ErrRotationConflict):
+		errMsg = fmt.Sprintf("Peer with public key '%s' no longer has the expected public key; re-read it and retry.", key)
+	case errors.Is(err, service.ErrTrafficStatsHidden):
+		errMsg = "Traffic statistics are disabled on this server."
+	case errors.Is(err, service.ErrAddressPoolExhausted):
+		errMsg = "No free address is left in the address pool."
+	case errors.Is(err, domain.ErrInvalidKeyFormat):
+		errMsg = "The supplied key is not a valid WireGuard key (expected 32 bytes, base64-encoded)."
+	case domain.CategoryOf(err) != 0:
+		errMsg = err.Error() // ErrAmbiguousAllowedIP, ErrInvalidAllowedIP, ErrInvalidDesiredState: the details are the message
+	}
+
+	switch domain.CategoryOf(err) {
+	case domain.CategoryMalformed:
+		return http.StatusBadRequest, domain.ErrorResponse{Error: errMsg}
+	case domain.CategoryInvalid:
+		return http.StatusUnprocessableEntity, domain.ErrorResponse{Error: errMsg}
+	case domain.CategoryConflict:
+		return http.StatusConflict, domain.ErrorResponse{Error: errMsg}
+	case domain.CategoryForbidden:
+		return http.StatusForbidden, domain.ErrorResponse{Error: errMsg}
 	default:
-		if err != nil {
+		if err != nil && !h.sanitizeErrs {
 			errMsg = err.Error()
 		}
-		statusCode = http.StatusInternalServerError
+		return http.StatusInternalServerError, domain.ErrorResponse{Error: errMsg, Code: domain.ErrorCodeInternal}
 	}
-	c.JSON(statusCode, domain.ErrorResponse{Error: errMsg})
+}
+
+// resultError is the error text of one peer's result in a bulk operation: the error itself, or with
+// error sanitization the message handleError would answer with.
+func (h *ConfigHandler) resultError(key string, err error) string {
+	if !h.sanitizeErrs {
+		return err.Error()
+	}
+	_, body := h.errorResponse(key, err)
+	return body.Error
+}
+
+// internalErrorResponse is the 500 body for err: summary followed by the error text, or only
+// summary when sanitize is set, since the text may contain internal paths, command lines and stderr.
+func internalErrorResponse(summary string, err error, sanitize bool) domain.ErrorResponse {
+	if sanitize {
+		return domain.ErrorResponse{Error: summary + ".", Code: domain.ErrorCodeInternal}
+	}
+	return domain.ErrorResponse{Error: summary + ": " + err.Error(), Code: domain.ErrorCodeInternal}
 }
 
 // GetAll godoc
 // @Summary      List all peer configurations
 // @Description  Retrieves a list of all currently configured WireGuard peers. Private keys of peers are not included.
+// @Description  With `?name=<prefix>`, only peers whose metadata name starts with the prefix (case-insensitive) are returned.
+// @Description  With one or more `?tag=`, only peers whose metadata carries every listed tag are returned (e.g. `?tag=env:staging&tag=team:ops`).
+// @Description  With `?fields=publicKey,allowedIps`, each object contains only the listed fields.
+// @Description  With `?createdAfter=` and/or `?createdBefore=` (RFC 3339), only peers whose metadata createdAt lies in [createdAfter, createdBefore) are returned, oldest first.
+// @Description  With `?maxAllowedIps=N`, each peer's allowedIps is cut to the first N entries and `allowedIpsMore` counts the rest; fetch the peer itself for the full list.
+// @Description  With `?format=csv`, the peers are returned as `text/csv` with the columns publicKey, allowedIps, endpoint, latestHandshake, rx, tx and keepalive; preshared keys are never included.
 // @Tags         configs
 // @Produce      json
+// @Produce      text/csv
+// @Param        name            query   string    false  "Metadata name prefix to filter by (case-insensitive)."
+// @Param        tag             query   []string  false  "Metadata tag the peer must carry; repeat for several (AND)."  collectionFormat(multi)
+// @Param        fields          query   string    false  "Comma-separated JSON field names to include (e.g. publicKey,allowedIps,latestHandshake)."
+// @Param        createdAfter    query   string    false  "Only peers created at or after this RFC 3339 time."
+// @Param        createdBefore   query   string    false  "Only peers created before this RFC 3339 time."
+// @Param        maxAllowedIps   query   int       false  "Maximum number of allowedIps entries per peer (positive)."
+// @Param        format          query   string    false  "Response format."  Enums(json, csv)  default(json)
+// @Param        X-WG-Interface  header  string    false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
 // @Success      200  {array}   domain.Config         "A list of peer configurations."
+// @Failure      400  {object}  domain.ErrorResponse  "Unknown field name in 'fields', invalid 'maxAllowedIps', an invalid creation window, a combination of 'name', 'tag' and the creation window, an unknown 'format', or 'fields' with format=csv."
 // @Failure      500  {object}  domain.ErrorResponse  "Internal server error."
 // @Failure      503  {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
 // @Router       /configs [get]
 func (h *ConfigHandler) GetAll(c *gin.Context) {
-	configs, err := h.svc.GetAll()
+	fields, ok := parseFieldSelection(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid format: expected 'json' or 'csv'."})
+		return
+	}
+	if format == "csv" && fields != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "'fields' cannot be combined with format=csv; the CSV columns are fixed."})
+		return
+	}
+	maxIPs := 0 // No limit
+	if raw := c.Query("maxAllowedIps"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid maxAllowedIps: expected a positive integer."})
+			return
+		}
+		maxIPs = n
+	}
+	after, before, windowed, ok := parseCreatedWindow(c)
+	if !ok {
+		return
+	}
+	prefix := c.Query("name")
+	if windowed && prefix != "" {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "'name' cannot be combined with 'createdAfter'/'createdBefore'."})
+		return
+	}
+	tags := c.QueryArray("tag")
+	if len(tags) > 0 && (windowed || prefix != "") {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "'tag' cannot be combined with 'name' or 'createdAfter'/'createdBefore'."})
+		return
+	}
+	var configs []domain.Config
+	var err error
+	if prefix != "" {
+		configs, err = h.service(c).FindByNamePrefix(c.Request.Context(), prefix)
+	} else if len(tags) > 0 {
+		configs, err = h.service(c).FindByTags(c.Request.Context(), tags)
+	} else if windowed {
+		configs, err = h.service(c).ListCreatedBetween(c.Request.Context(), after, before)
+	} else {
+		configs, err = h.service(c).GetAll(c.Request.Context())
+	}
 	if err != nil {
 		h.handleError(c, "GetAllPeers", "", err)
 		return
 	}
 	if configs == nil {
-		c.JSON(http.StatusOK, []domain.Config{})
+		configs = []domain.Config{}
+	}
+	if maxIPs > 0 {
+		for i := range configs {
+			if extra := len(configs[i].AllowedIps) - maxIPs; extra > 0 {
+				configs[i].AllowedIps = configs[i].AllowedIps[:maxIPs]
+				configs[i].AllowedIpsMore = extra
+			}
+		}
+	}
+	if format == "csv" {
+		respondCSV(c, configs)
+		return
+	}
+	respondWithFields(c, http.StatusOK, configs, fields)
+}
+
+// parseCreatedWindow reads the optional createdAfter/createdBefore query parameters.
+// It responds 400 and returns ok=false if either is not RFC 3339 or the window is empty.
+func parseCreatedWindow(c *gin.Context) (after, before time.Time, windowed, ok bool) {
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"createdAfter", &after}, {"createdBefore", &before}} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: fmt.Sprintf("Invalid %s: expected an RFC 3339 time, e.g. 2024-01-31T00:00:00Z.", p.name)})
+			return time.Time{}, time.Time{}, false, false
+		}
+		*p.dst = t
+		windowed = true
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "createdAfter must be earlier than createdBefore."})
+		return time.Time{}, time.Time{}, false, false
+	}
+	return after, before, windowed, true
+}
+
+// ExportPeers godoc
+// @Summary      Export all peers
+// @Description  Streams all peers as server-side WireGuard `[Peer]` blocks, suitable for backups or migrating to another server.
+// @Description  Blocks are written one peer at a time, so memory use does not grow with the number of peers.
+// @Description  If EXPORT_MAX_BYTES is set and reached, the document ends with a truncation comment.
+// @Description  The export includes preshared keys and must be handled as a secret.
+// @Tags         configs
+// @Produce      plain
+// @Param        X-WG-Interface  header    string                false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {string}  string                "WireGuard configuration fragment with one [Peer] block per peer."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/export [get]
+func (h *ConfigHandler) ExportPeers(c *gin.Context) {
+	logger.Logger.Info("ExportPeers request received")
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="peers.conf"`)
+	c.Header("Cache-Control", "no-store") // Contains preshared keys
+
+	stats, err := h.service(c).ExportPeers(c.Request.Context(), c.Writer)
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type") // Let the JSON error set its own
+			c.Writer.Header().Del("Content-Disposition")
+			h.handleError(c, "ExportPeers", "", err)
+			return
+		}
+		// The status line is already sent; the client sees a truncated body.
+		logger.Logger.Error("Peer export failed mid-stream", zap.Int("peersWritten", stats.Peers), zap.Error(err))
+		return
+	}
+	logger.Logger.Info("Successfully exported peers", zap.Int("peers", stats.Peers), zap.Bool("truncated", stats.Truncated))
+}
+
+// ListStale godoc
+// @Summary      List stale peer configurations
+// @Description  Returns peers that have never completed a handshake or whose latest handshake is older than `olderThan`.
+// @Description  Useful for finding provisioned-but-unused peers. Durations accept Go syntax plus days, e.g. `7d`, `24h`, `30m`, `1d12h`.
+// @Tags         configs
+// @Produce      json
+// @Param        olderThan       query     string                false  "Minimum handshake age for a peer to count as stale."  default(7d)
+// @Param        X-WG-Interface  header    string                false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {array}   domain.Config         "Stale peer configurations."
+// @Failure      400             {object}  domain.ErrorResponse  "Invalid olderThan duration."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/stale [get]
+func (h *ConfigHandler) ListStale(c *gin.Context) {
+	olderThanStr := c.DefaultQuery("olderThan", DefaultStaleOlderThan)
+	olderThan, err := ParseAgeDuration(olderThanStr)
+	if err != nil {
+		logger.Logger.Warn("Invalid olderThan for ListStale", zap.String("olderThan", olderThanStr), zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid olderThan: " + err.Error()})
+		return
+	}
+
+	configs, err := h.service(c).ListStale(c.Request.Context(), olderThan)
+	if err != nil {
+		h.handleError(c, "ListStalePeers", "", err)
+		return
+	}
+	if configs == nil {
+		configs = []domain.Config{}
+	}
+	c.JSON(http.StatusOK, configs)
+}
+
+// Summary godoc
+// @Summary      Count peers by connection state
+// @Description  Returns how many peers are online (latest handshake within ONLINE_THRESHOLD_SECONDS), offline, or have never connected,
+// @Description  so dashboards need not fetch and classify the full list.
+// @Tags         configs
+// @Produce      json
+// @Param        X-WG-Interface  header    string                false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.PeerSummary    "Peer counts."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/summary [get]
+func (h *ConfigHandler) Summary(c *gin.Context) {
+	summary, err := h.service(c).Summary(c.Request.Context())
+	if err != nil {
+		h.handleError(c, "PeerSummary", "", err)
 		return
 	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// TopTalkers godoc
+// @Summary      List the peers with the most traffic
+// @Description  Returns the `limit` peers with the most received (`by=rx`) or transmitted (`by=tx`) bytes, busiest first,
+// @Description  for capacity planning. Peers with equal counters are ordered by public key.
+// @Description  Answers 403 when EXPOSE_TRAFFIC_STATS=false, like /stats.
+// @Tags         configs
+// @Produce      json
+// @Param        by              query     string                false  "Traffic direction to rank by."  Enums(rx, tx)  default(rx)
+// @Param        limit           query     int                   false  "Maximum number of peers."      default(10)
+// @Param        X-WG-Interface  header    string                false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {array}   domain.Config         "Peers ordered by traffic."
+// @Failure      400             {object}  domain.ErrorResponse  "Invalid by or limit."
+// @Failure      403             {object}  domain.ErrorResponse  "Traffic statistics are disabled (EXPOSE_TRAFFIC_STATS=false)."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/top [get]
+func (h *ConfigHandler) TopTalkers(c *gin.Context) {
+	limit := DefaultTopTalkersLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid limit: expected a positive integer."})
+			return
+		}
+		limit = n
+	}
+
+	configs, err := h.service(c).TopTalkers(c.Request.Context(), c.DefaultQuery("by", "rx"), limit)
+	if err != nil {
+		h.handleError(c, "TopTalkers", "", err)
+		return
+	}
+	if configs == nil {
+		configs = []domain.Config{}
+	}
 	c.JSON(http.StatusOK, configs)
 }
 
+// GetPeerStats godoc
+// @Summary      Get live traffic statistics of a peer
+// @Description  Returns only the peer's latest handshake, received and transmitted bytes and whether it is online
+// @Description  (handshake within ONLINE_THRESHOLD_SECONDS), without keys or AllowedIPs, so dashboards can poll it cheaply.
+// @Description  Answers 403 when EXPOSE_TRAFFIC_STATS=false, like /stats.
+// @Tags         configs
+// @Accept       json
+// @Produce      json
+// @Param        getRequest      body      domain.GetConfigRequest  true   "Public key of the peer."
+// @Param        X-WG-Interface  header    string                   false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.PeerStats         "Peer traffic statistics."
+// @Failure      400             {object}  domain.ErrorResponse     "Invalid input (e.g., empty or malformed public key)."
+// @Failure      403             {object}  domain.ErrorResponse     "Traffic statistics are disabled (EXPOSE_TRAFFIC_STATS=false)."
+// @Failure      404             {object}  domain.ErrorResponse     "Peer not found."
+// @Failure      500             {object}  domain.ErrorResponse     "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse     "Service unavailable (WireGuard timeout)."
+// @Router       /configs/stats [post]
+func (h *ConfigHandler) GetPeerStats(c *gin.Context) {
+	var req domain.GetConfigRequest
+	if err := bindJSON(c, &req); err != nil {
+		logger.Logger.Error("Invalid JSON input for GetPeerStats", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	stats, err := h.service(c).PeerStats(c.Request.Context(), req.PublicKey)
+	if err != nil {
+		h.handleError(c, "GetPeerStats", req.PublicKey, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
 // GetConfig godoc
 // @Summary      Get configuration by public key
 // @Description  Retrieves detailed configuration for a specific peer identified by its public key. The peer's private key is not included.
 // @Tags         configs
 // @Accept       json
 // @Produce      json
-// @Param        getRequest  body      domain.GetConfigRequest  true  "Public key to retrieve configuration for."
-// @Success      200         {object}  domain.Config            "Peer's configuration."
-// @Failure      400         {object}  domain.ErrorResponse     "Invalid input (e.g., empty public key or malformed JSON)."
-// @Failure      404         {object}  domain.ErrorResponse     "Peer not found."
-// @Failure      500         {object}  domain.ErrorResponse     "Internal server error."
-// @Failure      503         {object}  domain.ErrorResponse     "Service unavailable (WireGuard timeout)."
+// @Param        getRequest      body      domain.GetConfigRequest  true  "Public key to retrieve configuration for."
+// @Param        fields          query     string                   false "Comma-separated JSON field names to include (e.g. publicKey,allowedIps)."
+// @Param        X-WG-Interface  header    string                   false "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.Config            "Peer's configuration."
+// @Failure      400             {object}  domain.ErrorResponse     "Invalid input (e.g., empty public key, malformed JSON or unknown field name)."
+// @Failure      404             {object}  domain.ErrorResponse     "Peer not found."
+// @Failure      500             {object}  domain.ErrorResponse     "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse     "Service unavailable (WireGuard timeout)."
 // @Router       /configs/get [post]
 func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	fields, ok := parseFieldSelection(c)
+	if !ok {
+		return
+	}
 	var req domain.GetConfigRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Logger.Error("Invalid JSON input for GetConfig", zap.Error(err))
 		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 		return
 	}
-	
+
 	logger.Logger.Info("GetConfig request received", zap.String("publicKey", req.PublicKey))
-	
-	cfg, err := h.svc.Get(req.PublicKey)
+
+	cfg, err := h.service(c).Get(c.Request.Context(), req.PublicKey)
 	if err != nil {
 		h.handleError(c, "GetPeerByPublicKey", req.PublicKey, err)
 		return
 	}
+	respondWithFields(c, http.StatusOK, cfg, fields)
+}
+
+// GetConfigByAllowedIP godoc
+// @Summary      Get configuration by assigned IP
+// @Description  Returns the peer whose AllowedIPs contain the given IP address, for when a client's address is known but not its public key.
+// @Tags         configs
+// @Produce      json
+// @Param        ip              query     string                true   "IP address to look up, e.g. 10.0.0.5."
+// @Param        X-WG-Interface  header    string                false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.Config         "Peer whose AllowedIPs contain the address."
+// @Failure      400             {object}  domain.ErrorResponse  "Missing or invalid IP address."
+// @Failure      404             {object}  domain.ErrorResponse  "No peer's AllowedIPs contain the address."
+// @Failure      409             {object}  domain.ErrorResponse  "More than one peer's AllowedIPs contain the address."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/by-ip [get]
+func (h *ConfigHandler) GetConfigByAllowedIP(c *gin.Context) {
+	ip := c.Query("ip")
+	if ip == "" {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Query parameter 'ip' is required."})
+		return
+	}
+
+	cfg, err := h.service(c).FindByAllowedIP(c.Request.Context(), ip)
+	if errors.Is(err, repository.ErrPeerNotFound) {
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: fmt.Sprintf("No peer has AllowedIPs containing '%s'.", ip)})
+		return
+	}
+	if err != nil {
+		h.handleError(c, "GetPeerByAllowedIP", "", err)
+		return
+	}
 	c.JSON(http.StatusOK, cfg)
 }
 
@@ -123,19 +569,28 @@ func (h *ConfigHandler) GetConfig(c *gin.Context) {
 // @Summary      Create new peer with server-generated keys
 // @Description  Adds a new peer. The server generates cryptographic keys for the peer.
 // @Description  The request body should specify AllowedIPs and optionally PreSharedKey and PersistentKeepalive.
-// @Description  The response includes the full peer configuration, including the server-generated PrivateKey, which the client must securely store.
+// @Description  Bare addresses in AllowedIPs become /32 (IPv4) or /128 (IPv6) unless NORMALIZE_BARE_IPS=false, in which case they are rejected.
+// @Description  If ADDRESS_POOL_CIDR is set and AllowedIPs is empty, the next free pool address is allocated and returned in `assignedAddress`.
+// @Description  The response includes the full peer configuration, including the server-generated PrivateKey, which the client must securely store. It is sent with `Cache-Control: no-store`.
+// @Description  It also carries `server: {publicKey, endpoint}`, so the client config can be built without another request.
+// @Description  To import an existing peer instead (e.g. when migrating), pass public_key and optionally the matching private_key.
+// @Description  An imported private key is verified against the public key, echoed in the response and never stored.
+// @Description  Set generate_preshared_key to have the server generate a preshared key when none is given; it is returned in `preSharedKey`.
 // @Tags         configs
 // @Accept       json
 // @Produce      json
-// @Param        peerRequest  body      domain.CreatePeerRequest  true  "Peer settings for creation (keys will be generated by server)."
-// @Success      201          {object}  domain.Config             "Peer created successfully. The response includes the generated private key."
-// @Failure      400          {object}  domain.ErrorResponse      "Invalid input if the request body is malformed or contains invalid data."
-// @Failure      500          {object}  domain.ErrorResponse      "Internal server error if peer creation or key generation fails."
-// @Failure      503          {object}  domain.ErrorResponse      "Service unavailable if a WireGuard command times out."
+// @Param        peerRequest     body      domain.CreatePeerRequest  true   "Peer settings for creation (keys will be generated by server unless public_key is given)."
+// @Param        X-WG-Interface  header    string                    false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      201             {object}  domain.Config             "Peer created successfully. The response includes the generated or imported private key."
+// @Failure      400             {object}  domain.ErrorResponse      "Invalid input if the request body is malformed or contains invalid data."
+// @Failure      422             {object}  domain.ErrorResponse      "An imported key pair does not match, or an AllowedIPs entry lies outside ALLOWED_IP_RANGES."
+// @Failure      409             {object}  domain.ErrorResponse      "A peer with the imported public key already exists, or the address pool is exhausted."
+// @Failure      500             {object}  domain.ErrorResponse      "Internal server error if peer creation or key generation fails."
+// @Failure      503             {object}  domain.ErrorResponse      "Service unavailable if a WireGuard command times out."
 // @Router       /configs [post]
 func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 	var req domain.CreatePeerRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Logger.Error("Invalid JSON input for CreateConfig (new peer with generated keys)", zap.Error(err))
 		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 		return
@@ -143,78 +598,174 @@ func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 	logger.Logger.Info("CreateConfig request received (server will generate keys)",
 		zap.Strings("allowedIPs", req.AllowedIps),
 		zap.Bool("presharedKeyProvided", req.PreSharedKey != ""),
+		zap.Bool("generatePresharedKey", req.GeneratePresharedKey),
 		zap.Int("persistentKeepalive", req.PersistentKeepalive))
 
-	createdPeerConfig, err := h.svc.CreateWithNewKeys(
+	if req.PublicKey != "" {
+		if req.GeneratePresharedKey {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "generate_preshared_key is only supported when the server generates the keys"})
+			return
+		}
+		h.importPeer(c, req)
+		return
+	}
+
+	createdPeerConfig, err := h.service(c).CreateWithNewKeys(c.Request.Context(),
 		req.AllowedIps,
 		req.PreSharedKey,
+		req.GeneratePresharedKey,
 		req.PersistentKeepalive,
 	)
 	if err != nil {
 		h.handleError(c, "CreatePeerWithNewKeys", "", err) // publicKey is not known before creation attempt
 		return
 	}
+	h.applyCreateMetadata(c, createdPeerConfig, req)
 	logger.Logger.Info("Successfully created new peer with server-generated keys",
 		zap.String("publicKey", createdPeerConfig.PublicKey)) // DO NOT log private key
+	c.Header("Cache-Control", "no-store") // Response carries the new private key
+	c.JSON(http.StatusCreated, createdPeerConfig)
+}
+
+// importPeer handles CreateConfig requests that carry an existing public key (and optionally its private key).
+func (h *ConfigHandler) importPeer(c *gin.Context, req domain.CreatePeerRequest) {
+	logger.Logger.Info("CreateConfig request is an import of existing keys",
+		zap.String("publicKey", req.PublicKey),
+		zap.Bool("privateKeyProvided", req.PrivateKey != "")) // DO NOT log private key
+
+	createdPeerConfig, err := h.service(c).CreateWithExistingKeys(c.Request.Context(),
+		req.PublicKey,
+		req.PrivateKey,
+		req.AllowedIps,
+		req.PreSharedKey,
+		req.PersistentKeepalive,
+	)
+	if err != nil {
+		h.handleError(c, "CreatePeerWithExistingKeys", req.PublicKey, err)
+		return
+	}
+	h.applyCreateMetadata(c, createdPeerConfig, req)
+	logger.Logger.Info("Successfully imported peer with existing keys",
+		zap.String("publicKey", createdPeerConfig.PublicKey))
+	c.Header("Cache-Control", "no-store") // Response may echo the imported private key
 	c.JSON(http.StatusCreated, createdPeerConfig)
 }
 
+// applyCreateMetadata stores the optional name, description and tags of a newly created peer.
+// The peer already exists at this point, so a metadata failure is logged rather than returned.
+func (h *ConfigHandler) applyCreateMetadata(c *gin.Context, created *domain.Config, req domain.CreatePeerRequest) {
+	if req.Name != "" || req.Description != "" {
+		md, err := h.service(c).SetPeerMetadata(created.PublicKey, req.Name, req.Description)
+		if err != nil {
+			logger.Logger.Warn("Peer created but its metadata could not be stored",
+				zap.String("publicKey", created.PublicKey), zap.Error(err))
+			return
+		}
+		created.Metadata = md
+	}
+	if len(req.Tags) > 0 {
+		md, err := h.service(c).SetPeerTags(created.PublicKey, req.Tags)
+		if err != nil {
+			logger.Logger.Warn("Peer created but its tags could not be stored",
+				zap.String("publicKey", created.PublicKey), zap.Error(err))
+			return
+		}
+		created.Metadata = md
+	}
+}
+
 // UpdateAllowedIPs godoc
 // @Summary      Update allowed IPs for a peer
 // @Description  Replaces the list of allowed IP addresses for an existing peer, identified by its public key.
+// @Description  Bare addresses become /32 (IPv4) or /128 (IPv6) unless NORMALIZE_BARE_IPS=false, in which case they are rejected.
+// @Description  An empty list clears all AllowedIPs and requires `?confirm=true` unless ALLOW_EMPTY_ALLOWED_IPS_UPDATE=true.
 // @Tags         configs
 // @Accept       json
 // @Produce      json
-// @Param        updateRequest  body      domain.UpdateAllowedIpsRequest  true  "Public key and new list of allowed IPs for the peer."
-// @Success      200            {object}  nil                             "Allowed IPs updated successfully (No body content in response)."
-// @Failure      400            {object}  domain.ErrorResponse            "Invalid input (e.g., missing public key or malformed body)."
-// @Failure      404            {object}  domain.ErrorResponse            "Peer not found."
-// @Failure      500            {object}  domain.ErrorResponse            "Internal server error."
-// @Failure      503            {object}  domain.ErrorResponse            "Service unavailable (WireGuard timeout)."
+// @Param        updateRequest   body      domain.UpdateAllowedIpsRequest  true  "Public key and new list of allowed IPs for the peer."
+// @Param        confirm         query     bool                            false "Confirm clearing all AllowedIPs with an empty list."
+// @Param        X-WG-Interface  header    string                          false "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  nil                             "Allowed IPs updated successfully (No body content in response)."
+// @Failure      400             {object}  domain.ErrorResponse            "Invalid input (e.g., missing public key, malformed body or unconfirmed empty list)."
+// @Failure      404             {object}  domain.ErrorResponse            "Peer not found."
+// @Failure      422             {object}  domain.ErrorResponse            "An AllowedIPs entry lies outside ALLOWED_IP_RANGES."
+// @Failure      500             {object}  domain.ErrorResponse            "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse            "Service unavailable (WireGuard timeout)."
 // @Router       /configs/update-allowed-ips [post]
 func (h *ConfigHandler) UpdateAllowedIPs(c *gin.Context) {
 	var req domain.UpdateAllowedIpsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Logger.Error("Invalid JSON input for UpdateAllowedIPs", zap.Error(err))
 		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 		return
 	}
-	
-	logger.Logger.Info("UpdateAllowedIPs request received", 
+
+	logger.Logger.Info("UpdateAllowedIPs request received",
 		zap.String("publicKey", req.PublicKey),
 		zap.Strings("allowedIPs", req.AllowedIps))
-	
-	if err := h.svc.UpdateAllowedIPs(req.PublicKey, req.AllowedIps); err != nil {
+
+	if !h.emptyAllowedIPsConfirmed(c, req.AllowedIps) {
+		return
+	}
+	if err := h.service(c).UpdateAllowedIPs(c.Request.Context(), req.PublicKey, req.AllowedIps); err != nil {
 		h.handleError(c, "UpdatePeerAllowedIPs", req.PublicKey, err)
 		return
 	}
 	c.Status(http.StatusOK) // Or 204 No Content
 }
 
+// emptyAllowedIPsConfirmed reports whether an AllowedIPs update may proceed. An empty list would
+// disable the peer, so unless that is allowed outright it needs ?confirm=true; otherwise it answers 400.
+func (h *ConfigHandler) emptyAllowedIPsConfirmed(c *gin.Context, ips []string) bool {
+	if len(ips) > 0 || h.allowEmptyIP {
+		return true
+	}
+	if confirmed, _ := strconv.ParseBool(c.Query("confirm")); confirmed {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "An empty allowedIps list removes all of the peer's AllowedIPs; repeat the request with ?confirm=true to clear them."})
+	return false
+}
+
 // DeleteConfig godoc
 // @Summary      Delete a peer configuration
 // @Description  Removes a peer from the WireGuard interface using its public key.
+// @Description  With `?verbose=true`, responds with 200 and reports whether the peer existed and was deleted.
 // @Tags         configs
 // @Accept       json
 // @Produce      json
-// @Param        deleteRequest  body      domain.DeleteConfigRequest  true  "Public key of the peer to delete."
-// @Success      204            {null}    nil                         "Peer deleted successfully (No Content)."
-// @Failure      400            {object}  domain.ErrorResponse        "Invalid input (e.g., empty public key or malformed JSON)."
-// @Failure      404            {object}  domain.ErrorResponse        "Peer not found (only if service layer can reliably detect this for delete operations)."
-// @Failure      500            {object}  domain.ErrorResponse        "Internal server error."
-// @Failure      503            {object}  domain.ErrorResponse        "Service unavailable (WireGuard timeout)."
+// @Param        deleteRequest   body      domain.DeleteConfigRequest  true  "Public key of the peer to delete."
+// @Param        verbose         query     bool                        false "Return {deleted, existed} instead of 204."
+// @Param        X-WG-Interface  header    string                      false "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.DeleteConfigResponse "Deletion result (verbose mode only)."
+// @Success      204             {null}    nil                         "Peer deleted successfully (No Content)."
+// @Failure      400             {object}  domain.ErrorResponse        "Invalid input (e.g., empty public key or malformed JSON)."
+// @Failure      404             {object}  domain.ErrorResponse        "Peer not found (only if service layer can reliably detect this for delete operations)."
+// @Failure      500             {object}  domain.ErrorResponse        "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse        "Service unavailable (WireGuard timeout)."
 // @Router       /configs/delete [post]
 func (h *ConfigHandler) DeleteConfig(c *gin.Context) {
 	var req domain.DeleteConfigRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Logger.Error("Invalid JSON input for DeleteConfig", zap.Error(err))
 		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 		return
 	}
-	
-	logger.Logger.Info("DeleteConfig request received", zap.String("publicKey", req.PublicKey))
-	
-	if err := h.svc.Delete(req.PublicKey); err != nil {
+
+	verbose, _ := strconv.ParseBool(c.Query("verbose"))
+	logger.Logger.Info("DeleteConfig request received", zap.String("publicKey", req.PublicKey), zap.Bool("verbose", verbose))
+
+	if verbose {
+		result, err := h.service(c).DeleteVerbose(c.Request.Context(), req.PublicKey)
+		if err != nil {
+			h.handleError(c, "DeletePeerConfigVerbose", req.PublicKey, err)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	if err := h.service(c).Delete(c.Request.Context(), req.PublicKey); err != nil {
 		h.handleError(c, "DeletePeerConfig", req.PublicKey, err)
 		return
 	}
@@ -227,69 +778,194 @@ func (h *ConfigHandler) DeleteConfig(c *gin.Context) {
 // @Description  The request body must contain the client's existing public key (to identify the peer on the server) and the client's corresponding private key.
 // @Description  The API uses these keys along with server configuration (server public key, endpoint) and the specific peer's details (AllowedIPs, PSK from server, Keepalive) to construct the .conf file.
 // @Description  The provided client private key is inserted directly into the .conf file. The API does not store this client-provided private key.
+// @Description  With `?encoding=base64`, the file is returned as JSON `{filename, contentBase64}` instead of plain text.
+// @Description  With `?encoding=datauri`, it is returned as JSON `{filename, dataUri}` where dataUri is a `data:text/plain;base64,...` URI usable as a download link.
+// @Description  An optional `persistent_keepalive` (0-65535) overrides the client's PersistentKeepalive; 0 omits it. The server-side peer is not changed.
+// @Description  An optional `allowed_ips` list of CIDR prefixes replaces the full-tunnel `0.0.0.0/0, ::/0` of the `[Peer]` section for split tunneling.
+// @Description  The response carries the client's private key and is sent with `Cache-Control: no-store` unless CLIENT_FILE_CACHE_CONTROL overrides it.
+// @Description  Unless CHECK_CLIENT_PRIVATE_KEY=false, a private key that is not 32 base64-encoded bytes is rejected with 400 and an all-zero one with 422.
+// @Description  With CONFIG_SIGNING_KEY set, the base64 Ed25519 signature of the .conf content is sent in `X-Config-Signature` and, for JSON encodings, in `signature`.
+// @Description  With `?explain=true` (JSON encodings only), `explain` tells whether DNS, MTU, endpoint and keepalive came from the request, the peer, a server default or the interface.
 // @Tags         configs
 // @Accept       json
 // @Produce      text/plain
+// @Produce      json
 // @Param        clientKeysRequest  body  domain.ClientFileRequest  true  "Client's public and private keys needed for .conf generation."
-// @Success      200 {file} string "The WireGuard .conf file content as plain text."
-// @Failure      400 {object} domain.ErrorResponse "Invalid input if the request body is malformed or required keys are missing."
+// @Param        encoding        query   string  false  "Response encoding: raw (default), base64 or datauri."  Enums(raw, base64, datauri)
+// @Param        explain         query   bool    false  "Annotate defaulted values with their source (base64 and datauri encodings)."
+// @Param        X-WG-Interface  header  string  false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200 {file} string "The WireGuard .conf file content as plain text, domain.ClientFileBase64Response with ?encoding=base64, or domain.ClientFileDataURIResponse with ?encoding=datauri."
+// @Failure      400 {object} domain.ErrorResponse "Invalid input if the request body is malformed, required keys are missing, the private key or an allowed_ips entry is malformed, the encoding is unknown, or explain is combined with the raw encoding."
+// @Failure      422 {object} domain.ErrorResponse "The private key is well-formed but weak."
 // @Failure      404 {object} domain.ErrorResponse "Peer not found if no peer matches the provided client_public_key."
 // @Failure      500 {object} domain.ErrorResponse "Internal server error if .conf file generation fails for other reasons."
 // @Failure      503 {object} domain.ErrorResponse "Service unavailable if a WireGuard command (e.g., during peer data fetch) times out."
 // @Router       /configs/client-file [post]
 func (h *ConfigHandler) GenerateClientConfigFile(c *gin.Context) {
+	encoding := c.DefaultQuery("encoding", "raw")
+	if encoding != "raw" && encoding != "base64" && encoding != "datauri" {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid encoding: expected 'raw', 'base64' or 'datauri'."})
+		return
+	}
+	explain, _ := strconv.ParseBool(c.Query("explain"))
+	if explain && encoding == "raw" {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "explain requires encoding=base64 or encoding=datauri."})
+		return
+	}
+
 	var req domain.ClientFileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Logger.Error("Invalid JSON input for GenerateClientConfigFile", zap.Error(err))
 		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 		return
 	}
 	logger.Logger.Info("GenerateClientConfigFile request received", zap.String("clientPublicKey", req.ClientPublicKey))
 
-	peerCfg, err := h.svc.Get(req.ClientPublicKey)
+	peerCfg, err := h.service(c).Get(c.Request.Context(), req.ClientPublicKey)
 	if err != nil {
 		h.handleError(c, "GenerateClientConfigFile_GetPeer", req.ClientPublicKey, err)
 		return
 	}
 
-	configFileContent, err := h.svc.BuildClientConfig(peerCfg, req.ClientPrivateKey) // Corrected call
+	opts := domain.ClientConfigOptions{PersistentKeepalive: req.PersistentKeepalive, AllowedIPs: req.AllowedIps}
+	configFileContent, err := h.service(c).BuildClientConfigWithOptions(peerCfg, req.ClientPrivateKey, opts)
 	if err != nil {
 		h.handleError(c, "GenerateClientConfigFile_BuildContent", req.ClientPublicKey, err)
 		return
 	}
+	var explanation *domain.ClientConfigExplanation
+	if explain {
+		explanation = h.service(c).ExplainClientConfig(*peerCfg, opts)
+	}
 
-	safeFilename := SanitizeFilename(req.ClientPublicKey) + ".conf"
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", safeFilename))
-	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(configFileContent))
+	if ce := logger.Logger.Check(zap.DebugLevel, "Client .conf generated with server identity"); ce != nil {
+		// Development aid for configs that will not connect; never log the client private key.
+		server := h.service(c).ServerIdentity()
+		ce.Write(
+			zap.String("clientPublicKey", req.ClientPublicKey),
+			zap.String("endpoint", server.Endpoint),
+			zap.String("serverPublicKeyPrefix", server.PublicKey[:min(10, len(server.PublicKey))]+"..."))
+	}
+
+	safeFilename := SanitizeFilenameWithOptions(req.ClientPublicKey, h.filenameOpts) + ".conf"
+	c.Header("Cache-Control", h.fileCache) // The file carries the client's private key
+	signature := h.signConfig(c, configFileContent)
+	switch encoding {
+	case "base64":
+		c.JSON(http.StatusOK, domain.ClientFileBase64Response{
+			Filename:      safeFilename,
+			ContentBase64: base64.StdEncoding.EncodeToString([]byte(configFileContent)),
+			Signature:     signature,
+			Explain:       explanation,
+		})
+	case "datauri":
+		c.JSON(http.StatusOK, domain.ClientFileDataURIResponse{
+			Filename:  safeFilename,
+			DataURI:   "data:text/plain;base64," + base64.StdEncoding.EncodeToString([]byte(configFileContent)),
+			Signature: signature,
+			Explain:   explanation,
+		})
+	default:
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", safeFilename))
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(configFileContent))
+	}
 	logger.Logger.Info("Successfully generated and sent client .conf file",
 		zap.String("clientPublicKey", req.ClientPublicKey),
-		zap.String("filename", safeFilename))
+		zap.String("filename", safeFilename),
+		zap.String("encoding", encoding))
+}
+
+// signConfig returns the base64 Ed25519 signature of conf and sets it as ConfigSignatureHeader.
+// It returns "" and sets nothing when signing is disabled.
+func (h *ConfigHandler) signConfig(c *gin.Context, conf string) string {
+	if h.signingKey == nil {
+		return ""
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(h.signingKey, []byte(conf)))
+	c.Header(ConfigSignatureHeader, signature)
+	return signature
+}
+
+// PreviewClientConfig godoc
+// @Summary      Preview a client .conf for a peer that is not registered yet
+// @Description  Builds the .conf POST /configs/client-file would return once a peer with the supplied values exists,
+// @Description  using the server's public key and endpoint. Nothing is read from or written to the WireGuard interface.
+// @Description  `dns` and `mtu` override the configured client values; AllowedIPs and keys are validated as on creation.
+// @Description  `dns` may have at most MAX_CLIENT_DNS comma-separated entries, each an IP address or a host name.
+// @Description  The response carries the private key and is sent with `Cache-Control: no-store` unless CLIENT_FILE_CACHE_CONTROL overrides it.
+// @Description  With `?explain=true`, `explain` tells whether DNS, MTU, endpoint and keepalive came from the request, the peer, a server default or the interface.
+// @Tags         configs
+// @Accept       json
+// @Produce      json
+// @Param        previewRequest  body      domain.PreviewConfigRequest   true  "Would-be peer values and the client's private key."
+// @Param        explain         query     bool                          false "Annotate defaulted values with their source."
+// @Param        X-WG-Interface  header    string                        false "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.PreviewConfigResponse  "Generated .conf content."
+// @Failure      400             {object}  domain.ErrorResponse          "Malformed body, key, AllowedIPs entry or DNS list."
+// @Failure      422             {object}  domain.ErrorResponse          "The private key is well-formed but weak."
+// @Failure      500             {object}  domain.ErrorResponse          "Internal server error."
+// @Router       /configs/preview [post]
+func (h *ConfigHandler) PreviewClientConfig(c *gin.Context) {
+	var req domain.PreviewConfigRequest
+	if err := bindJSON(c, &req); err != nil {
+		logger.Logger.Error("Invalid JSON input for PreviewClientConfig", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+	peer := domain.Config{
+		PublicKey:           req.PublicKey,
+		AllowedIps:          req.AllowedIps,
+		PreSharedKey:        req.PresharedKey,
+		PersistentKeepalive: req.PersistentKeepalive,
+	}
+	opts := domain.ClientConfigOptions{DNS: req.DNS, MTU: req.MTU}
+	conf, err := h.service(c).PreviewClientConfig(peer, req.PrivateKey, opts)
+	if err != nil {
+		h.handleError(c, "PreviewClientConfig", req.PublicKey, err)
+		return
+	}
+	resp := domain.PreviewConfigResponse{Conf: conf}
+	if explain, _ := strconv.ParseBool(c.Query("explain")); explain {
+		resp.Explain = h.service(c).ExplainClientConfig(peer, opts)
+	}
+	c.Header("Cache-Control", h.fileCache) // The .conf carries the client's private key
+	c.JSON(http.StatusOK, resp)
 }
 
 // RotatePeer godoc
 // @Summary      Rotate peer key
 // @Description  Rotates peer's keys. Server generates new keys. Old peer removed, new one created preserving AllowedIPs & Keepalive. Response includes new PrivateKey (client must store it).
+// @Description  Peer metadata (name, description, createdAt) moves to the new public key; an optional `name` replaces the stored name.
+// @Description  Like the create response, it carries `server: {publicKey, endpoint}` for building the new client config.
+// @Description  An optional `expectedPublicKey` makes the rotation conditional: it must equal `public_key` (422 otherwise),
+// @Description  and if the peer no longer has that key, e.g. after a concurrent rotation, 409 is returned instead of 404 and nothing changes.
 // @Tags         configs
 // @Accept       json
 // @Produce      json
-// @Param        rotateRequest  body      domain.RotatePeerRequest  true  "Public key of the peer to rotate."
-// @Success      200            {object}  domain.Config             "New peer configuration including new PrivateKey."
-// @Failure      400            {object}  domain.ErrorResponse      "Invalid input (e.g., empty public key or malformed JSON)."
-// @Failure      404            {object}  domain.ErrorResponse      "Peer not found."
-// @Failure      500            {object}  domain.ErrorResponse      "Internal server error (key rotation fails)."
-// @Failure      503            {object}  domain.ErrorResponse      "Service unavailable (WireGuard timeout)."
+// @Param        rotateRequest   body      domain.RotatePeerRequest  true   "Public key of the peer to rotate and an optional new name."
+// @Param        X-WG-Interface  header    string                    false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.Config             "New peer configuration including new PrivateKey."
+// @Failure      400             {object}  domain.ErrorResponse      "Invalid input (e.g., empty public key or malformed JSON)."
+// @Failure      404             {object}  domain.ErrorResponse      "Peer not found."
+// @Failure      409             {object}  domain.ErrorResponse      "The peer no longer has expectedPublicKey."
+// @Failure      422             {object}  domain.ErrorResponse      "expectedPublicKey differs from public_key."
+// @Failure      500             {object}  domain.ErrorResponse      "Internal server error (key rotation fails)."
+// @Failure      503             {object}  domain.ErrorResponse      "Service unavailable (WireGuard timeout)."
 // @Router       /configs/rotate [post]
 func (h *ConfigHandler) RotatePeer(c *gin.Context) {
 	var req domain.RotatePeerRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Logger.Error("Invalid JSON input for RotatePeer", zap.Error(err))
 		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 		return
 	}
-	
+
 	logger.Logger.Info("RotatePeer request received", zap.String("publicKey", req.PublicKey))
 
-	newCfg, err := h.svc.RotatePeerKey(req.PublicKey)
+	newCfg, err := h.service(c).RotatePeerKeyWithOptions(c.Request.Context(), req.PublicKey, domain.RotateOptions{
+		Name:              req.Name,
+		ExpectedPublicKey: req.ExpectedPublicKey,
+	})
 	if err != nil {
 		h.handleError(c, "RotatePeerKey", req.PublicKey, err)
 		return
@@ -300,14 +976,242 @@ func (h *ConfigHandler) RotatePeer(c *gin.Context) {
 	c.JSON(http.StatusOK, newCfg)
 }
 
-// SanitizeFilename removes characters problematic in filenames.
-func SanitizeFilename(name string) string {
-	replace := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|", " "} // Added space
-	for _, r := range replace {
-		name = strings.ReplaceAll(name, r, "_")
+// BulkRotatePeers godoc
+// @Summary      Rotate keys of several peers
+// @Description  Responds 200 if all rotations succeed and 207 if at least one fails. A result whose old peer could not be removed carries both the error and the new peer.
+// @Description  Responds 200 if all rotations succeed and 207 if at least one fails.
+// @Description  The response contains new private keys: treat it as sensitive. It is never logged and is sent with Cache-Control: no-store.
+// @Tags         configs
+// @Accept       json
+// @Produce      json
+// @Param        bulkRotateRequest  body      domain.BulkRotateRequest   true   "Public keys of the peers to rotate."
+// @Param        X-WG-Interface     header    string                     false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200                {object}  domain.BulkRotateResponse  "All peers rotated."
+// @Success      207                {object}  domain.BulkRotateResponse  "Some rotations failed; see per-key errors."
+// @Failure      400                {object}  domain.ErrorResponse       "Invalid input (e.g., empty key list or malformed JSON)."
+// @Router       /configs/bulk-rotate [post]
+func (h *ConfigHandler) BulkRotatePeers(c *gin.Context) {
+	var req domain.BulkRotateRequest
+	if err := bindJSON(c, &req); err != nil {
+		logger.Logger.Error("Invalid JSON input for BulkRotatePeers", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	logger.Logger.Info("BulkRotatePeers request received", zap.Int("count", len(req.PublicKeys)))
+
+	resp := domain.BulkRotateResponse{Results: make([]domain.BulkRotateResult, 0, len(req.PublicKeys))}
+	seen := make(map[string]struct{}, len(req.PublicKeys))
+	failed := 0
+	for _, publicKey := range req.PublicKeys {
+		result := domain.BulkRotateResult{PublicKey: publicKey}
+		if _, dup := seen[publicKey]; dup {
+			result.Error = "duplicate public key in request"
+			failed++
+			resp.Results = append(resp.Results, result)
+			continue
+		}
+		seen[publicKey] = struct{}{}
+
+		newCfg, err := h.service(c).RotatePeerKey(c.Request.Context(), publicKey)
+		// A rotation whose old peer could not be removed still returns the live new peer; its keys must reach the client.
+		result.NewConfig = newCfg
+		if err != nil {
+			logger.Logger.Warn("Bulk rotation failed for peer", zap.String("publicKey", publicKey), zap.Error(err))
+			result.Error = h.resultError(publicKey, err)
+			failed++
+		} else {
+			logger.Logger.Info("Bulk rotation succeeded for peer",
+				zap.String("oldPublicKey", publicKey),
+				zap.String("newPublicKey", newCfg.PublicKey)) // DO NOT log private key
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	logger.Logger.Info("BulkRotatePeers finished",
+		zap.Int("requested", len(req.PublicKeys)),
+		zap.Int("failed", failed),
+		zap.Int("status", status))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, resp)
+}
+
+// BulkRotatePresharedKeys godoc
+// @Summary      Rotate the preshared keys of all peers
+// @Description  Generates a fresh preshared key for every configured peer and applies it, e.g. after a PSK compromise.
+// @Description  Each peer is rotated as by a single preshared key rotation; public keys, AllowedIPs and keepalive are unchanged.
+// @Description  Responds 200 if all rotations succeed and 207 if at least one fails.
+// @Description  The response maps each public key to its new preshared key: treat it as sensitive. It is never logged and is sent with Cache-Control: no-store.
+// @Tags         configs
+// @Produce      json
+// @Param        X-WG-Interface  header    string                        false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.BulkRotatePSKResponse  "All preshared keys rotated."
+// @Success      207             {object}  domain.BulkRotatePSKResponse  "Some rotations failed; see failed."
+// @Failure      500             {object}  domain.ErrorResponse          "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse          "Service unavailable (WireGuard timeout)."
+// @Router       /configs/bulk-rotate-psk [post]
+func (h *ConfigHandler) BulkRotatePresharedKeys(c *gin.Context) {
+	configs, err := h.service(c).GetAll(c.Request.Context())
+	if err != nil {
+		h.handleError(c, "BulkRotatePresharedKeys", "", err)
+		return
+	}
+	logger.Logger.Info("BulkRotatePresharedKeys request received", zap.Int("peers", len(configs)))
+
+	resp := domain.BulkRotatePSKResponse{PresharedKeys: make(map[string]string, len(configs))}
+	for _, cfg := range configs {
+		psk, err := h.service(c).RotatePresharedKey(c.Request.Context(), cfg.PublicKey)
+		if err != nil {
+			logger.Logger.Warn("Bulk preshared key rotation failed for peer", zap.String("publicKey", cfg.PublicKey), zap.Error(err))
+			if resp.Failed == nil {
+				resp.Failed = make(map[string]string)
+			}
+			resp.Failed[cfg.PublicKey] = h.resultError(cfg.PublicKey, err)
+			continue
+		}
+		resp.PresharedKeys[cfg.PublicKey] = psk
+	}
+
+	status := http.StatusOK
+	if len(resp.Failed) > 0 {
+		status = http.StatusMultiStatus
+	}
+	logger.Logger.Info("BulkRotatePresharedKeys finished",
+		zap.Int("rotated", len(resp.PresharedKeys)),
+		zap.Int("failed", len(resp.Failed)),
+		zap.Int("status", status))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, resp)
+}
+
+// ApplyDesiredState godoc
+// @Summary      Apply a desired peer set
+// @Description  Reconciles the interface with a full desired-state document: missing peers are created and changed peers are updated.
+// @Description  Peers not in the document are deleted only with `?prune=true`; otherwise they are left untouched.
+// @Description  An empty presharedKey or zero keepalive leaves the existing value of a peer unchanged.
+// @Tags         configs
+// @Accept       json
+// @Produce      json
+// @Param        desiredState    body      []domain.DesiredPeer  true   "Complete desired peer set."
+// @Param        prune           query     bool                  false  "Delete peers that are not in the document."
+// @Param        X-WG-Interface  header    string                false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.ApplyResult    "Summary of the applied changes."
+// @Failure      400             {object}  domain.ErrorResponse  "Malformed JSON."
+// @Failure      422             {object}  domain.ErrorResponse  "Invalid desired state (missing or duplicate publicKey, overlapping AllowedIPs or AllowedIPs outside ALLOWED_IP_RANGES)."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error (changes made before the failure are kept)."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/apply [post]
+func (h *ConfigHandler) ApplyDesiredState(c *gin.Context) {
+	var desired []domain.DesiredPeer
+	if err := bindJSON(c, &desired); err != nil {
+		logger.Logger.Error("Invalid JSON input for ApplyDesiredState", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+	prune, _ := strconv.ParseBool(c.Query("prune"))
+	logger.Logger.Info("ApplyDesiredState request received", zap.Int("peers", len(desired)), zap.Bool("prune", prune))
+
+	result, err := h.service(c).ApplyDesiredState(c.Request.Context(), desired, prune)
+	if err != nil {
+		h.handleError(c, "ApplyDesiredState", "", err)
+		return
 	}
-	if len(name) > 64 {
-		name = name[:64]
+	c.JSON(http.StatusOK, result)
+}
+
+// PlanDesiredState godoc
+// @Summary      Preview applying a desired peer set
+// @Description  Computes the same diff as /configs/apply for the given document and `prune` flag, but changes nothing.
+// @Tags         configs
+// @Accept       json
+// @Produce      json
+// @Param        desiredState    body      []domain.DesiredPeer   true   "Complete desired peer set."
+// @Param        prune           query     bool                   false  "Plan deletion of peers that are not in the document."
+// @Param        X-WG-Interface  header    string                 false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.ReconcilePlan   "Planned changes."
+// @Failure      400             {object}  domain.ErrorResponse   "Malformed JSON."
+// @Failure      422             {object}  domain.ErrorResponse   "Invalid desired state (missing or duplicate publicKey, overlapping AllowedIPs or AllowedIPs outside ALLOWED_IP_RANGES)."
+// @Failure      500             {object}  domain.ErrorResponse   "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse   "Service unavailable (WireGuard timeout)."
+// @Router       /configs/plan [post]
+func (h *ConfigHandler) PlanDesiredState(c *gin.Context) {
+	var desired []domain.DesiredPeer
+	if err := bindJSON(c, &desired); err != nil {
+		logger.Logger.Error("Invalid JSON input for PlanDesiredState", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+	prune, _ := strconv.ParseBool(c.Query("prune"))
+	logger.Logger.Info("PlanDesiredState request received", zap.Int("peers", len(desired)), zap.Bool("prune", prune))
+
+	plan, err := h.service(c).PlanDesiredState(c.Request.Context(), desired, prune)
+	if err != nil {
+		h.handleError(c, "PlanDesiredState", "", err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// KeyFingerprint godoc
+// @Summary      Fingerprint a public key
+// @Description  Returns a short, stable fingerprint of a WireGuard public key for display in UIs and logs:
+// @Description  the first 8 bytes of the SHA-256 of the decoded key, as colon-separated hex groups.
+// @Tags         keys
+// @Accept       json
+// @Produce      json
+// @Param        fingerprintRequest  body      domain.KeyFingerprintRequest   true  "Public key to fingerprint."
+// @Success      200                 {object}  domain.KeyFingerprintResponse  "Fingerprint of the key."
+// @Failure      400                 {object}  domain.ErrorResponse           "Invalid input (e.g., missing or malformed public key)."
+// @Router       /keys/fingerprint [post]
+func (h *ConfigHandler) KeyFingerprint(c *gin.Context) {
+	var req domain.KeyFingerprintRequest
+	if err := bindJSON(c, &req); err != nil {
+		logger.Logger.Error("Invalid JSON input for KeyFingerprint", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	fingerprint, err := service.KeyFingerprint(req.PublicKey)
+	if err != nil {
+		h.handleError(c, "KeyFingerprint", req.PublicKey, err)
+		return
+	}
+	c.JSON(http.StatusOK, domain.KeyFingerprintResponse{PublicKey: req.PublicKey, Fingerprint: fingerprint})
+}
+
+// VerifyKeyPair godoc
+// @Summary      Verify a client key pair
+// @Description  Derives the public key from the supplied private key (via 'wg pubkey') and reports whether it matches the supplied public key.
+// @Description  Useful for a UI to confirm a private key belongs to a registered peer before generating a config.
+// @Description  The private key is only used for the derivation; it is neither stored nor logged.
+// @Tags         configs
+// @Accept       json
+// @Produce      json
+// @Param        verifyRequest   body      domain.VerifyKeyRequest   true   "Public key and the private key to check against it."
+// @Param        X-WG-Interface  header    string                    false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.VerifyKeyResponse  "Result of the comparison."
+// @Failure      400             {object}  domain.ErrorResponse      "Invalid input (e.g., missing keys, malformed JSON or a malformed private key)."
+// @Failure      500             {object}  domain.ErrorResponse      "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse      "Service unavailable (key derivation timed out)."
+// @Router       /configs/verify-key [post]
+func (h *ConfigHandler) VerifyKeyPair(c *gin.Context) {
+	var req domain.VerifyKeyRequest
+	if err := bindJSON(c, &req); err != nil {
+		logger.Logger.Error("Invalid JSON input for VerifyKeyPair", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	logger.Logger.Info("VerifyKeyPair request received", zap.String("publicKey", req.PublicKey)) // DO NOT log private key
+
+	matches, err := h.service(c).VerifyKeyPair(req.PublicKey, req.PrivateKey)
+	if err != nil {
+		h.handleError(c, "VerifyKeyPair", req.PublicKey, err)
+		return
 	}
-	return name
+	c.JSON(http.StatusOK, domain.VerifyKeyResponse{Matches: matches})
 }
@@ -2,38 +2,72 @@ package handler
 
 import (
 	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 
 	"wgMicro_api/internal/domain"
 	"wgMicro_api/internal/logger"
 	"wgMicro_api/internal/repository"
+	"wgMicro_api/internal/service"
 )
 
 // mockService implements ServiceInterface for testing ConfigHandler.
 type mockService struct {
-	GetFunc               func(publicKey string) (*domain.Config, error)
-	GetAllFunc            func() ([]domain.Config, error)
-	CreateWithNewKeysFunc func(allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error)
-	UpdateAllowedIPsFunc  func(publicKey string, ips []string) error
-	DeleteFunc            func(publicKey string) error
-	BuildClientConfigFunc func(peerCfg *domain.Config, clientPrivateKey string) (string, error)
-	RotatePeerKeyFunc     func(oldPublicKey string) (*domain.Config, error)
+	GetFunc                    func(publicKey string) (*domain.Config, error)
+	GetAllFunc                 func() ([]domain.Config, error)
+	FindByNamePrefixFunc       func(prefix string) ([]domain.Config, error)
+	FindByTagsFunc             func(tags []string) ([]domain.Config, error)
+	ListCreatedBetweenFunc     func(after, before time.Time) ([]domain.Config, error)
+	FindByAllowedIPFunc        func(ip string) (*domain.Config, error)
+	GetByPeerIDFunc            func(peerID string) (*domain.Config, error)
+	PatchPeerFunc              func(publicKey string, ops []domain.PatchOperation, allowEmptyAllowedIPs bool) (*domain.Config, error)
+	ListStaleFunc              func(olderThan time.Duration) ([]domain.Config, error)
+	SummaryFunc                func() (*domain.PeerSummary, error)
+	TopTalkersFunc             func(by string, limit int) ([]domain.Config, error)
+	PeerStatsFunc              func(publicKey string) (*domain.PeerStats, error)
+	CreateWithNewKeysFunc      func(allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error)
+	CreateWithExistingKeysFunc func(publicKey, privateKey string, allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error)
+	UpdateAllowedIPsFunc       func(publicKey string, ips []string) error
+	DeleteFunc                 func(publicKey string) error
+	DeleteVerboseFunc          func(publicKey string) (*domain.DeleteConfigResponse, error)
+	BuildClientConfigFunc      func(peerCfg *domain.Config, clientPrivateKey string) (string, error)
+	BuildClientConfigOptsFunc  func(peerCfg *domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error)
+	PreviewClientConfigFunc    func(peer domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error)
+	ExplainClientConfigFunc    func(peer domain.Config, opts domain.ClientConfigOptions) *domain.ClientConfigExplanation
+	RotatePeerKeyFunc          func(oldPublicKey string) (*domain.Config, error)
+	RotatePeerKeyWithOptsFunc  func(oldPublicKey string, opts domain.RotateOptions) (*domain.Config, error)
+	RotatePresharedKeyFunc     func(publicKey string) (string, error)
+	SetPeerMetadataFunc        func(publicKey, name, description string) (*domain.PeerMetadata, error)
+	SetPeerTagsFunc            func(publicKey string, tags []string) (*domain.PeerMetadata, error)
+	VerifyKeyPairFunc          func(publicKey, privateKey string) (bool, error)
+	ApplyDesiredStateFunc      func(desired []domain.DesiredPeer, prune bool) (*domain.ApplyResult, error)
+	PlanDesiredStateFunc       func(desired []domain.DesiredPeer, prune bool) (*domain.ReconcilePlan, error)
+	ExportPeersFunc            func(w io.Writer) (domain.ExportStats, error)
 }
 
 var _ ServiceInterface = &mockService{} // Ensure mockService implements ServiceInterface
 
-func (m *mockService) GetAll() ([]domain.Config, error) {
+func (m *mockService) GetAll(ctx context.Context) ([]domain.Config, error) {
 	if m.GetAllFunc != nil {
 		return m.GetAllFunc()
 	}
@@ -42,7 +76,49 @@ func (m *mockService) GetAll() ([]domain.Config, error) {
 	}, nil
 }
 
-func (m *mockService) Get(publicKey string) (*domain.Config, error) {
+func (m *mockService) FindByNamePrefix(ctx context.Context, prefix string) ([]domain.Config, error) {
+	if m.FindByNamePrefixFunc != nil {
+		return m.FindByNamePrefixFunc(prefix)
+	}
+	return []domain.Config{}, nil
+}
+
+func (m *mockService) FindByTags(ctx context.Context, tags []string) ([]domain.Config, error) {
+	if m.FindByTagsFunc != nil {
+		return m.FindByTagsFunc(tags)
+	}
+	return []domain.Config{}, nil
+}
+
+func (m *mockService) ListCreatedBetween(ctx context.Context, after, before time.Time) ([]domain.Config, error) {
+	if m.ListCreatedBetweenFunc != nil {
+		return m.ListCreatedBetweenFunc(after, before)
+	}
+	return []domain.Config{}, nil
+}
+
+func (m *mockService) GetByPeerID(ctx context.Context, peerID string) (*domain.Config, error) {
+	if m.GetByPeerIDFunc != nil {
+		return m.GetByPeerIDFunc(peerID)
+	}
+	return nil, errors.New("GetByPeerIDFunc not implemented")
+}
+
+func (m *mockService) PatchPeer(ctx context.Context, publicKey string, ops []domain.PatchOperation, allowEmptyAllowedIPs bool) (*domain.Config, error) {
+	if m.PatchPeerFunc != nil {
+		return m.PatchPeerFunc(publicKey, ops, allowEmptyAllowedIPs)
+	}
+	return nil, errors.New("PatchPeerFunc not implemented")
+}
+
+func (m *mockService) FindByAllowedIP(ctx context.Context, ip string) (*domain.Config, error) {
+	if m.FindByAllowedIPFunc != nil {
+		return m.FindByAllowedIPFunc(ip)
+	}
+	return nil, repository.ErrPeerNotFound
+}
+
+func (m *mockService) Get(ctx context.Context, publicKey string) (*domain.Config, error) {
 	if m.GetFunc != nil {
 		return m.GetFunc(publicKey)
 	}
@@ -55,9 +131,9 @@ func (m *mockService) Get(publicKey string) (*domain.Config, error) {
 	return nil, fmt.Errorf("mock error: unexpected key %s", publicKey)
 }
 
-func (m *mockService) CreateWithNewKeys(allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+func (m *mockService) CreateWithNewKeys(ctx context.Context, allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error) {
 	if m.CreateWithNewKeysFunc != nil {
-		return m.CreateWithNewKeysFunc(allowedIPs, presharedKey, persistentKeepalive)
+		return m.CreateWithNewKeysFunc(allowedIPs, presharedKey, generatePSK, persistentKeepalive)
 	}
 	// Этот метод не должен быть вызван в TestCreateConfig_InvalidInput,
 	// но для полноты мока оставим стандартное поведение.
@@ -70,7 +146,7 @@ func (m *mockService) CreateWithNewKeys(allowedIPs []string, presharedKey string
 	}, nil
 }
 
-func (m *mockService) UpdateAllowedIPs(publicKey string, ips []string) error {
+func (m *mockService) UpdateAllowedIPs(ctx context.Context, publicKey string, ips []string) error {
 	if m.UpdateAllowedIPsFunc != nil {
 		return m.UpdateAllowedIPsFunc(publicKey, ips)
 	}
@@ -80,7 +156,7 @@ func (m *mockService) UpdateAllowedIPs(publicKey string, ips []string) error {
 	return nil
 }
 
-func (m *mockService) Delete(publicKey string) error {
+func (m *mockService) Delete(ctx context.Context, publicKey string) error {
 	if m.DeleteFunc != nil {
 		return m.DeleteFunc(publicKey)
 	}
@@ -90,6 +166,42 @@ func (m *mockService) Delete(publicKey string) error {
 	return nil
 }
 
+func (m *mockService) PeerStats(ctx context.Context, publicKey string) (*domain.PeerStats, error) {
+	if m.PeerStatsFunc != nil {
+		return m.PeerStatsFunc(publicKey)
+	}
+	return nil, errors.New("PeerStatsFunc not implemented")
+}
+
+func (m *mockService) Summary(ctx context.Context) (*domain.PeerSummary, error) {
+	if m.SummaryFunc != nil {
+		return m.SummaryFunc()
+	}
+	return &domain.PeerSummary{}, nil
+}
+
+func (m *mockService) TopTalkers(ctx context.Context, by string, limit int) ([]domain.Config, error) {
+	if m.TopTalkersFunc != nil {
+		return m.TopTalkersFunc(by, limit)
+	}
+	return nil, errors.New("TopTalkersFunc not implemented")
+}
+
+func (m *mockService) ListStale(ctx context.Context, olderThan time.Duration) ([]domain.Config, error) {
+	if m.ListStaleFunc != nil {
+		return m.ListStaleFunc(olderThan)
+	}
+	return []domain.Config{}, nil
+}
+
+func (m *mockService) DeleteVerbose(ctx context.Context, publicKey string) (*domain.DeleteConfigResponse, error) {
+	if m.DeleteVerboseFunc != nil {
+		return m.DeleteVerboseFunc(publicKey)
+	}
+	existed := publicKey != "non_existent_key_for_delete"
+	return &domain.DeleteConfigResponse{Deleted: existed, Existed: existed}, nil
+}
+
 func (m *mockService) BuildClientConfig(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
 	if m.BuildClientConfigFunc != nil {
 		return m.BuildClientConfigFunc(peerCfg, clientPrivateKey)
@@ -101,7 +213,28 @@ func (m *mockService) BuildClientConfig(peerCfg *domain.Config, clientPrivateKey
 	return "", fmt.Errorf("mock BuildClientConfig error for peer %s", peerCfg.PublicKey)
 }
 
-func (m *mockService) RotatePeerKey(oldPublicKey string) (*domain.Config, error) {
+func (m *mockService) PreviewClientConfig(peer domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error) {
+	if m.PreviewClientConfigFunc != nil {
+		return m.PreviewClientConfigFunc(peer, clientPrivateKey, opts)
+	}
+	return m.BuildClientConfigWithOptions(&peer, clientPrivateKey, opts)
+}
+
+func (m *mockService) BuildClientConfigWithOptions(peerCfg *domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error) {
+	if m.BuildClientConfigOptsFunc != nil {
+		return m.BuildClientConfigOptsFunc(peerCfg, clientPrivateKey, opts)
+	}
+	return m.BuildClientConfig(peerCfg, clientPrivateKey) // Default: behave like a plain build
+}
+
+func (m *mockService) RotatePresharedKey(ctx context.Context, publicKey string) (string, error) {
+	if m.RotatePresharedKeyFunc != nil {
+		return m.RotatePresharedKeyFunc(publicKey)
+	}
+	return "", errors.New("mockService.RotatePresharedKey not implemented for this test")
+}
+
+func (m *mockService) RotatePeerKey(ctx context.Context, oldPublicKey string) (*domain.Config, error) {
 	if m.RotatePeerKeyFunc != nil {
 		return m.RotatePeerKeyFunc(oldPublicKey)
 	}
@@ -115,6 +248,73 @@ func (m *mockService) RotatePeerKey(oldPublicKey string) (*domain.Config, error)
 	return nil, repository.ErrPeerNotFound
 }
 
+func (m *mockService) RotatePeerKeyWithOptions(ctx context.Context, oldPublicKey string, opts domain.RotateOptions) (*domain.Config, error) {
+	if m.RotatePeerKeyWithOptsFunc != nil {
+		return m.RotatePeerKeyWithOptsFunc(oldPublicKey, opts)
+	}
+	return m.RotatePeerKey(context.Background(), oldPublicKey) // Default: behave like a plain rotation
+}
+
+func (m *mockService) SetPeerMetadata(publicKey, name, description string) (*domain.PeerMetadata, error) {
+	if m.SetPeerMetadataFunc != nil {
+		return m.SetPeerMetadataFunc(publicKey, name, description)
+	}
+	return &domain.PeerMetadata{Name: name, Description: description}, nil
+}
+
+func (m *mockService) ExplainClientConfig(peer domain.Config, opts domain.ClientConfigOptions) *domain.ClientConfigExplanation {
+	if m.ExplainClientConfigFunc != nil {
+		return m.ExplainClientConfigFunc(peer, opts)
+	}
+	return &domain.ClientConfigExplanation{}
+}
+
+func (m *mockService) ServerIdentity() *domain.ServerIdentity {
+	return &domain.ServerIdentity{PublicKey: "mockServerPubKeyForTests", Endpoint: "mock.example.com:51820"}
+}
+
+func (m *mockService) SetPeerTags(publicKey string, tags []string) (*domain.PeerMetadata, error) {
+	if m.SetPeerTagsFunc != nil {
+		return m.SetPeerTagsFunc(publicKey, tags)
+	}
+	return &domain.PeerMetadata{Tags: tags}, nil
+}
+
+func (m *mockService) VerifyKeyPair(publicKey, privateKey string) (bool, error) {
+	if m.VerifyKeyPairFunc != nil {
+		return m.VerifyKeyPairFunc(publicKey, privateKey)
+	}
+	return publicKey == "matching_pub_key" && privateKey == "matching_priv_key", nil
+}
+
+func (m *mockService) ApplyDesiredState(ctx context.Context, desired []domain.DesiredPeer, prune bool) (*domain.ApplyResult, error) {
+	if m.ApplyDesiredStateFunc != nil {
+		return m.ApplyDesiredStateFunc(desired, prune)
+	}
+	return &domain.ApplyResult{Created: []string{}, Updated: []string{}, Deleted: []string{}, Unchanged: []string{}}, nil
+}
+
+func (m *mockService) PlanDesiredState(ctx context.Context, desired []domain.DesiredPeer, prune bool) (*domain.ReconcilePlan, error) {
+	if m.PlanDesiredStateFunc != nil {
+		return m.PlanDesiredStateFunc(desired, prune)
+	}
+	return &domain.ReconcilePlan{Create: []string{}, Update: []string{}, Delete: []string{}}, nil
+}
+
+func (m *mockService) ExportPeers(ctx context.Context, w io.Writer) (domain.ExportStats, error) {
+	if m.ExportPeersFunc != nil {
+		return m.ExportPeersFunc(w)
+	}
+	return domain.ExportStats{}, nil
+}
+
+func (m *mockService) CreateWithExistingKeys(ctx context.Context, publicKey, privateKey string, allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+	if m.CreateWithExistingKeysFunc != nil {
+		return m.CreateWithExistingKeysFunc(publicKey, privateKey, allowedIPs, presharedKey, persistentKeepalive)
+	}
+	return &domain.Config{PublicKey: publicKey, PrivateKey: privateKey, AllowedIps: allowedIPs}, nil
+}
+
 func TestGetAllHandler(t *testing.T) {
 	logger.Logger = zaptest.NewLogger(t)
 	gin.SetMode(gin.TestMode)
@@ -135,6 +335,370 @@ func TestGetAllHandler(t *testing.T) {
 	}
 }
 
+func TestGetAllHandler_CSV(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+	peers := []domain.Config{
+		{PublicKey: "csvPeerOne", PreSharedKey: "secretPSK", Endpoint: "192.0.2.1:51820", AllowedIps: []string{"10.0.0.2/32", "fd00::2/128"},
+			LatestHandshake: 1700000000, ReceiveBytes: 100, TransmitBytes: 200, PersistentKeepalive: 25},
+		{PublicKey: "csvPeerTwo", AllowedIps: []string{}},
+	}
+	r := gin.New()
+	r.GET("/configs", NewConfigHandler(&mockService{GetAllFunc: func() ([]domain.Config, error) { return peers, nil }}).GetAll)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/configs?format=csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.NotContains(t, w.Body.String(), "secretPSK", "Preshared keys must be redacted")
+	assert.Contains(t, w.Body.String(), `"10.0.0.2/32,fd00::2/128"`, "AllowedIPs containing commas should be quoted")
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"publicKey", "allowedIps", "endpoint", "latestHandshake", "rx", "tx", "keepalive"},
+		{"csvPeerOne", "10.0.0.2/32,fd00::2/128", "192.0.2.1:51820", "1700000000", "100", "200", "25"},
+		{"csvPeerTwo", "", "", "0", "0", "0", "0"},
+	}, rows)
+
+	assert.Equal(t, http.StatusBadRequest, get("/configs?format=xml").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/configs?format=csv&fields=publicKey").Code)
+}
+
+func TestGetAllHandler_NamePrefix(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+	var gotPrefix string
+	mockSvc := &mockService{
+		GetAllFunc: func() ([]domain.Config, error) {
+			t.Error("GetAll should not be called when a name prefix is given")
+			return nil, nil
+		},
+		FindByNamePrefixFunc: func(prefix string) ([]domain.Config, error) {
+			gotPrefix = prefix
+			return []domain.Config{{PublicKey: "namedPeer", Metadata: &domain.PeerMetadata{Name: "Alice-laptop"}}}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.GET("/configs", h.GetAll)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/configs?name=alice", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", gotPrefix)
+	var resp []domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 1)
+	assert.Equal(t, "namedPeer", resp[0].PublicKey)
+}
+
+func TestGetAllHandler_CreatedWindow(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+	var gotAfter, gotBefore time.Time
+	calls := 0
+	mockSvc := &mockService{
+		ListCreatedBetweenFunc: func(after, before time.Time) ([]domain.Config, error) {
+			calls++
+			gotAfter, gotBefore = after, before
+			return []domain.Config{{PublicKey: "windowPeer"}}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.GET("/configs", h.GetAll)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/configs?createdAfter=2024-05-01T00:00:00Z&createdBefore=2024-06-01T00:00:00Z")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), gotAfter.UTC())
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), gotBefore.UTC())
+	var resp []domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 1)
+
+	for name, url := range map[string]string{
+		"Malformed_time":     "/configs?createdAfter=yesterday",
+		"After_not_before":   "/configs?createdAfter=2024-06-01T00:00:00Z&createdBefore=2024-05-01T00:00:00Z",
+		"Equal_bounds":       "/configs?createdAfter=2024-06-01T00:00:00Z&createdBefore=2024-06-01T00:00:00Z",
+		"Combined_with_name": "/configs?name=alice&createdAfter=2024-05-01T00:00:00Z",
+	} {
+		assert.Equal(t, http.StatusBadRequest, get(url).Code, name)
+	}
+	assert.Equal(t, 1, calls, "Rejected requests must not reach the service")
+}
+
+func TestGetAllHandler_MaxAllowedIps(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+	mockSvc := &mockService{
+		GetAllFunc: func() ([]domain.Config, error) {
+			return []domain.Config{
+				{PublicKey: "widePeer", AllowedIps: []string{"10.0.0.2/32", "10.1.0.0/24", "10.2.0.0/24", "fd00::2/128"}},
+				{PublicKey: "narrowPeer", AllowedIps: []string{"10.0.0.3/32"}},
+			}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.GET("/configs", h.GetAll)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/configs?maxAllowedIps=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp []domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 2)
+	assert.Equal(t, []string{"10.0.0.2/32", "10.1.0.0/24"}, resp[0].AllowedIps)
+	assert.Equal(t, 2, resp[0].AllowedIpsMore, "The overflow indicator should count the cut entries")
+	assert.Equal(t, []string{"10.0.0.3/32"}, resp[1].AllowedIps)
+	var raw []map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+	assert.NotContains(t, raw[1], "allowedIpsMore", "Untruncated peers carry no indicator")
+
+	w = get("/configs")
+	var full []domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &full))
+	assert.Len(t, full[0].AllowedIps, 4, "Without the parameter the list is complete")
+	assert.Zero(t, full[0].AllowedIpsMore)
+
+	for _, bad := range []string{"0", "-1", "many"} {
+		assert.Equal(t, http.StatusBadRequest, get("/configs?maxAllowedIps="+bad).Code, bad)
+	}
+}
+
+func TestGetAllHandler_FieldSelection(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+	mockSvc := &mockService{
+		GetAllFunc: func() ([]domain.Config, error) {
+			return []domain.Config{{
+				PublicKey:       "fieldsPeer",
+				PreSharedKey:    "secretPSK",
+				AllowedIps:      []string{"10.0.0.7/32"},
+				LatestHandshake: 1700000000,
+				ReceiveBytes:    42,
+			}}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.GET("/configs", h.GetAll)
+
+	t.Run("Subset_projection", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/configs?fields=publicKey,allowedIps,latestHandshake", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp []map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp, 1)
+		assert.Equal(t, map[string]interface{}{
+			"publicKey":       "fieldsPeer",
+			"allowedIps":      []interface{}{"10.0.0.7/32"},
+			"latestHandshake": float64(1700000000),
+		}, resp[0])
+	})
+
+	t.Run("Unknown_field_rejected", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/configs?fields=publicKey,bogus", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var resp domain.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Contains(t, resp.Error, "bogus")
+	})
+}
+
+func TestGetConfigByKey_FieldSelection(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+	const publicKey = "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg="
+	mockSvc := &mockService{
+		GetFunc: func(key string) (*domain.Config, error) {
+			return &domain.Config{PublicKey: key, AllowedIps: []string{"10.0.0.8/32"}, PersistentKeepalive: 25}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.UseRawPath = true
+	r.UnescapePathValues = false
+	r.GET("/configs/:publicKey", h.GetConfigByKey)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/configs/"+url.PathEscape(publicKey)+"?fields=publicKey", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]interface{}{"publicKey": publicKey}, resp)
+}
+
+func TestGetConfigByAllowedIP_StatusCodes(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := &mockService{
+		FindByAllowedIPFunc: func(ip string) (*domain.Config, error) {
+			switch ip {
+			case "10.0.0.5":
+				return &domain.Config{PublicKey: "ipPeer", AllowedIps: []string{"10.0.0.5/32"}}, nil
+			case "10.1.0.200":
+				return nil, fmt.Errorf("%w: 10.1.0.200 is in the AllowedIPs of a, b", service.ErrAmbiguousAllowedIP)
+			case "bogus":
+				return nil, service.ErrInvalidAllowedIP
+			}
+			return nil, repository.ErrPeerNotFound
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.GET("/configs/by-ip", h.GetConfigByAllowedIP)
+
+	testCases := []struct {
+		query          string
+		expectedStatus int
+	}{
+		{query: "?ip=10.0.0.5", expectedStatus: http.StatusOK},
+		{query: "?ip=10.2.0.1", expectedStatus: http.StatusNotFound},
+		{query: "?ip=10.1.0.200", expectedStatus: http.StatusConflict},
+		{query: "?ip=bogus", expectedStatus: http.StatusBadRequest},
+		{query: "", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/configs/by-ip"+tc.query, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, tc.expectedStatus, w.Code, "query %q", tc.query)
+		if tc.expectedStatus == http.StatusOK {
+			var resp domain.Config
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "ipPeer", resp.PublicKey)
+		}
+	}
+}
+
+func TestHandleError_Sanitization(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	internalErr := errors.New("wg show wg0 dump: execution failed: exit status 1; output: /usr/bin/wg: Operation not permitted")
+	mockSvc := &mockService{
+		GetAllFunc: func() ([]domain.Config, error) { return nil, internalErr },
+	}
+
+	testCases := []struct {
+		name          string
+		sanitize      bool
+		expectedError string
+	}{
+		{name: "Production_is_sanitized", sanitize: true, expectedError: "An unexpected error occurred."},
+		{name: "Development_is_verbose", sanitize: false, expectedError: internalErr.Error()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewConfigHandler(mockSvc, WithErrorSanitization(tc.sanitize))
+			r := gin.New()
+			r.GET("/configs", h.GetAll)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/configs", nil)
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusInternalServerError, w.Code)
+
+			var resp domain.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.expectedError, resp.Error)
+			assert.Equal(t, domain.ErrorCodeInternal, resp.Code)
+		})
+	}
+}
+
+// listenPortFunc is a ListenPortChanger backed by a function.
+type listenPortFunc func(port int) (string, error)
+
+func (f listenPortFunc) SetListenPort(ctx context.Context, port int) (string, error) { return f(port) }
+
+// failingListRepo is a repository whose ListConfigs always fails.
+type failingListRepo struct {
+	repository.Repo
+	err error
+}
+
+func (r failingListRepo) ListConfigs(ctx context.Context) ([]domain.Config, error) { return nil, r.err }
+
+// TestErrorSanitization_OtherEndpoints tests that bulk results and the admin and server endpoints
+// hide internal error text like handleError does.
+func TestErrorSanitization_OtherEndpoints(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	internalErr := errors.New("wg set wg0 peer x preshared-key /dev/stdin: execution failed: exit status 1")
+	mockSvc := &mockService{
+		GetAllFunc:             func() ([]domain.Config, error) { return []domain.Config{{PublicKey: "pskPeer"}}, nil },
+		RotatePresharedKeyFunc: func(publicKey string) (string, error) { return "", internalErr },
+	}
+	failingPort := listenPortFunc(func(port int) (string, error) { return "", internalErr })
+
+	post := func(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for _, sanitize := range []bool{true, false} {
+		r := gin.New()
+		r.POST("/configs/bulk-rotate-psk", NewConfigHandler(mockSvc, WithErrorSanitization(sanitize)).BulkRotatePresharedKeys)
+		r.POST("/server/listen-port", NewServerHandler(domain.ServerInfo{},
+			WithListenPortChanger(failingPort), WithServerErrorSanitization(sanitize)).SetListenPort)
+		r.POST("/admin/refresh", NewAdminHandler(failingListRepo{Repo: repository.NewFakeWGRepository(), err: internalErr}, nil, nil,
+			WithAdminErrorSanitization(sanitize)).Refresh)
+
+		w := post(r, "/configs/bulk-rotate-psk", "")
+		require.Equal(t, http.StatusMultiStatus, w.Code)
+		var bulk domain.BulkRotatePSKResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bulk))
+
+		for path, w := range map[string]*httptest.ResponseRecorder{
+			"/server/listen-port": post(r, "/server/listen-port", `{"port": 51821}`),
+			"/admin/refresh":      post(r, "/admin/refresh", ""),
+		} {
+			require.Equal(t, http.StatusInternalServerError, w.Code, path)
+			if sanitize {
+				assert.NotContains(t, w.Body.String(), "execution failed", "%s must hide the error text", path)
+			} else {
+				assert.Contains(t, w.Body.String(), "execution failed", "%s keeps the error text without sanitization", path)
+			}
+		}
+		if sanitize {
+			assert.Equal(t, "An unexpected error occurred.", bulk.Failed["pskPeer"])
+		} else {
+			assert.Equal(t, internalErr.Error(), bulk.Failed["pskPeer"])
+		}
+	}
+}
+
 func TestGetConfig_Success_Updated(t *testing.T) {
 	logger.Logger = zaptest.NewLogger(t)
 	gin.SetMode(gin.TestMode)
@@ -220,6 +784,54 @@ func TestGetConfig_NotFound(t *testing.T) {
 	assert.Equal(t, expectedErrorMessage, respError.Error)
 }
 
+// TestGetPeerStats tests that POST /configs/stats returns the service's statistics without key material.
+func TestGetPeerStats(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	handshake := time.Unix(1700000000, 0).UTC()
+	mockSvc := &mockService{
+		PeerStatsFunc: func(publicKey string) (*domain.PeerStats, error) {
+			switch publicKey {
+			case "onlinePeer":
+				return &domain.PeerStats{PublicKey: publicKey, LatestHandshake: &handshake, ReceiveBytes: 1024, TransmitBytes: 2048, Online: true}, nil
+			case "neverPeer":
+				return &domain.PeerStats{PublicKey: publicKey}, nil
+			case "hiddenPeer":
+				return nil, service.ErrTrafficStatsHidden
+			}
+			return nil, repository.ErrPeerNotFound
+		},
+	}
+	r := gin.New()
+	r.POST("/configs/stats", NewConfigHandler(mockSvc).GetPeerStats)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/configs/stats", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Online_peer", func(t *testing.T) {
+		w := post(`{"public_key":"onlinePeer"}`)
+		require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+		assert.JSONEq(t, `{"publicKey":"onlinePeer","latestHandshake":"`+handshake.Format(time.RFC3339)+`","receiveBytes":1024,"transmitBytes":2048,"online":true}`, w.Body.String())
+	})
+
+	t.Run("Never_connected_peer", func(t *testing.T) {
+		w := post(`{"public_key":"neverPeer"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "latestHandshake")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, post(`{"public_key":"missingPeer"}`).Code)
+		assert.Equal(t, http.StatusForbidden, post(`{"public_key":"hiddenPeer"}`).Code, "Hidden traffic answers 403, like /stats")
+		assert.Equal(t, http.StatusBadRequest, post(`{}`).Code)
+	})
+}
+
 // TestCreateConfig_InvalidInput tests peer creation with invalid JSON body.
 func TestCreateConfig_InvalidInput(t *testing.T) {
 	logger.Logger = zaptest.NewLogger(t)
@@ -227,7 +839,7 @@ func TestCreateConfig_InvalidInput(t *testing.T) {
 
 	mockSvc := &mockService{
 		// CreateWithNewKeysFunc не должен быть вызван, так как ошибка на этапе биндинга
-		CreateWithNewKeysFunc: func(allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+		CreateWithNewKeysFunc: func(allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error) {
 			t.Error("mockService.CreateWithNewKeysFunc should not be called in TestCreateConfig_InvalidInput")
 			return nil, fmt.Errorf("service method should not be called")
 		},
@@ -275,7 +887,7 @@ func TestCreateConfig_Success(t *testing.T) {
 		PersistentKeepalive: createReq.PersistentKeepalive,
 	}
 	mockSvc := &mockService{
-		CreateWithNewKeysFunc: func(allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+		CreateWithNewKeysFunc: func(allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error) {
 			assert.Equal(t, createReq.AllowedIps, allowedIPs)
 			assert.Equal(t, createReq.PreSharedKey, presharedKey)
 			assert.Equal(t, createReq.PersistentKeepalive, persistentKeepalive)
@@ -293,6 +905,7 @@ func TestCreateConfig_Success(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	r.ServeHTTP(w, req)
 	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), "The response carries a private key")
 	var respCfg domain.Config
 	err = json.Unmarshal(w.Body.Bytes(), &respCfg)
 	require.NoError(t, err)
@@ -303,6 +916,41 @@ func TestCreateConfig_Success(t *testing.T) {
 	assert.Equal(t, expectedCreatedPeer.PersistentKeepalive, respCfg.PersistentKeepalive)
 }
 
+// TestCreateConfig_GeneratePresharedKey tests that generate_preshared_key reaches the service and is rejected for imports.
+func TestCreateConfig_GeneratePresharedKey(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := &mockService{
+		CreateWithNewKeysFunc: func(allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error) {
+			assert.True(t, generatePSK, "The flag should be passed to the service")
+			return &domain.Config{PublicKey: "generatedPSKPeer", PreSharedKey: "serverGeneratedPSK"}, nil
+		},
+		CreateWithExistingKeysFunc: func(publicKey, privateKey string, allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+			t.Error("Imports must not be attempted with generate_preshared_key")
+			return nil, nil
+		},
+	}
+	r := gin.New()
+	r.POST("/configs", NewConfigHandler(mockSvc).CreateConfig)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/configs", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"allowed_ips":["10.50.0.2/32"],"generate_preshared_key":true}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var respCfg domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &respCfg))
+	assert.Equal(t, "serverGeneratedPSK", respCfg.PreSharedKey)
+
+	w = post(`{"public_key":"importedKey","allowed_ips":["10.50.0.3/32"],"generate_preshared_key":true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 // TestCreateConfig_ServiceError tests peer creation when the service layer returns an error.
 func TestCreateConfig_ServiceError(t *testing.T) {
 	logger.Logger = zaptest.NewLogger(t)
@@ -311,7 +959,7 @@ func TestCreateConfig_ServiceError(t *testing.T) {
 	serviceErrorMessage := "simulated service layer error during peer creation"
 
 	mockSvc := &mockService{
-		CreateWithNewKeysFunc: func(allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+		CreateWithNewKeysFunc: func(allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error) {
 			// Имитируем ошибку от сервисного слоя
 			return nil, errors.New(serviceErrorMessage)
 		},
@@ -393,6 +1041,59 @@ func TestUpdateAllowedIPs_Success(t *testing.T) {
 	assert.Empty(t, w.Body.String(), "Response body should be empty for 200 OK in this case")
 }
 
+// TestUpdateAllowedIPs_EmptyListNeedsConfirm tests that clearing all AllowedIPs is rejected unless confirmed or allowed.
+func TestUpdateAllowedIPs_EmptyListNeedsConfirm(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		query          string
+		expectedStatus int
+	}{
+		{name: "Rejected_without_confirm", query: "", expectedStatus: http.StatusBadRequest},
+		{name: "Rejected_with_confirm_false", query: "?confirm=false", expectedStatus: http.StatusBadRequest},
+		{name: "Cleared_with_confirm", query: "?confirm=true", expectedStatus: http.StatusOK},
+		{name: "Cleared_when_allowed", opts: []Option{WithEmptyAllowedIPsUpdate(true)}, query: "", expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var updatedIPs []string
+			serviceCalled := false
+			mockSvc := &mockService{
+				UpdateAllowedIPsFunc: func(publicKey string, ips []string) error {
+					serviceCalled = true
+					updatedIPs = ips
+					return nil
+				},
+			}
+			r := gin.New()
+			h := NewConfigHandler(mockSvc, tc.opts...)
+			r.POST("/configs/update-allowed-ips", h.UpdateAllowedIPs)
+
+			body, err := json.Marshal(domain.UpdateAllowedIpsRequest{PublicKey: "peer_to_clear", AllowedIps: []string{}})
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/configs/update-allowed-ips"+tc.query, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus == http.StatusBadRequest {
+				assert.False(t, serviceCalled, "An unconfirmed empty update must not reach the service")
+				var respError domain.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &respError))
+				assert.Contains(t, respError.Error, "confirm=true")
+				return
+			}
+			assert.True(t, serviceCalled)
+			assert.Empty(t, updatedIPs)
+		})
+	}
+}
+
 // TestUpdateAllowedIPs_NotFound tests updating allowed IPs for a non-existent peer.
 func TestUpdateAllowedIPs_NotFound(t *testing.T) {
 	logger.Logger = zaptest.NewLogger(t)
@@ -748,39 +1449,405 @@ func TestGenerateClientConfigFile_Success(t *testing.T) {
 	assert.Equal(t, expectedContentDisposition, w.Header().Get("Content-Disposition"), "Content-Disposition header mismatch")
 
 	assert.Equal(t, expectedConfContent, w.Body.String(), "Response body (conf file content) mismatch")
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), "Client files carry a private key and must not be cached")
 }
 
-// TestGenerateClientConfigFile_PeerNotFound tests .conf file generation when the peer is not found.
-func TestGenerateClientConfigFile_PeerNotFound(t *testing.T) {
+func TestGenerateClientConfigFile_KeepaliveOverride(t *testing.T) {
 	logger.Logger = zaptest.NewLogger(t)
 	gin.SetMode(gin.TestMode)
 
-	clientPublicKeyNotFound := "clientPubKeyNotFoundOnServer"
-	clientPrivateKeyAttempt := "anyClientPrivateKey" // Не важен, т.к. до BuildClientConfig не дойдет
-
-	serviceGetCalled := false
+	var gotOpts *domain.ClientConfigOptions
 	mockSvc := &mockService{
-		GetFunc: func(publicKey string) (*domain.Config, error) {
-			serviceGetCalled = true
-			require.Equal(t, clientPublicKeyNotFound, publicKey, "PublicKey passed to Get service mismatch")
-			return nil, repository.ErrPeerNotFound // Имитируем, что пир не найден
-		},
-		BuildClientConfigFunc: func(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
-			// Этот метод не должен быть вызван
-			t.Errorf("mockService.BuildClientConfigFunc should not be called in TestGenerateClientConfigFile_PeerNotFound")
-			return "", fmt.Errorf("BuildClientConfig should not have been called")
+		BuildClientConfigOptsFunc: func(peerCfg *domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error) {
+			gotOpts = &opts
+			return "[Interface]\n", nil
 		},
 	}
-	h := NewConfigHandler(mockSvc)
-
 	r := gin.New()
-	r.POST("/configs/client-file", h.GenerateClientConfigFile)
-
-	reqPayload := domain.ClientFileRequest{
-		ClientPublicKey:  clientPublicKeyNotFound,
-		ClientPrivateKey: clientPrivateKeyAttempt,
+	r.POST("/configs/client-file", NewConfigHandler(mockSvc).GenerateClientConfigFile)
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/configs/client-file", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
 	}
-	body, err := json.Marshal(reqPayload)
+
+	require.Equal(t, http.StatusOK, post(`{"client_public_key":"existing_key","client_private_key":"k","persistent_keepalive":25}`))
+	require.NotNil(t, gotOpts.PersistentKeepalive)
+	assert.Equal(t, 25, *gotOpts.PersistentKeepalive)
+
+	require.Equal(t, http.StatusOK, post(`{"client_public_key":"existing_key","client_private_key":"k"}`))
+	assert.Nil(t, gotOpts.PersistentKeepalive, "Without the field the peer's value is mirrored")
+	assert.Empty(t, gotOpts.AllowedIPs, "Without allowed_ips the full tunnel is kept")
+
+	require.Equal(t, http.StatusOK, post(`{"client_public_key":"existing_key","client_private_key":"k","allowed_ips":["10.8.0.0/24"]}`))
+	assert.Equal(t, []string{"10.8.0.0/24"}, gotOpts.AllowedIPs, "Split-tunnel routes should be passed to the service")
+
+	gotOpts = nil
+	assert.Equal(t, http.StatusBadRequest, post(`{"client_public_key":"existing_key","client_private_key":"k","persistent_keepalive":65536}`))
+	assert.Equal(t, http.StatusBadRequest, post(`{"client_public_key":"existing_key","client_private_key":"k","persistent_keepalive":-1}`))
+	assert.Nil(t, gotOpts, "Out-of-range values must be rejected before building")
+}
+
+func TestGenerateClientConfigFile_CacheControlOverride(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := &mockService{
+		BuildClientConfigFunc: func(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
+			return "[Interface]\n", nil
+		},
+	}
+	body, err := json.Marshal(domain.ClientFileRequest{ClientPublicKey: "existing_key", ClientPrivateKey: "cachePrivKey"})
+	require.NoError(t, err)
+
+	for _, tc := range []struct{ configured, expected string }{
+		{configured: "private, no-store, max-age=0", expected: "private, no-store, max-age=0"},
+		{configured: "", expected: "no-store"},
+	} {
+		r := gin.New()
+		r.POST("/configs/client-file", NewConfigHandler(mockSvc, WithClientFileCacheControl(tc.configured)).GenerateClientConfigFile)
+		for _, url := range []string{"/configs/client-file", "/configs/client-file?encoding=base64"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.expected, w.Header().Get("Cache-Control"), url)
+		}
+	}
+}
+
+func TestGenerateClientConfigFile_Base64MatchesRaw(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	confContent := "[Interface]\nPrivateKey = base64PrivKey\nAddress = 10.0.0.98/32\n\n[Peer]\nPublicKey = mockServerPubKey\nAllowedIPs = 0.0.0.0/0, ::/0\n"
+	mockSvc := &mockService{
+		BuildClientConfigFunc: func(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
+			return confContent, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.POST("/configs/client-file", h.GenerateClientConfigFile)
+
+	body, err := json.Marshal(domain.ClientFileRequest{ClientPublicKey: "existing_key", ClientPrivateKey: "base64PrivKey"})
+	require.NoError(t, err)
+	post := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	raw := post("/configs/client-file")
+	require.Equal(t, http.StatusOK, raw.Code)
+
+	encoded := post("/configs/client-file?encoding=base64")
+	require.Equal(t, http.StatusOK, encoded.Code)
+	assert.Contains(t, encoded.Header().Get("Content-Type"), "application/json")
+	assert.Empty(t, encoded.Header().Get("Content-Disposition"))
+	var resp domain.ClientFileBase64Response
+	require.NoError(t, json.Unmarshal(encoded.Body.Bytes(), &resp))
+	decoded, err := base64.StdEncoding.DecodeString(resp.ContentBase64)
+	require.NoError(t, err)
+	assert.Equal(t, raw.Body.String(), string(decoded), "Decoded content should match the raw text endpoint")
+	assert.Equal(t, SanitizeFilename("existing_key")+".conf", resp.Filename)
+
+	dataURI := post("/configs/client-file?encoding=datauri")
+	require.Equal(t, http.StatusOK, dataURI.Code)
+	var uriResp domain.ClientFileDataURIResponse
+	require.NoError(t, json.Unmarshal(dataURI.Body.Bytes(), &uriResp))
+	payload, ok := strings.CutPrefix(uriResp.DataURI, "data:text/plain;base64,")
+	require.True(t, ok, "Unexpected data URI prefix: %s", uriResp.DataURI)
+	decoded, err = base64.StdEncoding.DecodeString(payload)
+	require.NoError(t, err)
+	assert.Equal(t, confContent, string(decoded), "The data URI should decode to the config content")
+	assert.Equal(t, resp.Filename, uriResp.Filename)
+
+	assert.Equal(t, http.StatusBadRequest, post("/configs/client-file?encoding=hex").Code)
+}
+
+// TestClientConfig_Explain tests that ?explain=true adds the service's source attribution to the JSON
+// client config responses, and is refused for the raw .conf.
+func TestClientConfig_Explain(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	explanation := &domain.ClientConfigExplanation{
+		DNS:                 domain.ExplainedString{Value: "9.9.9.9", Source: domain.SourceRequest},
+		MTU:                 domain.ExplainedInt{Value: 1420, Source: domain.SourceInterfaceFallback},
+		Endpoint:            domain.ExplainedString{Value: "vpn.example.com:51820", Source: domain.SourceServerDefault},
+		PersistentKeepalive: domain.ExplainedInt{Value: 25, Source: domain.SourcePeer},
+	}
+	var explainedOpts domain.ClientConfigOptions
+	mockSvc := &mockService{
+		BuildClientConfigFunc: func(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
+			return "[Interface]\n", nil
+		},
+		PreviewClientConfigFunc: func(peer domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error) {
+			return "[Interface]\n", nil
+		},
+		ExplainClientConfigFunc: func(peer domain.Config, opts domain.ClientConfigOptions) *domain.ClientConfigExplanation {
+			explainedOpts = opts
+			return explanation
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.POST("/configs/client-file", h.GenerateClientConfigFile)
+	r.POST("/configs/preview", h.PreviewClientConfig)
+
+	post := func(url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	fileBody := `{"client_public_key":"existing_key","client_private_key":"somePrivKey"}`
+
+	t.Run("Client_file_base64", func(t *testing.T) {
+		w := post("/configs/client-file?encoding=base64&explain=true", fileBody)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp domain.ClientFileBase64Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, explanation, resp.Explain)
+	})
+
+	t.Run("Preview_passes_overrides", func(t *testing.T) {
+		w := post("/configs/preview?explain=true", `{"public_key":"somePub","private_key":"somePriv","dns":"9.9.9.9"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp domain.PreviewConfigResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, explanation, resp.Explain)
+		require.NotNil(t, explainedOpts.DNS)
+		assert.Equal(t, "9.9.9.9", *explainedOpts.DNS)
+	})
+
+	t.Run("Omitted_without_explain", func(t *testing.T) {
+		w := post("/configs/preview", `{"public_key":"somePub","private_key":"somePriv"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "explain")
+	})
+
+	t.Run("Raw_rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post("/configs/client-file?explain=true", fileBody).Code)
+	})
+}
+
+// TestGenerateClientConfigFile_Signature tests that with a signing key every encoding carries a valid
+// Ed25519 signature over the .conf content, and that nothing is added without one.
+func TestGenerateClientConfigFile_Signature(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	confContent := "[Interface]\nPrivateKey = base64PrivKey\nAddress = 10.0.0.97/32\n\n[Peer]\nPublicKey = mockServerPubKey\n"
+	mockSvc := &mockService{
+		BuildClientConfigFunc: func(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
+			return confContent, nil
+		},
+	}
+	publicKey, signingKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	body, err := json.Marshal(domain.ClientFileRequest{ClientPublicKey: "existing_key", ClientPrivateKey: "base64PrivKey"})
+	require.NoError(t, err)
+	post := func(h *ConfigHandler, url string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/configs/client-file", h.GenerateClientConfigFile)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+	verify := func(t *testing.T, signature string) {
+		t.Helper()
+		raw, err := base64.StdEncoding.DecodeString(signature)
+		require.NoError(t, err)
+		assert.True(t, ed25519.Verify(publicKey, []byte(confContent), raw), "Signature should verify over the returned content")
+	}
+
+	signed := NewConfigHandler(mockSvc, WithConfigSigningKey(signingKey))
+
+	t.Run("Raw_signature_header", func(t *testing.T) {
+		w := post(signed, "/configs/client-file")
+		assert.Equal(t, confContent, w.Body.String())
+		verify(t, w.Header().Get(ConfigSignatureHeader))
+	})
+
+	t.Run("JSON_signature_field", func(t *testing.T) {
+		w := post(signed, "/configs/client-file?encoding=base64")
+		var resp domain.ClientFileBase64Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		verify(t, resp.Signature)
+		assert.Equal(t, resp.Signature, w.Header().Get(ConfigSignatureHeader))
+
+		w = post(signed, "/configs/client-file?encoding=datauri")
+		var uriResp domain.ClientFileDataURIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &uriResp))
+		verify(t, uriResp.Signature)
+	})
+
+	t.Run("Unsigned_by_default", func(t *testing.T) {
+		w := post(NewConfigHandler(mockSvc), "/configs/client-file?encoding=base64")
+		assert.Empty(t, w.Header().Get(ConfigSignatureHeader))
+		assert.NotContains(t, w.Body.String(), "signature")
+	})
+}
+
+// TestGenerateClientConfigFile_DebugLogsServerIdentity tests that development (debug-level) logging records
+// the endpoint and server key prefix used for a generation, and never the client private key.
+func TestGenerateClientConfigFile_DebugLogsServerIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const clientPrivateKey = "clientPrivateKeyMustNotBeLogged="
+
+	generate := func(level zapcore.Level) *observer.ObservedLogs {
+		core, logs := observer.New(level)
+		previous := logger.Logger
+		logger.Logger = zap.New(core)
+		defer func() { logger.Logger = previous }()
+
+		r := gin.New()
+		mockSvc := &mockService{
+			BuildClientConfigFunc: func(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
+				return "[Interface]\nPrivateKey = " + clientPrivateKey + "\n", nil
+			},
+		}
+		r.POST("/configs/client-file", NewConfigHandler(mockSvc).GenerateClientConfigFile)
+		body, err := json.Marshal(domain.ClientFileRequest{ClientPublicKey: "existing_key", ClientPrivateKey: clientPrivateKey})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/configs/client-file", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return logs
+	}
+
+	logs := generate(zapcore.DebugLevel) // Development mode logs at debug level
+	entries := logs.FilterMessage("Client .conf generated with server identity").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "mock.example.com:51820", fields["endpoint"])
+	assert.Equal(t, "mockServer...", fields["serverPublicKeyPrefix"])
+	for _, entry := range logs.All() {
+		for _, value := range entry.ContextMap() {
+			assert.NotContains(t, fmt.Sprint(value), clientPrivateKey, "The client private key must never be logged")
+		}
+	}
+
+	logs = generate(zapcore.InfoLevel) // Production
+	assert.Zero(t, logs.FilterMessage("Client .conf generated with server identity").Len())
+}
+
+// TestHandleError_Categories tests that each error category maps to its status, through wrapping.
+func TestHandleError_Categories(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{fmt.Errorf("%w: not valid base64", domain.ErrInvalidKeyFormat), http.StatusBadRequest},
+		{service.ErrInvalidPrivateKey, http.StatusBadRequest},
+		{fmt.Errorf("%w: %q", service.ErrInvalidPublicKey, "short"), http.StatusBadRequest},
+		{fmt.Errorf("%w: %q is not a valid CIDR prefix", service.ErrInvalidAllowedIP, "10.0.0.300/32"), http.StatusBadRequest},
+		{fmt.Errorf("%w: 5 entries exceed the limit of 4", service.ErrInvalidClientDNS), http.StatusBadRequest},
+		{fmt.Errorf("operation 0 (move /allowedIps): %w", service.ErrInvalidPatch), http.StatusBadRequest},
+		{fmt.Errorf("operation 0 (replace /publicKey): %w: publicKey", service.ErrReadOnlyField), http.StatusUnprocessableEntity},
+		{service.ErrPatchTestFailed, http.StatusConflict},
+		{fmt.Errorf("%w: \"total\"", service.ErrInvalidTopOrder), http.StatusBadRequest},
+		{service.ErrTrafficStatsHidden, http.StatusForbidden},
+		{fmt.Errorf("%w: rotating a, expected b", service.ErrRotationKeyMismatch), http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w: key is all zeros", service.ErrWeakPrivateKey), http.StatusUnprocessableEntity},
+		{service.ErrKeyPairMismatch, http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w: %q is not within 10.8.0.0/24", service.ErrAllowedIPOutOfRange, "0.0.0.0/0"), http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w: overlapping AllowedIPs: x", service.ErrInvalidDesiredState), http.StatusUnprocessableEntity},
+		{fmt.Errorf("cannot import peer: %w", service.ErrPeerAlreadyExists), http.StatusConflict},
+		{service.ErrRotationConflict, http.StatusConflict},
+		{fmt.Errorf("%w: 10.0.0.2", service.ErrAmbiguousAllowedIP), http.StatusConflict},
+		{service.ErrAddressPoolExhausted, http.StatusConflict},
+		{repository.ErrPeerNotFound, http.StatusNotFound},
+		{repository.ErrWgTimeout, http.StatusServiceUnavailable},
+		{errors.New("exit status 1"), http.StatusInternalServerError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			r := gin.New()
+			r.GET("/fail", func(c *gin.Context) { NewConfigHandler(&mockService{}).handleError(c, "Test", "somePeer", tc.err) })
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/fail", nil)
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+// TestGenerateClientConfigFile_WeakPrivateKey tests that a well-formed but weak client private key yields 422.
+func TestGenerateClientConfigFile_WeakPrivateKey(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := &mockService{
+		BuildClientConfigFunc: func(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
+			return "", fmt.Errorf("%w: key is all zeros", service.ErrWeakPrivateKey)
+		},
+	}
+	r := gin.New()
+	r.POST("/configs/client-file", NewConfigHandler(mockSvc).GenerateClientConfigFile)
+
+	body, err := json.Marshal(domain.ClientFileRequest{ClientPublicKey: "existing_key", ClientPrivateKey: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs/client-file", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var respError domain.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &respError))
+	assert.Contains(t, respError.Error, "all zeros")
+	assert.NotContains(t, w.Body.String(), "AAAAAAAAAAAA", "The private key must not be echoed back")
+}
+
+// TestGenerateClientConfigFile_PeerNotFound tests .conf file generation when the peer is not found.
+func TestGenerateClientConfigFile_PeerNotFound(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	clientPublicKeyNotFound := "clientPubKeyNotFoundOnServer"
+	clientPrivateKeyAttempt := "anyClientPrivateKey" // Не важен, т.к. до BuildClientConfig не дойдет
+
+	serviceGetCalled := false
+	mockSvc := &mockService{
+		GetFunc: func(publicKey string) (*domain.Config, error) {
+			serviceGetCalled = true
+			require.Equal(t, clientPublicKeyNotFound, publicKey, "PublicKey passed to Get service mismatch")
+			return nil, repository.ErrPeerNotFound // Имитируем, что пир не найден
+		},
+		BuildClientConfigFunc: func(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
+			// Этот метод не должен быть вызван
+			t.Errorf("mockService.BuildClientConfigFunc should not be called in TestGenerateClientConfigFile_PeerNotFound")
+			return "", fmt.Errorf("BuildClientConfig should not have been called")
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+
+	r := gin.New()
+	r.POST("/configs/client-file", h.GenerateClientConfigFile)
+
+	reqPayload := domain.ClientFileRequest{
+		ClientPublicKey:  clientPublicKeyNotFound,
+		ClientPrivateKey: clientPrivateKeyAttempt,
+	}
+	body, err := json.Marshal(reqPayload)
 	require.NoError(t, err, "Failed to marshal ClientFileRequest payload")
 
 	w := httptest.NewRecorder()
@@ -839,7 +1906,6 @@ func TestGenerateClientConfigFile_InvalidInput_BadJSON(t *testing.T) {
 	// Например: "invalid request body: invalid character 'n' looking for beginning of object key string"
 }
 
-
 // TestGenerateClientConfigFile_ServiceError tests .conf file generation when the service's BuildClientConfig returns an error.
 func TestGenerateClientConfigFile_ServiceError(t *testing.T) {
 	logger.Logger = zaptest.NewLogger(t)
@@ -956,6 +2022,97 @@ func TestRotatePeer_Success(t *testing.T) {
 	assert.Equal(t, expectedNewConfigAfterRotation.PersistentKeepalive, respConfig.PersistentKeepalive, "PersistentKeepalive mismatch")
 }
 
+// TestBulkRotatePeers_OldPeerLeftBehind tests that a rotation whose old peer could not be deleted
+// still returns the new peer's keys next to the error.
+func TestBulkRotatePeers_OldPeerLeftBehind(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	newCfg := &domain.Config{PublicKey: "liveNewPublicKey", PrivateKey: "liveNewPrivateKey"}
+	mockSvc := &mockService{
+		RotatePeerKeyFunc: func(oldPublicKey string) (*domain.Config, error) {
+			return newCfg, errors.New("new peer created, but failed to delete old peer")
+		},
+	}
+	r := gin.New()
+	r.POST("/configs/bulk-rotate", NewConfigHandler(mockSvc).BulkRotatePeers)
+
+	body, err := json.Marshal(domain.BulkRotateRequest{PublicKeys: []string{"stuckOldPublicKey"}})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs/bulk-rotate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	var resp domain.BulkRotateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 1)
+	assert.NotEmpty(t, resp.Results[0].Error)
+	require.NotNil(t, resp.Results[0].NewConfig, "The live new peer must not be lost")
+	assert.Equal(t, "liveNewPrivateKey", resp.Results[0].NewConfig.PrivateKey)
+}
+
+// TestRotatePeer_WithName tests that an optional name in the request reaches the service.
+func TestRotatePeer_WithName(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	var gotOpts domain.RotateOptions
+	mockSvc := &mockService{
+		RotatePeerKeyWithOptsFunc: func(oldPublicKey string, opts domain.RotateOptions) (*domain.Config, error) {
+			gotOpts = opts
+			return &domain.Config{
+				PublicKey: "renamedNewPubKey",
+				Metadata:  &domain.PeerMetadata{Name: opts.Name},
+			}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.POST("/configs/rotate", h.RotatePeer)
+
+	body, err := json.Marshal(domain.RotatePeerRequest{PublicKey: "renamedOldPubKey", Name: "bob-desktop"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs/rotate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bob-desktop", gotOpts.Name)
+	var resp domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Metadata)
+	assert.Equal(t, "bob-desktop", resp.Metadata.Name)
+}
+
+func TestRotatePeer_ExpectedPublicKeyConflict(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	var gotOpts domain.RotateOptions
+	mockSvc := &mockService{
+		RotatePeerKeyWithOptsFunc: func(oldPublicKey string, opts domain.RotateOptions) (*domain.Config, error) {
+			gotOpts = opts
+			return nil, fmt.Errorf("%w: peer's current key is other", service.ErrRotationConflict)
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.POST("/configs/rotate", h.RotatePeer)
+
+	body, err := json.Marshal(domain.RotatePeerRequest{PublicKey: "casOldPubKey", ExpectedPublicKey: "casStaleKey"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs/rotate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "casStaleKey", gotOpts.ExpectedPublicKey)
+}
+
 // TestRotatePeer_NotFound tests key rotation for a non-existent peer.
 func TestRotatePeer_NotFound(t *testing.T) {
 	logger.Logger = zaptest.NewLogger(t)
@@ -1055,3 +2212,386 @@ func TestRotatePeer_ServiceError(t *testing.T) {
 	//  	assert.Equal(t, "WireGuard operation timed out. The service might be temporarily unavailable or under heavy load.", respError.Error, "Error message mismatch for timeout")
 	// }
 }
+
+// TestVerifyKeyPair_Handler tests the key pair verification endpoint for matching and mismatched pairs.
+func TestVerifyKeyPair_Handler(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	h := NewConfigHandler(&mockService{})
+	r := gin.New()
+	r.POST("/configs/verify-key", h.VerifyKeyPair)
+
+	testCases := []struct {
+		name          string
+		privateKey    string
+		expectMatches bool
+	}{
+		{name: "Matching_pair", privateKey: "matching_priv_key", expectMatches: true},
+		{name: "Mismatched_pair", privateKey: "other_priv_key", expectMatches: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := json.Marshal(domain.VerifyKeyRequest{PublicKey: "matching_pub_key", PrivateKey: tc.privateKey})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/configs/verify-key", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp domain.VerifyKeyResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.expectMatches, resp.Matches)
+		})
+	}
+}
+
+// TestVerifyKeyPair_InvalidPrivateKey tests that a private key rejected by 'wg pubkey' yields 400.
+func TestVerifyKeyPair_InvalidPrivateKey(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := &mockService{
+		VerifyKeyPairFunc: func(publicKey, privateKey string) (bool, error) {
+			return false, service.ErrInvalidPrivateKey
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.POST("/configs/verify-key", h.VerifyKeyPair)
+
+	body, err := json.Marshal(domain.VerifyKeyRequest{PublicKey: "some_pub_key", PrivateKey: "garbage"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/configs/verify-key", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotContains(t, w.Body.String(), "garbage", "Private key must not be echoed back")
+}
+
+// TestExportPeers_Handler tests that the export is streamed as a text attachment
+// and that a failure before anything is written still yields a JSON error.
+func TestExportPeers_Handler(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		h := NewConfigHandler(&mockService{
+			ExportPeersFunc: func(w io.Writer) (domain.ExportStats, error) {
+				_, err := io.WriteString(w, "[Peer]\nPublicKey = exportedKey\n")
+				return domain.ExportStats{Peers: 1}, err
+			},
+		})
+		r := gin.New()
+		r.GET("/configs/export", h.ExportPeers)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/configs/export", nil)
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "peers.conf")
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "[Peer]\nPublicKey = exportedKey\n", w.Body.String())
+	})
+
+	t.Run("Error_before_streaming", func(t *testing.T) {
+		h := NewConfigHandler(&mockService{
+			ExportPeersFunc: func(w io.Writer) (domain.ExportStats, error) {
+				return domain.ExportStats{}, repository.ErrWgTimeout
+			},
+		})
+		r := gin.New()
+		r.GET("/configs/export", h.ExportPeers)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/configs/export", nil)
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		var errResp domain.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+		assert.NotEmpty(t, errResp.Error)
+	})
+}
+
+// TestKeyFingerprint_Handler tests fingerprinting a valid key and rejecting a malformed one.
+func TestKeyFingerprint_Handler(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	h := NewConfigHandler(&mockService{})
+	r := gin.New()
+	r.POST("/keys/fingerprint", h.KeyFingerprint)
+
+	post := func(publicKey string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(domain.KeyFingerprintRequest{PublicKey: publicKey})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/keys/fingerprint", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	validKey := "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg="
+	w := post(validKey)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp domain.KeyFingerprintResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, validKey, resp.PublicKey)
+	assert.Regexp(t, `^[0-9a-f]{4}(:[0-9a-f]{4}){3}$`, resp.Fingerprint)
+
+	w = post("definitely-not-a-key")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestDeleteConfig_Verbose tests that ?verbose=true returns 200 with existence info instead of 204.
+func TestDeleteConfig_Verbose(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	h := NewConfigHandler(&mockService{
+		DeleteFunc: func(publicKey string) error {
+			t.Errorf("strict Delete should not be called in verbose mode")
+			return nil
+		},
+	})
+	r := gin.New()
+	r.POST("/configs/delete", h.DeleteConfig)
+
+	testCases := []struct {
+		name      string
+		publicKey string
+		expected  domain.DeleteConfigResponse
+	}{
+		{name: "Existing_peer", publicKey: "existing_key_for_delete", expected: domain.DeleteConfigResponse{Deleted: true, Existed: true}},
+		{name: "Non_existing_peer", publicKey: "non_existent_key_for_delete", expected: domain.DeleteConfigResponse{Deleted: false, Existed: false}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := json.Marshal(domain.DeleteConfigRequest{PublicKey: tc.publicKey})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/configs/delete?verbose=true", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp domain.DeleteConfigResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.expected, resp)
+		})
+	}
+}
+
+// TestListStale_Handler tests that olderThan is parsed (including day units) and passed to the service.
+func TestListStale_Handler(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	var receivedOlderThan time.Duration
+	mockSvc := &mockService{
+		ListStaleFunc: func(olderThan time.Duration) ([]domain.Config, error) {
+			receivedOlderThan = olderThan
+			return []domain.Config{{PublicKey: "neverConnectedPeer", AllowedIps: []string{"10.0.0.9/32"}}}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.GET("/configs/stale", h.ListStale)
+
+	testCases := []struct {
+		query    string
+		expected time.Duration
+	}{
+		{query: "?olderThan=7d", expected: 7 * 24 * time.Hour},
+		{query: "?olderThan=24h", expected: 24 * time.Hour},
+		{query: "?olderThan=30m", expected: 30 * time.Minute},
+		{query: "?olderThan=1d12h", expected: 36 * time.Hour},
+		{query: "", expected: 7 * 24 * time.Hour}, // Default
+	}
+
+	for _, tc := range testCases {
+		t.Run("olderThan"+tc.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, "/configs/stale"+tc.query, nil)
+			require.NoError(t, err)
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.expected, receivedOlderThan)
+			var configs []domain.Config
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &configs))
+			require.Len(t, configs, 1)
+			assert.Equal(t, "neverConnectedPeer", configs[0].PublicKey)
+		})
+	}
+}
+
+// TestListStale_InvalidDuration tests that an unparsable olderThan yields 400 without calling the service.
+func TestListStale_InvalidDuration(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := &mockService{
+		ListStaleFunc: func(olderThan time.Duration) ([]domain.Config, error) {
+			t.Errorf("service should not be called for an invalid duration")
+			return nil, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.GET("/configs/stale", h.ListStale)
+
+	for _, value := range []string{"seven", "7x", "-5m", "d"} {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/configs/stale?olderThan="+value, nil)
+		require.NoError(t, err)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "olderThan=%s should be rejected", value)
+	}
+}
+
+// TestTopTalkers_Handler tests that by and limit reach the service, with defaults, and that a bad limit yields 400.
+func TestTopTalkers_Handler(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	var receivedBy string
+	var receivedLimit int
+	mockSvc := &mockService{
+		TopTalkersFunc: func(by string, limit int) ([]domain.Config, error) {
+			receivedBy, receivedLimit = by, limit
+			return []domain.Config{{PublicKey: "busyPeer", ReceiveBytes: 4096}}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.GET("/configs/top", h.TopTalkers)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/configs/top"+query, nil)
+		require.NoError(t, err)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "rx", receivedBy)
+	assert.Equal(t, DefaultTopTalkersLimit, receivedLimit)
+	var configs []domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &configs))
+	require.Len(t, configs, 1)
+	assert.Equal(t, "busyPeer", configs[0].PublicKey)
+
+	require.Equal(t, http.StatusOK, get("?by=tx&limit=3").Code)
+	assert.Equal(t, "tx", receivedBy)
+	assert.Equal(t, 3, receivedLimit)
+
+	for _, limit := range []string{"0", "-1", "ten"} {
+		assert.Equal(t, http.StatusBadRequest, get("?limit="+limit).Code, "limit=%s should be rejected", limit)
+	}
+}
+
+// TestApplyDesiredState_Handler tests that the body and the prune flag reach the service and errors map to 422.
+func TestApplyDesiredState_Handler(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	var receivedPrune bool
+	var receivedDesired []domain.DesiredPeer
+	mockSvc := &mockService{
+		ApplyDesiredStateFunc: func(desired []domain.DesiredPeer, prune bool) (*domain.ApplyResult, error) {
+			receivedDesired, receivedPrune = desired, prune
+			if len(desired) == 0 {
+				return nil, fmt.Errorf("%w: test", service.ErrInvalidDesiredState)
+			}
+			return &domain.ApplyResult{Created: []string{desired[0].PublicKey}}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.POST("/configs/apply", h.ApplyDesiredState)
+
+	body := `[{"publicKey":"applyPeer","allowedIps":["10.0.0.5/32"],"keepalive":25}]`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs/apply?prune=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, receivedPrune)
+	require.Len(t, receivedDesired, 1)
+	assert.Equal(t, domain.DesiredPeer{PublicKey: "applyPeer", AllowedIps: []string{"10.0.0.5/32"}, Keepalive: 25}, receivedDesired[0])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/configs/apply", strings.NewReader(`[]`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "Invalid desired state should map to 422")
+	assert.False(t, receivedPrune, "prune should default to false")
+}
+
+// TestCreateConfig_ImportExistingKeys tests that a create request with public_key imports the peer
+// and that a mismatched key pair is rejected with 422.
+func TestCreateConfig_ImportExistingKeys(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := &mockService{
+		CreateWithNewKeysFunc: func(allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error) {
+			t.Errorf("keys must not be generated when importing a peer")
+			return nil, nil
+		},
+		CreateWithExistingKeysFunc: func(publicKey, privateKey string, allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+			if privateKey != "matching_priv_key" {
+				return nil, service.ErrKeyPairMismatch
+			}
+			return &domain.Config{PublicKey: publicKey, PrivateKey: privateKey, AllowedIps: allowedIPs}, nil
+		},
+	}
+	h := NewConfigHandler(mockSvc)
+	r := gin.New()
+	r.POST("/configs", h.CreateConfig)
+
+	send := func(reqBody domain.CreatePeerRequest) *httptest.ResponseRecorder {
+		body, err := json.Marshal(reqBody)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/configs", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(domain.CreatePeerRequest{PublicKey: "migrated_pub_key", PrivateKey: "matching_priv_key", AllowedIps: []string{"10.0.0.7/32"}})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), "The response echoes the private key")
+	var created domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "migrated_pub_key", created.PublicKey)
+	assert.Equal(t, "matching_priv_key", created.PrivateKey)
+
+	w = send(domain.CreatePeerRequest{PublicKey: "migrated_pub_key", PrivateKey: "other_priv_key"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.NotContains(t, w.Body.String(), "other_priv_key", "Private key must not be echoed in errors")
+
+	w = send(domain.CreatePeerRequest{PrivateKey: "matching_priv_key"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "private_key without public_key should be rejected")
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// csvColumns is the header row of GET /configs?format=csv. Preshared keys are deliberately left out.
+var csvColumns = []string{"publicKey", "allowedIps", "endpoint", "latestHandshake", "rx", "tx", "keepalive"}
+
+// respondCSV writes configs as text/csv, one row per peer. AllowedIPs are joined with commas
+// in a single quoted cell.
+func respondCSV(c *gin.Context, configs []domain.Config) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="peers.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(csvColumns)
+	for _, cfg := range configs {
+		_ = w.Write([]string{
+			cfg.PublicKey,
+			strings.Join(cfg.AllowedIps, ","),
+			cfg.Endpoint,
+			strconv.FormatInt(cfg.LatestHandshake, 10),
+			strconv.FormatUint(cfg.ReceiveBytes, 10),
+			strconv.FormatUint(cfg.TransmitBytes, 10),
+			strconv.Itoa(cfg.PersistentKeepalive),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		logger.Logger.Error("Failed to write CSV peer list", zap.Error(err))
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStaleOlderThan is the handshake age used by ListStale when olderThan is not given.
+const DefaultStaleOlderThan = "7d"
+
+// ParseAgeDuration parses a duration such as "7d", "24h", "30m" or "1d12h".
+// It accepts everything time.ParseDuration does plus a leading whole-day component with the "d" unit.
+// Negative durations are rejected.
+func ParseAgeDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("duration is empty")
+	}
+
+	var days time.Duration
+	if i := strings.IndexByte(s, 'd'); i >= 0 {
+		n, err := strconv.Atoi(s[:i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid day count in duration %q", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		s = s[i+1:]
+	}
+
+	var rest time.Duration
+	if s != "" {
+		var err error
+		rest, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %w", err)
+		}
+		if rest < 0 {
+			return 0, errors.New("duration must not be negative")
+		}
+	}
+	return days + rest, nil
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// configFieldNames is the set of JSON field names of domain.Config that ?fields= may select.
+var configFieldNames = jsonFieldNames(reflect.TypeOf(domain.Config{}))
+
+// jsonFieldNames returns the JSON names of the exported fields of struct type t.
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	names := make(map[string]struct{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = struct{}{}
+	}
+	return names
+}
+
+// parseFieldSelection reads the comma-separated ?fields= parameter.
+// It returns nil fields when the parameter is absent, meaning "all fields".
+// On an unknown field name it writes a 400 response and returns ok=false.
+func parseFieldSelection(c *gin.Context) (fields []string, ok bool) {
+	raw := c.Query("fields")
+	if raw == "" {
+		return nil, true
+	}
+	var unknown []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, known := configFieldNames[name]; !known {
+			unknown = append(unknown, name)
+			continue
+		}
+		fields = append(fields, name)
+	}
+	if len(unknown) > 0 {
+		valid := make([]string, 0, len(configFieldNames))
+		for name := range configFieldNames {
+			valid = append(valid, name)
+		}
+		sort.Strings(valid)
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error: "Unknown field(s) in 'fields': " + strings.Join(unknown, ", ") + ". Valid fields: " + strings.Join(valid, ", ") + ".",
+		})
+		return nil, false
+	}
+	return fields, true
+}
+
+// projectFields serializes v and keeps only the given top-level keys of the resulting object,
+// or of each object if v serializes to an array. Fields omitted by omitempty stay omitted.
+func projectFields(v any, fields []string) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	keep := func(obj map[string]json.RawMessage) map[string]json.RawMessage {
+		out := make(map[string]json.RawMessage, len(fields))
+		for _, name := range fields {
+			if value, ok := obj[name]; ok {
+				out[name] = value
+			}
+		}
+		return out
+	}
+
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		var objs []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &objs); err != nil {
+			return nil, err
+		}
+		projected := make([]map[string]json.RawMessage, 0, len(objs))
+		for _, obj := range objs {
+			projected = append(projected, keep(obj))
+		}
+		return projected, nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return keep(obj), nil
+}
+
+// respondWithFields writes v as JSON, projected onto fields if any were selected.
+func respondWithFields(c *gin.Context, status int, v any, fields []string) {
+	if fields == nil {
+		c.JSON(status, v)
+		return
+	}
+	projected, err := projectFields(v, fields)
+	if err != nil {
+		logger.Logger.Error("Failed to project response fields", zap.Strings("fields", fields), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "Failed to build response."})
+		return
+	}
+	c.JSON(status, projected)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultFilenameMaxLength is the maximum length (in characters) of a sanitized filename
+// when FilenameOptions.MaxLength is not set.
+const DefaultFilenameMaxLength = 64
+
+// fallbackFilename is used when sanitizing leaves nothing of the original name.
+const fallbackFilename = "client"
+
+// NonASCIIMode controls how SanitizeFilenameWithOptions treats non-ASCII characters.
+type NonASCIIMode string
+
+const (
+	NonASCIIKeep          NonASCIIMode = "keep"          // Leave non-ASCII characters as they are
+	NonASCIITransliterate NonASCIIMode = "transliterate" // Replace with ASCII look-alikes (Cyrillic, Latin diacritics); replace the rest with '_'
+	NonASCIIDrop          NonASCIIMode = "drop"          // Remove non-ASCII characters
+)
+
+// FilenameOptions configures SanitizeFilenameWithOptions.
+type FilenameOptions struct {
+	MaxLength int          // Maximum length in characters; values <= 0 mean DefaultFilenameMaxLength
+	NonASCII  NonASCIIMode // Treatment of non-ASCII characters; empty means NonASCIIKeep
+}
+
+// DefaultFilenameOptions are the options used by SanitizeFilename.
+var DefaultFilenameOptions = FilenameOptions{MaxLength: DefaultFilenameMaxLength, NonASCII: NonASCIIKeep}
+
+// filenameReplacer replaces characters problematic in filenames and Content-Disposition headers.
+var filenameReplacer = strings.NewReplacer(
+	"/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", "\"", "_", "<", "_", ">", "_", "|", "_", " ", "_",
+)
+
+// cyrillicToLatin is a simple Russian transliteration table used in NonASCIITransliterate mode.
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// SanitizeFilename removes characters problematic in filenames using DefaultFilenameOptions.
+func SanitizeFilename(name string) string {
+	return SanitizeFilenameWithOptions(name, DefaultFilenameOptions)
+}
+
+// SanitizeFilenameWithOptions makes name safe to use as a download filename:
+// problematic characters and control characters become '_', non-ASCII characters are handled per opts.NonASCII,
+// runs of '_' are collapsed, leading dots are stripped (no hidden files) and the result is truncated to opts.MaxLength.
+func SanitizeFilenameWithOptions(name string, opts FilenameOptions) string {
+	maxLength := opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultFilenameMaxLength
+	}
+
+	name = filenameReplacer.Replace(name)
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsControl(r):
+			b.WriteRune('_')
+		case r < unicode.MaxASCII:
+			b.WriteRune(r)
+		case opts.NonASCII == NonASCIIDrop:
+			// skip
+		case opts.NonASCII == NonASCIITransliterate:
+			b.WriteString(transliterateRune(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name = b.String()
+
+	for strings.Contains(name, "__") {
+		name = strings.ReplaceAll(name, "__", "_")
+	}
+	name = strings.TrimLeft(name, ".")
+
+	if runes := []rune(name); len(runes) > maxLength {
+		name = string(runes[:maxLength])
+	}
+	if name == "" || name == "_" {
+		return fallbackFilename
+	}
+	return name
+}
+
+// transliterateRune returns an ASCII replacement for a non-ASCII rune, or "_" if none is known.
+func transliterateRune(r rune) string {
+	lower := unicode.ToLower(r)
+	if latin, ok := cyrillicToLatin[lower]; ok {
+		if lower != r && latin != "" {
+			return strings.ToUpper(latin[:1]) + latin[1:]
+		}
+		return latin
+	}
+	// Strip diacritics: decompose (e.g. 'é' -> 'e' + U+0301) and keep the ASCII base letter.
+	var ascii strings.Builder
+	for _, d := range norm.NFD.String(string(r)) {
+		if d < unicode.MaxASCII {
+			ascii.WriteRune(d)
+		}
+	}
+	if ascii.Len() > 0 {
+		return ascii.String()
+	}
+	return "_"
+}
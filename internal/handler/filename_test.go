@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeFilename_LeadingDots(t *testing.T) {
+	assert.Equal(t, "bashrc", SanitizeFilename(".bashrc"))
+	traversal := SanitizeFilename("../../etc/passwd")
+	assert.NotContains(t, traversal, "/", "Path separators should not survive sanitizing")
+	assert.False(t, strings.HasPrefix(traversal, "."), "Sanitized name should not start with a dot")
+	assert.Equal(t, fallbackFilename, SanitizeFilename("..."), "A name made only of dots should fall back to the default")
+}
+
+func TestSanitizeFilename_RunsOfSpecialChars(t *testing.T) {
+	assert.Equal(t, "a_b_c", SanitizeFilename("a//\\\\::b  ** c"))
+	// Base64 keys contain '/', '+' and '='; only '/' is replaced.
+	assert.Equal(t, "abc_def+ghi=", SanitizeFilename("abc//def+ghi="))
+}
+
+func TestSanitizeFilename_Unicode(t *testing.T) {
+	name := "Пётр café 🚀"
+
+	assert.Equal(t, "Пётр_café_🚀", SanitizeFilenameWithOptions(name, FilenameOptions{NonASCII: NonASCIIKeep}))
+	assert.Equal(t, "Petr_cafe_", SanitizeFilenameWithOptions(name, FilenameOptions{NonASCII: NonASCIITransliterate}))
+	assert.Equal(t, "_caf_", SanitizeFilenameWithOptions(name, FilenameOptions{NonASCII: NonASCIIDrop}))
+}
+
+func TestSanitizeFilename_MaxLength(t *testing.T) {
+	long := strings.Repeat("k", 100)
+
+	assert.Len(t, SanitizeFilename(long), DefaultFilenameMaxLength)
+	assert.Len(t, SanitizeFilenameWithOptions(long, FilenameOptions{MaxLength: 10}), 10)
+	// Truncation counts characters, not bytes, so multi-byte names are not cut mid-rune.
+	assert.Equal(t, "ПётрП", SanitizeFilenameWithOptions(strings.Repeat("Пётр", 5), FilenameOptions{MaxLength: 5}))
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// InterfaceHeader names the WireGuard interface a /configs request applies to, e.g. "wg1".
+// Requests without it use the service passed to NewConfigHandler.
+const InterfaceHeader = "X-WG-Interface"
+
+// interfaceServiceKey is the gin context key under which SelectInterface stores the chosen service.
+const interfaceServiceKey = "wgInterfaceService"
+
+// WithInterfaceServices serves the WireGuard interfaces in services by name: a request whose
+// InterfaceHeader names one of them is handled by its service. The default interface should be
+// included too, so naming it explicitly works. Without this option only the default is served.
+func WithInterfaceServices(services map[string]ServiceInterface) Option {
+	return func(h *ConfigHandler) {
+		h.interfaces = services
+	}
+}
+
+// SelectInterface picks the service for the interface named in InterfaceHeader.
+// An interface that is not served answers 404 before the handler runs.
+func (h *ConfigHandler) SelectInterface() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.GetHeader(InterfaceHeader)
+		if name == "" {
+			c.Next()
+			return
+		}
+		svc, ok := h.interfaces[name]
+		if !ok {
+			logger.Logger.Warn("Request for an unknown WireGuard interface", zap.String("interface", name))
+			c.AbortWithStatusJSON(http.StatusNotFound, domain.ErrorResponse{Error: "Unknown WireGuard interface: " + name})
+			return
+		}
+		c.Set(interfaceServiceKey, svc)
+		c.Next()
+	}
+}
+
+// service returns the service chosen by SelectInterface for c, or the default one.
+func (h *ConfigHandler) service(c *gin.Context) ServiceInterface {
+	if svc, ok := c.Get(interfaceServiceKey); ok {
+		return svc.(ServiceInterface)
+	}
+	return h.svc
+}
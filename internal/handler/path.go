@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// publicKeyParam returns the URL-decoded, format-checked :publicKey path parameter.
+// Base64 keys may contain '/', so clients must send it as %2F and the router must match on the raw path
+// (gin's UseRawPath, without UnescapePathValues) for the key to reach the handler in one piece.
+// Decoding uses path rules, so a literal '+' stays '+'. On a malformed key it responds with 400
+// and returns false, so 404 is reserved for well-formed keys that are not configured.
+func publicKeyParam(c *gin.Context) (string, bool) {
+	key, err := url.PathUnescape(c.Param("publicKey"))
+	key = strings.TrimSpace(key)
+	if err != nil || !domain.IsValidKey(key) {
+		logger.Logger.Warn("Rejected malformed public key in path", zap.String("publicKey", key), zap.String("path", c.FullPath()))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Malformed public key in path: expected a base64-encoded 32-byte WireGuard key with '/' encoded as %2F."})
+		return "", false
+	}
+	return key, true
+}
+
+// GetConfigByKey godoc
+// @Summary      Get configuration by public key (path)
+// @Description  Same as POST /configs/get, with the URL-encoded public key in the path.
+// @Tags         configs
+// @Produce      json
+// @Param        publicKey       path      string                true  "URL-encoded public key of the peer."
+// @Param        fields          query     string                false "Comma-separated JSON field names to include (e.g. publicKey,allowedIps)."
+// @Param        X-WG-Interface  header    string                false "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.Config         "Peer's configuration."
+// @Failure      400             {object}  domain.ErrorResponse  "Malformed public key or unknown field name."
+// @Failure      404             {object}  domain.ErrorResponse  "Peer not found."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/{publicKey} [get]
+func (h *ConfigHandler) GetConfigByKey(c *gin.Context) {
+	key, ok := publicKeyParam(c)
+	if !ok {
+		return
+	}
+	fields, ok := parseFieldSelection(c)
+	if !ok {
+		return
+	}
+	cfg, err := h.service(c).Get(c.Request.Context(), key)
+	if err != nil {
+		h.handleError(c, "GetPeerByPublicKey", key, err)
+		return
+	}
+	respondWithFields(c, http.StatusOK, cfg, fields)
+}
+
+// GetConfigByPeerID godoc
+// @Summary      Get configuration by peer ID
+// @Description  Same as GET /configs/{publicKey}, looked up by the short `peerId` included in peer responses
+// @Description  (16 base32 characters of the SHA-256 of the public key) instead of the base64 key.
+// @Tags         configs
+// @Produce      json
+// @Param        peerId          path      string                true  "Peer ID, e.g. h4zcuwakognvtzdi (case-insensitive)."
+// @Param        fields          query     string                false "Comma-separated JSON field names to include (e.g. publicKey,allowedIps)."
+// @Param        X-WG-Interface  header    string                false "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.Config         "Peer's configuration."
+// @Failure      400             {object}  domain.ErrorResponse  "Malformed peer ID or unknown field name."
+// @Failure      404             {object}  domain.ErrorResponse  "No peer has this ID."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/by-id/{peerId} [get]
+func (h *ConfigHandler) GetConfigByPeerID(c *gin.Context) {
+	fields, ok := parseFieldSelection(c)
+	if !ok {
+		return
+	}
+	peerID := c.Param("peerId")
+	cfg, err := h.service(c).GetByPeerID(c.Request.Context(), peerID)
+	if errors.Is(err, repository.ErrPeerNotFound) {
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: fmt.Sprintf("No peer has ID '%s'.", peerID)})
+		return
+	}
+	if err != nil {
+		h.handleError(c, "GetPeerByPeerID", "", err)
+		return
+	}
+	respondWithFields(c, http.StatusOK, cfg, fields)
+}
+
+// UpdateAllowedIPsByKey godoc
+// @Summary      Update allowed IPs for a peer (path)
+// @Description  Same as POST /configs/update-allowed-ips, with the URL-encoded public key in the path.
+// @Tags         configs
+// @Accept       json
+// @Param        publicKey       path      string                   true  "URL-encoded public key of the peer."
+// @Param        updateRequest   body      domain.AllowedIpsUpdate  true  "New list of allowed IPs for the peer."
+// @Param        confirm         query     bool                     false "Confirm clearing all AllowedIPs with an empty list."
+// @Param        X-WG-Interface  header    string                   false "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  nil                      "Allowed IPs updated successfully (No body content in response)."
+// @Failure      400             {object}  domain.ErrorResponse     "Malformed public key or body, or unconfirmed empty list."
+// @Failure      404             {object}  domain.ErrorResponse     "Peer not found."
+// @Failure      422             {object}  domain.ErrorResponse     "An AllowedIPs entry lies outside ALLOWED_IP_RANGES."
+// @Failure      500             {object}  domain.ErrorResponse     "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse     "Service unavailable (WireGuard timeout)."
+// @Router       /configs/{publicKey}/allowed-ips [put]
+func (h *ConfigHandler) UpdateAllowedIPsByKey(c *gin.Context) {
+	key, ok := publicKeyParam(c)
+	if !ok {
+		return
+	}
+	var req domain.AllowedIpsUpdate
+	if err := bindJSON(c, &req); err != nil {
+		logger.Logger.Error("Invalid JSON input for UpdateAllowedIPsByKey", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+	if !h.emptyAllowedIPsConfirmed(c, req.AllowedIps) {
+		return
+	}
+	if err := h.service(c).UpdateAllowedIPs(c.Request.Context(), key, req.AllowedIps); err != nil {
+		h.handleError(c, "UpdatePeerAllowedIPs", key, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// PatchConfigByKey godoc
+// @Summary      Patch a peer (JSON Patch)
+// @Description  Applies an RFC 6902 JSON Patch to the peer identified by the URL-encoded public key and writes only the result with a single `wg set`.
+// @Description  Patchable paths are `/allowedIps` (also `/allowedIps/<index>`, and `/allowedIps/-` to append), `/presharedKey` and `/persistentKeepalive`;
+// @Description  supported operations are add, remove, replace and test. Removing `/presharedKey` or `/persistentKeepalive` turns it off.
+// @Description  Read-only fields such as `/publicKey`, `/endpoint` or the traffic counters are rejected with 422.
+// @Description  The patch is all-or-nothing. A result without AllowedIPs requires `?confirm=true` unless ALLOW_EMPTY_ALLOWED_IPS_UPDATE=true.
+// @Tags         configs
+// @Accept       application/json-patch+json
+// @Accept       json
+// @Produce      json
+// @Param        publicKey       path      string                   true  "URL-encoded public key of the peer."
+// @Param        patch           body      []domain.PatchOperation  true  "JSON Patch operations, applied in order."
+// @Param        confirm         query     bool                     false "Confirm a patch that removes all AllowedIPs."
+// @Param        X-WG-Interface  header    string                   false "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.Config            "Peer after the patch."
+// @Failure      400             {object}  domain.ErrorResponse     "Malformed key, patch or value, or an unconfirmed empty AllowedIPs list."
+// @Failure      404             {object}  domain.ErrorResponse     "Peer not found."
+// @Failure      409             {object}  domain.ErrorResponse     "A test operation did not match."
+// @Failure      422             {object}  domain.ErrorResponse     "The patch touches a read-only field, or an AllowedIPs entry lies outside ALLOWED_IP_RANGES."
+// @Failure      500             {object}  domain.ErrorResponse     "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse     "Service unavailable (WireGuard timeout)."
+// @Router       /configs/{publicKey} [patch]
+func (h *ConfigHandler) PatchConfigByKey(c *gin.Context) {
+	key, ok := publicKeyParam(c)
+	if !ok {
+		return
+	}
+	var ops []domain.PatchOperation
+	if err := bindJSON(c, &ops); err != nil {
+		logger.Logger.Error("Invalid JSON input for PatchConfigByKey", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: expected a JSON Patch array: " + err.Error()})
+		return
+	}
+	confirmed, _ := strconv.ParseBool(c.Query("confirm"))
+	cfg, err := h.service(c).PatchPeer(c.Request.Context(), key, ops, h.allowEmptyIP || confirmed)
+	if err != nil {
+		h.handleError(c, "PatchPeer", key, err)
+		return
+	}
+	c.JSON(http.StatusOK, cfg)
+}
+
+// GetFullConfigByKey godoc
+// @Summary      Get a peer's configuration and client .conf in one call
+// @Description  Returns the peer identified by the URL-encoded public key together with its generated client .conf,
+// @Description  as POST /configs/client-file would produce it for the supplied private key.
+// @Description  The private key is only inserted into the .conf; it is neither stored nor logged.
+// @Description  The response is sent with `Cache-Control: no-store` unless CLIENT_FILE_CACHE_CONTROL overrides it.
+// @Description  With CONFIG_SIGNING_KEY set, the Ed25519 signature of the .conf is returned in `X-Config-Signature` and `signature`.
+// @Tags         configs
+// @Accept       json
+// @Produce      json
+// @Param        publicKey       path      string                     true   "URL-encoded public key of the peer."
+// @Param        fullRequest     body      domain.FullConfigRequest   true   "Client's private key and an optional keepalive override."
+// @Param        X-WG-Interface  header    string                     false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.FullConfigResponse  "Peer configuration and .conf content."
+// @Failure      400             {object}  domain.ErrorResponse       "Malformed public key, body or private key."
+// @Failure      422             {object}  domain.ErrorResponse       "The private key is well-formed but weak."
+// @Failure      404             {object}  domain.ErrorResponse       "Peer not found."
+// @Failure      500             {object}  domain.ErrorResponse       "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse       "Service unavailable (WireGuard timeout)."
+// @Router       /configs/{publicKey}/full [post]
+func (h *ConfigHandler) GetFullConfigByKey(c *gin.Context) {
+	key, ok := publicKeyParam(c)
+	if !ok {
+		return
+	}
+	var req domain.FullConfigRequest
+	if err := bindJSON(c, &req); err != nil {
+		logger.Logger.Error("Invalid JSON input for GetFullConfigByKey", zap.Error(err))
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+	cfg, err := h.service(c).Get(c.Request.Context(), key)
+	if err != nil {
+		h.handleError(c, "GetFullConfig_GetPeer", key, err)
+		return
+	}
+	conf, err := h.service(c).BuildClientConfigWithOptions(cfg, req.ClientPrivateKey, domain.ClientConfigOptions{
+		PersistentKeepalive: req.PersistentKeepalive,
+	})
+	if err != nil {
+		h.handleError(c, "GetFullConfig_BuildContent", key, err)
+		return
+	}
+	c.Header("Cache-Control", h.fileCache) // The .conf carries the client's private key
+	c.JSON(http.StatusOK, domain.FullConfigResponse{
+		Config:    *cfg,
+		Filename:  SanitizeFilenameWithOptions(key, h.filenameOpts) + ".conf",
+		Conf:      conf,
+		Signature: h.signConfig(c, conf),
+	})
+}
+
+// DeleteConfigByKey godoc
+// @Summary      Delete a peer configuration (path)
+// @Description  Removes the peer identified by the URL-encoded public key in the path.
+// @Description  Unlike POST /configs/delete, a well-formed key that is not configured yields 404.
+// @Description  With `?verbose=true`, responds with 200 and reports whether the peer existed and was deleted.
+// @Tags         configs
+// @Produce      json
+// @Param        publicKey       path      string                      true   "URL-encoded public key of the peer."
+// @Param        verbose         query     bool                        false  "Return {deleted, existed} instead of 204."
+// @Param        X-WG-Interface  header    string                      false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.DeleteConfigResponse "Deletion result (verbose mode only)."
+// @Success      204             {null}    nil                         "Peer deleted successfully (No Content)."
+// @Failure      400             {object}  domain.ErrorResponse        "Malformed public key."
+// @Failure      404             {object}  domain.ErrorResponse        "Peer not found."
+// @Failure      500             {object}  domain.ErrorResponse        "Internal server error."
+// @Failure      503             {object}  domain.ErrorResponse        "Service unavailable (WireGuard timeout)."
+// @Router       /configs/{publicKey} [delete]
+func (h *ConfigHandler) DeleteConfigByKey(c *gin.Context) {
+	key, ok := publicKeyParam(c)
+	if !ok {
+		return
+	}
+	result, err := h.service(c).DeleteVerbose(c.Request.Context(), key)
+	if err != nil {
+		h.handleError(c, "DeletePeerConfig", key, err)
+		return
+	}
+	if !result.Existed {
+		h.handleError(c, "DeletePeerConfig", key, repository.ErrPeerNotFound)
+		return
+	}
+	if verbose, _ := strconv.ParseBool(c.Query("verbose")); verbose {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RotatePeerByKey godoc
+// @Summary      Rotate peer key (path)
+// @Description  Same as POST /configs/rotate, with the URL-encoded public key in the path. An optional `name` query parameter replaces the stored metadata name.
+// @Tags         configs
+// @Produce      json
+// @Param        publicKey       path      string                true   "URL-encoded public key of the peer to rotate."
+// @Param        name            query     string                false  "New metadata name for the rotated peer."
+// @Param        X-WG-Interface  header    string                false  "WireGuard interface, e.g. wg1; defaults to WG_INTERFACE, unknown names answer 404."
+// @Success      200             {object}  domain.Config         "New peer configuration including new PrivateKey."
+// @Failure      400             {object}  domain.ErrorResponse  "Malformed public key."
+// @Failure      404             {object}  domain.ErrorResponse  "Peer not found."
+// @Failure      500             {object}  domain.ErrorResponse  "Internal server error (key rotation fails)."
+// @Failure      503             {object}  domain.ErrorResponse  "Service unavailable (WireGuard timeout)."
+// @Router       /configs/{publicKey}/rotate [post]
+func (h *ConfigHandler) RotatePeerByKey(c *gin.Context) {
+	key, ok := publicKeyParam(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store") // Response carries a new private key
+	newCfg, err := h.service(c).RotatePeerKeyWithOptions(c.Request.Context(), key, domain.RotateOptions{Name: c.Query("name")})
+	if err != nil {
+		h.handleError(c, "RotatePeerKey", key, err)
+		return
+	}
+	logger.Logger.Info("Successfully rotated peer key",
+		zap.String("oldPublicKey", key),
+		zap.String("newPublicKey", newCfg.PublicKey)) // DO NOT log private key
+	c.JSON(http.StatusOK, newCfg)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListenPortChanger changes the interface listen port and returns the endpoint client configs use afterwards.
+type ListenPortChanger interface {
+	SetListenPort(ctx context.Context, port int) (string, error)
+}
+
+// ServerHandler serves information about this server's WireGuard interface.
+type ServerHandler struct {
+	mu         sync.RWMutex // Guards info, which POST /server/listen-port changes
+	info       domain.ServerInfo
+	listenPort ListenPortChanger // nil disables POST /server/listen-port
+
+	sanitizeErrs bool // Hide internal error details from clients, as WithErrorSanitization does
+}
+
+// ServerOption customizes a ServerHandler.
+type ServerOption func(*ServerHandler)
+
+// WithListenPortChanger enables POST /server/listen-port, changing the port through ch.
+func WithListenPortChanger(ch ListenPortChanger) ServerOption {
+	return func(h *ServerHandler) {
+		h.listenPort = ch
+	}
+}
+
+// WithServerErrorSanitization is WithErrorSanitization for the server endpoints.
+func WithServerErrorSanitization(enabled bool) ServerOption {
+	return func(h *ServerHandler) {
+		h.sanitizeErrs = enabled
+	}
+}
+
+// NewServerHandler creates a new ServerHandler reporting the given server information.
+func NewServerHandler(info domain.ServerInfo, opts ...ServerOption) *ServerHandler {
+	h := &ServerHandler{info: info}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// GetServerInfo godoc
+// @Summary      Get server interface information
+// @Description  Returns the instance name and description (SERVER_NAME, SERVER_DESCRIPTION), the server's public key, endpoint, listen port, configured interface addresses
+// @Description  and the result of the startup check comparing them with the live interface addresses.
+// @Tags         server
+// @Produce      json
+// @Success      200  {object}  domain.ServerInfo  "Server interface information."
+// @Router       /server [get]
+func (h *ServerHandler) GetServerInfo(c *gin.Context) {
+	h.mu.RLock()
+	info := h.info
+	h.mu.RUnlock()
+	c.JSON(http.StatusOK, info)
+}
+
+// ListenPort returns the interface listen port, including changes made through POST /server/listen-port.
+func (h *ServerHandler) ListenPort() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.info.ListenPort
+}
+
+// GetPublicKey godoc
+// @Summary      Get server public key
+// @Description  Returns only the server's public key as plain text, without JSON or a trailing newline,
+// @Description  so it can be piped straight into client config scripts.
+// @Tags         server
+// @Produce      plain
+// @Success      200  {string}  string  "Server public key."
+// @Router       /server/publickey [get]
+func (h *ServerHandler) GetPublicKey(c *gin.Context) {
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(h.info.PublicKey))
+}
+
+// SetListenPort godoc
+// @Summary      Change the interface listen port
+// @Description  Runs `wg set <interface> listen-port <port>` and uses the new port in the endpoint of client configs generated from then on.
+// @Description  Existing client configs keep the old endpoint and must be regenerated. The change is lost when the interface is recreated unless WG_PERSIST_CHANGES saves it; SERVER_LISTEN_PORT is not updated.
+// @Description  Requires `Authorization: Bearer <ADMIN_TOKEN>`; the endpoint is not registered when ADMIN_TOKEN is unset.
+// @Tags         server
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        listenPortRequest  body      domain.ListenPortRequest   true  "New listen port."
+// @Success      200                {object}  domain.ListenPortResponse  "Listen port changed."
+// @Failure      400                {object}  domain.ErrorResponse       "Malformed body or missing port."
+// @Failure      401                {object}  domain.ErrorResponse       "Missing or wrong admin token."
+// @Failure      422                {object}  domain.ErrorResponse       "Port outside 1-65535."
+// @Failure      500                {object}  domain.ErrorResponse       "The interface rejected the change."
+// @Failure      503                {object}  domain.ErrorResponse       "Service unavailable (WireGuard timeout), or changing the port is not available."
+// @Router       /server/listen-port [post]
+func (h *ServerHandler) SetListenPort(c *gin.Context) {
+	if h.listenPort == nil {
+		c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "Changing the listen port is not available."})
+		return
+	}
+	var req domain.ListenPortRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	endpoint, err := h.listenPort.SetListenPort(c.Request.Context(), req.Port)
+	if err != nil {
+		switch {
+		case domain.CategoryOf(err) == domain.CategoryMalformed:
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		case domain.CategoryOf(err) == domain.CategoryInvalid:
+			c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, repository.ErrWgTimeout):
+			c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "WireGuard operation timed out."})
+		default:
+			c.JSON(http.StatusInternalServerError, internalErrorResponse("Failed to change listen port", err, h.sanitizeErrs))
+		}
+		return
+	}
+
+	h.mu.Lock()
+	h.info.ListenPort = req.Port
+	h.info.Endpoint = endpoint
+	h.mu.Unlock()
+	logger.Logger.Warn("Interface listen port changed", zap.Int("listenPort", req.Port), zap.String("clientIP", c.ClientIP()))
+	c.JSON(http.StatusOK, domain.ListenPortResponse{ListenPort: req.Port, Endpoint: endpoint})
+}
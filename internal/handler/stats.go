@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+
+	"wgMicro_api/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsSource provides cached interface statistics and the health of whatever collects them.
+type StatsSource interface {
+	Stats() (domain.InterfaceStats, bool)
+	Status() domain.CollectorStatus
+}
+
+// StatsHandler serves cached interface statistics and the stats collector status.
+type StatsHandler struct {
+	source      StatsSource
+	hideTraffic bool // Answer /stats with 403 instead of exposing traffic totals
+}
+
+// StatsOption customizes a StatsHandler.
+type StatsOption func(*StatsHandler)
+
+// WithTrafficExposed controls whether GET /stats serves aggregate traffic; when false it answers 403.
+func WithTrafficExposed(expose bool) StatsOption {
+	return func(h *StatsHandler) {
+		h.hideTraffic = !expose
+	}
+}
+
+// NewStatsHandler creates a new StatsHandler reading from source.
+func NewStatsHandler(source StatsSource, opts ...StatsOption) *StatsHandler {
+	h := &StatsHandler{source: source}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// GetStats godoc
+// @Summary      Get aggregate interface statistics
+// @Description  Returns the peer count and total traffic from the latest background collection.
+// @Description  The data may be up to one collection interval old; see /server/collector for collector health.
+// @Description  rxDelta/txDelta (total and per peer) are the bytes moved between the previous collection (deltaSince) and this one.
+// @Tags         server
+// @Produce      json
+// @Success      200  {object}  domain.InterfaceStats  "Latest collected statistics."
+// @Failure      403  {object}  domain.ErrorResponse   "Traffic statistics are disabled (EXPOSE_TRAFFIC_STATS=false)."
+// @Failure      503  {object}  domain.ErrorResponse   "No successful collection yet."
+// @Router       /stats [get]
+func (h *StatsHandler) GetStats(c *gin.Context) {
+	if h.hideTraffic {
+		c.JSON(http.StatusForbidden, domain.ErrorResponse{Error: "Traffic statistics are disabled on this server."})
+		return
+	}
+	stats, ok := h.source.Stats()
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "Statistics have not been collected yet."})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetCollectorStatus godoc
+// @Summary      Get stats collector status
+// @Description  Reports the background stats collector's last success time, last error and consecutive failures,
+// @Description  so operators can tell whether /stats is stale because collection keeps failing.
+// @Tags         server
+// @Produce      json
+// @Success      200  {object}  domain.CollectorStatus  "Collector status."
+// @Router       /server/collector [get]
+func (h *StatsHandler) GetCollectorStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.source.Status())
+}
@@ -57,7 +57,11 @@ func Init(isDevelopment bool) {
 		log.Panicf("failed to initialize zap logger: %v", err)
 	}
 
-	Logger = logger
+	// Mirror entries into Ring as JSON lines for GET /admin/logs/stream.
+	ringCore := Ring.Core(zapcore.NewJSONEncoder(encoderCfg), cfg.Level)
+	Logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, ringCore)
+	}))
 	Logger.Info("Logger initialized",
 		zap.Bool("developmentMode", isDevelopment),
 		zap.String("logLevel", cfg.Level.Level().String()),
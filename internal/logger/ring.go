@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultRingSize is the number of recent log lines kept by Ring.
+const DefaultRingSize = 500
+
+// subscriberBuffer is how many lines a slow subscriber may lag behind before lines are dropped for it.
+const subscriberBuffer = 64
+
+// Ring holds the most recent log lines written by Logger once Init has run.
+var Ring = NewRingBuffer(DefaultRingSize)
+
+// RingBuffer is an in-memory zapcore.WriteSyncer keeping the last N encoded log lines
+// and fanning new lines out to subscribers. Writing never blocks on a subscriber.
+type RingBuffer struct {
+	mu          sync.Mutex
+	lines       []string
+	next        int // Index the next line is written to once the buffer is full
+	size        int
+	subscribers map[chan string]struct{}
+}
+
+// NewRingBuffer creates a RingBuffer holding up to size lines.
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		size = DefaultRingSize
+	}
+	return &RingBuffer{size: size, subscribers: make(map[chan string]struct{})}
+}
+
+// Core returns a zapcore.Core encoding entries at or above level into the buffer.
+func (b *RingBuffer) Core(enc zapcore.Encoder, level zapcore.LevelEnabler) zapcore.Core {
+	return zapcore.NewCore(enc, zapcore.AddSync(b), level)
+}
+
+// Write stores one encoded entry; zap calls it once per entry.
+func (b *RingBuffer) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.lines) < b.size {
+		b.lines = append(b.lines, line)
+	} else {
+		b.lines[b.next] = line
+		b.next = (b.next + 1) % b.size
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- line:
+		default: // Subscriber is too slow; drop the line for it rather than block logging
+		}
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer; the buffer has nothing to flush.
+func (b *RingBuffer) Sync() error { return nil }
+
+// Recent returns the buffered lines, oldest first.
+func (b *RingBuffer) Recent() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.recentLocked()
+}
+
+func (b *RingBuffer) recentLocked() []string {
+	out := make([]string, 0, len(b.lines))
+	out = append(out, b.lines[b.next:]...)
+	return append(out, b.lines[:b.next]...)
+}
+
+// Subscribe returns the buffered lines and a channel receiving every line written afterwards,
+// with no gap or overlap between the two. cancel must be called to release the subscription.
+func (b *RingBuffer) Subscribe() (recent []string, lines <-chan string, cancel func()) {
+	ch := make(chan string, subscriberBuffer)
+
+	b.mu.Lock()
+	recent = b.recentLocked()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+		})
+	}
+	return recent, ch, cancel
+}
@@ -0,0 +1,48 @@
+// internal/metrics/metrics.go
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// RotationsTotal counts every key rotation attempt, successful or not.
+	RotationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wg_rotations_total",
+		Help: "Total number of peer key rotation attempts.",
+	})
+
+	// RotationFailuresTotal counts key rotations that returned an error.
+	RotationFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wg_rotation_failures_total",
+		Help: "Total number of peer key rotations that failed.",
+	})
+
+	// RotationOldPeerDeleteFailuresTotal counts rotations where the new peer was created
+	// but the old one could not be removed, leaving both on the interface.
+	RotationOldPeerDeleteFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wg_rotation_old_peer_delete_failures_total",
+		Help: "Total number of key rotations where the old peer could not be deleted after the new one was created.",
+	})
+
+	// RequestsTotal counts handled HTTP requests by method, route pattern and status class ("2xx", "4xx", ...).
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wg_http_requests_total",
+		Help: "Total number of HTTP requests by method, route and status class.",
+	}, []string{"method", "route", "status_class"})
+
+	// RequestDuration observes how long HTTP requests take, by method and route pattern.
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wg_http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Handler serves the default Prometheus registry in the text exposition format.
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// PeerLister lists the peers whose count and traffic totals are exported as gauges.
+type PeerLister interface {
+	ListConfigs(ctx context.Context) ([]domain.Config, error)
+}
+
+// PeerGauges exports the peer count and traffic totals, reusing the last listing for ttl
+// so frequent scrapes do not run 'wg show dump' every time.
+type PeerGauges struct {
+	lister  PeerLister
+	ttl     time.Duration
+	traffic bool // Export the traffic totals; false with EXPOSE_TRAFFIC_STATS=false
+	now     func() time.Time
+
+	peersDesc, rxDesc, txDesc *prometheus.Desc
+
+	mu        sync.Mutex
+	fetchedAt time.Time
+	fetched   bool
+	err       error
+	peers     int
+	rx, tx    uint64
+}
+
+func newPeerGauges(lister PeerLister, ttl time.Duration, traffic bool) *PeerGauges {
+	return &PeerGauges{
+		lister:    lister,
+		ttl:       ttl,
+		traffic:   traffic,
+		now:       time.Now,
+		peersDesc: prometheus.NewDesc("wg_peers", "Number of peers on the WireGuard interface.", nil, nil),
+		rxDesc:    prometheus.NewDesc("wg_peers_receive_bytes", "Bytes received from all peers, as reported by 'wg'.", nil, nil),
+		txDesc:    prometheus.NewDesc("wg_peers_transmit_bytes", "Bytes transmitted to all peers, as reported by 'wg'.", nil, nil),
+	}
+}
+
+// RegisterPeerGauges registers the wg_peers, wg_peers_receive_bytes and wg_peers_transmit_bytes gauges
+// with the default Prometheus registry. A scrape lists peers at most once per ttl; 0 lists on every scrape.
+// With traffic false only wg_peers is exported, so hidden traffic statistics do not leak through /metrics.
+func RegisterPeerGauges(lister PeerLister, ttl time.Duration, traffic bool) (*PeerGauges, error) {
+	gauges := newPeerGauges(lister, ttl, traffic)
+	if err := prometheus.Register(gauges); err != nil {
+		return nil, err
+	}
+	return gauges, nil
+}
+
+// Reset drops the cached listing, so the next scrape lists the peers again.
+func (c *PeerGauges) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetched = false
+}
+
+// Describe implements prometheus.Collector.
+func (c *PeerGauges) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.peersDesc
+	if c.traffic {
+		ch <- c.rxDesc
+		ch <- c.txDesc
+	}
+}
+
+// Collect implements prometheus.Collector. While the peers cannot be listed the gauges are left out,
+// so a broken interface shows up as missing series rather than as zero peers.
+func (c *PeerGauges) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.fetched || c.now().Sub(c.fetchedAt) >= c.ttl {
+		c.refresh()
+	}
+	if c.err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.peersDesc, prometheus.GaugeValue, float64(c.peers))
+	if c.traffic {
+		ch <- prometheus.MustNewConstMetric(c.rxDesc, prometheus.GaugeValue, float64(c.rx))
+		ch <- prometheus.MustNewConstMetric(c.txDesc, prometheus.GaugeValue, float64(c.tx))
+	}
+}
+
+func (c *PeerGauges) refresh() {
+	c.fetchedAt = c.now()
+	c.fetched = true
+	peers, err := c.lister.ListConfigs(context.Background())
+	if c.err = err; err != nil {
+		logger.Logger.Warn("Failed to list peers for Prometheus gauges", zap.Error(err))
+		return
+	}
+	c.peers, c.rx, c.tx = len(peers), 0, 0
+	for _, peer := range peers {
+		c.rx += peer.ReceiveBytes
+		c.tx += peer.TransmitBytes
+	}
+}
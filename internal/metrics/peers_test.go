@@ -0,0 +1,88 @@
+// internal/metrics/peers_test.go
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// countingLister returns fixed peers, or err, and counts calls.
+type countingLister struct {
+	peers []domain.Config
+	err   error
+	calls int
+}
+
+func (l *countingLister) ListConfigs(ctx context.Context) ([]domain.Config, error) {
+	l.calls++
+	return l.peers, l.err
+}
+
+func scrape(t *testing.T, reg *prometheus.Registry) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestPeerGauges_CachesListing(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	lister := &countingLister{peers: []domain.Config{
+		{PublicKey: "a", ReceiveBytes: 100, TransmitBytes: 10},
+		{PublicKey: "b", ReceiveBytes: 23, TransmitBytes: 5},
+	}}
+	collector := newPeerGauges(lister, 5*time.Second, true)
+	now := time.Unix(1700000000, 0)
+	collector.now = func() time.Time { return now }
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(collector))
+
+	body := scrape(t, reg)
+	assert.Contains(t, body, "wg_peers 2\n")
+	assert.Contains(t, body, "wg_peers_receive_bytes 123\n")
+	assert.Contains(t, body, "wg_peers_transmit_bytes 15\n")
+
+	lister.peers = lister.peers[:1]
+	now = now.Add(time.Second)
+	assert.Contains(t, scrape(t, reg), "wg_peers 2\n", "Scrapes within the cache window reuse the listing")
+	assert.Equal(t, 1, lister.calls)
+
+	now = now.Add(5 * time.Second)
+	assert.Contains(t, scrape(t, reg), "wg_peers 1\n")
+	assert.Equal(t, 2, lister.calls)
+
+	lister.peers = nil
+	collector.Reset()
+	assert.Contains(t, scrape(t, reg), "wg_peers 0\n", "A reset listing is fetched again within the cache window")
+	assert.Equal(t, 3, lister.calls)
+
+	lister.err = errors.New("wg unavailable")
+	now = now.Add(5 * time.Second)
+	assert.NotContains(t, scrape(t, reg), "wg_peers", "Gauges are left out while peers cannot be listed")
+}
+
+func TestPeerGauges_HiddenTraffic(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	lister := &countingLister{peers: []domain.Config{{PublicKey: "a", ReceiveBytes: 100, TransmitBytes: 10}}}
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(newPeerGauges(lister, 0, false)))
+
+	body := scrape(t, reg)
+	assert.Contains(t, body, "wg_peers 1\n")
+	assert.NotContains(t, body, "wg_peers_receive_bytes", "EXPOSE_TRAFFIC_STATS=false hides the traffic totals")
+	assert.NotContains(t, body, "wg_peers_transmit_bytes")
+}
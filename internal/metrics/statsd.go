@@ -0,0 +1,108 @@
+// internal/metrics/statsd.go
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"wgMicro_api/internal/domain"
+)
+
+// StatsDPrefix is prepended to every metric name sent to StatsD.
+const StatsDPrefix = "wgmicro."
+
+// StatsSource provides the cached interface statistics pushed as gauges.
+type StatsSource interface {
+	Stats() (domain.InterfaceStats, bool)
+}
+
+// StatsD pushes gauges and timers to a StatsD server over UDP, for environments without Prometheus.
+// Sends are fire-and-forget: a missing or slow server never delays the API.
+type StatsD struct {
+	conn net.Conn
+}
+
+// NewStatsD connects to the StatsD server at addr (host:port).
+func NewStatsD(addr string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD at %s: %w", addr, err)
+	}
+	return &StatsD{conn: conn}, nil
+}
+
+// Close closes the connection to the StatsD server.
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// Gauge sends name as a gauge with value.
+func (s *StatsD) Gauge(name string, value uint64) {
+	s.send(fmt.Sprintf("%s%s:%d|g", StatsDPrefix, name, value))
+}
+
+// Timing sends name as a timer of d, in milliseconds.
+func (s *StatsD) Timing(name string, d time.Duration) {
+	s.send(fmt.Sprintf("%s%s:%d|ms", StatsDPrefix, name, d.Milliseconds()))
+}
+
+func (s *StatsD) send(line string) {
+	_, _ = s.conn.Write([]byte(line)) // UDP: a lost packet is a lost sample, not an error
+}
+
+// Run pushes the peer count and, if traffic is true, the traffic totals of source every interval
+// until ctx is done. Nothing is sent until source has a snapshot.
+func (s *StatsD) Run(ctx context.Context, interval time.Duration, source StatsSource, traffic bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if stats, ok := source.Stats(); ok {
+			s.Gauge("peers", uint64(stats.PeerCount))
+			if traffic {
+				s.Gauge("rx_bytes", stats.ReceiveBytes)
+				s.Gauge("tx_bytes", stats.TransmitBytes)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Middleware times every request and sends it as requests.<method>.<route>, e.g. requests.post.configs_get.
+// Unmatched routes are reported as requests.<method>.unmatched.
+func (s *StatsD) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		s.Timing("requests."+strings.ToLower(c.Request.Method)+"."+statsDRouteName(c.FullPath()), time.Since(start))
+	}
+}
+
+// statsDRouteName turns a gin route pattern into a StatsD name segment: "/configs/:publicKey/rotate"
+// becomes "configs_publicKey_rotate". Characters StatsD treats specially are dropped.
+func statsDRouteName(route string) string {
+	if route == "" {
+		return "unmatched"
+	}
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '.', '-':
+			return '_'
+		case ':', '*', '|', '@':
+			return -1
+		}
+		return r
+	}, strings.Trim(route, "/"))
+	if name == "" {
+		return "root"
+	}
+	return name
+}
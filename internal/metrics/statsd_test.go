@@ -0,0 +1,96 @@
+// internal/metrics/statsd_test.go
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+)
+
+// listenStatsD starts a UDP listener standing in for a StatsD server and returns its address
+// and a function reading the next n lines.
+func listenStatsD(t *testing.T) (string, func(n int) []string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	read := func(n int) []string {
+		t.Helper()
+		var lines []string
+		buf := make([]byte, 1024)
+		for len(lines) < n {
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+			size, _, err := conn.ReadFrom(buf)
+			require.NoError(t, err, "Expected %d StatsD lines, got %v", n, lines)
+			lines = append(lines, string(buf[:size]))
+		}
+		return lines
+	}
+	return conn.LocalAddr().String(), read
+}
+
+type fixedStats struct {
+	stats domain.InterfaceStats
+	ok    bool
+}
+
+func (f fixedStats) Stats() (domain.InterfaceStats, bool) { return f.stats, f.ok }
+
+func TestStatsD_PushesGauges(t *testing.T) {
+	addr, read := listenStatsD(t)
+	s, err := NewStatsD(addr)
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx, time.Hour, fixedStats{stats: domain.InterfaceStats{PeerCount: 3, ReceiveBytes: 1024, TransmitBytes: 2048}, ok: true}, true)
+
+	assert.Equal(t, []string{"wgmicro.peers:3|g", "wgmicro.rx_bytes:1024|g", "wgmicro.tx_bytes:2048|g"}, read(3))
+}
+
+func TestStatsD_HiddenTraffic(t *testing.T) {
+	addr, read := listenStatsD(t)
+	s, err := NewStatsD(addr)
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx, 10*time.Millisecond, fixedStats{stats: domain.InterfaceStats{PeerCount: 3, ReceiveBytes: 1024, TransmitBytes: 2048}, ok: true}, false)
+
+	assert.Equal(t, []string{"wgmicro.peers:3|g", "wgmicro.peers:3|g"}, read(2), "Only the peer count is pushed while traffic is hidden")
+}
+
+func TestStatsD_RequestTimers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	addr, read := listenStatsD(t)
+	s, err := NewStatsD(addr)
+	require.NoError(t, err)
+	defer s.Close()
+
+	r := gin.New()
+	r.Use(s.Middleware())
+	r.POST("/configs/:publicKey/rotate", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/configs/somePeer/rotate", "/nowhere"} {
+		req, _ := http.NewRequest(http.MethodPost, path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	lines := read(2)
+	assert.True(t, strings.HasPrefix(lines[0], "wgmicro.requests.post.configs_publicKey_rotate:"), lines[0])
+	assert.True(t, strings.HasSuffix(lines[0], "|ms"), lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "wgmicro.requests.post.unmatched:"), "Unmatched paths must not create one metric each: %s", lines[1])
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// WithWriteBatchWindow makes CreateConfig and UpdateAllowedIPs wait up to window for other writes
+// and apply all of them with a single 'wg syncconf' instead of one 'wg set' each.
+// Every call still returns the result of its own change. Deletes, and writes whose context is
+// marked with ImmediateWrite, bypass the batch. A non-positive window disables batching.
+//
+// While batching is enabled the repository assumes it is the only writer of the interface:
+// the batch rewrites the whole peer list, so a peer added behind its back between reading the
+// interface and 'wg syncconf' would be removed again.
+func WithWriteBatchWindow(window time.Duration) Option {
+	return func(r *WGRepository) {
+		r.batchWindow = window
+	}
+}
+
+type immediateWriteKey struct{}
+
+// ImmediateWrite returns a context whose writes skip the batching window of WithWriteBatchWindow,
+// for changes that must be visible on the interface as soon as the call returns, such as key rotation.
+func ImmediateWrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, immediateWriteKey{}, true)
+}
+
+// batchedWrite is one CreateConfig or UpdateAllowedIPs call waiting for the next flush.
+type batchedWrite struct {
+	cfg         domain.Config // Peer to create; only PublicKey and AllowedIps are used for an update
+	allowedOnly bool          // Replace only the allowed IPs, like 'wg set peer <key> allowed-ips'
+	done        chan error    // Receives the outcome of this write
+}
+
+// batching reports whether a write with ctx should join the batch.
+func (r *WGRepository) batching(ctx context.Context) bool {
+	immediate, _ := ctx.Value(immediateWriteKey{}).(bool)
+	return r.batchWindow > 0 && !immediate
+}
+
+// lockWrites serializes writes that bypass the batch with batch flushes and with each other, so a flush
+// never drops a peer created while it was running and UpdatePeer's read-modify-write sees no other write.
+// It locks even without batching, so unbatched writes also run one at a time.
+func (r *WGRepository) lockWrites() func() {
+	r.writeMu.Lock()
+	return r.writeMu.Unlock
+}
+
+// enqueueWrite adds w to the pending batch, starting the window if it is the first write,
+// and waits for its result. The wait ignores cancellation of ctx: once queued, the write is
+// applied with the next flush, and the caller is told whether it took effect.
+func (r *WGRepository) enqueueWrite(w *batchedWrite) error {
+	w.done = make(chan error, 1)
+	r.batchMu.Lock()
+	r.batchPending = append(r.batchPending, w)
+	if len(r.batchPending) == 1 {
+		time.AfterFunc(r.batchWindow, r.flushWrites)
+	}
+	r.batchMu.Unlock()
+	return <-w.done
+}
+
+// flushWrites applies every pending write with one 'wg syncconf' and reports each write's outcome.
+// A write that is invalid on its own fails alone; a failure of the interface fails the whole batch.
+func (r *WGRepository) flushWrites() {
+	r.batchMu.Lock()
+	writes := r.batchPending
+	r.batchPending = nil
+	r.batchMu.Unlock()
+
+	unlock := r.lockWrites()
+	defer unlock()
+
+	ctx := context.Background() // The callers are waiting regardless of their contexts; see enqueueWrite
+	valid := writes[:0:0]
+	for _, w := range writes {
+		if err := validateBatchedWrite(w.cfg); err != nil {
+			w.done <- fmt.Errorf("peer %s: %w", w.cfg.PublicKey, err)
+			continue
+		}
+		valid = append(valid, w)
+	}
+	if len(valid) == 0 {
+		return
+	}
+
+	err := r.syncWrites(ctx, valid)
+	for _, w := range valid {
+		w.done <- err
+	}
+	if err != nil {
+		logger.Logger.Error("Batched peer changes could not be applied",
+			zap.Int("writes", len(valid)), zap.Error(err), zap.String("interface", r.iface))
+		return
+	}
+	logger.Logger.Info("Applied batched peer changes with 'wg syncconf'",
+		zap.Int("writes", len(valid)), zap.String("interface", r.iface))
+	r.saveConfig(ctx)
+}
+
+// syncWrites reads the interface, applies writes in arrival order and passes the result to 'wg syncconf'.
+func (r *WGRepository) syncWrites(ctx context.Context, writes []*batchedWrite) error {
+	lines, err := r.dumpLines(ctx)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("interface %s: %w", r.iface, ErrInterfaceDown)
+	}
+	info, err := r.parseInterfaceLine(lines[0])
+	if err != nil {
+		return err
+	}
+
+	var order []string
+	peers := make(map[string]domain.Config)
+	put := func(cfg domain.Config) {
+		if _, ok := peers[cfg.PublicKey]; !ok {
+			order = append(order, cfg.PublicKey)
+		}
+		peers[cfg.PublicKey] = cfg
+	}
+	_ = r.iterPeerLines(lines[1:], func(cfg domain.Config) error {
+		put(cfg)
+		return nil
+	})
+	for _, w := range writes {
+		// Mirror 'wg set': unspecified settings of an existing peer are kept.
+		peer := peers[w.cfg.PublicKey]
+		peer.PublicKey = w.cfg.PublicKey
+		peer.AllowedIps = w.cfg.AllowedIps
+		if !w.allowedOnly {
+			if w.cfg.PreSharedKey != "" {
+				peer.PreSharedKey = w.cfg.PreSharedKey
+			}
+			if w.cfg.PersistentKeepalive > 0 {
+				peer.PersistentKeepalive = w.cfg.PersistentKeepalive
+			}
+		}
+		put(peer)
+	}
+
+	var conf strings.Builder
+	conf.WriteString("[Interface]\n")
+	fmt.Fprintf(&conf, "PrivateKey = %s\n", info.PrivateKey)
+	fmt.Fprintf(&conf, "ListenPort = %d\n", info.ListenPort)
+	if info.FwMark != 0 {
+		fmt.Fprintf(&conf, "FwMark = 0x%x\n", info.FwMark)
+	}
+	for _, key := range order {
+		peer := peers[key]
+		fmt.Fprintf(&conf, "\n[Peer]\nPublicKey = %s\n", peer.PublicKey)
+		if peer.PreSharedKey != "" {
+			fmt.Fprintf(&conf, "PresharedKey = %s\n", peer.PreSharedKey)
+		}
+		if peer.Endpoint != "" {
+			fmt.Fprintf(&conf, "Endpoint = %s\n", peer.Endpoint)
+		}
+		if len(peer.AllowedIps) > 0 {
+			fmt.Fprintf(&conf, "AllowedIPs = %s\n", strings.Join(peer.AllowedIps, ", "))
+		}
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(&conf, "PersistentKeepalive = %d\n", peer.PersistentKeepalive)
+		}
+	}
+
+	if _, err := r.runWgCommandInput(ctx, conf.String(), "syncconf", r.iface, "/dev/stdin"); err != nil {
+		return fmt.Errorf("failed to apply batched peer changes on interface %s: %w", r.iface, err)
+	}
+	return nil
+}
+
+// validateBatchedWrite rejects a write that 'wg syncconf' would refuse, so it cannot fail the rest of its batch.
+func validateBatchedWrite(cfg domain.Config) error {
+	if !domain.IsValidKey(cfg.PublicKey) {
+		return errors.New("invalid public key")
+	}
+	if cfg.PreSharedKey != "" && !domain.IsValidKey(cfg.PreSharedKey) {
+		return errors.New("invalid preshared key")
+	}
+	for _, ip := range cfg.AllowedIps {
+		if _, _, err := net.ParseCIDR(ip); err != nil {
+			return fmt.Errorf("invalid allowed IP %q: %w", ip, err)
+		}
+	}
+	return nil
+}
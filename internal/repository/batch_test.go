@@ -0,0 +1,133 @@
+// internal/repository/batch_test.go
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// syncRunner answers 'wg show dump' with one existing peer and records 'wg set' and 'wg syncconf' calls.
+type syncRunner struct {
+	mu        sync.Mutex
+	syncconfs []string // stdin of every 'wg syncconf'
+	sets      int
+	failSync  bool
+}
+
+func (s *syncRunner) Run(ctx context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch args[0] {
+	case "syncconf":
+		s.syncconfs = append(s.syncconfs, stdin)
+		if s.failSync {
+			return nil, []byte("Unable to modify interface: Operation not permitted"), errors.New("exit status 1")
+		}
+	case "set":
+		s.sets++
+	case "show":
+		return []byte("serverPriv\tserverPub\t51820\t0xca6c\n" +
+			testKey(0) + "\t(none)\t192.0.2.1:51820\t10.0.0.1/32\t0\t0\t0\t25\n"), nil, nil
+	}
+	return nil, nil, nil
+}
+
+// testKey returns a distinct valid WireGuard key.
+func testKey(i int) string {
+	raw := make([]byte, domain.KeyLen)
+	raw[0] = byte(i + 1)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestWriteBatch_ConcurrentWritesShareOneSyncconf(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	runner := &syncRunner{}
+	repo := NewWGRepository("wg_batch_test", time.Second, WithCommandRunner(runner), WithWriteBatchWindow(50*time.Millisecond))
+
+	const writes = 5
+	errs := make([]error, writes+1)
+	var wg sync.WaitGroup
+	for i := 1; i <= writes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.CreateConfig(context.Background(), domain.Config{PublicKey: testKey(i), AllowedIps: []string{fmt.Sprintf("10.0.0.%d/32", i+1)}})
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[0] = repo.UpdateAllowedIPs(context.Background(), testKey(0), []string{"10.0.1.0/24"})
+	}()
+	wg.Wait()
+
+	for i, err := range errs {
+		assert.NoError(t, err, "write %d", i)
+	}
+	require.Len(t, runner.syncconfs, 1, "Concurrent writes should be applied with a single syncconf")
+	assert.Zero(t, runner.sets)
+
+	conf := runner.syncconfs[0]
+	assert.Contains(t, conf, "[Interface]\nPrivateKey = serverPriv\nListenPort = 51820\nFwMark = 0xca6c\n")
+	assert.Contains(t, conf, "PublicKey = "+testKey(0)+"\nEndpoint = 192.0.2.1:51820\nAllowedIPs = 10.0.1.0/24\nPersistentKeepalive = 25\n",
+		"An update should keep the peer's other settings")
+	for i := 1; i <= writes; i++ {
+		assert.Contains(t, conf, "PublicKey = "+testKey(i)+"\nAllowedIPs = "+fmt.Sprintf("10.0.0.%d/32", i+1)+"\n")
+	}
+}
+
+func TestWriteBatch_PerWriteResults(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+
+	t.Run("Invalid_write_fails_alone", func(t *testing.T) {
+		runner := &syncRunner{}
+		repo := NewWGRepository("wg_batch_test", time.Second, WithCommandRunner(runner), WithWriteBatchWindow(50*time.Millisecond))
+
+		var good, bad error
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			good = repo.UpdateAllowedIPs(context.Background(), testKey(0), []string{"10.0.1.0/24"})
+		}()
+		go func() {
+			defer wg.Done()
+			bad = repo.UpdateAllowedIPs(context.Background(), testKey(1), []string{"not-a-cidr"})
+		}()
+		wg.Wait()
+
+		assert.NoError(t, good)
+		assert.ErrorContains(t, bad, "invalid allowed IP")
+		require.Len(t, runner.syncconfs, 1)
+		assert.NotContains(t, runner.syncconfs[0], testKey(1))
+	})
+
+	t.Run("Syncconf_failure_fails_every_write", func(t *testing.T) {
+		repo := NewWGRepository("wg_batch_test", time.Second, WithCommandRunner(&syncRunner{failSync: true}), WithWriteBatchWindow(10*time.Millisecond))
+		err := repo.CreateConfig(context.Background(), domain.Config{PublicKey: testKey(2)})
+		assert.ErrorContains(t, err, "failed to apply batched peer changes")
+	})
+}
+
+func TestWriteBatch_DeletesAndImmediateWritesBypassBatch(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	runner := &syncRunner{}
+	repo := NewWGRepository("wg_batch_test", time.Second, WithCommandRunner(runner), WithWriteBatchWindow(time.Hour))
+
+	require.NoError(t, repo.DeleteConfig(context.Background(), testKey(0)))
+	require.NoError(t, repo.CreateConfig(ImmediateWrite(context.Background()), domain.Config{PublicKey: testKey(1), AllowedIps: []string{"10.0.0.2/32"}}))
+	assert.Equal(t, 2, runner.sets)
+	assert.Empty(t, runner.syncconfs)
+}
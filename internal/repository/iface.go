@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// AddressLister is implemented by repositories that can report the addresses
+// currently assigned to the WireGuard interface. It is optional: callers should
+// type-assert a Repo and treat a missing implementation as "addresses unknown".
+type AddressLister interface {
+	// InterfaceAddresses returns the live interface addresses in CIDR notation (e.g., "10.0.0.1/24").
+	InterfaceAddresses() ([]string, error)
+}
+
+// InterfaceDescriber is implemented by repositories that can describe the managed interface
+// and the WireGuard tooling behind it, for diagnostics. Like AddressLister, it is optional.
+type InterfaceDescriber interface {
+	// InterfaceName returns the name of the managed WireGuard interface (e.g., "wg0").
+	InterfaceName() string
+	// WgVersion returns the version string reported by 'wg --version'.
+	WgVersion() (string, error)
+}
+
+// ConfigIterator is implemented by repositories that can hand out peers one at a time,
+// so large peer sets can be processed without building a slice. It is optional like AddressLister.
+type ConfigIterator interface {
+	// IterConfigs calls fn for each peer; iteration stops at the first error returned by fn.
+	IterConfigs(ctx context.Context, fn func(domain.Config) error) error
+}
+
+// MTUReader is implemented by repositories that can read the WireGuard interface MTU.
+// It is optional like AddressLister; client configs use it when no MTU is configured.
+type MTUReader interface {
+	// InterfaceMTU returns the interface MTU in bytes.
+	InterfaceMTU() (int, error)
+}
+
+// ListenPortSetter is implemented by repositories that can change the interface's UDP listen port.
+type ListenPortSetter interface {
+	// SetListenPort makes the interface listen on port.
+	SetListenPort(ctx context.Context, port int) error
+}
+
+// PeerUpdater is implemented by repositories that can set all mutable settings of an existing peer
+// in one call, including removing its preshared key or keepalive. It is optional like AddressLister.
+type PeerUpdater interface {
+	// UpdatePeer reads the peer, lets mutate change it and sets its AllowedIps, PreSharedKey and
+	// PersistentKeepalive to the result; an empty PreSharedKey removes the key and a zero PersistentKeepalive
+	// turns keepalive off. No other write to the repository runs between the read and the write.
+	// An error from mutate is returned unchanged and leaves the peer untouched; a missing peer is ErrPeerNotFound.
+	UpdatePeer(ctx context.Context, publicKey string, mutate func(cfg *domain.Config) error) error
+}
+
+// Ensure WGRepository implements AddressLister, InterfaceDescriber, ConfigIterator, MTUReader, ListenPortSetter and PeerUpdater
+var (
+	_ ListenPortSetter   = (*WGRepository)(nil)
+	_ PeerUpdater        = (*WGRepository)(nil)
+	_ AddressLister      = (*WGRepository)(nil)
+	_ InterfaceDescriber = (*WGRepository)(nil)
+	_ ConfigIterator     = (*WGRepository)(nil)
+	_ MTUReader          = (*WGRepository)(nil)
+)
+
+// InterfaceName returns the name of the managed WireGuard interface.
+func (r *WGRepository) InterfaceName() string {
+	return r.iface
+}
+
+// WgVersion runs 'wg --version' and returns its trimmed output,
+// e.g. "wireguard-tools v1.0.20210914 - https://git.zx2c4.com/wireguard-tools/".
+func (r *WGRepository) WgVersion() (string, error) {
+	out, err := r.runWgCommand(context.Background(), "--version")
+	if err != nil {
+		if errors.Is(err, ErrWgTimeout) {
+			return "", ErrWgTimeout
+		}
+		return "", fmt.Errorf("failed to get wg version: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// SetListenPort runs 'wg set <interface> listen-port <port>'. It is serialized with batched writes,
+// whose 'wg syncconf' would otherwise restore the listen port read before the change.
+func (r *WGRepository) SetListenPort(ctx context.Context, port int) error {
+	unlock := r.lockWrites()
+	defer unlock()
+	if _, err := r.runWgCommand(ctx, "set", r.iface, "listen-port", strconv.Itoa(port)); err != nil {
+		return fmt.Errorf("failed to set listen port %d on interface %s: %w", port, r.iface, err)
+	}
+	logger.Logger.Info("Changed interface listen port", zap.Int("listenPort", port), zap.String("interface", r.iface))
+	r.saveConfig(ctx)
+	return nil
+}
+
+// UpdatePeer reads the peer with GetConfig, applies mutate and runs a single
+// 'wg set <interface> peer <key> allowed-ips ... persistent-keepalive ... preshared-key ...'.
+// The preshared key is piped through stdin; '/dev/null' removes it. Like SetListenPort it bypasses
+// write batching; the write lock is held from the read to the write, so the update is atomic.
+func (r *WGRepository) UpdatePeer(ctx context.Context, publicKey string, mutate func(cfg *domain.Config) error) error {
+	if publicKey == "" {
+		return errors.New("public key is required to update a peer")
+	}
+	unlock := r.lockWrites()
+	defer unlock()
+
+	cfg, err := r.GetConfig(ctx, publicKey)
+	if err != nil {
+		return err
+	}
+	if err := mutate(cfg); err != nil {
+		return err
+	}
+
+	keepalive := "off"
+	if cfg.PersistentKeepalive > 0 {
+		keepalive = strconv.Itoa(cfg.PersistentKeepalive)
+	}
+	psk := "/dev/null"
+	if cfg.PreSharedKey != "" {
+		psk = "/dev/stdin"
+	}
+	args := []string{"set", r.iface, "peer", publicKey,
+		"allowed-ips", strings.Join(cfg.AllowedIps, ","),
+		"persistent-keepalive", keepalive,
+		"preshared-key", psk}
+	if _, err := r.runWgCommandInput(ctx, cfg.PreSharedKey, args...); err != nil {
+		return fmt.Errorf("failed to update peer %s on interface %s: %w", publicKey, r.iface, err)
+	}
+	logger.Logger.Info("Updated peer settings", zap.String("publicKey", publicKey), zap.String("interface", r.iface))
+	r.saveConfig(ctx)
+	return nil
+}
+
+// InterfaceAddresses reads the interface addresses with 'ip -o addr show dev <interface>'.
+func (r *WGRepository) InterfaceAddresses() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cmdTimeout)
+	defer cancel()
+
+	stdout, stderr, err := r.runner.Run(ctx, "", "ip", "-o", "addr", "show", "dev", r.iface)
+	if ctx.Err() == context.DeadlineExceeded {
+		logger.Logger.Error("'ip addr show' timed out", zap.String("interface", r.iface), zap.Duration("timeout", r.cmdTimeout))
+		return nil, ErrWgTimeout
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ip addr show dev %s: execution failed: %w; output: %s", r.iface, err, strings.TrimSpace(string(stderr)))
+	}
+
+	addrs := parseIPAddrOutput(string(stdout))
+	logger.Logger.Debug("Read live interface addresses", zap.String("interface", r.iface), zap.Strings("addresses", addrs))
+	return addrs, nil
+}
+
+// parseIPAddrOutput extracts addresses from 'ip -o addr show' output.
+// Each line looks like: "4: wg0    inet 10.0.0.1/24 scope global wg0\       valid_lft forever ...".
+func parseIPAddrOutput(out string) []string {
+	addrs := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] == "inet" || fields[i] == "inet6" {
+				addrs = append(addrs, fields[i+1])
+				break
+			}
+		}
+	}
+	return addrs
+}
+
+// InterfaceMTU reads the interface MTU with 'ip -o link show dev <interface>'.
+func (r *WGRepository) InterfaceMTU() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cmdTimeout)
+	defer cancel()
+
+	stdout, stderr, err := r.runner.Run(ctx, "", "ip", "-o", "link", "show", "dev", r.iface)
+	if ctx.Err() == context.DeadlineExceeded {
+		logger.Logger.Error("'ip link show' timed out", zap.String("interface", r.iface), zap.Duration("timeout", r.cmdTimeout))
+		return 0, ErrWgTimeout
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ip link show dev %s: execution failed: %w; output: %s", r.iface, err, strings.TrimSpace(string(stderr)))
+	}
+
+	mtu, err := parseIPLinkMTU(string(stdout))
+	if err != nil {
+		return 0, fmt.Errorf("ip link show dev %s: %w", r.iface, err)
+	}
+	logger.Logger.Debug("Read live interface MTU", zap.String("interface", r.iface), zap.Int("mtu", mtu))
+	return mtu, nil
+}
+
+// parseIPLinkMTU extracts the MTU from 'ip -o link show' output.
+// The line looks like: "4: wg0: <POINTOPOINT,NOARP,UP,LOWER_UP> mtu 1420 qdisc noqueue state UNKNOWN ...".
+func parseIPLinkMTU(out string) (int, error) {
+	fields := strings.Fields(out)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "mtu" {
+			mtu, err := strconv.Atoi(fields[i+1])
+			if err != nil || mtu <= 0 {
+				return 0, fmt.Errorf("invalid mtu value %q", fields[i+1])
+			}
+			return mtu, nil
+		}
+	}
+	return 0, errors.New("no mtu in output")
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/logger"
+)
+
+// interfaceUpTimeout bounds 'wg-quick up', which also configures addresses, routes and PostUp hooks
+// and may take longer than a single 'wg' command.
+const interfaceUpTimeout = 30 * time.Second
+
+// InterfaceExists reports whether the managed WireGuard interface exists, using 'wg show <interface>'.
+// A "No such device" failure means the interface is missing; any other failure is returned as an error.
+func (r *WGRepository) InterfaceExists() (bool, error) {
+	out, err := r.runWgCommand(context.Background(), "show", r.iface)
+	if err == nil {
+		return true, nil
+	}
+	if strings.Contains(string(out), "No such device") {
+		return false, nil
+	}
+	return false, err
+}
+
+// EnsureInterfaceUp brings the interface up with 'wg-quick up' if it does not exist yet.
+// configPath is passed to wg-quick instead of the interface name when set
+// (wg-quick then reads that file; otherwise it uses /etc/wireguard/<interface>.conf).
+// It reports whether the interface had to be created.
+func (r *WGRepository) EnsureInterfaceUp(configPath string) (bool, error) {
+	exists, err := r.InterfaceExists()
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether interface %s exists: %w", r.iface, err)
+	}
+	if exists {
+		logger.Logger.Info("WireGuard interface already exists, not creating it", zap.String("interface", r.iface))
+		return false, nil
+	}
+
+	target := r.iface
+	if configPath != "" {
+		target = configPath
+	}
+	logger.Logger.Info("WireGuard interface does not exist, bringing it up", zap.String("interface", r.iface), zap.String("wgQuickTarget", target))
+
+	ctx, cancel := context.WithTimeout(context.Background(), interfaceUpTimeout)
+	defer cancel()
+	_, stderr, err := r.runner.Run(ctx, "", "wg-quick", "up", target)
+	if ctx.Err() == context.DeadlineExceeded {
+		return false, fmt.Errorf("wg-quick up %s: %w", target, ErrWgTimeout)
+	}
+	if err != nil {
+		return false, fmt.Errorf("wg-quick up %s: execution failed: %w; output: %s", target, err, strings.TrimSpace(string(stderr)))
+	}
+
+	exists, err = r.InterfaceExists()
+	if err != nil {
+		return true, fmt.Errorf("failed to verify interface %s after wg-quick up: %w", r.iface, err)
+	}
+	if !exists {
+		return true, fmt.Errorf("interface %s still does not exist after wg-quick up %s", r.iface, target)
+	}
+	logger.Logger.Info("WireGuard interface brought up", zap.String("interface", r.iface))
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"wgMicro_api/internal/logger"
+)
+
+// ifaceRunner simulates an interface that exists only after 'wg-quick up' succeeded.
+type ifaceRunner struct {
+	exists  bool
+	upFails bool
+	calls   []string
+}
+
+func (r *ifaceRunner) Run(ctx context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	r.calls = append(r.calls, strings.Join(append([]string{name}, args...), " "))
+	switch {
+	case name == "wg-quick":
+		if r.upFails {
+			return nil, []byte("RTNETLINK answers: Operation not permitted"), errors.New("exit status 1")
+		}
+		r.exists = true
+		return nil, nil, nil
+	case r.exists:
+		return []byte("interface: wg_up_test\n"), nil, nil
+	default:
+		return nil, []byte("Unable to access interface: No such device\n"), errors.New("exit status 1")
+	}
+}
+
+func TestEnsureInterfaceUp(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+
+	t.Run("Existing_interface_is_left_alone", func(t *testing.T) {
+		runner := &ifaceRunner{exists: true}
+		repo := NewWGRepository("wg_up_test", time.Second, WithCommandRunner(runner))
+
+		created, err := repo.EnsureInterfaceUp("")
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, []string{"wg show wg_up_test"}, runner.calls)
+	})
+
+	t.Run("Missing_interface_is_brought_up", func(t *testing.T) {
+		runner := &ifaceRunner{}
+		repo := NewWGRepository("wg_up_test", time.Second, WithCommandRunner(runner))
+
+		created, err := repo.EnsureInterfaceUp("/app/wg_up_test.conf")
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, []string{"wg show wg_up_test", "wg-quick up /app/wg_up_test.conf", "wg show wg_up_test"}, runner.calls)
+	})
+
+	t.Run("Failed_wg_quick_is_reported", func(t *testing.T) {
+		runner := &ifaceRunner{upFails: true}
+		repo := NewWGRepository("wg_up_test", time.Second, WithCommandRunner(runner))
+
+		_, err := repo.EnsureInterfaceUp("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "wg-quick up wg_up_test")
+		assert.Contains(t, err.Error(), "Operation not permitted")
+	})
+}
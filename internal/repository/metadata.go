@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// MetadataStore keeps sidecar peer metadata (name, description, creation time) keyed by public key.
+type MetadataStore interface {
+	// Get returns the metadata for publicKey and whether any is stored.
+	Get(publicKey string) (domain.PeerMetadata, bool)
+	// Set stores md for publicKey, replacing any previous entry.
+	Set(publicKey string, md domain.PeerMetadata) error
+	// Delete removes the entry for publicKey. Deleting a missing entry is not an error.
+	Delete(publicKey string) error
+}
+
+// MetadataReloader is implemented by metadata stores backed by external storage
+// that may be edited out of band. It is optional; in-memory stores need not implement it.
+type MetadataReloader interface {
+	// Reload replaces the in-memory entries with the current contents of the backing storage.
+	Reload() error
+}
+
+// MetadataLister is implemented by metadata stores that can enumerate their entries.
+// It is optional like MetadataReloader; orphan pruning needs it.
+type MetadataLister interface {
+	// Keys returns the public keys that have a stored entry, in no particular order.
+	Keys() []string
+}
+
+// Ensure FileMetadataStore implements MetadataStore, MetadataReloader and MetadataLister
+var (
+	_ MetadataStore    = (*FileMetadataStore)(nil)
+	_ MetadataReloader = (*FileMetadataStore)(nil)
+	_ MetadataLister   = (*FileMetadataStore)(nil)
+)
+
+// FileMetadataStore is a MetadataStore persisted as a JSON object in a single file.
+// With an empty path it keeps entries in memory only.
+type FileMetadataStore struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]domain.PeerMetadata
+}
+
+// NewFileMetadataStore creates a store backed by path, loading existing entries if the file exists.
+// An empty path yields an in-memory store.
+func NewFileMetadataStore(path string) (*FileMetadataStore, error) {
+	s := &FileMetadataStore{path: path, entries: make(map[string]domain.PeerMetadata)}
+	if path == "" {
+		return s, nil
+	}
+
+	entries, err := readMetadataFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s.entries = entries
+	logger.Logger.Info("Loaded peer metadata", zap.String("path", path), zap.Int("entries", len(s.entries)))
+	return s, nil
+}
+
+// Reload re-reads the backing file, picking up out-of-band edits. If the file cannot be read
+// or parsed, the current entries are kept. For an in-memory store it does nothing.
+func (s *FileMetadataStore) Reload() error {
+	if s.path == "" {
+		return nil
+	}
+	entries, err := readMetadataFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+	logger.Logger.Info("Reloaded peer metadata", zap.String("path", s.path), zap.Int("entries", len(s.entries)))
+	return nil
+}
+
+// readMetadataFile parses the metadata file at path. A missing or empty file yields no entries.
+func readMetadataFile(path string) (map[string]domain.PeerMetadata, error) {
+	entries := make(map[string]domain.PeerMetadata)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Logger.Info("Metadata file does not exist yet, starting empty", zap.String("path", path))
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata file %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata file %s: %w", path, err)
+		}
+	}
+	return entries, nil
+}
+
+// Get returns the metadata for publicKey and whether any is stored.
+func (s *FileMetadataStore) Get(publicKey string) (domain.PeerMetadata, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	md, ok := s.entries[publicKey]
+	return md, ok
+}
+
+// Keys returns the public keys that have a stored entry.
+func (s *FileMetadataStore) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Set stores md for publicKey and persists the store.
+func (s *FileMetadataStore) Set(publicKey string, md domain.PeerMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[publicKey] = md
+	return s.saveLocked()
+}
+
+// Delete removes the entry for publicKey and persists the store.
+func (s *FileMetadataStore) Delete(publicKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[publicKey]; !ok {
+		return nil
+	}
+	delete(s.entries, publicKey)
+	return s.saveLocked()
+}
+
+// saveLocked writes all entries to the backing file via a temporary file and rename,
+// so a crash never leaves a truncated file behind. The caller must hold s.mu.
+func (s *FileMetadataStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode peer metadata: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary metadata file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace metadata file %s: %w", s.path, err)
+	}
+	return nil
+}
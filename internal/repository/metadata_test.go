@@ -0,0 +1,68 @@
+// internal/repository/metadata_test.go
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+func TestFileMetadataStore_PersistsAcrossReload(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	path := filepath.Join(t.TempDir(), "metadata.json")
+
+	store, err := NewFileMetadataStore(path)
+	require.NoError(t, err, "A missing file should start an empty store")
+
+	md := domain.PeerMetadata{Name: "alice", Description: "laptop", CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	require.NoError(t, store.Set("peerA", md))
+	require.NoError(t, store.Set("peerB", domain.PeerMetadata{Name: "bob"}))
+	require.NoError(t, store.Delete("peerB"))
+	require.NoError(t, store.Delete("neverStored"), "Deleting a missing entry is not an error")
+
+	reloaded, err := NewFileMetadataStore(path)
+	require.NoError(t, err)
+	got, ok := reloaded.Get("peerA")
+	require.True(t, ok)
+	assert.Equal(t, md, got)
+	_, ok = reloaded.Get("peerB")
+	assert.False(t, ok)
+}
+
+func TestFileMetadataStore_ReloadPicksUpExternalEdits(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	store, err := NewFileMetadataStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Set("peerA", domain.PeerMetadata{Name: "alice"}))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"peerB":{"name":"bob"}}`), 0o600))
+	require.NoError(t, store.Reload())
+	_, ok := store.Get("peerA")
+	assert.False(t, ok, "Entries removed from the file should be gone after a reload")
+	got, ok := store.Get("peerB")
+	require.True(t, ok)
+	assert.Equal(t, "bob", got.Name)
+
+	require.NoError(t, os.WriteFile(path, []byte("{broken"), 0o600))
+	assert.Error(t, store.Reload())
+	_, ok = store.Get("peerB")
+	assert.True(t, ok, "A failed reload should keep the current entries")
+}
+
+func TestFileMetadataStore_InvalidFile(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+
+	_, err := NewFileMetadataStore(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/logger"
+)
+
+// WithPersistChanges makes the repository run 'wg-quick save' after every successful peer change,
+// so peers created through the API survive 'wg-quick down/up' and reboots. configPath is passed
+// to wg-quick instead of the interface name when set, as for EnsureInterfaceUp.
+// wg-quick save rewrites only the peers from the running interface and keeps the [Interface] section.
+func WithPersistChanges(enabled bool, configPath string) Option {
+	return func(r *WGRepository) {
+		r.persist = enabled
+		r.persistTarget = configPath
+	}
+}
+
+// saveConfig runs 'wg-quick save' when persistence is enabled. The change it follows has already
+// been applied to the live interface, so a failure is logged rather than returned, and the save
+// ignores cancellation of ctx. Saves are serialized because wg-quick writes through a fixed temp file.
+func (r *WGRepository) saveConfig(ctx context.Context) {
+	if !r.persist {
+		return
+	}
+	target := r.iface
+	if r.persistTarget != "" {
+		target = r.persistTarget
+	}
+
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.cmdTimeout)
+	defer cancel()
+	_, stderr, err := r.runner.Run(ctx, "", "wg-quick", "save", target)
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("wg-quick save %s: %w", target, ErrWgTimeout)
+	}
+	if err != nil {
+		logger.Logger.Error("Peer change applied but could not be saved; it will be lost when the interface restarts",
+			zap.String("interface", r.iface),
+			zap.String("wgQuickTarget", target),
+			zap.String("stderr", strings.TrimSpace(string(stderr))),
+			zap.Error(err))
+		return
+	}
+	logger.Logger.Debug("Saved WireGuard configuration", zap.String("interface", r.iface), zap.String("wgQuickTarget", target))
+}
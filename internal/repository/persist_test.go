@@ -0,0 +1,92 @@
+// internal/repository/persist_test.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// saveRunner records every command and optionally fails 'wg-quick save'.
+type saveRunner struct {
+	saveFails bool
+	calls     []string
+}
+
+func (r *saveRunner) Run(ctx context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	r.calls = append(r.calls, strings.Join(append([]string{name}, args...), " "))
+	if name == "wg-quick" && r.saveFails {
+		return nil, []byte("Permission denied"), errors.New("exit status 1")
+	}
+	return nil, nil, nil
+}
+
+func (r *saveRunner) saves() int {
+	n := 0
+	for _, call := range r.calls {
+		if strings.HasPrefix(call, "wg-quick save") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPersistChanges(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	t.Run("Every_change_is_saved", func(t *testing.T) {
+		runner := &saveRunner{}
+		repo := NewWGRepository("wg_save_test", time.Second, WithCommandRunner(runner), WithPersistChanges(true, ""))
+
+		require.NoError(t, repo.CreateConfig(ctx, domain.Config{PublicKey: "peerA", AllowedIps: []string{"10.0.0.2/32"}}))
+		require.NoError(t, repo.CreateConfig(ctx, domain.Config{PublicKey: "peerB", PreSharedKey: "psk"}))
+		require.NoError(t, repo.UpdateAllowedIPs(ctx, "peerA", []string{"10.0.0.3/32"}))
+		require.NoError(t, repo.DeleteConfig(ctx, "peerB"))
+
+		assert.Equal(t, 4, runner.saves())
+		assert.Equal(t, "wg-quick save wg_save_test", runner.calls[1], "Each 'wg set' should be followed by a save")
+	})
+
+	t.Run("Config_path_is_passed_to_wg_quick", func(t *testing.T) {
+		runner := &saveRunner{}
+		repo := NewWGRepository("wg_save_test", time.Second, WithCommandRunner(runner), WithPersistChanges(true, "/app/wg_save_test.conf"))
+
+		require.NoError(t, repo.DeleteConfig(ctx, "peerA"))
+		assert.Equal(t, []string{"wg set wg_save_test peer peerA remove", "wg-quick save /app/wg_save_test.conf"}, runner.calls)
+	})
+
+	t.Run("Disabled_never_saves", func(t *testing.T) {
+		runner := &saveRunner{}
+		repo := NewWGRepository("wg_save_test", time.Second, WithCommandRunner(runner))
+
+		require.NoError(t, repo.CreateConfig(ctx, domain.Config{PublicKey: "peerA"}))
+		require.NoError(t, repo.DeleteConfig(ctx, "peerA"))
+		assert.Zero(t, runner.saves())
+	})
+
+	t.Run("Save_failure_does_not_fail_the_change", func(t *testing.T) {
+		runner := &saveRunner{saveFails: true}
+		repo := NewWGRepository("wg_save_test", time.Second, WithCommandRunner(runner), WithPersistChanges(true, ""))
+
+		assert.NoError(t, repo.UpdateAllowedIPs(ctx, "peerA", []string{"10.0.0.4/32"}))
+		assert.Equal(t, 1, runner.saves())
+	})
+
+	t.Run("Failed_change_is_not_saved", func(t *testing.T) {
+		runner := &stubRunner{delay: time.Second}
+		repo := NewWGRepository("wg_save_test", 10*time.Millisecond, WithCommandRunner(runner), WithPersistChanges(true, ""))
+
+		require.ErrorIs(t, repo.DeleteConfig(ctx, "peerA"), ErrWgTimeout)
+		assert.Len(t, runner.calls, 1)
+	})
+}
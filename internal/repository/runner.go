@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// CommandRunner abstracts the execution of external utilities such as 'wg'.
+// The default implementation shells out via os/exec; tests can substitute a stub
+// to exercise command-dependent logic without WireGuard tools installed.
+type CommandRunner interface {
+	// Run executes the named command with the given arguments.
+	// If stdin is non-empty, it is piped to the command's standard input.
+	// Stdout and stderr are returned separately; err is the error reported by the process, if any.
+	Run(ctx context.Context, stdin string, name string, args ...string) (stdout []byte, stderr []byte, err error)
+}
+
+// ExecRunner is the default CommandRunner, backed by os/exec.
+type ExecRunner struct {
+	// Env is the complete environment of started commands, in "KEY=value" form.
+	// Nil inherits the process environment; a non-nil empty slice runs commands with none.
+	Env []string
+}
+
+// Run implements CommandRunner using exec.CommandContext.
+func (r ExecRunner) Run(ctx context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = r.Env
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// AllowedEnv returns the process environment restricted to the named variables, for ExecRunner.Env.
+// Unset names are skipped. An empty allow-list returns nil, i.e. the full environment is inherited.
+func AllowedEnv(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
@@ -0,0 +1,41 @@
+// internal/repository/runner_test.go
+package repository
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowedEnv(t *testing.T) {
+	t.Setenv("WGMICRO_TEST_LOCALE", "C.UTF-8")
+	t.Setenv("WGMICRO_TEST_SECRET", "must-not-leak")
+
+	assert.Nil(t, AllowedEnv(nil), "No allow-list inherits the full environment")
+	assert.Equal(t, []string{"WGMICRO_TEST_LOCALE=C.UTF-8"}, AllowedEnv([]string{"WGMICRO_TEST_LOCALE", "WGMICRO_TEST_UNSET"}))
+	assert.Equal(t, []string{}, AllowedEnv([]string{"WGMICRO_TEST_UNSET"}), "Only unset names yield an empty, not inherited, environment")
+}
+
+func TestExecRunner_Env(t *testing.T) {
+	if _, err := exec.LookPath("env"); err != nil {
+		t.Skip("'env' utility not available")
+	}
+	t.Setenv("WGMICRO_TEST_SECRET", "must-not-leak")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restricted := ExecRunner{Env: []string{"LC_ALL=C", "WGMICRO_TEST_PATH=/usr/bin"}}
+	stdout, _, err := restricted.Run(ctx, "", "env")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LC_ALL=C", "WGMICRO_TEST_PATH=/usr/bin"}, strings.Fields(string(stdout)),
+		"The command should see exactly the configured environment")
+
+	stdout, _, err = ExecRunner{}.Run(ctx, "", "env")
+	require.NoError(t, err)
+	assert.Contains(t, string(stdout), "WGMICRO_TEST_SECRET=must-not-leak", "A nil Env inherits the process environment")
+}
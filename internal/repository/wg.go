@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -24,33 +24,78 @@ var ErrWgTimeout = errors.New("wireguard command timed out")
 // does not exist on the WireGuard interface.
 var ErrPeerNotFound = errors.New("peer not found")
 
+// ErrInterfaceDown is returned by GetInterfaceInfo when 'wg show <iface> dump' produces no output,
+// which happens when the interface is down or does not exist.
+var ErrInterfaceDown = errors.New("wireguard interface returned no data, it may be down")
+
+// Field counts of the two kinds of lines in 'wg show <iface> dump' output.
+// The interface line is: private key, public key, listen port, fwmark.
+// A peer line is: public key, preshared key, endpoint, allowed IPs, latest handshake, rx, tx, keepalive.
+const (
+	interfaceDumpFields = 4
+	peerDumpFields      = 8
+)
+
 // DefaultWgCmdTimeout defines the default timeout for 'wg' commands if not specified
 // during WGRepository initialization. This serves as a fallback.
 const DefaultWgCmdTimeout = 5 * time.Second
 
 // Repo is an interface that defines methods for interacting with a WireGuard interface.
 // This abstraction allows for different implementations, such as a real one using 'wg' commands
-// or a fake one for testing.
+// or a fake one for testing. Every method takes the caller's context: cancelling it stops
+// the underlying command, and the per-command timeout is applied on top of it.
 type Repo interface {
 	// ListConfigs retrieves all current peer configurations from the WireGuard interface.
-	ListConfigs() ([]domain.Config, error)
+	ListConfigs(ctx context.Context) ([]domain.Config, error)
 	// GetConfig retrieves a specific peer configuration by its public key.
 	// Returns ErrPeerNotFound if the peer does not exist.
-	GetConfig(publicKey string) (*domain.Config, error)
+	GetConfig(ctx context.Context, publicKey string) (*domain.Config, error)
 	// CreateConfig adds a new peer to the WireGuard interface with the specified configuration.
 	// This typically involves setting the public key, allowed IPs, and optionally preshared key
 	// and persistent keepalive.
-	CreateConfig(cfg domain.Config) error
+	CreateConfig(ctx context.Context, cfg domain.Config) error
 	// UpdateAllowedIPs replaces the list of allowed IP networks for an existing peer.
-	UpdateAllowedIPs(publicKey string, allowedIps []string) error
+	UpdateAllowedIPs(ctx context.Context, publicKey string, allowedIps []string) error
 	// DeleteConfig removes a peer from the WireGuard interface using its public key.
-	DeleteConfig(publicKey string) error
+	DeleteConfig(ctx context.Context, publicKey string) error
+	// GetInterfaceInfo returns the live interface keys, listen port and fwmark.
+	// Returns ErrInterfaceDown if the interface reports nothing.
+	GetInterfaceInfo(ctx context.Context) (*domain.InterfaceInfo, error)
 }
 
 // WGRepository implements the Repo interface by interacting with the 'wg' command-line utility.
 type WGRepository struct {
-	iface      string        // Name of the WireGuard interface (e.g., "wg0") to manage.
-	cmdTimeout time.Duration // Timeout duration for executing 'wg' commands.
+	iface         string        // Name of the WireGuard interface (e.g., "wg0") to manage.
+	cmdTimeout    time.Duration // Timeout duration for executing 'wg' commands.
+	runner        CommandRunner // Executes 'wg' and auxiliary system utilities such as 'ip'.
+	slowThreshold time.Duration // Commands running longer than this are logged as slow; 0 disables.
+	persist       bool          // Run 'wg-quick save' after every successful change.
+	persistTarget string        // Config file passed to 'wg-quick save'; empty means the interface name.
+	saveMu        sync.Mutex    // Serializes 'wg-quick save' runs.
+	batchWindow   time.Duration // How long writes wait to be applied together; 0 disables batching.
+	batchMu       sync.Mutex    // Guards batchPending.
+	batchPending  []*batchedWrite
+	writeMu       sync.Mutex // Held by batch flushes and by writes bypassing the batch.
+}
+
+// Option customizes a WGRepository created by NewWGRepository.
+type Option func(*WGRepository)
+
+// WithCommandRunner overrides the CommandRunner used by the repository.
+func WithCommandRunner(runner CommandRunner) Option {
+	return func(r *WGRepository) {
+		if runner != nil {
+			r.runner = runner
+		}
+	}
+}
+
+// WithSlowCommandThreshold makes the repository log a warning for every 'wg' command
+// that takes longer than threshold. A non-positive threshold disables the warning.
+func WithSlowCommandThreshold(threshold time.Duration) Option {
+	return func(r *WGRepository) {
+		r.slowThreshold = threshold
+	}
 }
 
 // NewWGRepository creates a new instance of WGRepository.
@@ -59,7 +104,7 @@ type WGRepository struct {
 // cmdTimeout: The maximum duration to wait for 'wg' commands to complete.
 //
 //	If non-positive, DefaultWgCmdTimeout is used.
-func NewWGRepository(iface string, cmdTimeout time.Duration) *WGRepository {
+func NewWGRepository(iface string, cmdTimeout time.Duration, opts ...Option) *WGRepository {
 	if iface == "" {
 		// Interface name is critical for all operations.
 		logger.Logger.Fatal("WireGuard interface name cannot be empty for WGRepository")
@@ -74,32 +119,51 @@ func NewWGRepository(iface string, cmdTimeout time.Duration) *WGRepository {
 	logger.Logger.Info("WGRepository initialized",
 		zap.String("interface", iface),
 		zap.Duration("commandTimeout", cmdTimeout))
-	return &WGRepository{
+	r := &WGRepository{
 		iface:      iface,
 		cmdTimeout: cmdTimeout,
+		runner:     ExecRunner{},
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 // runWgCommand executes a 'wg' utility command with the configured timeout and arguments.
 // It centralizes common logic for command execution, context handling, timeout, and error logging.
+// The timeout is a child of ctx, so the command is also killed when the caller gives up;
+// that case returns the caller's context error rather than ErrWgTimeout.
 // The 'args' parameter should contain all arguments to 'wg' *after* the 'wg' command itself
 // (e.g., "show", "wg0", "dump").
 // Returns the combined output (stdout and stderr) of the command and an error if one occurred.
-func (r *WGRepository) runWgCommand(args ...string) ([]byte, error) {
+func (r *WGRepository) runWgCommand(parent context.Context, args ...string) ([]byte, error) {
+	return r.runWgCommandInput(parent, "", args...)
+}
+
+// runWgCommandInput is runWgCommand with stdin piped to the command. stdin is never logged,
+// since it carries key material.
+func (r *WGRepository) runWgCommandInput(parent context.Context, stdin string, args ...string) ([]byte, error) {
 	fullArgs := strings.Join(args, " ")
 	logger.Logger.Debug("Executing 'wg' command",
 		zap.String("interface", r.iface), // Though r.iface is often part of args, logging it here is for consistency
 		zap.String("commandArgs", fullArgs),
 		zap.Duration("timeout", r.cmdTimeout))
 
-	ctx, cancel := context.WithTimeout(context.Background(), r.cmdTimeout)
+	ctx, cancel := context.WithTimeout(parent, r.cmdTimeout)
 	defer cancel()
 
-	// The command is always 'wg'.
-	cmd := exec.CommandContext(ctx, "wg", args...)
-	out, err := cmd.CombinedOutput() // Captures both stdout and stderr.
+	out, err := r.execWg(ctx, stdin, args...) // Captures both stdout and stderr.
 
-	if ctx.Err() == context.DeadlineExceeded {
+	// A command that succeeded took effect, even if the caller went away meanwhile.
+	if err != nil && parent.Err() != nil {
+		logger.Logger.Warn("WireGuard command abandoned by caller",
+			zap.String("commandArgs", fullArgs),
+			zap.Error(parent.Err()),
+			zap.String("interface", r.iface))
+		return nil, fmt.Errorf("wg %s: %w", fullArgs, parent.Err())
+	}
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
 		logger.Logger.Error("WireGuard command timed out",
 			zap.String("commandArgs", fullArgs),
 			zap.Duration("timeout", r.cmdTimeout),
@@ -123,62 +187,64 @@ func (r *WGRepository) runWgCommand(args ...string) ([]byte, error) {
 	return out, nil
 }
 
+// execWg runs 'wg' with args through the repository's CommandRunner and returns stdout followed by stderr,
+// like exec.Cmd.CombinedOutput. If stdin is non-empty, it is piped to the command.
+// Commands exceeding the slow-command threshold are logged as warnings with their arguments and elapsed time.
+func (r *WGRepository) execWg(ctx context.Context, stdin string, args ...string) ([]byte, error) {
+	start := time.Now()
+	stdout, stderr, err := r.runner.Run(ctx, stdin, "wg", args...)
+	elapsed := time.Since(start)
+
+	if r.slowThreshold > 0 && elapsed > r.slowThreshold {
+		logger.Logger.Warn("Slow WireGuard command",
+			zap.String("commandArgs", strings.Join(args, " ")),
+			zap.Duration("elapsed", elapsed),
+			zap.Duration("threshold", r.slowThreshold),
+			zap.String("interface", r.iface))
+	}
+	return append(stdout, stderr...), err
+}
+
 // ListConfigs retrieves all current peer configurations by executing 'wg show <interface> dump'.
 // It parses the tab-separated output from the command.
-func (r *WGRepository) ListConfigs() ([]domain.Config, error) {
-	// Construct arguments for 'wg show <interface> dump'
-	commandArgs := []string{"show", r.iface, "dump"}
-	out, err := r.runWgCommand(commandArgs...)
+func (r *WGRepository) ListConfigs(ctx context.Context) ([]domain.Config, error) {
+	configs := []domain.Config{}
+	err := r.IterConfigs(ctx, func(cfg domain.Config) error {
+		configs = append(configs, cfg)
+		return nil
+	})
 	if err != nil {
-		// If it's a timeout, runWgCommand already returned ErrWgTimeout.
-		// Otherwise, it's a different execution error.
-		// No need to wrap ErrWgTimeout again, just return it.
-		if errors.Is(err, ErrWgTimeout) {
-			return nil, ErrWgTimeout
-		}
-		// For other errors, wrap them to indicate context of ListConfigs.
-		return nil, fmt.Errorf("failed to list peer configurations for interface %s: %w", r.iface, err)
+		return nil, err
 	}
+	logger.Logger.Debug("Successfully parsed peer configurations", zap.Int("count", len(configs)), zap.String("interface", r.iface))
+	return configs, nil
+}
 
-	outputStr := strings.TrimSpace(string(out))
-	if outputStr == "" {
+// IterConfigs executes 'wg show <interface> dump' and calls fn for each peer in output order,
+// without collecting them into a slice. Iteration stops at the first error returned by fn,
+// which is returned unchanged.
+func (r *WGRepository) IterConfigs(ctx context.Context, fn func(domain.Config) error) error {
+	lines, err := r.dumpLines(ctx)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
 		// This can happen if the interface is down or has no peers and 'wg show <iface> dump' returns nothing.
 		logger.Logger.Debug("`wg show dump` returned empty output, assuming no peers or interface data.", zap.String("interface", r.iface))
-		return []domain.Config{}, nil
+		return nil
 	}
+	return r.iterPeerLines(lines, fn)
+}
 
-	lines := strings.Split(outputStr, "\n")
-	var configs []domain.Config
-
-	// The first line of "wg show <iface> dump" output is usually the server's own interface details.
-	// We are interested in peer configurations, which appear on subsequent lines.
-	// A peer line starts with the peer's public key.
-	// An interface line (the first one) starts with the server's private key, then public key, listen port, fwmark.
-	// We skip the first line if it doesn't parse as a peer or if it matches server details.
-	// A more robust way is to check if the number of fields matches a peer or an interface.
-	// For `wg show <iface> dump`, the first line is always the interface itself if it's up.
-	isFirstLine := true
+// iterPeerLines parses the peer lines of 'wg show <interface> dump' output and calls fn for each.
+func (r *WGRepository) iterPeerLines(lines []string, fn func(domain.Config) error) error {
 	for _, line := range lines {
-		if line == "" {
-			continue // Skip any empty lines
-		}
-		if isFirstLine {
-			isFirstLine = false
-			// Heuristic: if the line contains the interface name or doesn't look like a peer, skip.
-			// A peer public key is typically 44 chars, base64.
-			// An interface line for 'wg show <iface> dump' has 4 fields: privkey, pubkey, listen_port, fwmark
-			// A peer line has 8 fields: pubkey, psk, endpoint,  allowed_ips, handshake, rx, tx, keepalive
-			// The provided parsing logic below expects peer data.
-			parts := strings.Fields(line)
-			if len(parts) != 8 { // If it's not 8 fields, it's likely the interface line or malformed.
-				logger.Logger.Debug("Skipping non-peer line from `wg show dump` output", zap.String("line", line), zap.String("interface", r.iface))
-				continue
-			}
-		}
-
 		parts := strings.Fields(line)
-		// Expected fields for a peer: PublicKey, PreSharedKey, Endpoint, AllowedIPs, LatestHandshake, RxBytes, TxBytes, PersistentKeepalive
-		if len(parts) < 8 {
+		if len(parts) == interfaceDumpFields {
+			// The interface line (normally the first one) is read by GetInterfaceInfo, never as a peer.
+			continue
+		}
+		if len(parts) < peerDumpFields {
 			logger.Logger.Warn("Skipping malformed peer line in 'wg show dump' output",
 				zap.String("line", line),
 				zap.Int("numParts", len(parts)),
@@ -237,7 +303,7 @@ func (r *WGRepository) ListConfigs() ([]domain.Config, error) {
 			}
 		}
 
-		configs = append(configs, domain.Config{
+		err := fn(domain.Config{
 			PublicKey:           publicKey,
 			PreSharedKey:        preSharedKey,
 			Endpoint:            endpoint,
@@ -247,19 +313,78 @@ func (r *WGRepository) ListConfigs() ([]domain.Config, error) {
 			TransmitBytes:       transmitBytes,
 			PersistentKeepalive: persistentKeepaliveVal,
 		})
+		if err != nil {
+			return err
+		}
 	}
-	logger.Logger.Debug("Successfully parsed peer configurations", zap.Int("count", len(configs)), zap.String("interface", r.iface))
-	return configs, nil
+	return nil
+}
+
+// GetInterfaceInfo parses the interface line of 'wg show <interface> dump'.
+func (r *WGRepository) GetInterfaceInfo(ctx context.Context) (*domain.InterfaceInfo, error) {
+	lines, err := r.dumpLines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("interface %s: %w", r.iface, ErrInterfaceDown)
+	}
+	return r.parseInterfaceLine(lines[0])
+}
+
+// parseInterfaceLine parses the interface line of 'wg show <interface> dump' output.
+func (r *WGRepository) parseInterfaceLine(line string) (*domain.InterfaceInfo, error) {
+	parts := strings.Fields(line)
+	if len(parts) != interfaceDumpFields {
+		return nil, fmt.Errorf("unexpected interface line in 'wg show %s dump' output: %d fields", r.iface, len(parts))
+	}
+
+	listenPort, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen port %q for interface %s: %w", parts[2], r.iface, err)
+	}
+	var fwMark uint64
+	if parts[3] != "off" {
+		// 'wg' prints the fwmark in hex, e.g. "0xca6c".
+		if fwMark, err = strconv.ParseUint(parts[3], 0, 32); err != nil {
+			return nil, fmt.Errorf("invalid fwmark %q for interface %s: %w", parts[3], r.iface, err)
+		}
+	}
+	return &domain.InterfaceInfo{
+		PrivateKey: parts[0],
+		PublicKey:  parts[1],
+		ListenPort: listenPort,
+		FwMark:     uint32(fwMark),
+	}, nil
+}
+
+// dumpLines runs 'wg show <interface> dump' and returns its non-empty lines.
+func (r *WGRepository) dumpLines(ctx context.Context) ([]string, error) {
+	out, err := r.runWgCommand(ctx, "show", r.iface, "dump")
+	if err != nil {
+		// If it's a timeout, runWgCommand already returned ErrWgTimeout.
+		if errors.Is(err, ErrWgTimeout) {
+			return nil, ErrWgTimeout
+		}
+		return nil, fmt.Errorf("failed to dump interface %s: %w", r.iface, err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
 }
 
 // GetConfig retrieves a specific peer's configuration.
 // It iterates through all configurations obtained from ListConfigs.
 // Returns ErrPeerNotFound if no peer matches the given publicKey.
-func (r *WGRepository) GetConfig(publicKey string) (*domain.Config, error) {
+func (r *WGRepository) GetConfig(ctx context.Context, publicKey string) (*domain.Config, error) {
 	if publicKey == "" {
 		return nil, errors.New("public key cannot be empty when fetching peer config") // Or a more specific validation error
 	}
-	allConfigs, err := r.ListConfigs()
+	allConfigs, err := r.ListConfigs(ctx)
 	if err != nil {
 		// Error is already contextualized by ListConfigs or runWgCommand.
 		return nil, err
@@ -278,7 +403,7 @@ func (r *WGRepository) GetConfig(publicKey string) (*domain.Config, error) {
 // CreateConfig adds a new peer to the WireGuard interface.
 // It constructs and executes 'wg set <interface> peer <publicKey> [preshared-key <file|/dev/stdin>] [allowed-ips <ip1,ip2...>] [persistent-keepalive <interval>]'.
 // The preshared-key, if provided, is passed via stdin for security.
-func (r *WGRepository) CreateConfig(cfg domain.Config) error {
+func (r *WGRepository) CreateConfig(parent context.Context, cfg domain.Config) error {
 	if cfg.PublicKey == "" {
 		return errors.New("public key is required to create peer config")
 	}
@@ -288,6 +413,11 @@ func (r *WGRepository) CreateConfig(cfg domain.Config) error {
 		logger.Logger.Warn("Creating peer config with no AllowedIPs specified.", zap.String("publicKey", cfg.PublicKey), zap.String("interface", r.iface))
 		// return errors.New("allowedIps are required to create peer config") // Re-enable if strictness is desired
 	}
+	if r.batching(parent) {
+		return r.enqueueWrite(&batchedWrite{cfg: cfg})
+	}
+	unlock := r.lockWrites()
+	defer unlock()
 
 	// Base arguments: wg set <interface> peer <publicKey>
 	args := []string{"set", r.iface, "peer", cfg.PublicKey}
@@ -296,10 +426,9 @@ func (r *WGRepository) CreateConfig(cfg domain.Config) error {
 	if cfg.PreSharedKey != "" {
 		// Create a temporary list of args for the PSK command.
 		pskArgs := append(args, "preshared-key", "/dev/stdin")
-		// Append other non-PSK related args that should be part of this same 'wg set' command
-		if len(cfg.AllowedIps) > 0 {
-			pskArgs = append(pskArgs, "allowed-ips", strings.Join(cfg.AllowedIps, ","))
-		}
+		// Append other non-PSK related args that should be part of this same 'wg set' command.
+		// allowed-ips is always passed, as below, so updating an existing peer to none removes its ranges.
+		pskArgs = append(pskArgs, "allowed-ips", strings.Join(cfg.AllowedIps, ","))
 		if cfg.PersistentKeepalive > 0 {
 			pskArgs = append(pskArgs, "persistent-keepalive", strconv.Itoa(cfg.PersistentKeepalive))
 		}
@@ -308,14 +437,15 @@ func (r *WGRepository) CreateConfig(cfg domain.Config) error {
 		logger.Logger.Debug("Executing 'wg set peer' with PresharedKey (via stdin)",
 			zap.String("args", strings.Join(pskArgs, " ")), zap.String("interface", r.iface))
 
-		ctx, cancel := context.WithTimeout(context.Background(), r.cmdTimeout)
+		ctx, cancel := context.WithTimeout(parent, r.cmdTimeout)
 		defer cancel()
 
-		cmd := exec.CommandContext(ctx, "wg", pskArgs...)
-		cmd.Stdin = strings.NewReader(cfg.PreSharedKey) // Pipe PSK to stdin
-
-		out, err := cmd.CombinedOutput()
-		if ctx.Err() == context.DeadlineExceeded {
+		out, err := r.execWg(ctx, cfg.PreSharedKey, pskArgs...) // Pipe PSK to stdin
+		if err != nil && parent.Err() != nil {
+			logger.Logger.Warn("WireGuard 'set peer' (with PSK) command abandoned by caller", zap.String("publicKey", cfg.PublicKey), zap.Error(parent.Err()), zap.String("interface", r.iface))
+			return fmt.Errorf("wg set peer %s with PSK: %w", cfg.PublicKey, parent.Err())
+		}
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
 			logger.Logger.Error("WireGuard 'set peer' (with PSK) command timed out", zap.String("publicKey", cfg.PublicKey), zap.String("interface", r.iface))
 			return ErrWgTimeout
 		}
@@ -325,6 +455,7 @@ func (r *WGRepository) CreateConfig(cfg domain.Config) error {
 			return fmt.Errorf("wg set peer %s with PSK: failed: %w; output: %s", cfg.PublicKey, err, string(out))
 		}
 		logger.Logger.Info("Successfully created/updated peer with PSK", zap.String("publicKey", cfg.PublicKey), zap.String("interface", r.iface))
+		r.saveConfig(parent)
 		return nil // Successfully created with PSK
 	}
 
@@ -343,19 +474,20 @@ func (r *WGRepository) CreateConfig(cfg domain.Config) error {
 		args = append(args, "persistent-keepalive", strconv.Itoa(cfg.PersistentKeepalive))
 	}
 
-	_, err := r.runWgCommand(args...)
+	_, err := r.runWgCommand(parent, args...)
 	if err != nil {
 		// runWgCommand already logged the error. Wrap it for context.
 		return fmt.Errorf("failed to create peer config for %s on interface %s: %w", cfg.PublicKey, r.iface, err)
 	}
 	logger.Logger.Info("Successfully created/updated peer (no PSK)", zap.String("publicKey", cfg.PublicKey), zap.String("interface", r.iface))
+	r.saveConfig(parent)
 	return nil
 }
 
 // UpdateAllowedIPs replaces the list of allowed IP networks for an existing peer.
 // Executes 'wg set <interface> peer <publicKey> allowed-ips <ip1,ip2...>'.
 // An empty 'allowedIps' slice will attempt to remove all allowed IPs for the peer.
-func (r *WGRepository) UpdateAllowedIPs(publicKey string, allowedIps []string) error {
+func (r *WGRepository) UpdateAllowedIPs(ctx context.Context, publicKey string, allowedIps []string) error {
 	if publicKey == "" {
 		return errors.New("public key is required to update peer's allowed IPs")
 	}
@@ -368,26 +500,35 @@ func (r *WGRepository) UpdateAllowedIPs(publicKey string, allowedIps []string) e
 			zap.String("publicKey", publicKey), zap.String("interface", r.iface))
 	}
 
+	if r.batching(ctx) {
+		return r.enqueueWrite(&batchedWrite{cfg: domain.Config{PublicKey: publicKey, AllowedIps: allowedIps}, allowedOnly: true})
+	}
+	unlock := r.lockWrites()
+	defer unlock()
+
 	args := []string{"set", r.iface, "peer", publicKey, "allowed-ips", ipsString}
 
-	_, err := r.runWgCommand(args...)
+	_, err := r.runWgCommand(ctx, args...)
 	if err != nil {
 		// runWgCommand already logged the error. Wrap it for context.
 		return fmt.Errorf("failed to update allowed IPs for peer %s on interface %s: %w", publicKey, r.iface, err)
 	}
 	logger.Logger.Info("Successfully updated allowed IPs", zap.String("publicKey", publicKey), zap.Strings("newAllowedIPs", allowedIps), zap.String("interface", r.iface))
+	r.saveConfig(ctx)
 	return nil
 }
 
 // DeleteConfig removes a peer from the WireGuard interface.
 // Executes 'wg set <interface> peer <publicKey> remove'.
-func (r *WGRepository) DeleteConfig(publicKey string) error {
+func (r *WGRepository) DeleteConfig(ctx context.Context, publicKey string) error {
 	if publicKey == "" {
 		return errors.New("public key is required to delete peer config")
 	}
+	unlock := r.lockWrites() // Deletes are never batched
+	defer unlock()
 	args := []string{"set", r.iface, "peer", publicKey, "remove"}
 
-	_, err := r.runWgCommand(args...)
+	_, err := r.runWgCommand(ctx, args...)
 	if err != nil {
 		// 'wg set ... remove' on a non-existent peer usually does not result in an error code,
 		// but if 'runWgCommand' returns an error, it's likely a more fundamental issue.
@@ -395,5 +536,6 @@ func (r *WGRepository) DeleteConfig(publicKey string) error {
 		return fmt.Errorf("failed to delete peer %s from interface %s: %w", publicKey, r.iface, err)
 	}
 	logger.Logger.Info("Successfully deleted peer", zap.String("publicKey", publicKey), zap.String("interface", r.iface))
+	r.saveConfig(ctx)
 	return nil
 }
@@ -1,20 +1,29 @@
 package repository
 
 import (
-	"fmt"
+	"context"
+	"sort"
+
 	"wgMicro_api/internal/domain"
 )
 
 // FakeWGRepository удовлетворяет тому же API, что и WGRepository
 type FakeWGRepository struct {
 	Data map[string]domain.Config
+	// Addresses — адреса интерфейса, которые возвращает InterfaceAddresses
+	Addresses []string
+	// Iface и Version — то, что возвращают InterfaceName и WgVersion
+	Iface   string
+	Version string
+	// Info — то, что возвращает GetInterfaceInfo; nil означает, что интерфейс опущен
+	Info *domain.InterfaceInfo
 }
 
 func NewFakeWGRepository() *FakeWGRepository {
 	return &FakeWGRepository{Data: make(map[string]domain.Config)}
 }
 
-func (f *FakeWGRepository) ListConfigs() ([]domain.Config, error) {
+func (f *FakeWGRepository) ListConfigs(ctx context.Context) ([]domain.Config, error) {
 	var out []domain.Config
 	for _, cfg := range f.Data {
 		out = append(out, cfg)
@@ -22,30 +31,82 @@ func (f *FakeWGRepository) ListConfigs() ([]domain.Config, error) {
 	return out, nil
 }
 
-func (f *FakeWGRepository) GetConfig(key string) (*domain.Config, error) {
+// IterConfigs calls fn for each peer in public key order, so output is deterministic.
+func (f *FakeWGRepository) IterConfigs(ctx context.Context, fn func(domain.Config) error) error {
+	keys := make([]string, 0, len(f.Data))
+	for key := range f.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(f.Data[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FakeWGRepository) GetConfig(ctx context.Context, key string) (*domain.Config, error) {
 	cfg, ok := f.Data[key]
 	if !ok {
-		return nil, fmt.Errorf("not found")
+		return nil, ErrPeerNotFound
 	}
 	return &cfg, nil
 }
 
-func (f *FakeWGRepository) CreateConfig(cfg domain.Config) error {
+func (f *FakeWGRepository) CreateConfig(ctx context.Context, cfg domain.Config) error {
 	f.Data[cfg.PublicKey] = cfg
 	return nil
 }
 
-func (f *FakeWGRepository) UpdateAllowedIPs(key string, ips []string) error {
+func (f *FakeWGRepository) UpdateAllowedIPs(ctx context.Context, key string, ips []string) error {
 	cfg, ok := f.Data[key]
 	if !ok {
-		return fmt.Errorf("not found")
+		return ErrPeerNotFound
 	}
 	cfg.AllowedIps = ips
 	f.Data[key] = cfg
 	return nil
 }
 
-func (f *FakeWGRepository) DeleteConfig(key string) error {
+func (f *FakeWGRepository) UpdatePeer(ctx context.Context, publicKey string, mutate func(cfg *domain.Config) error) error {
+	existing, ok := f.Data[publicKey]
+	if !ok {
+		return ErrPeerNotFound
+	}
+	cfg := existing
+	cfg.AllowedIps = append([]string(nil), existing.AllowedIps...)
+	if err := mutate(&cfg); err != nil {
+		return err
+	}
+	existing.AllowedIps = cfg.AllowedIps
+	existing.PreSharedKey = cfg.PreSharedKey
+	existing.PersistentKeepalive = cfg.PersistentKeepalive
+	f.Data[publicKey] = existing
+	return nil
+}
+
+func (f *FakeWGRepository) DeleteConfig(ctx context.Context, key string) error {
 	delete(f.Data, key)
 	return nil
 }
+
+func (f *FakeWGRepository) InterfaceAddresses() ([]string, error) {
+	return append([]string(nil), f.Addresses...), nil
+}
+
+func (f *FakeWGRepository) InterfaceName() string {
+	return f.Iface
+}
+
+func (f *FakeWGRepository) WgVersion() (string, error) {
+	return f.Version, nil
+}
+
+func (f *FakeWGRepository) GetInterfaceInfo(ctx context.Context) (*domain.InterfaceInfo, error) {
+	if f.Info == nil {
+		return nil, ErrInterfaceDown
+	}
+	info := *f.Info
+	return &info, nil
+}
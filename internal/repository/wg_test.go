@@ -0,0 +1,222 @@
+// internal/repository/wg_test.go
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// stubRunner is a CommandRunner returning fixed output after an optional delay.
+type stubRunner struct {
+	delay  time.Duration
+	stdout string
+	calls  [][]string
+}
+
+func (s *stubRunner) Run(ctx context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	s.calls = append(s.calls, append([]string{name}, args...))
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	return []byte(s.stdout), nil, nil
+}
+
+// observeLogs replaces the global logger with one recording entries at Warn level and above.
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zapcore.WarnLevel)
+	previous := logger.Logger
+	logger.Logger = zap.New(core)
+	t.Cleanup(func() { logger.Logger = previous })
+	return logs
+}
+
+func TestRunWgCommand_SlowCommandWarning(t *testing.T) {
+	logs := observeLogs(t)
+	runner := &stubRunner{delay: 30 * time.Millisecond}
+	repo := NewWGRepository("wg_slow_test", time.Second,
+		WithCommandRunner(runner),
+		WithSlowCommandThreshold(5*time.Millisecond),
+	)
+
+	_, err := repo.runWgCommand(context.Background(), "show", "wg_slow_test", "dump")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"wg", "show", "wg_slow_test", "dump"}}, runner.calls)
+
+	slow := logs.FilterMessage("Slow WireGuard command").All()
+	require.Len(t, slow, 1, "A slow command should produce exactly one warning")
+	fields := slow[0].ContextMap()
+	assert.Equal(t, "show wg_slow_test dump", fields["commandArgs"])
+	assert.GreaterOrEqual(t, fields["elapsed"], 30*time.Millisecond)
+}
+
+func TestRunWgCommand_NoWarningBelowThresholdOrDisabled(t *testing.T) {
+	logs := observeLogs(t)
+
+	fast := NewWGRepository("wg_fast_test", time.Second,
+		WithCommandRunner(&stubRunner{}),
+		WithSlowCommandThreshold(time.Second),
+	)
+	_, err := fast.runWgCommand(context.Background(), "show", "wg_fast_test", "dump")
+	require.NoError(t, err)
+
+	disabled := NewWGRepository("wg_disabled_test", time.Second,
+		WithCommandRunner(&stubRunner{delay: 10 * time.Millisecond}),
+	)
+	_, err = disabled.runWgCommand(context.Background(), "show", "wg_disabled_test", "dump")
+	require.NoError(t, err)
+
+	assert.Zero(t, logs.FilterMessage("Slow WireGuard command").Len())
+}
+
+func TestRunWgCommand_CallerCancellationAndTimeout(t *testing.T) {
+	repo := NewWGRepository("wg_ctx_test", 20*time.Millisecond, WithCommandRunner(&stubRunner{delay: time.Second}))
+	_, err := repo.ListConfigs(context.Background())
+	assert.ErrorIs(t, err, ErrWgTimeout, "The per-command timeout should still apply")
+
+	repo = NewWGRepository("wg_ctx_test", time.Minute, WithCommandRunner(&stubRunner{delay: time.Second}))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	err = repo.DeleteConfig(ctx, "somePeer")
+	assert.ErrorIs(t, err, context.Canceled, "A cancelled caller should stop the command")
+	assert.NotErrorIs(t, err, ErrWgTimeout)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+// cancelingRunner succeeds after cancelling the caller's context, as when a client disconnects
+// just as 'wg set' finishes.
+type cancelingRunner struct{ cancel context.CancelFunc }
+
+func (r cancelingRunner) Run(ctx context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	r.cancel()
+	return nil, nil, nil
+}
+
+func TestRunWgCommand_SucceededDespiteCancellation(t *testing.T) {
+	for _, psk := range []string{"", "psk"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		repo := NewWGRepository("wg_ctx_test", time.Minute, WithCommandRunner(cancelingRunner{cancel: cancel}))
+		err := repo.CreateConfig(ctx, domain.Config{PublicKey: "somePeer", AllowedIps: []string{"10.0.0.2/32"}, PreSharedKey: psk})
+		assert.NoError(t, err, "A change that was applied must not be reported as failed (psk=%q)", psk)
+	}
+}
+
+func TestIterConfigs_StopsOnCallbackError(t *testing.T) {
+	dump := "serverPriv\tserverPub\t51820\toff\n" +
+		"peerOne\t(none)\t(none)\t10.0.0.2/32\t0\t0\t0\toff\n" +
+		"peerTwo\tpsk\t192.0.2.1:51820\t10.0.0.3/32,fd00::3/128\t1700000000\t10\t20\t25\n" +
+		"peerThree\t(none)\t(none)\t(none)\t0\t0\t0\toff\n"
+	repo := NewWGRepository("wg_iter_test", time.Second, WithCommandRunner(&stubRunner{stdout: dump}))
+
+	var seen []string
+	stop := assert.AnError
+	err := repo.IterConfigs(context.Background(), func(cfg domain.Config) error {
+		seen = append(seen, cfg.PublicKey)
+		if cfg.PublicKey == "peerTwo" {
+			assert.Equal(t, []string{"10.0.0.3/32", "fd00::3/128"}, cfg.AllowedIps)
+			assert.Equal(t, 25, cfg.PersistentKeepalive)
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []string{"peerOne", "peerTwo"}, seen, "Iteration should stop at the callback error")
+
+	all, err := repo.ListConfigs(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestGetInterfaceInfo(t *testing.T) {
+	dump := "serverPriv\tserverPub\t51820\t0xca6c\n" +
+		"peerOne\t(none)\t(none)\t10.0.0.2/32\t0\t0\t0\toff\n"
+	repo := NewWGRepository("wg_info_test", time.Second, WithCommandRunner(&stubRunner{stdout: dump}))
+
+	info, err := repo.GetInterfaceInfo(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.InterfaceInfo{PrivateKey: "serverPriv", PublicKey: "serverPub", ListenPort: 51820, FwMark: 0xca6c}, *info)
+
+	raw, err := json.Marshal(info)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "serverPriv", "The private key must not be serialized")
+
+	peers, err := repo.ListConfigs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, peers, 1, "The interface line must not be parsed as a peer")
+	assert.Equal(t, "peerOne", peers[0].PublicKey)
+
+	off, err := NewWGRepository("wg_info_test", time.Second, WithCommandRunner(&stubRunner{stdout: "priv\tpub\t51820\toff\n"})).GetInterfaceInfo(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, off.FwMark)
+
+	_, err = NewWGRepository("wg_info_test", time.Second, WithCommandRunner(&stubRunner{})).GetInterfaceInfo(context.Background())
+	assert.ErrorIs(t, err, ErrInterfaceDown)
+}
+
+func TestInterfaceMTU(t *testing.T) {
+	runner := &stubRunner{stdout: "4: wg_mtu_test: <POINTOPOINT,NOARP,UP,LOWER_UP> mtu 1420 qdisc noqueue state UNKNOWN mode DEFAULT group default qlen 1000\\    link/none \n"}
+	repo := NewWGRepository("wg_mtu_test", time.Second, WithCommandRunner(runner))
+
+	mtu, err := repo.InterfaceMTU()
+	require.NoError(t, err)
+	assert.Equal(t, 1420, mtu)
+	assert.Equal(t, [][]string{{"ip", "-o", "link", "show", "dev", "wg_mtu_test"}}, runner.calls)
+
+	_, err = NewWGRepository("wg_mtu_test", time.Second, WithCommandRunner(&stubRunner{stdout: "garbage"})).InterfaceMTU()
+	assert.Error(t, err)
+}
+
+func TestCreateConfig_PresharedKeyPeerWithoutAllowedIPs(t *testing.T) {
+	runner := &stubRunner{}
+	repo := NewWGRepository("wg_create_test", time.Second, WithCommandRunner(runner))
+
+	require.NoError(t, repo.CreateConfig(context.Background(), domain.Config{PublicKey: "peerKey", PreSharedKey: "psk"}))
+	assert.Equal(t, [][]string{
+		{"wg", "set", "wg_create_test", "peer", "peerKey", "preshared-key", "/dev/stdin", "allowed-ips", ""},
+	}, runner.calls, "An empty AllowedIPs list must clear the peer's ranges")
+}
+
+func TestUpdatePeer_SingleWgSet(t *testing.T) {
+	dump := "serverPriv\tserverPub\t51820\toff\n" +
+		"peerKey\t(none)\t(none)\t10.0.0.2/32\t0\t0\t0\toff\n"
+	runner := &stubRunner{stdout: dump}
+	repo := NewWGRepository("wg_update_test", time.Second, WithCommandRunner(runner))
+
+	require.NoError(t, repo.UpdatePeer(context.Background(), "peerKey", func(cfg *domain.Config) error {
+		assert.Equal(t, []string{"10.0.0.2/32"}, cfg.AllowedIps, "mutate sees the current peer")
+		cfg.AllowedIps = append(cfg.AllowedIps, "10.0.1.0/24")
+		cfg.PreSharedKey = "psk"
+		cfg.PersistentKeepalive = 25
+		return nil
+	}))
+	require.NoError(t, repo.UpdatePeer(context.Background(), "peerKey", func(cfg *domain.Config) error { return nil }))
+	show := []string{"wg", "show", "wg_update_test", "dump"}
+	assert.Equal(t, [][]string{
+		show,
+		{"wg", "set", "wg_update_test", "peer", "peerKey", "allowed-ips", "10.0.0.2/32,10.0.1.0/24", "persistent-keepalive", "25", "preshared-key", "/dev/stdin"},
+		show,
+		{"wg", "set", "wg_update_test", "peer", "peerKey", "allowed-ips", "10.0.0.2/32", "persistent-keepalive", "off", "preshared-key", "/dev/null"},
+	}, runner.calls, "Removed settings are turned off explicitly")
+
+	runner.calls = nil
+	err := repo.UpdatePeer(context.Background(), "peerKey", func(cfg *domain.Config) error { return assert.AnError })
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.ErrorIs(t, repo.UpdatePeer(context.Background(), "otherKey", func(cfg *domain.Config) error { return nil }), ErrPeerNotFound)
+	assert.Equal(t, [][]string{show, show}, runner.calls, "A failed mutate or a missing peer runs no 'wg set'")
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// RequireAdminToken rejects requests whose Authorization header is not "Bearer <token>".
+func RequireAdminToken(token string) gin.HandlerFunc {
+	expected := []byte(token)
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), expected) != 1 {
+			logger.Logger.Warn("Rejected admin request", zap.String("path", c.Request.URL.Path), zap.String("clientIP", c.ClientIP()))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Error: "A valid admin token is required."})
+			return
+		}
+		c.Next()
+	}
+}
+
+// resetCaches resets the router's own caches, such as the readiness result, before POST /admin/refresh runs.
+func resetCaches(caches ...interface{ Reset() }) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, cache := range caches {
+			cache.Reset()
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gzip compresses response bodies of at least minBytes for clients sending Accept-Encoding: gzip.
+// Smaller bodies are sent as is, since compressing them costs more CPU than it saves bytes.
+// Bodies are buffered until minBytes is reached, so streaming endpoints only start sending once
+// they have produced that much; Server-Sent Events and already encoded responses are never compressed.
+func Gzip(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+			c.Next()
+			return
+		}
+		w := &gzipWriter{ResponseWriter: c.Writer, minBytes: minBytes}
+		c.Writer = w
+		defer w.finish()
+		c.Header("Vary", "Accept-Encoding")
+		c.Next()
+	}
+}
+
+// gzipWriter buffers the body until it is known to be large enough to compress.
+type gzipWriter struct {
+	gin.ResponseWriter
+	minBytes int
+	buf      bytes.Buffer
+	gz       *gzip.Writer // Set once compression has started
+	decided  bool         // Whether the body is compressed (gz != nil) or passed through
+}
+
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf.Write(p)
+	if w.buf.Len() >= w.minBytes {
+		if err := w.decide(w.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports whether the handler has produced body bytes, including buffered ones,
+// so handlers checking it before writing an error response keep working.
+func (w *gzipWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends what has been written so far. An undecided body is sent uncompressed,
+// because the handler wants it on the wire before the threshold may ever be reached.
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// compressible reports whether the response may be gzip-encoded.
+func (w *gzipWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") {
+		return false
+	}
+	status := w.Status()
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// decide fixes the encoding and writes out the buffered bytes.
+func (w *gzipWriter) decide(compress bool) error {
+	w.decided = true
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+		_, err := w.gz.Write(w.buf.Bytes())
+		w.buf.Reset()
+		return err
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// finish sends a body that stayed below the threshold, or completes the gzip stream.
+func (w *gzipWriter) finish() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
@@ -0,0 +1,71 @@
+// internal/server/gzip_test.go
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzip_MinBytesThreshold(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const minBytes = 1024
+	small := strings.Repeat("s", minBytes-1)
+	large := strings.Repeat("l", minBytes*4)
+
+	r := gin.New()
+	r.Use(Gzip(minBytes))
+	r.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, small) })
+	r.GET("/large", func(c *gin.Context) { c.String(http.StatusOK, large) })
+	r.GET("/events", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, large)
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	t.Run("Small_response_is_uncompressed", func(t *testing.T) {
+		w := get("/small", "gzip, deflate")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, small, w.Body.String())
+	})
+
+	t.Run("Large_response_is_compressed", func(t *testing.T) {
+		w := get("/large", "gzip, deflate")
+		require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Less(t, w.Body.Len(), len(large))
+		zr, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, large, string(decoded))
+	})
+
+	t.Run("Client_without_gzip_gets_plain_body", func(t *testing.T) {
+		w := get("/large", "")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, w.Body.String())
+	})
+
+	t.Run("Event_stream_is_never_compressed", func(t *testing.T) {
+		w := get("/events", "gzip")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, w.Body.String())
+	})
+}
@@ -1,8 +1,14 @@
 package server
 
 import (
-	"errors"   // For errors.Is
-	"net/http" // Standard HTTP status codes and utilities
+	"context"
+	"crypto/rand"     // For the throwaway peer key of the write check
+	"encoding/base64" // For encoding the throwaway peer key
+	"errors"          // For errors.Is
+	"fmt"             // For wrapping write check errors
+	"net/http"        // Standard HTTP status codes and utilities
+	"strconv"         // For parsing the verbose query flag
+	"time"            // For the readiness cache window
 
 	// For simulating work or timeouts if needed in probes
 	"wgMicro_api/internal/domain"     // For HealthResponse and ReadinessResponse structures
@@ -28,16 +34,95 @@ func HealthLiveness(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// errNoPeers marks a readiness failure caused by an empty peer list when peers are required.
+var errNoPeers = errors.New("no peers configured")
+
+// errWriteCheck marks a readiness failure caused by the ?checkWrite=true probe.
+var errWriteCheck = errors.New("write check failed")
+
+// readinessConfig holds optional behaviour of the readiness probe.
+type readinessConfig struct {
+	requirePeers    bool
+	allowWriteCheck bool
+	listenPort      func() int    // Current UDP port to probe in verbose responses; nil disables the probe
+	cacheTTL        time.Duration // How long a check result is reused; 0 checks on every probe
+	rejectWrites    bool          // Fail mutating requests fast while the cached result is a failure
+}
+
+// ReadinessOption customizes HealthReadiness.
+type ReadinessOption func(*readinessConfig)
+
+// RequirePeers makes the readiness probe report "not ready" while the interface has no peers.
+func RequirePeers(required bool) ReadinessOption {
+	return func(c *readinessConfig) {
+		c.requirePeers = required
+	}
+}
+
+// AllowWriteCheck enables the ?checkWrite=true readiness parameter. The check adds and removes
+// a throwaway peer, so it is opt-in; while disabled the parameter is ignored.
+func AllowWriteCheck(allowed bool) ReadinessOption {
+	return func(c *readinessConfig) {
+		c.allowWriteCheck = allowed
+	}
+}
+
+// CheckListenPort makes verbose readiness responses report whether the WireGuard UDP listen port
+// is bound on this host. port is called on every verbose probe, so a port changed at runtime is followed.
+// The result is informational and does not change the readiness status.
+func CheckListenPort(port func() int) ReadinessOption {
+	return func(c *readinessConfig) {
+		c.listenPort = port
+	}
+}
+
+// CacheReadiness reuses a readiness check result for ttl (a quarter of it after a failure),
+// so aggressive probing does not run 'wg show dump' on every request. Write checks are never cached.
+func CacheReadiness(ttl time.Duration) ReadinessOption {
+	return func(c *readinessConfig) {
+		c.cacheTTL = ttl
+	}
+}
+
+// RejectWritesWhenNotReady makes mutating /configs requests answer 503 with Retry-After while the
+// cached readiness result is a failure, instead of attempting a 'wg' command that would fail as well.
+// It relies on CacheReadiness: without a cache window there is no result to consult and nothing is rejected.
+// An interface that is only "not ready" because it has no peers (RequirePeers) still accepts changes.
+// Requests for an interface added with WithInterfaceRepos consult that interface's own cached result.
+func RejectWritesWhenNotReady(enabled bool) ReadinessOption {
+	return func(c *readinessConfig) {
+		c.rejectWrites = enabled
+	}
+}
+
+// newReadinessConfig applies opts to the default readiness configuration.
+func newReadinessConfig(opts ...ReadinessOption) readinessConfig {
+	cfg := readinessConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
 // HealthReadiness godoc
 // @Summary      Readiness probe for the service
 // @Description  Indicates if the application is ready to accept and process new requests.
 // @Description  This typically involves checking dependencies like database connections or, in this case, WireGuard utility accessibility.
+// @Description  If READY_REQUIRES_PEERS is enabled, an interface without any peers is reported as not ready.
+// @Description  Results are reused for READINESS_CACHE_MS (a quarter of it after a failure), so rapid probes may see a slightly old result.
+// @Description  With READY_REJECT_WRITES, a cached failure also makes changes to /configs answer 503 with Retry-After until it expires.
+// @Description  With `?verbose=true`, the response also includes the `wg` version and the interface name and,
+// @Description  if READY_CHECK_LISTEN_PORT is enabled, whether the WireGuard UDP listen port is bound.
+// @Description  With `?checkWrite=true` and READY_ALLOW_WRITE_CHECK enabled, the probe also adds and removes a throwaway peer
+// @Description  with a random key to verify that the interface can be modified (e.g. CAP_NET_ADMIN is present).
 // @Tags         health
 // @Produce      json
+// @Param        verbose  query  bool  false  "Include wgVersion, interface and listen port state in the response."
+// @Param        checkWrite  query  bool  false  "Also verify write capability (requires READY_ALLOW_WRITE_CHECK)."
 // @Success      200  {object}  domain.ReadinessResponse "Service is ready to handle requests."
 // @Failure      503  {object}  domain.ReadinessResponse "Service is not ready, e.g., WireGuard is inaccessible or command timed out."
 // @Router       /readyz [get]
-func HealthReadiness(repo repository.Repo) gin.HandlerFunc {
+func HealthReadiness(repo repository.Repo, opts ...ReadinessOption) gin.HandlerFunc {
 	if repo == nil {
 		// This is a programming error; repo should always be provided.
 		// Log fatal, as the readiness probe cannot function.
@@ -51,20 +136,43 @@ func HealthReadiness(repo repository.Repo) gin.HandlerFunc {
 		// }
 	}
 
+	cfg := newReadinessConfig(opts...)
+	return readinessProbe(repo, cfg, newReadinessCache(cfg.cacheTTL))
+}
+
+// checkReadiness runs the cacheable part of the readiness check against repo.
+func checkReadiness(ctx context.Context, repo repository.Repo, cfg readinessConfig) error {
+	// Attempt a lightweight operation to check WireGuard accessibility.
+	// ListConfigs is suitable as it performs a 'wg show dump'.
+	peers, err := repo.ListConfigs(ctx) // Timeout for this is handled by the repository's cmdTimeout.
+	if err == nil && cfg.requirePeers && len(peers) == 0 {
+		err = errNoPeers
+	}
+	return err
+}
+
+// readinessProbe is HealthReadiness with its check results stored in cache, so the router can
+// share them with the pre-flight of RejectWritesWhenNotReady.
+func readinessProbe(repo repository.Repo, cfg readinessConfig, cache *readinessCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Attempt a lightweight operation to check WireGuard accessibility.
-		// ListConfigs is suitable as it performs a 'wg show dump'.
-		_, err := repo.ListConfigs() // Timeout for this is handled by the repository's cmdTimeout.
+		err := cache.check(func() error { return checkReadiness(c.Request.Context(), repo, cfg) })
+		if checkWrite, _ := strconv.ParseBool(c.Query("checkWrite")); err == nil && checkWrite && cfg.allowWriteCheck {
+			err = checkWriteCapability(c.Request.Context(), repo)
+		}
 
 		if err != nil {
 			// If ListConfigs fails, the service is not ready.
-			logger.Logger.Warn("Readiness probe failed: Error connecting to or querying WireGuard.",
+			logger.Logger.Warn("Readiness probe failed: WireGuard is not accessible or not in the required state.",
 				zap.Error(err))
 
 			errMsg := "WireGuard utility is not accessible or responding."
 			// Provide more specific error message if it's a known type.
 			if errors.Is(err, repository.ErrWgTimeout) {
 				errMsg = "WireGuard command timed out during readiness check."
+			} else if errors.Is(err, errNoPeers) {
+				errMsg = "WireGuard interface has no peers."
+			} else if errors.Is(err, errWriteCheck) {
+				errMsg = "WireGuard interface cannot be modified."
 			} else if err.Error() != "" { // Use error from repo if it's not a timeout and not empty
 				errMsg = "WireGuard check failed: " + err.Error()
 			}
@@ -73,12 +181,73 @@ func HealthReadiness(repo repository.Repo) gin.HandlerFunc {
 				Status: "not ready",
 				Error:  errMsg,
 			}
+			if verbose, _ := strconv.ParseBool(c.Query("verbose")); verbose {
+				addReadinessDiagnostics(&response, repo, cfg)
+			}
 			c.JSON(http.StatusServiceUnavailable, response)
 			return
 		}
 
 		// If ListConfigs succeeds, WireGuard is accessible.
 		response := domain.ReadinessResponse{Status: "ready"}
+		if verbose, _ := strconv.ParseBool(c.Query("verbose")); verbose {
+			addReadinessDiagnostics(&response, repo, cfg)
+		}
 		c.JSON(http.StatusOK, response)
 	}
 }
+
+// listenPortOf returns the port to probe for cfg, or 0 when the probe is disabled.
+func listenPortOf(cfg readinessConfig) int {
+	if cfg.listenPort == nil {
+		return 0
+	}
+	return cfg.listenPort()
+}
+
+// addReadinessDiagnostics fills the verbose readiness fields: the listen port state if enabled,
+// and the interface details if the repository can describe its interface.
+// A failure to probe the port or read the version is logged but does not affect readiness.
+func addReadinessDiagnostics(response *domain.ReadinessResponse, repo repository.Repo, cfg readinessConfig) {
+	if port := listenPortOf(cfg); port > 0 {
+		response.ListenPort = port
+		bound, err := udpPortBound(port)
+		if err != nil {
+			logger.Logger.Warn("Readiness probe: failed to probe WireGuard listen port", zap.Int("listenPort", port), zap.Error(err))
+		} else {
+			response.ListenPortBound = &bound
+		}
+	}
+
+	describer, ok := repo.(repository.InterfaceDescriber)
+	if !ok {
+		return
+	}
+	response.Interface = describer.InterfaceName()
+	version, err := describer.WgVersion()
+	if err != nil {
+		logger.Logger.Warn("Readiness probe: failed to read wg version", zap.Error(err))
+		return
+	}
+	response.WgVersion = version
+}
+
+// checkWriteCapability adds a peer with a random public key and no allowed IPs, then removes it again.
+// The key is never handed out, so the peer cannot collide with or affect real peers.
+// The removal ignores cancellation of ctx, so a probe giving up midway does not leave the peer behind.
+func checkWriteCapability(ctx context.Context, repo repository.Repo) error {
+	raw := make([]byte, domain.KeyLen)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("%w: generating throwaway key: %v", errWriteCheck, err)
+	}
+	key := base64.StdEncoding.EncodeToString(raw)
+
+	if err := repo.CreateConfig(repository.ImmediateWrite(ctx), domain.Config{PublicKey: key}); err != nil {
+		return fmt.Errorf("%w: %w", errWriteCheck, err)
+	}
+	if err := repo.DeleteConfig(context.WithoutCancel(ctx), key); err != nil {
+		logger.Logger.Error("Readiness probe: failed to remove throwaway write check peer", zap.String("publicKey", key), zap.Error(err))
+		return fmt.Errorf("%w: %w", errWriteCheck, err)
+	}
+	return nil
+}
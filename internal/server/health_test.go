@@ -0,0 +1,272 @@
+// internal/server/health_test.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+func TestHealthReadiness_VerboseFields(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	fakeRepo := repository.NewFakeWGRepository()
+	fakeRepo.Iface = "wg_health_test"
+	fakeRepo.Version = "wireguard-tools v1.0.20210914 - https://git.zx2c4.com/wireguard-tools/"
+
+	r := gin.New()
+	r.GET("/readyz", HealthReadiness(fakeRepo))
+
+	probe := func(t *testing.T, url string) (domain.ReadinessResponse, map[string]interface{}) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp domain.ReadinessResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+		return resp, raw
+	}
+
+	t.Run("Default_is_minimal", func(t *testing.T) {
+		resp, raw := probe(t, "/readyz")
+		assert.Equal(t, "ready", resp.Status)
+		assert.NotContains(t, raw, "wgVersion")
+		assert.NotContains(t, raw, "interface")
+	})
+
+	t.Run("Verbose_includes_diagnostics", func(t *testing.T) {
+		resp, _ := probe(t, "/readyz?verbose=true")
+		assert.Equal(t, "ready", resp.Status)
+		assert.Equal(t, fakeRepo.Version, resp.WgVersion)
+		assert.Equal(t, fakeRepo.Iface, resp.Interface)
+	})
+}
+
+func TestHealthReadiness_VerboseListenPort(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	conn, err := net.ListenPacket("udp", ":0")
+	require.NoError(t, err)
+	defer conn.Close()
+	bound := conn.LocalAddr().(*net.UDPAddr).Port
+	port := bound - 1 // The port before a runtime change; not bound by this test
+
+	r := gin.New()
+	r.GET("/readyz", HealthReadiness(repository.NewFakeWGRepository(), CheckListenPort(func() int { return port })))
+	probe := func() domain.ReadinessResponse {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/readyz?verbose=true", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp domain.ReadinessResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	require.Equal(t, port, probe().ListenPort)
+
+	port = bound // As after POST /server/listen-port
+	resp := probe()
+	assert.Equal(t, bound, resp.ListenPort, "The probe should follow a port changed at runtime")
+	require.NotNil(t, resp.ListenPortBound)
+	assert.True(t, *resp.ListenPortBound)
+}
+
+func TestHealthReadiness_RequirePeers(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name           string
+		opts           []ReadinessOption
+		expectedStatus int
+		expectedState  string
+	}{
+		{name: "Default_zero_peers_is_ready", opts: nil, expectedStatus: http.StatusOK, expectedState: "ready"},
+		{name: "Required_zero_peers_is_not_ready", opts: []ReadinessOption{RequirePeers(true)}, expectedStatus: http.StatusServiceUnavailable, expectedState: "not ready"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/readyz", HealthReadiness(repository.NewFakeWGRepository(), tc.opts...)) // Empty peer list
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedStatus, w.Code)
+			var resp domain.ReadinessResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.expectedState, resp.Status)
+		})
+	}
+
+	t.Run("Required_with_peers_is_ready", func(t *testing.T) {
+		fakeRepo := repository.NewFakeWGRepository()
+		fakeRepo.Data["somePeer"] = domain.Config{PublicKey: "somePeer"}
+		r := gin.New()
+		r.GET("/readyz", HealthReadiness(fakeRepo, RequirePeers(true)))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+// setFailingRunner answers 'wg show dump' with an empty interface and fails every 'wg set' when failSet is true.
+type setFailingRunner struct {
+	failSet bool
+	sets    [][]string
+}
+
+func (r *setFailingRunner) Run(ctx context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	if len(args) > 0 && args[0] == "set" {
+		r.sets = append(r.sets, args)
+		if r.failSet {
+			return nil, []byte("Unable to modify interface: Operation not permitted"), errors.New("exit status 1")
+		}
+		return nil, nil, nil
+	}
+	return []byte("serverPriv\tserverPub\t51820\toff\n"), nil, nil
+}
+
+func TestHealthReadiness_CheckWrite(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name           string
+		failSet        bool
+		allowed        bool
+		expectedStatus int
+		expectedSets   int
+	}{
+		{name: "Write_success_is_ready", failSet: false, allowed: true, expectedStatus: http.StatusOK, expectedSets: 2},
+		{name: "Write_failure_is_not_ready", failSet: true, allowed: true, expectedStatus: http.StatusServiceUnavailable, expectedSets: 1},
+		{name: "Disabled_ignores_parameter", failSet: true, allowed: false, expectedStatus: http.StatusOK, expectedSets: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &setFailingRunner{failSet: tc.failSet}
+			repo := repository.NewWGRepository("wg_write_test", time.Second, repository.WithCommandRunner(runner))
+			r := gin.New()
+			r.GET("/readyz", HealthReadiness(repo, AllowWriteCheck(tc.allowed)))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/readyz?checkWrite=true", nil)
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedStatus, w.Code)
+			require.Len(t, runner.sets, tc.expectedSets)
+			if tc.expectedSets == 2 {
+				assert.Equal(t, runner.sets[0][3], runner.sets[1][3], "The same throwaway peer should be added and removed")
+				assert.Equal(t, "remove", runner.sets[1][len(runner.sets[1])-1])
+			}
+			if tc.expectedStatus == http.StatusServiceUnavailable {
+				var resp domain.ReadinessResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "WireGuard interface cannot be modified.", resp.Error)
+			}
+		})
+	}
+}
+
+// countingListRepo counts ListConfigs calls and optionally fails them.
+type countingListRepo struct {
+	repository.Repo
+	calls int
+	err   error
+}
+
+func (r *countingListRepo) ListConfigs(ctx context.Context) ([]domain.Config, error) {
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.Repo.ListConfigs(ctx)
+}
+
+func TestHealthReadiness_CachesResults(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	probe := func(r *gin.Engine) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Probes_within_window_reuse_result", func(t *testing.T) {
+		repo := &countingListRepo{Repo: repository.NewFakeWGRepository()}
+		r := gin.New()
+		r.GET("/readyz", HealthReadiness(repo, CacheReadiness(time.Minute)))
+
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, probe(r))
+		}
+		assert.Equal(t, 1, repo.calls, "Repeated probes within the window should not re-invoke the repository")
+	})
+
+	t.Run("Disabled_checks_every_probe", func(t *testing.T) {
+		repo := &countingListRepo{Repo: repository.NewFakeWGRepository()}
+		r := gin.New()
+		r.GET("/readyz", HealthReadiness(repo))
+
+		probe(r)
+		probe(r)
+		assert.Equal(t, 2, repo.calls)
+	})
+
+	t.Run("Failure_shortens_window", func(t *testing.T) {
+		cache := newReadinessCache(time.Second)
+		now := time.Unix(1700000000, 0)
+		cache.now = func() time.Time { return now }
+		calls := 0
+		failing := func() error { calls++; return errors.New("wg unavailable") }
+
+		require.Error(t, cache.check(failing))
+		now = now.Add(100 * time.Millisecond)
+		require.Error(t, cache.check(failing))
+		assert.Equal(t, 1, calls, "A failure is reused within the shortened window")
+
+		now = now.Add(200 * time.Millisecond) // 300ms after the failure: past ttl/4, within ttl
+		require.Error(t, cache.check(failing))
+		assert.Equal(t, 2, calls, "A failed result should be re-checked before the full window ends")
+	})
+
+	t.Run("Abandoned_check_not_cached", func(t *testing.T) {
+		repo := &countingListRepo{Repo: repository.NewFakeWGRepository(), err: fmt.Errorf("wg show wg0 dump: %w", context.Canceled)}
+		r := gin.New()
+		r.GET("/readyz", HealthReadiness(repo, CacheReadiness(time.Minute)))
+
+		assert.Equal(t, http.StatusServiceUnavailable, probe(r))
+		repo.err = nil
+		assert.Equal(t, http.StatusOK, probe(r), "A check cut short by its caller must not be reused")
+		assert.Equal(t, 2, repo.calls)
+	})
+}
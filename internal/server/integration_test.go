@@ -2,18 +2,29 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strconv" // Added for MTU test
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
 
 	"wgMicro_api/internal/config" // We'll use the Config struct directly
@@ -70,6 +81,7 @@ func setupIntegrationTestEnvironment(t *testing.T) (router *gin.Engine, repo rep
 		appConfig.DerivedKeyGenTimeout,
 		appConfig.ClientConfig.DNSServers,
 		appConfig.ClientConfig.MTU, // Pass MTU
+		service.WithKeyGenerator(service.NativeKeyGenerator{}),
 	)
 	cfgHandler := handler.NewConfigHandler(svc)
 	testRouter := NewRouter(cfgHandler, testRepo)
@@ -109,12 +121,12 @@ func TestIntegration_PeerLifecycle(t *testing.T) {
 	// 2. Get Peer
 	t.Run("GetPeer", func(t *testing.T) {
 		require.NotEmpty(t, createdPeer.PublicKey)
-		
+
 		getReqBody := domain.GetConfigRequest{
 			PublicKey: createdPeer.PublicKey,
 		}
 		bodyBytes, _ := json.Marshal(getReqBody)
-		
+
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest(http.MethodPost, "/configs/get", bytes.NewBuffer(bodyBytes))
 		req.Header.Set("Content-Type", "application/json")
@@ -163,12 +175,12 @@ func TestIntegration_PeerLifecycle(t *testing.T) {
 	// 4. Delete Peer
 	t.Run("DeletePeer", func(t *testing.T) {
 		require.NotEmpty(t, createdPeer.PublicKey)
-		
+
 		deleteReqBody := domain.DeleteConfigRequest{
 			PublicKey: createdPeer.PublicKey,
 		}
 		bodyBytes, _ := json.Marshal(deleteReqBody)
-		
+
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest(http.MethodPost, "/configs/delete", bytes.NewBuffer(bodyBytes))
 		req.Header.Set("Content-Type", "application/json")
@@ -180,7 +192,7 @@ func TestIntegration_PeerLifecycle(t *testing.T) {
 		// or use the repository.Repo interface methods.
 		// Here, we assume fakeRepoImpl is the concrete *repository.FakeWGRepository instance.
 		if concreteFakeRepo, ok := fakeRepoImpl.(*repository.FakeWGRepository); ok { // Type assertion
-			_, err := concreteFakeRepo.GetConfig(createdPeer.PublicKey)
+			_, err := concreteFakeRepo.GetConfig(context.Background(), createdPeer.PublicKey)
 			assert.Error(t, err, "DeletePeer: peer should no longer be found in the repository after deletion") // Adjusted for FakeWGRepository's GetConfig
 			// Depending on FakeWGRepository's GetConfig error for not found, you might need:
 			// assert.EqualError(t, err, "not found", "Expected 'not found' error from FakeWGRepository")
@@ -194,3 +206,1025 @@ func TestIntegration_PeerLifecycle(t *testing.T) {
 		}
 	})
 }
+
+// bulkRotate sends POST /configs/bulk-rotate and decodes the response.
+func bulkRotate(t *testing.T, router *gin.Engine, publicKeys []string) (int, domain.BulkRotateResponse) {
+	t.Helper()
+	bodyBytes, err := json.Marshal(domain.BulkRotateRequest{PublicKeys: publicKeys})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs/bulk-rotate", bytes.NewBuffer(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), "Bulk rotate response must not be cached")
+	var resp domain.BulkRotateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestIntegration_BulkRotate_AllSuccess(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	oldKeys := []string{"bulkRotatePeerOneAAAAAAAAAAAAAAAAAAAAAAAAAA=", "bulkRotatePeerTwoAAAAAAAAAAAAAAAAAAAAAAAAAA="}
+	for i, key := range oldKeys {
+		fakeRepo.Data[key] = domain.Config{PublicKey: key, AllowedIps: []string{fmt.Sprintf("10.100.1.%d/32", i+2)}}
+	}
+
+	status, resp := bulkRotate(t, router, oldKeys)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, resp.Results, len(oldKeys))
+
+	for i, result := range resp.Results {
+		assert.Equal(t, oldKeys[i], result.PublicKey)
+		assert.Empty(t, result.Error)
+		require.NotNil(t, result.NewConfig)
+		assert.NotEmpty(t, result.NewConfig.PrivateKey, "New private key must be returned")
+		assert.Equal(t, []string{fmt.Sprintf("10.100.1.%d/32", i+2)}, result.NewConfig.AllowedIps, "AllowedIPs should be preserved")
+
+		_, err := fakeRepo.GetConfig(context.Background(), oldKeys[i])
+		assert.ErrorIs(t, err, repository.ErrPeerNotFound, "Old key should be gone")
+		_, err = fakeRepo.GetConfig(context.Background(), result.NewConfig.PublicKey)
+		assert.NoError(t, err, "New key should be present")
+	}
+	assert.Len(t, fakeRepo.Data, len(oldKeys))
+}
+
+func TestIntegration_BulkRotate_PartialFailure(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	existingKey := "bulkRotateExistingPeerAAAAAAAAAAAAAAAAAAAAA="
+	missingKey := "bulkRotateMissingPeerAAAAAAAAAAAAAAAAAAAAAA="
+	fakeRepo.Data[existingKey] = domain.Config{PublicKey: existingKey, AllowedIps: []string{"10.100.2.2/32"}}
+
+	status, resp := bulkRotate(t, router, []string{existingKey, missingKey})
+	require.Equal(t, http.StatusMultiStatus, status)
+	require.Len(t, resp.Results, 2)
+
+	ok, failed := resp.Results[0], resp.Results[1]
+	require.NotNil(t, ok.NewConfig)
+	assert.Empty(t, ok.Error)
+	_, err := fakeRepo.GetConfig(context.Background(), existingKey)
+	assert.ErrorIs(t, err, repository.ErrPeerNotFound, "Old key should be gone")
+	_, err = fakeRepo.GetConfig(context.Background(), ok.NewConfig.PublicKey)
+	assert.NoError(t, err, "New key should be present")
+
+	assert.Equal(t, missingKey, failed.PublicKey)
+	assert.Nil(t, failed.NewConfig)
+	assert.Contains(t, failed.Error, "peer not found")
+}
+
+func TestIntegration_BulkRotatePresharedKeys(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	oldPSK := base64.StdEncoding.EncodeToString(make([]byte, domain.KeyLen))
+	keys := []string{
+		"bulkPSKPeerOneAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+		"bulkPSKPeerTwoAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+		"bulkPSKPeerThreeAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+	}
+	for i, key := range keys {
+		fakeRepo.Data[key] = domain.Config{PublicKey: key, PreSharedKey: oldPSK, AllowedIps: []string{fmt.Sprintf("10.100.4.%d/32", i+2)}, PersistentKeepalive: 25}
+	}
+
+	rotate := func() (int, domain.BulkRotatePSKResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/configs/bulk-rotate-psk", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), "The response carries preshared keys")
+		var resp domain.BulkRotatePSKResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	status, resp := rotate()
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Failed)
+	require.Len(t, resp.PresharedKeys, len(keys))
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		psk := resp.PresharedKeys[key]
+		assert.True(t, domain.IsValidKey(psk), "New preshared key should be a WireGuard key")
+		assert.NotEqual(t, oldPSK, psk)
+		assert.False(t, seen[psk], "Every peer should get a distinct preshared key")
+		seen[psk] = true
+
+		peer := fakeRepo.Data[key]
+		assert.Equal(t, psk, peer.PreSharedKey, "The new key should be applied")
+		assert.Equal(t, []string{fmt.Sprintf("10.100.4.%d/32", i+2)}, peer.AllowedIps)
+		assert.Equal(t, 25, peer.PersistentKeepalive)
+	}
+	assert.Len(t, fakeRepo.Data, len(keys), "No peers should be added or removed")
+
+	fakeRepo.Data["notAWireGuardKey"] = domain.Config{PublicKey: "notAWireGuardKey"}
+	status, resp = rotate()
+	require.Equal(t, http.StatusMultiStatus, status)
+	assert.Len(t, resp.PresharedKeys, len(keys))
+	assert.Contains(t, resp.Failed["notAWireGuardKey"], "not a valid WireGuard key")
+}
+
+// scrapeCounter fetches /metrics and returns the current value of the named unlabelled counter.
+func scrapeCounter(t *testing.T, router *gin.Engine, name string) float64 {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, name+" "); ok {
+			f, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err, "Metric %s has a non-numeric value", name)
+			return f
+		}
+	}
+	t.Fatalf("Metric %s not found in /metrics output", name)
+	return 0
+}
+
+// rotatePeer sends POST /configs/rotate and returns the status code.
+func rotatePeer(t *testing.T, router *gin.Engine, publicKey string) int {
+	t.Helper()
+	bodyBytes, err := json.Marshal(domain.RotatePeerRequest{PublicKey: publicKey})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs/rotate", bytes.NewBuffer(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestIntegration_RotationMetrics(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	// Counters live in the process-wide registry, so compare deltas rather than absolute values.
+	rotationsBefore := scrapeCounter(t, router, "wg_rotations_total")
+	failuresBefore := scrapeCounter(t, router, "wg_rotation_failures_total")
+	deleteFailuresBefore := scrapeCounter(t, router, "wg_rotation_old_peer_delete_failures_total")
+
+	fakeRepo.Data["metricsRotatePeerAAAAAAAAAAAAAAAAAAAAAAAAAA="] = domain.Config{PublicKey: "metricsRotatePeerAAAAAAAAAAAAAAAAAAAAAAAAAA=", AllowedIps: []string{"10.100.3.2/32"}}
+	require.Equal(t, http.StatusOK, rotatePeer(t, router, "metricsRotatePeerAAAAAAAAAAAAAAAAAAAAAAAAAA="))
+	assert.Equal(t, rotationsBefore+1, scrapeCounter(t, router, "wg_rotations_total"))
+	assert.Equal(t, failuresBefore, scrapeCounter(t, router, "wg_rotation_failures_total"), "Successful rotation must not count as a failure")
+
+	require.Equal(t, http.StatusNotFound, rotatePeer(t, router, "metricsMissingPeerAAAAAAAAAAAAAAAAAAAAAAAAA="))
+	assert.Equal(t, rotationsBefore+2, scrapeCounter(t, router, "wg_rotations_total"))
+	assert.Equal(t, failuresBefore+1, scrapeCounter(t, router, "wg_rotation_failures_total"))
+	assert.Equal(t, deleteFailuresBefore, scrapeCounter(t, router, "wg_rotation_old_peer_delete_failures_total"))
+}
+
+func TestIntegration_PathKeyRoutes(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	// A well-formed key containing '/' and '+', which must be sent as %2F and %2B.
+	existingKey := "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg="
+	missingKey := "BDFTfugHHNOHfPC3B4NSGfRmNE4zs+ZXM2ikT8//RUU="
+	fakeRepo.Data[existingKey] = domain.Config{PublicKey: existingKey, AllowedIps: []string{"10.100.4.2/32"}}
+
+	do := func(method, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/configs/"+url.PathEscape(key), nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Encoded_valid_key", func(t *testing.T) {
+		w := do(http.MethodGet, existingKey)
+		require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+		var cfg domain.Config
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cfg))
+		assert.Equal(t, existingKey, cfg.PublicKey)
+	})
+
+	t.Run("Malformed_key_is_400", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodDelete} {
+			w := do(method, "not-a-wireguard-key")
+			assert.Equal(t, http.StatusBadRequest, w.Code, method)
+		}
+	})
+
+	t.Run("Valid_missing_key_is_404", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodDelete} {
+			w := do(method, missingKey)
+			assert.Equal(t, http.StatusNotFound, w.Code, method)
+		}
+	})
+
+	t.Run("Delete_existing_key", func(t *testing.T) {
+		w := do(http.MethodDelete, existingKey)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.NotContains(t, fakeRepo.Data, existingKey)
+	})
+}
+
+func TestIntegration_ImportMalformedPublicKey(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+
+	body, err := json.Marshal(domain.CreatePeerRequest{PublicKey: "not-a-wireguard-key", AllowedIps: []string{"10.100.6.2/32"}})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Body: %s", w.Body.String())
+	assert.Empty(t, repo.(*repository.FakeWGRepository).Data, "A malformed key must not reach the repository")
+}
+
+func TestIntegration_PeerIDLookup(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	key := "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg="
+	fakeRepo.Data[key] = domain.Config{PublicKey: key, AllowedIps: []string{"10.100.5.2/32"}}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/configs")
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	peerID := listed[0].PeerID
+	require.Len(t, peerID, 16, "List responses should include the peer ID")
+
+	w = get("/configs/by-id/" + peerID)
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	var found domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &found))
+	assert.Equal(t, key, found.PublicKey, "The peer ID should resolve back to the peer")
+	assert.Equal(t, peerID, found.PeerID)
+
+	assert.Equal(t, http.StatusNotFound, get("/configs/by-id/aaaaaaaaaaaaaaaa").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/configs/by-id/not-an-id").Code)
+	assert.Equal(t, http.StatusOK, get("/configs/by-ip?ip=10.100.5.2").Code, "Static /configs routes must still match")
+}
+
+func TestIntegration_PatchPeer(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	key := "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg="
+	fakeRepo.Data[key] = domain.Config{PublicKey: key, AllowedIps: []string{"10.100.6.2/32"}, PersistentKeepalive: 25}
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPatch, "/configs/"+url.PathEscape(key), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch(`[{"op":"replace","path":"/persistentKeepalive","value":10},{"op":"add","path":"/allowedIps/-","value":"10.100.7.0/24"}]`)
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	var patched domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &patched))
+	assert.Equal(t, 10, patched.PersistentKeepalive)
+	assert.Equal(t, []string{"10.100.6.2/32", "10.100.7.0/24"}, patched.AllowedIps)
+	assert.Equal(t, patched.AllowedIps, fakeRepo.Data[key].AllowedIps)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, patch(`[{"op":"replace","path":"/publicKey","value":"x"}]`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch(`{"op":"replace","path":"/persistentKeepalive","value":5}`).Code, "The body must be an array")
+	assert.Equal(t, http.StatusBadRequest, patch(`[{"op":"remove","path":"/allowedIps"}]`).Code, "Clearing AllowedIPs needs confirmation")
+	assert.Equal(t, http.StatusConflict, patch(`[{"op":"test","path":"/persistentKeepalive","value":25}]`).Code)
+	assert.Equal(t, 10, fakeRepo.Data[key].PersistentKeepalive)
+}
+
+func TestIntegration_FullConfigByKey(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	peerKey := "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg="
+	clientPrivateKey := "BDFTfugHHNOHfPC3B4NSGfRmNE4zs+ZXM2ikT8//RUU="
+	fakeRepo.Data[peerKey] = domain.Config{PublicKey: peerKey, AllowedIps: []string{"10.100.5.2/32"}, PersistentKeepalive: 25}
+
+	body, err := json.Marshal(domain.FullConfigRequest{ClientPrivateKey: clientPrivateKey})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/configs/"+url.PathEscape(peerKey)+"/full", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var resp domain.FullConfigResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	assert.Equal(t, peerKey, resp.Config.PublicKey)
+	assert.Equal(t, []string{"10.100.5.2/32"}, resp.Config.AllowedIps)
+	require.NotEmpty(t, resp.Conf)
+	assert.Contains(t, resp.Conf, "PrivateKey = "+clientPrivateKey)
+	assert.Contains(t, resp.Conf, "Address = "+strings.Join(resp.Config.AllowedIps, ", "), "The .conf should describe the returned peer")
+	assert.Contains(t, resp.Conf, fmt.Sprintf("PersistentKeepalive = %d", resp.Config.PersistentKeepalive))
+	assert.True(t, strings.HasSuffix(resp.Filename, ".conf"))
+}
+
+func TestIntegration_CreateAndRotateIncludeServer(t *testing.T) {
+	router, _, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+
+	post := func(target string, body interface{}) map[string]interface{} {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, target, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Less(t, w.Code, 300, "Body: %s", w.Body.String())
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+		return raw
+	}
+	wantServer := map[string]interface{}{
+		"publicKey": testIntegrationServerPublicKey,
+		"endpoint":  "integration.test.vpn:51820",
+	}
+
+	created := post("/configs", domain.CreatePeerRequest{AllowedIps: []string{"10.100.7.2/32"}})
+	assert.Equal(t, wantServer, created["server"])
+
+	rotated := post("/configs/rotate", domain.RotatePeerRequest{PublicKey: created["publicKey"].(string)})
+	assert.Equal(t, wantServer, rotated["server"])
+}
+
+func TestIntegration_PeerSummary(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	now := time.Now()
+	fakeRepo.Data["onlinePeer"] = domain.Config{PublicKey: "onlinePeer", LatestHandshake: now.Add(-30 * time.Second).Unix()}
+	fakeRepo.Data["offlinePeer"] = domain.Config{PublicKey: "offlinePeer", LatestHandshake: now.Add(-time.Hour).Unix()}
+	fakeRepo.Data["newPeerOne"] = domain.Config{PublicKey: "newPeerOne"}
+	fakeRepo.Data["newPeerTwo"] = domain.Config{PublicKey: "newPeerTwo"}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/configs/summary", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	var summary domain.PeerSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, domain.PeerSummary{Total: 4, Online: 1, Offline: 1, NeverConnected: 2}, summary)
+}
+
+func TestIntegration_PeerTags(t *testing.T) {
+	router, _, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+
+	do := func(method, target string, body interface{}) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			b, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = bytes.NewReader(b)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, target, reader)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(ip string, tags ...string) domain.Config {
+		w := do(http.MethodPost, "/configs", domain.CreatePeerRequest{AllowedIps: []string{ip}, Tags: tags})
+		require.Equal(t, http.StatusCreated, w.Code, "Body: %s", w.Body.String())
+		var created domain.Config
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		require.NotNil(t, created.Metadata)
+		assert.Equal(t, tags, created.Metadata.Tags, "The create response should carry the tags")
+		return created
+	}
+	opsStaging := create("10.100.6.2/32", "env:staging", "team:ops")
+	create("10.100.6.3/32", "env:prod", "team:ops")
+	devStaging := create("10.100.6.4/32", "env:staging", "team:dev")
+
+	w := do(http.MethodGet, "/configs/"+url.PathEscape(opsStaging.PublicKey), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var fetched domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	require.NotNil(t, fetched.Metadata)
+	assert.Equal(t, []string{"env:staging", "team:ops"}, fetched.Metadata.Tags)
+
+	list := func(query string) []string {
+		w := do(http.MethodGet, "/configs?"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+		var configs []domain.Config
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &configs))
+		keys := make([]string, 0, len(configs))
+		for _, cfg := range configs {
+			keys = append(keys, cfg.PublicKey)
+		}
+		return keys
+	}
+	assert.ElementsMatch(t, []string{opsStaging.PublicKey, devStaging.PublicKey}, list("tag=env:staging"))
+	assert.Equal(t, []string{opsStaging.PublicKey}, list("tag=env:staging&tag=team:ops"), "Repeated tags should be ANDed")
+	assert.Empty(t, list("tag=env:staging&tag=team:qa"))
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/configs?tag=env:staging&name=alice", nil).Code)
+}
+
+func TestIntegration_PreviewMatchesRealGeneration(t *testing.T) {
+	router, repo, cleanup := setupIntegrationTestEnvironment(t)
+	defer cleanup()
+	fakeRepo := repo.(*repository.FakeWGRepository)
+
+	privKey, pubKey, err := service.NativeKeyGenerator{}.GenerateKeyPair()
+	require.NoError(t, err)
+	psk, err := service.NativeKeyGenerator{}.GeneratePSK()
+	require.NoError(t, err)
+	post := func(path string, payload interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/configs/preview", domain.PreviewConfigRequest{
+		PublicKey:           pubKey,
+		PrivateKey:          privKey,
+		AllowedIps:          []string{"10.100.6.2/32"},
+		PresharedKey:        psk,
+		PersistentKeepalive: 25,
+	})
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var preview domain.PreviewConfigResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.Empty(t, fakeRepo.Data, "A preview must not register the peer")
+
+	// Register the same peer and generate its file for real.
+	fakeRepo.Data[pubKey] = domain.Config{PublicKey: pubKey, AllowedIps: []string{"10.100.6.2/32"}, PreSharedKey: psk, PersistentKeepalive: 25}
+	w = post("/configs/client-file", domain.ClientFileRequest{ClientPublicKey: pubKey, ClientPrivateKey: privKey})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, w.Body.String(), preview.Conf, "The preview should be exactly the file generated once the peer exists")
+
+	t.Run("Overrides_and_validation", func(t *testing.T) {
+		noDNS, mtu := "", 1280
+		w := post("/configs/preview", domain.PreviewConfigRequest{PublicKey: pubKey, PrivateKey: privKey, AllowedIps: []string{"10.100.6.3"}, DNS: &noDNS, MTU: &mtu})
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp domain.PreviewConfigResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.NotContains(t, resp.Conf, "DNS =")
+		assert.Contains(t, resp.Conf, "MTU = 1280")
+		assert.Contains(t, resp.Conf, "Address = 10.100.6.3/32", "AllowedIPs are normalized as on creation")
+
+		assert.Equal(t, http.StatusBadRequest, post("/configs/preview", domain.PreviewConfigRequest{PublicKey: "not-a-key", PrivateKey: privKey}).Code)
+		assert.Equal(t, http.StatusBadRequest, post("/configs/preview", domain.PreviewConfigRequest{PublicKey: pubKey, PrivateKey: privKey, AllowedIps: []string{"not-an-ip"}}).Code)
+	})
+}
+
+// failingListRepo wraps a Repo and makes ListConfigs fail with err while it is set.
+type failingListRepo struct {
+	repository.Repo
+	err error
+}
+
+func (r *failingListRepo) ListConfigs(ctx context.Context) ([]domain.Config, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.Repo.ListConfigs(ctx)
+}
+
+func TestIntegration_CollectorErrorSurfaces(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := &failingListRepo{Repo: repository.NewFakeWGRepository(), err: errors.New("simulated dump failure")}
+	collector := service.NewStatsCollector(repo, time.Minute)
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+		service.WithKeyGenerator(service.NativeKeyGenerator{}))
+	router := NewRouter(handler.NewConfigHandler(svc), repo, WithStatsHandler(handler.NewStatsHandler(collector)))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Error(t, collector.Collect(context.Background()))
+	require.Error(t, collector.Collect(context.Background()))
+
+	w := get("/server/collector")
+	require.Equal(t, http.StatusOK, w.Code)
+	var status domain.CollectorStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "simulated dump failure", status.LastError)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.True(t, status.Stale)
+	assert.Nil(t, status.LastSuccessAt)
+
+	assert.Equal(t, http.StatusServiceUnavailable, get("/stats").Code, "No stats before the first successful collection")
+
+	repo.err = nil
+	require.NoError(t, collector.Collect(context.Background()))
+	assert.Equal(t, http.StatusOK, get("/stats").Code)
+}
+
+func TestIntegration_ServerPublicKeyPlainText(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+		service.WithKeyGenerator(service.NativeKeyGenerator{}))
+	serverHandler := handler.NewServerHandler(domain.ServerInfo{Interface: "wg0", PublicKey: testIntegrationServerPublicKey})
+	router := NewRouter(handler.NewConfigHandler(svc), repo, WithServerHandler(serverHandler))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/server/publickey", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+	assert.Equal(t, testIntegrationServerPublicKey, w.Body.String(), "Body should be the bare key, without JSON or whitespace")
+}
+
+// recordingRunner records WireGuard commands and answers all of them successfully.
+type recordingRunner struct {
+	calls [][]string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	r.calls = append(r.calls, args)
+	return nil, nil, nil
+}
+
+func TestIntegration_SetListenPort(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	const adminToken = "integration-admin-token"
+	runner := &recordingRunner{}
+	repo := repository.NewWGRepository("wg_port_test", time.Second, repository.WithCommandRunner(runner))
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	serverHandler := handler.NewServerHandler(domain.ServerInfo{Interface: "wg_port_test", Endpoint: "integration.test.vpn:51820", ListenPort: 51820},
+		handler.WithListenPortChanger(svc))
+	router := NewRouter(handler.NewConfigHandler(svc), repo,
+		WithServerHandler(serverHandler),
+		WithAdminHandler(handler.NewAdminHandler(repo, nil, nil), adminToken))
+
+	setPort := func(body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/server/listen-port", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Valid_port_applied", func(t *testing.T) {
+		w := setPort(`{"port":51821}`, adminToken)
+		require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+		assert.JSONEq(t, `{"listenPort":51821,"endpoint":"integration.test.vpn:51821"}`, w.Body.String())
+		assert.Equal(t, [][]string{{"set", "wg_port_test", "listen-port", "51821"}}, runner.calls)
+		assert.Equal(t, "integration.test.vpn:51821", svc.ServerIdentity().Endpoint, "Client configs should use the new port")
+
+		w = httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/server", nil)
+		router.ServeHTTP(w, req)
+		var info domain.ServerInfo
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+		assert.Equal(t, 51821, info.ListenPort)
+		assert.Equal(t, "integration.test.vpn:51821", info.Endpoint)
+	})
+
+	t.Run("Out_of_range_rejected", func(t *testing.T) {
+		runner.calls = nil
+		for _, body := range []string{`{"port":70000}`, `{"port":-1}`} {
+			assert.Equal(t, http.StatusUnprocessableEntity, setPort(body, adminToken).Code, "body %s", body)
+		}
+		for _, body := range []string{`{"port":0}`, `{}`} {
+			assert.Equal(t, http.StatusBadRequest, setPort(body, adminToken).Code, "A missing port is a malformed body: %s", body)
+		}
+		assert.Empty(t, runner.calls, "Rejected ports must not reach the interface")
+	})
+
+	t.Run("Requires_admin_token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, setPort(`{"port":51822}`, "").Code)
+	})
+}
+
+func TestIntegration_ServerName(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	const name = "eu-west-1"
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	serverHandler := handler.NewServerHandler(domain.ServerInfo{
+		Interface:   "wg0",
+		Name:        name,
+		Description: "Frankfurt office gateway",
+		PublicKey:   testIntegrationServerPublicKey,
+	})
+	get := func(router *gin.Engine, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	router := NewRouter(handler.NewConfigHandler(svc), repo, WithServerHandler(serverHandler), WithServerNameHeader(name))
+	w := get(router, "/server")
+	require.Equal(t, http.StatusOK, w.Code)
+	var info domain.ServerInfo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, name, info.Name)
+	assert.Equal(t, "Frankfurt office gateway", info.Description)
+	assert.Equal(t, name, w.Header().Get("X-Server-Name"))
+	assert.Equal(t, name, get(router, "/healthz").Header().Get("X-Server-Name"), "The header is sent on every response")
+
+	plain := NewRouter(handler.NewConfigHandler(svc), repo, WithServerHandler(serverHandler))
+	assert.Empty(t, get(plain, "/server").Header().Get("X-Server-Name"), "The header is opt-in")
+}
+
+func TestIntegration_AdminRefreshPicksUpOutOfBandChanges(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	const adminToken = "integration-admin-token"
+	metadataPath := filepath.Join(t.TempDir(), "metadata.json")
+	metadataStore, err := repository.NewFileMetadataStore(metadataPath)
+	require.NoError(t, err)
+
+	repo := repository.NewFakeWGRepository()
+	collector := service.NewStatsCollector(repo, time.Minute)
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+		service.WithKeyGenerator(service.NativeKeyGenerator{}),
+		service.WithMetadataStore(metadataStore))
+	gauges := &resetCounter{}
+	router := NewRouter(handler.NewConfigHandler(svc), repo,
+		WithStatsHandler(handler.NewStatsHandler(collector)),
+		WithReadinessOptions(RequirePeers(true), CacheReadiness(time.Hour)),
+		WithAdminHandler(handler.NewAdminHandler(repo, metadataStore, collector, handler.WithCacheResetters(gauges)), adminToken))
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	statsPeerCount := func() int {
+		w := do(http.MethodGet, "/stats", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var stats domain.InterfaceStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		return stats.PeerCount
+	}
+
+	require.NoError(t, collector.Collect(context.Background()))
+	require.Equal(t, 0, statsPeerCount())
+	require.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/readyz", "").Code, "No peers yet; the failure is cached")
+
+	// Out-of-band changes: a peer added with 'wg' directly and a hand-edited metadata file.
+	repo.Data["outOfBandPeer"] = domain.Config{PublicKey: "outOfBandPeer", AllowedIps: []string{"10.0.0.9/32"}}
+	require.NoError(t, os.WriteFile(metadataPath, []byte(`{"outOfBandPeer":{"name":"edited-by-hand"}}`), 0o600))
+	assert.Equal(t, 0, statsPeerCount(), "Cached stats should not see the change before a refresh")
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/readyz", "").Code, "The cached readiness result is reused before a refresh")
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/admin/refresh", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/admin/refresh", "wrong-token").Code)
+
+	w := do(http.MethodPost, "/admin/refresh", adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp domain.RefreshResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, domain.RefreshResponse{Peers: 1, MetadataReloaded: true, StatsRefreshed: true}, resp)
+
+	assert.Equal(t, 1, statsPeerCount(), "Stats should reflect the out-of-band peer after a refresh")
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/readyz", "").Code, "A refresh should drop the cached readiness result")
+	assert.Equal(t, 1, gauges.resets, "A refresh should reset the registered caches")
+	w = do(http.MethodGet, "/configs?name=edited", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var named []domain.Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &named))
+	require.Len(t, named, 1, "Reloaded metadata should be visible to peer lookups")
+	assert.Equal(t, "outOfBandPeer", named[0].PublicKey)
+}
+
+func TestIntegration_AdminPruneInactive(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	const adminToken = "integration-admin-token"
+	metadataStore, err := repository.NewFileMetadataStore("")
+	require.NoError(t, err)
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+		service.WithMetadataStore(metadataStore))
+	router := NewRouter(handler.NewConfigHandler(svc), repo,
+		WithAdminHandler(handler.NewAdminHandler(repo, metadataStore, nil, handler.WithInactivePruner(svc)), adminToken))
+
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	for _, cfg := range []domain.Config{
+		{PublicKey: "neverConnectedPeerAAAAAAAAAAAAAAAAAAAAAAAAA=", AllowedIps: []string{"10.0.0.2/32"}},
+		{PublicKey: "connectedPeer", AllowedIps: []string{"10.0.0.3/32"}, LatestHandshake: time.Now().Unix(), ReceiveBytes: 4096, TransmitBytes: 2048},
+	} {
+		repo.Data[cfg.PublicKey] = cfg
+		require.NoError(t, metadataStore.Set(cfg.PublicKey, domain.PeerMetadata{CreatedAt: old}))
+	}
+
+	prune := func(query, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/admin/prune-inactive"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, prune("", `{"olderThan":"7d"}`).Code, "Pruning must be confirmed")
+	assert.Equal(t, http.StatusBadRequest, prune("?confirm=true", `{"olderThan":"0s"}`).Code, "A zero age would prune every never-connected peer")
+	assert.Len(t, repo.Data, 2)
+
+	w := prune("?confirm=true", `{"olderThan":"7d"}`)
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	var resp domain.PruneInactiveResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"neverConnectedPeerAAAAAAAAAAAAAAAAAAAAAAAAA="}, resp.Pruned)
+	assert.NotContains(t, repo.Data, "neverConnectedPeerAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	assert.Contains(t, repo.Data, "connectedPeer")
+}
+
+func TestIntegration_MaintenanceMode(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	const adminToken = "integration-admin-token"
+	repo := repository.NewFakeWGRepository()
+	repo.Data["existingPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="] = domain.Config{PublicKey: "existingPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", AllowedIps: []string{"10.0.0.2/32"}}
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+		service.WithKeyGenerator(service.NativeKeyGenerator{}))
+	maintenance := NewMaintenance(true) // As with MAINTENANCE_MODE=true
+	router := NewRouter(handler.NewConfigHandler(svc), repo,
+		WithMaintenance(maintenance),
+		WithAdminHandler(handler.NewAdminHandler(repo, nil, nil, handler.WithMaintenanceSwitch(maintenance), handler.WithInactivePruner(svc)), adminToken))
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func() *httptest.ResponseRecorder {
+		return do(http.MethodPost, "/configs", `{"allowed_ips":["10.0.0.3/32"]}`, "")
+	}
+
+	w := create()
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "maintenance mode")
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodDelete, "/configs/existingPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "", "").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodPost, "/configs/rotate", `{"public_key":"existingPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`, "").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodPost, "/admin/prune-inactive?confirm=true", `{"olderThan":"1h"}`, adminToken).Code)
+	assert.Len(t, repo.Data, 1, "Nothing should change in maintenance mode")
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/configs", "", "").Code, "Reads stay available")
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/configs/summary", "", "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/configs/get", `{"public_key":"existingPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`, "").Code, "POST reads stay available")
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/healthz", "", "").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/admin/maintenance", `{"enabled":false}`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/maintenance", `{}`, adminToken).Code)
+	w = do(http.MethodPost, "/admin/maintenance", `{"enabled":false}`, adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"enabled":false}`, w.Body.String())
+
+	assert.Equal(t, http.StatusCreated, create().Code, "Changes are accepted again after maintenance")
+}
+
+func TestIntegration_RejectWritesWhenNotReady(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	build := func(listErr error, opts ...ReadinessOption) (*gin.Engine, *repository.FakeWGRepository) {
+		fake := repository.NewFakeWGRepository()
+		repo := &countingListRepo{Repo: fake, err: listErr}
+		svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+			service.WithKeyGenerator(service.NativeKeyGenerator{}))
+		return NewRouter(handler.NewConfigHandler(svc), repo, WithReadinessOptions(opts...)), fake
+	}
+	do := func(router *gin.Engine, method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	const createBody = `{"allowed_ips":["10.0.0.3/32"]}`
+	wgDown := errors.New("Unable to access interface: No such device")
+
+	t.Run("Failed_readiness_short_circuits_create", func(t *testing.T) {
+		router, fake := build(wgDown, CacheReadiness(time.Minute), RejectWritesWhenNotReady(true))
+		assert.Equal(t, http.StatusCreated, do(router, http.MethodPost, "/configs", createBody).Code, "Before any failed probe, writes are attempted")
+
+		require.Equal(t, http.StatusServiceUnavailable, do(router, http.MethodGet, "/readyz", "").Code)
+		w := do(router, http.MethodPost, "/configs", `{"allowed_ips":["10.0.0.4/32"]}`)
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "not ready")
+		assert.Equal(t, "15", w.Header().Get("Retry-After"), "A failure is cached for a quarter of the window")
+		assert.Len(t, fake.Data, 1, "The create must not reach the repository")
+	})
+
+	t.Run("Disabled_attempts_the_write", func(t *testing.T) {
+		router, fake := build(wgDown, CacheReadiness(time.Minute))
+		require.Equal(t, http.StatusServiceUnavailable, do(router, http.MethodGet, "/readyz", "").Code)
+		assert.Equal(t, http.StatusCreated, do(router, http.MethodPost, "/configs", createBody).Code)
+		assert.Len(t, fake.Data, 1)
+	})
+
+	t.Run("Missing_peers_do_not_block_writes", func(t *testing.T) {
+		router, fake := build(nil, CacheReadiness(time.Minute), RequirePeers(true), RejectWritesWhenNotReady(true))
+		require.Equal(t, http.StatusServiceUnavailable, do(router, http.MethodGet, "/readyz", "").Code)
+		assert.Equal(t, http.StatusCreated, do(router, http.MethodPost, "/configs", createBody).Code, "Adding a peer is how an empty interface becomes ready")
+		assert.Len(t, fake.Data, 1)
+	})
+
+	t.Run("Each_interface_is_judged_on_its_own_readiness", func(t *testing.T) {
+		newService := func(listErr error) (*service.ConfigService, repository.Repo, *repository.FakeWGRepository) {
+			fake := repository.NewFakeWGRepository()
+			repo := &countingListRepo{Repo: fake, err: listErr}
+			return service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+				service.WithKeyGenerator(service.NativeKeyGenerator{})), repo, fake
+		}
+		wg0, wg0Repo, wg0Fake := newService(wgDown)
+		wg1, wg1Repo, wg1Fake := newService(nil)
+		wg2, wg2Repo, wg2Fake := newService(wgDown)
+		router := NewRouter(handler.NewConfigHandler(wg0, handler.WithInterfaceServices(map[string]handler.ServiceInterface{
+			"wg0": wg0, "wg1": wg1, "wg2": wg2,
+		})), wg0Repo,
+			WithInterfaceRepos(map[string]repository.Repo{"wg1": wg1Repo, "wg2": wg2Repo}),
+			WithReadinessOptions(CacheReadiness(time.Minute), RejectWritesWhenNotReady(true)))
+		create := func(iface string) int {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/configs", strings.NewReader(createBody))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(handler.InterfaceHeader, iface)
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		require.Equal(t, http.StatusServiceUnavailable, do(router, http.MethodGet, "/readyz", "").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, create("wg0"), "Naming the default interface uses the probe's result")
+		assert.Equal(t, http.StatusCreated, create("wg1"), "A failing default interface does not block a healthy one")
+		assert.Equal(t, http.StatusServiceUnavailable, create("wg2"), "A broken additional interface is checked without a probe")
+		assert.Empty(t, wg0Fake.Data)
+		assert.Len(t, wg1Fake.Data, 1)
+		assert.Empty(t, wg2Fake.Data)
+	})
+}
+
+func TestIntegration_AdminRoutesDisabledWithoutToken(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	router := NewRouter(handler.NewConfigHandler(svc), repo, WithAdminHandler(handler.NewAdminHandler(repo, nil, nil), ""))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/admin/refresh", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestIntegration_AdminLogStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buf := logger.NewRingBuffer(16)
+	previous := logger.Logger
+	logger.Logger = zap.New(buf.Core(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.InfoLevel))
+	t.Cleanup(func() { logger.Logger = previous })
+
+	const adminToken = "integration-admin-token"
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	router := NewRouter(handler.NewConfigHandler(svc), repo,
+		WithAdminHandler(handler.NewAdminHandler(repo, nil, nil, handler.WithLogBuffer(buf)), adminToken))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	logger.Logger.Info("line written before the stream opened")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/admin/logs/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	waitFor := func(message string) {
+		t.Helper()
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(data), &entry), "Each data line should be one JSON log entry")
+			if entry["msg"] == message {
+				return
+			}
+		}
+		t.Fatalf("stream ended before %q was received: %v", message, scanner.Err())
+	}
+
+	waitFor("line written before the stream opened")
+	logger.Logger.Info("line written while streaming")
+	waitFor("line written while streaming")
+}
+
+func TestIntegration_TrafficStatsHidden(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	const peerKey = testIntegrationServerPublicKey // Any well-formed key works for the fake repository
+	for _, expose := range []bool{true, false} {
+		repo := repository.NewFakeWGRepository()
+		repo.Data[peerKey] = domain.Config{PublicKey: peerKey, AllowedIps: []string{"10.0.0.7/32"}, ReceiveBytes: 1024, TransmitBytes: 2048}
+		collector := service.NewStatsCollector(repo, time.Minute)
+		require.NoError(t, collector.Collect(context.Background()))
+		svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+			service.WithTrafficStats(expose))
+		router := NewRouter(handler.NewConfigHandler(svc), repo,
+			WithStatsHandler(handler.NewStatsHandler(collector, handler.WithTrafficExposed(expose))))
+
+		get := func(path string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := get("/configs")
+		require.Equal(t, http.StatusOK, w.Code)
+		var list []map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		require.Len(t, list, 1)
+
+		w = get("/configs/" + url.PathEscape(peerKey))
+		require.Equal(t, http.StatusOK, w.Code)
+		var single map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &single))
+
+		for _, field := range []string{"receiveBytes", "transmitBytes"} {
+			if expose {
+				assert.Contains(t, list[0], field)
+				assert.Contains(t, single, field)
+			} else {
+				assert.NotContains(t, list[0], field, "GET /configs must not expose %s when disabled", field)
+				assert.NotContains(t, single, field, "GET /configs/{key} must not expose %s when disabled", field)
+			}
+		}
+
+		if expose {
+			assert.Equal(t, http.StatusOK, get("/stats").Code)
+			assert.Equal(t, http.StatusOK, get("/configs/top").Code)
+		} else {
+			assert.Equal(t, http.StatusForbidden, get("/stats").Code)
+			assert.Equal(t, http.StatusForbidden, get("/configs/top").Code)
+		}
+	}
+}
+
+// resetCounter is a handler.CacheResetter that counts resets.
+type resetCounter struct{ resets int }
+
+func (r *resetCounter) Reset() { r.resets++ }
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// Maintenance is the process-wide maintenance-mode switch. While it is on,
+// routes guarded by RejectDuringMaintenance answer 503 and reads keep working.
+type Maintenance struct {
+	enabled atomic.Bool
+}
+
+// NewMaintenance creates a switch that starts in the given state (MAINTENANCE_MODE).
+func NewMaintenance(enabled bool) *Maintenance {
+	m := &Maintenance{}
+	m.enabled.Store(enabled)
+	return m
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// SetEnabled turns maintenance mode on or off.
+func (m *Maintenance) SetEnabled(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+// RejectDuringMaintenance aborts requests with 503 while m is enabled. A nil m never rejects.
+func RejectDuringMaintenance(m *Maintenance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m != nil && m.Enabled() {
+			logger.Logger.Info("Rejected change during maintenance", zap.String("method", c.Request.Method), zap.String("path", c.Request.URL.Path))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "The service is in maintenance mode; changes are temporarily disabled."})
+		}
+	}
+}
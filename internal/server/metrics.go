@@ -0,0 +1,27 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"wgMicro_api/internal/metrics"
+)
+
+// MetricsMiddleware counts every request in wg_http_requests_total and observes its duration in
+// wg_http_request_duration_seconds, labelled with the route pattern (e.g. "/configs/:publicKey")
+// so public keys do not become label values. Unmatched requests use the route "unmatched".
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		statusClass := strconv.Itoa(c.Writer.Status()/100) + "xx"
+		metrics.RequestsTotal.WithLabelValues(c.Request.Method, route, statusClass).Inc()
+		metrics.RequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// udpPortBound reports whether some socket on this host is already bound to the UDP port,
+// by trying to bind it: "address already in use" means the port is taken, e.g. by WireGuard.
+// A successful bind means nothing listens there; the probe socket is closed immediately.
+func udpPortBound(port int) (bool, error) {
+	conn, err := net.ListenPacket("udp", ":"+strconv.Itoa(port))
+	if err == nil {
+		conn.Close()
+		return false, nil
+	}
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return true, nil
+	}
+	return false, err
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUDPPortBound(t *testing.T) {
+	conn, err := net.ListenPacket("udp", ":0")
+	require.NoError(t, err)
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	bound, err := udpPortBound(port)
+	require.NoError(t, err)
+	assert.True(t, bound, "A port held by another socket should be reported as bound")
+
+	require.NoError(t, conn.Close())
+	bound, err = udpPortBound(port)
+	require.NoError(t, err)
+	assert.False(t, bound, "A released port should be reported as not bound")
+}
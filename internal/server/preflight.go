@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/handler"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// interfaceReadiness is the readiness state of an interface added with WithInterfaceRepos.
+// No probe checks it, so the write pre-flight refreshes its cache itself, at most once per cache window.
+type interfaceReadiness struct {
+	repo  repository.Repo
+	cache *readinessCache
+}
+
+// rejectWhileNotReady aborts requests with 503 while the readiness result of the interface they
+// select (handler.InterfaceHeader) is a cached failure, with Retry-After set to when that result
+// expires and the next probe checks again. The default interface uses cache, filled by the probe.
+// A missing-peers failure (RequirePeers) does not reject: adding a peer is how it is fixed.
+func rejectWhileNotReady(cache *readinessCache, cfg readinessConfig, interfaces map[string]interfaceReadiness) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		selected := cache
+		if iface, ok := interfaces[c.GetHeader(handler.InterfaceHeader)]; ok {
+			selected = iface.cache
+			if selected.ttl > 0 {
+				_ = selected.check(func() error { return checkReadiness(c.Request.Context(), iface.repo, cfg) })
+			}
+		}
+		remaining, err := selected.lastFailure()
+		if err == nil || errors.Is(err, errNoPeers) {
+			return
+		}
+		logger.Logger.Info("Rejected change while WireGuard is not ready",
+			zap.String("method", c.Request.Method), zap.String("path", c.Request.URL.Path),
+			zap.String("interface", c.GetHeader(handler.InterfaceHeader)), zap.Error(err))
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "WireGuard is not ready; changes are rejected until the readiness check passes again."})
+	}
+}
+
+// allGuards runs guards in order and stops at the first one that aborts the request.
+// Guards must not call c.Next; gin moves on to the route handler once they return.
+func allGuards(guards ...gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, guard := range guards {
+			if guard(c); c.IsAborted() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// failedCheckTTLDivisor shortens how long a failed readiness result is reused,
+// so a recovering interface is noticed sooner than the full cache window.
+const failedCheckTTLDivisor = 4
+
+// readinessCache reuses the result of the last readiness check for a short window.
+// Concurrent probes arriving while a check runs wait for it and share its result,
+// so a burst of probes causes at most one 'wg show dump'.
+type readinessCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	result    error
+	valid     bool
+}
+
+func newReadinessCache(ttl time.Duration) *readinessCache {
+	return &readinessCache{ttl: ttl, now: time.Now}
+}
+
+// check returns the cached result if it is still fresh, otherwise runs fn and caches its result.
+// With a non-positive ttl every call runs fn. A context error is returned but not cached: it means
+// the probe that ran fn went away, not that WireGuard is unhealthy.
+func (c *readinessCache) check(fn func() error) error {
+	if c.ttl <= 0 {
+		return fn()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ttl := c.ttl
+	if c.result != nil {
+		ttl /= failedCheckTTLDivisor
+	}
+	if c.valid && c.now().Sub(c.checkedAt) < ttl {
+		return c.result
+	}
+
+	result := fn()
+	if errors.Is(result, context.Canceled) || errors.Is(result, context.DeadlineExceeded) {
+		return result
+	}
+	c.result = result
+	c.checkedAt = c.now()
+	c.valid = true
+	return c.result
+}
+
+// Reset drops the cached result, so the next probe runs the check again.
+func (c *readinessCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.valid = false
+}
+
+// lastFailure returns the cached failed result and how long it stays cached, or nil if the last
+// check succeeded, has expired or never ran. It never runs a check itself.
+func (c *readinessCache) lastFailure() (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.valid || c.result == nil {
+		return 0, nil
+	}
+	remaining := c.ttl/failedCheckTTLDivisor - c.now().Sub(c.checkedAt)
+	if remaining <= 0 {
+		return 0, nil
+	}
+	return remaining, c.result
+}
@@ -1,18 +1,137 @@
 package server
 
 import (
+	"sort"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
 
+	"wgMicro_api/internal/domain"
 	"wgMicro_api/internal/handler"
 	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/metrics"
 	"wgMicro_api/internal/repository"
 )
 
-func NewRouter(cfgHandler *handler.ConfigHandler, repo repository.Repo) *gin.Engine {
+// Option customizes the router created by NewRouter.
+type Option func(*routerOptions)
+
+// routerOptions holds optional handlers and settings for NewRouter.
+type routerOptions struct {
+	serverHandler    *handler.ServerHandler
+	statsHandler     *handler.StatsHandler
+	readinessOptions []ReadinessOption
+	adminHandler     *handler.AdminHandler
+	adminToken       string
+	gzipMinBytes     int    // 0 disables response compression
+	serverName       string // Sent as X-Server-Name on every response when non-empty
+	strictJSON       bool   // Reject JSON bodies with fields the target struct does not have
+	swagger          bool   // Serve Swagger UI under /swagger
+	maintenance      *Maintenance
+	statsd           *metrics.StatsD            // Receives request timers; nil disables them
+	interfaceRepos   map[string]repository.Repo // Additional interfaces served with X-WG-Interface, by name
+	livenessPath     string                     // Empty means domain.DefaultLivenessPath
+	readinessPath    string                     // Empty means domain.DefaultReadinessPath
+}
+
+// WithServerHandler registers GET /server backed by the given handler.
+func WithServerHandler(h *handler.ServerHandler) Option {
+	return func(o *routerOptions) {
+		o.serverHandler = h
+	}
+}
+
+// WithStatsHandler registers GET /stats and GET /server/collector backed by the given handler.
+func WithStatsHandler(h *handler.StatsHandler) Option {
+	return func(o *routerOptions) {
+		o.statsHandler = h
+	}
+}
+
+// WithAdminHandler registers the /admin endpoints backed by the given handler, guarded by token.
+// With an empty token the endpoints are not registered.
+func WithAdminHandler(h *handler.AdminHandler, token string) Option {
+	return func(o *routerOptions) {
+		o.adminHandler = h
+		o.adminToken = token
+	}
+}
+
+// WithGzip compresses responses of at least minBytes for clients accepting gzip. 0 disables compression.
+func WithGzip(minBytes int) Option {
+	return func(o *routerOptions) {
+		o.gzipMinBytes = minBytes
+	}
+}
+
+// WithServerNameHeader adds an X-Server-Name header with name to every response,
+// so clients of several API instances can tell which one answered. An empty name adds nothing.
+func WithServerNameHeader(name string) Option {
+	return func(o *routerOptions) {
+		o.serverName = name
+	}
+}
+
+// WithStrictJSON makes JSON request bodies with unknown fields fail binding, so handlers answer 400
+// naming the field instead of silently ignoring e.g. a misspelled "allowed_ip".
+// It only affects the router it is passed to.
+func WithStrictJSON(enabled bool) Option {
+	return func(o *routerOptions) {
+		o.strictJSON = enabled
+	}
+}
+
+// WithSwagger registers the Swagger UI under /swagger/*any. Without it the UI is not served.
+func WithSwagger(enabled bool) Option {
+	return func(o *routerOptions) {
+		o.swagger = enabled
+	}
+}
+
+// WithStatsD sends the duration of every request to s as a StatsD timer.
+func WithStatsD(s *metrics.StatsD) Option {
+	return func(o *routerOptions) {
+		o.statsd = s
+	}
+}
+
+// WithMaintenance makes the mutating /configs endpoints and /admin/prune-inactive answer 503 while m is enabled.
+func WithMaintenance(m *Maintenance) Option {
+	return func(o *routerOptions) {
+		o.maintenance = m
+	}
+}
+
+// WithInterfaceRepos gives the write pre-flight of RejectWritesWhenNotReady the repositories of the
+// interfaces served besides the default one, by the name selected with handler.InterfaceHeader,
+// so each interface is rejected on its own readiness result.
+func WithInterfaceRepos(repos map[string]repository.Repo) Option {
+	return func(o *routerOptions) {
+		o.interfaceRepos = repos
+	}
+}
+
+// WithProbePaths serves the liveness and readiness probes at the given paths instead of
+// /healthz and /readyz, for orchestrators expecting e.g. /live and /ready. An empty path keeps its default.
+func WithProbePaths(liveness, readiness string) Option {
+	return func(o *routerOptions) {
+		o.livenessPath = liveness
+		o.readinessPath = readiness
+	}
+}
+
+// WithReadinessOptions passes options to the /readyz probe.
+func WithReadinessOptions(opts ...ReadinessOption) Option {
+	return func(o *routerOptions) {
+		o.readinessOptions = append(o.readinessOptions, opts...)
+	}
+}
+
+func NewRouter(cfgHandler *handler.ConfigHandler, repo repository.Repo, opts ...Option) *gin.Engine {
 	if cfgHandler == nil {
 		logger.Logger.Fatal("ConfigHandler cannot be nil for NewRouter")
 	}
@@ -20,28 +139,149 @@ func NewRouter(cfgHandler *handler.ConfigHandler, repo repository.Repo) *gin.Eng
 		logger.Logger.Fatal("Repository cannot be nil for NewRouter (required for readiness probe)")
 	}
 
+	options := routerOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	r := gin.New()
+	// Match routes on the escaped path so a public key with '/' sent as %2F stays one :publicKey segment.
+	// Handlers decode parameters themselves: gin's own unescaping would turn the '+' of base64 keys into spaces.
+	r.UseRawPath = true
+	r.UnescapePathValues = false
 	r.Use(gin.Recovery())
 	r.Use(ZapLogger(logger.Logger)) // Передаем глобальный логгер
-	r.Use(cors.Default())           // Включаем CORS с настройками по умолчанию
+	r.Use(corsMiddleware())         // CORS по умолчанию, плюс заголовок выбора интерфейса
+	r.Use(MetricsMiddleware())      // Request counters and durations for /metrics
+	if options.strictJSON {
+		r.Use(handler.StrictJSON())
+	}
+	if options.serverName != "" {
+		r.Use(func(c *gin.Context) {
+			c.Header("X-Server-Name", options.serverName)
+			c.Next()
+		})
+	}
+	if options.statsd != nil {
+		r.Use(options.statsd.Middleware())
+	}
+	if options.gzipMinBytes > 0 {
+		r.Use(Gzip(options.gzipMinBytes))
+	}
+
+	readiness := newReadinessConfig(options.readinessOptions...)
+	readinessResults := newReadinessCache(readiness.cacheTTL)
+	interfaceResults := make(map[string]interfaceReadiness, len(options.interfaceRepos))
+	caches := []interface{ Reset() }{readinessResults}
+	for name, ifaceRepo := range options.interfaceRepos {
+		iface := interfaceReadiness{repo: ifaceRepo, cache: newReadinessCache(readiness.cacheTTL)}
+		interfaceResults[name] = iface
+		caches = append(caches, iface.cache)
+	}
 
 	// Health Check Endpoints
-	r.GET("/healthz", HealthLiveness)       // Убедись, что HealthLiveness определен в health.go
-	r.GET("/readyz", HealthReadiness(repo)) // Убедись, что HealthReadiness определен в health.go
-
-	// API Routes - All endpoints now use JSON body for consistency
-	r.GET("/configs", cfgHandler.GetAll)                               // List all configs (no params needed)
-	r.POST("/configs", cfgHandler.CreateConfig)                        // Create new config with JSON body
-	r.POST("/configs/get", cfgHandler.GetConfig)                       // Get specific config with JSON body
-	r.POST("/configs/update-allowed-ips", cfgHandler.UpdateAllowedIPs) // Update allowed IPs with JSON body
-	r.POST("/configs/delete", cfgHandler.DeleteConfig)                 // Delete config with JSON body
-	r.POST("/configs/client-file", cfgHandler.GenerateClientConfigFile) // Generate client file with JSON body
-	r.POST("/configs/rotate", cfgHandler.RotatePeer)                    // Rotate peer key with JSON body
+	livenessPath, readinessPath := domain.DefaultLivenessPath, domain.DefaultReadinessPath
+	if options.livenessPath != "" {
+		livenessPath = options.livenessPath
+	}
+	if options.readinessPath != "" {
+		readinessPath = options.readinessPath
+	}
+	r.GET(livenessPath, HealthLiveness)                                     // Убедись, что HealthLiveness определен в health.go
+	r.GET(readinessPath, readinessProbe(repo, readiness, readinessResults)) // Shares its cached results with the write pre-flight
+
+	r.GET("/metrics", metrics.Handler()) // Prometheus metrics (requests, key rotations, peer gauges, Go runtime)
+
+	mutating := RejectDuringMaintenance(options.maintenance) // Guards every route that changes peers
+	if readiness.rejectWrites {
+		mutating = allGuards(mutating, rejectWhileNotReady(readinessResults, readiness, interfaceResults))
+	}
+
+	// API Routes - JSON-body endpoints, plus path-parameter variants taking a URL-encoded public key
+	configs := r.Group("/configs", cfgHandler.SelectInterface())                       // X-WG-Interface picks the WireGuard interface
+	configs.GET("", cfgHandler.GetAll)                                                 // List all configs (no params needed)
+	configs.GET("/stale", cfgHandler.ListStale)                                        // List never-connected or long-idle peers (?olderThan=7d)
+	configs.GET("/summary", cfgHandler.Summary)                                        // Count peers online / offline / never connected
+	configs.GET("/top", cfgHandler.TopTalkers)                                         // Top-N peers by received or transmitted bytes (?by=rx|tx&limit=10)
+	configs.GET("/export", cfgHandler.ExportPeers)                                     // Stream all peers as [Peer] blocks
+	configs.GET("/by-ip", cfgHandler.GetConfigByAllowedIP)                             // Find the peer whose AllowedIPs contain ?ip=
+	configs.GET("/by-id/:peerId", cfgHandler.GetConfigByPeerID)                        // Find the peer by its short peerId
+	configs.POST("", mutating, cfgHandler.CreateConfig)                                // Create new config with JSON body
+	configs.POST("/get", cfgHandler.GetConfig)                                         // Get specific config with JSON body
+	configs.POST("/stats", cfgHandler.GetPeerStats)                                    // Handshake and traffic counters of one peer, without keys
+	configs.POST("/update-allowed-ips", mutating, cfgHandler.UpdateAllowedIPs)         // Update allowed IPs with JSON body
+	configs.POST("/delete", mutating, cfgHandler.DeleteConfig)                         // Delete config with JSON body
+	configs.POST("/client-file", cfgHandler.GenerateClientConfigFile)                  // Generate client file with JSON body
+	configs.POST("/preview", cfgHandler.PreviewClientConfig)                           // Build a .conf for a not yet registered peer
+	configs.POST("/rotate", mutating, cfgHandler.RotatePeer)                           // Rotate peer key with JSON body
+	configs.POST("/bulk-rotate", mutating, cfgHandler.BulkRotatePeers)                 // Rotate several peer keys with JSON body
+	configs.POST("/bulk-rotate-psk", mutating, cfgHandler.BulkRotatePresharedKeys)     // Give every peer a new preshared key
+	configs.POST("/verify-key", cfgHandler.VerifyKeyPair)                              // Verify a client key pair with JSON body
+	configs.POST("/apply", mutating, cfgHandler.ApplyDesiredState)                     // Reconcile peers with a desired-state document (?prune=true deletes extras)
+	configs.POST("/plan", cfgHandler.PlanDesiredState)                                 // Preview /configs/apply without changing anything
+	configs.GET("/:publicKey", cfgHandler.GetConfigByKey)                              // Path variant of /configs/get (URL-encoded key)
+	configs.PUT("/:publicKey/allowed-ips", mutating, cfgHandler.UpdateAllowedIPsByKey) // Path variant of /configs/update-allowed-ips
+	configs.DELETE("/:publicKey", mutating, cfgHandler.DeleteConfigByKey)              // Path variant of /configs/delete (404 if absent)
+	configs.PATCH("/:publicKey", mutating, cfgHandler.PatchConfigByKey)                // JSON Patch of allowedIps, presharedKey, persistentKeepalive
+	configs.POST("/:publicKey/rotate", mutating, cfgHandler.RotatePeerByKey)           // Path variant of /configs/rotate
+	configs.POST("/:publicKey/full", cfgHandler.GetFullConfigByKey)                    // Peer config plus generated .conf (private key in body)
+	r.POST("/keys/fingerprint", cfgHandler.KeyFingerprint)                             // Short display fingerprint of a public key
+
+	if options.serverHandler != nil {
+		r.GET("/server", options.serverHandler.GetServerInfo)          // Server interface info and address drift check
+		r.GET("/server/publickey", options.serverHandler.GetPublicKey) // Server public key as plain text, for scripts
+		if options.adminToken != "" {
+			r.POST("/server/listen-port", RequireAdminToken(options.adminToken), mutating, options.serverHandler.SetListenPort) // Change the listen port (ADMIN_TOKEN)
+		}
+	}
+	if options.statsHandler != nil {
+		r.GET("/stats", options.statsHandler.GetStats)                      // Cached aggregate peer statistics
+		r.GET("/server/collector", options.statsHandler.GetCollectorStatus) // Stats collector last success/error
+	}
+	if options.adminHandler != nil {
+		if options.adminToken == "" {
+			logger.Logger.Warn("Admin endpoints disabled: no admin token configured")
+		} else {
+			admin := r.Group("/admin", RequireAdminToken(options.adminToken))
+			admin.POST("/refresh", resetCaches(caches...), options.adminHandler.Refresh) // Drop caches, reload metadata, recompute stats
+			admin.GET("/logs/stream", options.adminHandler.StreamLogs)                   // SSE tail of recent log lines
+			admin.POST("/prune-inactive", mutating, options.adminHandler.PruneInactive)  // Delete old peers that never connected
+			admin.POST("/maintenance", options.adminHandler.SetMaintenance)              // Turn rejection of /configs changes on or off
+		}
+	}
+
+	if options.swagger {
+		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+		logger.Logger.Info("Swagger UI available at /swagger/index.html")
+	} else {
+		logger.Logger.Info("Swagger UI disabled")
+	}
 
 	logger.Logger.Info("Router initialized with CORS (default), all routes and middleware.")
+	logRoutes(r)
 	return r
 }
 
+// corsMiddleware allows any origin like cors.Default, and also lets browser clients send
+// handler.InterfaceHeader to select an interface.
+func corsMiddleware() gin.HandlerFunc {
+	cfg := cors.DefaultConfig()
+	cfg.AllowAllOrigins = true
+	cfg.AddAllowHeaders(handler.InterfaceHeader)
+	return cors.New(cfg)
+}
+
+// logRoutes logs the route table once, so the endpoints enabled by the current configuration can be checked.
+func logRoutes(r *gin.Engine) {
+	routes := r.Routes()
+	table := make([]string, 0, len(routes))
+	for _, route := range routes {
+		table = append(table, route.Method+" "+route.Path)
+	}
+	sort.Strings(table)
+	logger.Logger.Info("Registered routes", zap.Int("count", len(table)), zap.Strings("routes", table))
+}
+
 func ZapLogger(log *zap.Logger) gin.HandlerFunc {
 	if log == nil {
 		// Это не должно произойти, если logger.Init вызывается до NewRouter
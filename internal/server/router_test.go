@@ -0,0 +1,274 @@
+// internal/server/router_test.go
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/handler"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+	"wgMicro_api/internal/service"
+)
+
+func TestNewRouter_LogsRouteTable(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger.Logger = zap.New(core)
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	build := func(opts ...Option) (*gin.Engine, []string) {
+		logs.TakeAll()
+		r := NewRouter(handler.NewConfigHandler(svc), repo, opts...)
+		entries := logs.FilterMessage("Registered routes").All()
+		require.Len(t, entries, 1, "The route table should be logged exactly once")
+		logged, ok := entries[0].ContextMap()["routes"].([]interface{})
+		require.True(t, ok)
+		table := make([]string, 0, len(logged))
+		for _, route := range logged {
+			table = append(table, route.(string))
+		}
+		assert.EqualValues(t, len(r.Routes()), entries[0].ContextMap()["count"])
+		return r, table
+	}
+	registered := func(r *gin.Engine) []string {
+		var table []string
+		for _, route := range r.Routes() {
+			table = append(table, route.Method+" "+route.Path)
+		}
+		return table
+	}
+
+	r, table := build()
+	assert.ElementsMatch(t, registered(r), table)
+	assert.Contains(t, table, "GET /configs")
+	assert.NotContains(t, table, "GET /server", "Optional handlers that are not configured are not registered")
+
+	r, table = build(WithServerHandler(handler.NewServerHandler(domain.ServerInfo{PublicKey: testIntegrationServerPublicKey})))
+	assert.ElementsMatch(t, registered(r), table)
+	assert.Contains(t, table, "GET /server")
+	assert.Contains(t, table, "GET /server/publickey")
+}
+
+func TestNewRouter_StrictJSON(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	const payload = `{"allowed_ips": ["10.100.7.2/32"], "allowed_ip": ["10.100.7.3/32"]}` // Typo'd extra field
+	build := func(opts ...Option) *gin.Engine {
+		repo := repository.NewFakeWGRepository()
+		svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0,
+			service.WithKeyGenerator(service.NativeKeyGenerator{}))
+		return NewRouter(handler.NewConfigHandler(svc), repo, opts...)
+	}
+	create := func(router *gin.Engine) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/configs", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	// Both routers exist at once, so a setting leaking from one to the other would show.
+	strict, lenient := build(WithStrictJSON(true)), build()
+
+	t.Run("Strict_rejects_unknown_field", func(t *testing.T) {
+		w := create(strict)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var resp domain.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Contains(t, resp.Error, `"allowed_ip"`, "The error should name the unknown field")
+	})
+
+	t.Run("Lenient_ignores_unknown_field", func(t *testing.T) {
+		w := create(lenient)
+		require.Equal(t, http.StatusCreated, w.Code, "Body: %s", w.Body.String())
+		var cfg domain.Config
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cfg))
+		assert.Equal(t, []string{"10.100.7.2/32"}, cfg.AllowedIps)
+	})
+	assert.False(t, binding.EnableDecoderDisallowUnknownFields, "The process-wide gin setting must stay untouched")
+}
+
+func TestNewRouter_Swagger(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	get := func(opts ...Option) int {
+		router := NewRouter(handler.NewConfigHandler(svc), repo, opts...)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil) // gin-swagger routes on RequestURI
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get(WithSwagger(true)), "Development serves Swagger UI")
+	assert.Equal(t, http.StatusNotFound, get(WithSwagger(false)), "Production does not serve Swagger UI by default")
+	assert.Equal(t, http.StatusNotFound, get(), "Swagger UI is opt-in for the router")
+}
+
+func TestNewRouter_RequestMetrics(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	router := NewRouter(handler.NewConfigHandler(svc), repo)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, get("/configs").Code)
+	require.Equal(t, http.StatusNotFound, get("/configs/BDFTfugHHNOHfPC3B4NSGfRmNE4zs%2BZXM2ikT8%2F%2FRUU%3D").Code)
+	require.Equal(t, http.StatusNotFound, get("/no-such-route").Code)
+
+	body := get("/metrics").Body.String()
+	assert.Contains(t, body, `wg_http_requests_total{method="GET",route="/configs",status_class="2xx"}`)
+	assert.Contains(t, body, `wg_http_requests_total{method="GET",route="/configs/:publicKey",status_class="4xx"}`,
+		"Routes are labelled with their pattern, not the public key")
+	assert.Contains(t, body, `wg_http_requests_total{method="GET",route="unmatched",status_class="4xx"}`)
+	assert.Contains(t, body, `wg_http_request_duration_seconds_count{method="GET",route="/configs"}`)
+	assert.NotContains(t, body, "BDFTfugHHNOHfPC3B4NSGfRmNE4zs")
+}
+
+func TestNewRouter_ProbePaths(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	probe := func(router *gin.Engine, target string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Code
+	}
+
+	custom := NewRouter(handler.NewConfigHandler(svc), repo, WithProbePaths("/live", "/ready"))
+	assert.Equal(t, http.StatusOK, probe(custom, "/live"))
+	assert.Equal(t, http.StatusOK, probe(custom, "/ready"))
+	assert.Equal(t, http.StatusNotFound, probe(custom, "/healthz"), "The default paths are replaced, not kept alongside")
+	assert.Equal(t, http.StatusNotFound, probe(custom, "/readyz"))
+
+	defaults := NewRouter(handler.NewConfigHandler(svc), repo, WithProbePaths("", ""))
+	assert.Equal(t, http.StatusOK, probe(defaults, domain.DefaultLivenessPath))
+	assert.Equal(t, http.StatusOK, probe(defaults, domain.DefaultReadinessPath))
+}
+
+func TestNewRouter_RoutesStayUnderReservedPrefixes(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	router := NewRouter(handler.NewConfigHandler(svc), repo,
+		WithServerHandler(handler.NewServerHandler(domain.ServerInfo{Interface: "wg0"})),
+		WithStatsHandler(handler.NewStatsHandler(service.NewStatsCollector(repo, time.Minute))),
+		WithAdminHandler(handler.NewAdminHandler(repo, nil, nil), "admin-token"),
+		WithSwagger(true))
+
+	// The configuration loader only keeps probe paths clear of these prefixes, so a new top-level route must be added there too.
+	for _, route := range router.Routes() {
+		if route.Path == domain.DefaultLivenessPath || route.Path == domain.DefaultReadinessPath {
+			continue
+		}
+		reserved := false
+		for _, prefix := range domain.ReservedPathPrefixes {
+			if route.Path == prefix || strings.HasPrefix(route.Path, prefix+"/") {
+				reserved = true
+			}
+		}
+		assert.True(t, reserved, "%s %s is not under domain.ReservedPathPrefixes", route.Method, route.Path)
+	}
+}
+
+func TestNewRouter_InterfaceSelection(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	newService := func(peer string) (*service.ConfigService, *repository.FakeWGRepository) {
+		repo := repository.NewFakeWGRepository()
+		repo.Data[peer] = domain.Config{PublicKey: peer, AllowedIps: []string{"10.0.0.2/32"}}
+		return service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0), repo
+	}
+	wg0Peer, wg1Peer := "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg=", "BDFTfugHHNOHfPC3B4NSGfRmNE4zs+ZXM2ikT8//RUU="
+	wg0, repo := newService(wg0Peer)
+	wg1, wg1Repo := newService(wg1Peer)
+	router := NewRouter(handler.NewConfigHandler(wg0, handler.WithInterfaceServices(map[string]handler.ServiceInterface{
+		"wg0": wg0, "wg1": wg1,
+	})), repo)
+	list := func(iface string) (int, []string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/configs", nil)
+		if iface != "" {
+			req.Header.Set(handler.InterfaceHeader, iface)
+		}
+		router.ServeHTTP(w, req)
+		var configs []domain.Config
+		_ = json.Unmarshal(w.Body.Bytes(), &configs)
+		keys := make([]string, 0, len(configs))
+		for _, cfg := range configs {
+			keys = append(keys, cfg.PublicKey)
+		}
+		return w.Code, keys
+	}
+
+	code, keys := list("")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{wg0Peer}, keys, "Without the header the default interface is used")
+	code, keys = list("wg0")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{wg0Peer}, keys)
+	code, keys = list("wg1")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{wg1Peer}, keys)
+	code, _ = list("wg7")
+	assert.Equal(t, http.StatusNotFound, code, "An interface that is not served should be 404")
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/configs/"+url.PathEscape(wg1Peer), nil)
+	req.Header.Set(handler.InterfaceHeader, "wg1")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code, "Body: %s", w.Body.String())
+	assert.Empty(t, wg1Repo.Data, "Writes should go to the selected interface")
+	assert.Len(t, repo.Data, 1)
+}
+
+func TestNewRouter_CORSAllowsInterfaceHeader(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewFakeWGRepository()
+	svc := service.NewConfigService(repo, testIntegrationServerPublicKey, "integration.test.vpn:51820", time.Second, "", 0)
+	router := NewRouter(handler.NewConfigHandler(svc), repo)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/configs", nil)
+	req.Header.Set("Origin", "https://panel.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", handler.InterfaceHeader)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, strings.ToLower(w.Header().Get("Access-Control-Allow-Headers")), strings.ToLower(handler.InterfaceHeader))
+}
@@ -0,0 +1,172 @@
+// internal/service/allowedips.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// ErrAmbiguousAllowedIP is returned when more than one peer's AllowedIPs contain a looked-up address.
+var ErrAmbiguousAllowedIP = domain.NewCategorizedError(domain.CategoryConflict, "address matches more than one peer")
+
+// ErrInvalidAllowedIP is returned when an AllowedIPs entry is not a valid CIDR prefix
+// (or, with bare IP normalization enabled, a valid IP address).
+var ErrInvalidAllowedIP = domain.NewCategorizedError(domain.CategoryMalformed, "invalid allowed IP")
+
+// ErrAllowedIPOutOfRange is returned when an AllowedIPs entry is not contained in any range
+// permitted by WithAllowedIPRanges.
+var ErrAllowedIPOutOfRange = domain.NewCategorizedError(domain.CategoryInvalid, "allowed IP outside the permitted ranges")
+
+// WithAllowedIPRanges restricts the AllowedIPs that may be requested to networks contained in one of ranges,
+// e.g. the VPN subnet, so clients cannot claim arbitrary or publicly routable ranges.
+// No ranges means no restriction.
+func WithAllowedIPRanges(ranges []netip.Prefix) Option {
+	return func(s *ConfigService) {
+		s.allowedRanges = nil
+		for _, r := range ranges {
+			s.allowedRanges = append(s.allowedRanges, r.Masked())
+		}
+	}
+}
+
+// WithBareIPNormalization controls whether AllowedIPs entries without a prefix length are accepted.
+// When enabled (the default), a bare IPv4 address becomes /32 and a bare IPv6 address /128;
+// when disabled, every entry must carry an explicit prefix.
+func WithBareIPNormalization(enabled bool) Option {
+	return func(s *ConfigService) {
+		s.requireIPPrefix = !enabled
+	}
+}
+
+// normalizeAllowedIPs validates ips and returns them trimmed, with bare addresses converted
+// to host prefixes unless explicit prefixes are required. Valid prefixes are kept as written.
+// Entries denoting the same network (e.g. "10.0.0.1" and "10.0.0.1/32") are collapsed to the first one.
+func (s *ConfigService) normalizeAllowedIPs(ips []string) ([]string, error) {
+	if ips == nil {
+		return nil, nil
+	}
+	normalized := make([]string, 0, len(ips))
+	seen := make(map[netip.Prefix]struct{}, len(ips))
+	for _, raw := range ips {
+		entry := strings.TrimSpace(raw)
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%w: %q is not a valid CIDR prefix", ErrInvalidAllowedIP, raw)
+			}
+			if s.requireIPPrefix {
+				return nil, fmt.Errorf("%w: %q has no prefix length (e.g. /32 or /128)", ErrInvalidAllowedIP, raw)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+			entry = prefix.String()
+		}
+
+		if err := s.checkAllowedIPRange(prefix, raw); err != nil {
+			return nil, err
+		}
+
+		// 'wg' masks host bits, so 10.0.0.5/24 and 10.0.0.0/24 are the same network.
+		if _, dup := seen[prefix.Masked()]; dup {
+			logger.Logger.Debug("Service: Dropping duplicate AllowedIPs entry", zap.String("entry", raw))
+			continue
+		}
+		seen[prefix.Masked()] = struct{}{}
+		normalized = append(normalized, entry)
+	}
+	return normalized, nil
+}
+
+// checkAllowedIPRange returns ErrAllowedIPOutOfRange, naming entry, if prefix is not inside a permitted range.
+func (s *ConfigService) checkAllowedIPRange(prefix netip.Prefix, entry string) error {
+	if len(s.allowedRanges) == 0 {
+		return nil
+	}
+	for _, r := range s.allowedRanges {
+		if r.Bits() <= prefix.Bits() && r.Contains(prefix.Masked().Addr()) {
+			return nil
+		}
+	}
+	ranges := make([]string, len(s.allowedRanges))
+	for i, r := range s.allowedRanges {
+		ranges[i] = r.String()
+	}
+	return fmt.Errorf("%w: %q is not within %s", ErrAllowedIPOutOfRange, entry, strings.Join(ranges, ", "))
+}
+
+// checkDesiredRanges applies checkAllowedIPRange to every parsable AllowedIPs entry of a desired-state document.
+// Unparsable entries are left for the repository to reject, as in allowedIPConflicts.
+func (s *ConfigService) checkDesiredRanges(desired []domain.DesiredPeer) error {
+	for _, peer := range desired {
+		for _, raw := range peer.AllowedIps {
+			entry := strings.TrimSpace(raw)
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				addr, addrErr := netip.ParseAddr(entry)
+				if addrErr != nil {
+					continue
+				}
+				prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			if err := s.checkAllowedIPRange(prefix, raw); err != nil {
+				return fmt.Errorf("peer %s: %w", peer.PublicKey, err)
+			}
+		}
+	}
+	return nil
+}
+
+// FindByAllowedIP returns the peer whose AllowedIPs contain ip, e.g. "10.0.0.5".
+// It returns repository.ErrPeerNotFound if no peer matches and ErrAmbiguousAllowedIP if several do.
+func (s *ConfigService) FindByAllowedIP(ctx context.Context, ip string) (*domain.Config, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not a valid IP address", ErrInvalidAllowedIP, ip)
+	}
+
+	configs, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		logger.Logger.Error("Service: Failed to list configs for AllowedIP lookup", zap.Error(err))
+		return nil, err
+	}
+	var matches []domain.Config
+	for _, cfg := range configs {
+		if allowedIPsContain(cfg.AllowedIps, addr) {
+			matches = append(matches, cfg)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, repository.ErrPeerNotFound
+	case 1:
+		found := matches[0]
+		s.prepareConfig(&found)
+		return &found, nil
+	default:
+		keys := make([]string, 0, len(matches))
+		for _, cfg := range matches {
+			keys = append(keys, cfg.PublicKey)
+		}
+		logger.Logger.Warn("Service: Address is covered by several peers", zap.String("ip", addr.String()), zap.Strings("publicKeys", keys))
+		return nil, fmt.Errorf("%w: %s is in the AllowedIPs of %s", ErrAmbiguousAllowedIP, addr, strings.Join(keys, ", "))
+	}
+}
+
+// allowedIPsContain reports whether any valid CIDR entry of ips contains addr.
+func allowedIPsContain(ips []string, addr netip.Addr) bool {
+	for _, raw := range ips {
+		if prefix, err := netip.ParsePrefix(raw); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+// internal/service/allowedips_test.go
+package service
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/repository"
+)
+
+func TestCreateWithNewKeys_NormalizesBareIPs(t *testing.T) {
+	repo := newFakeRepository()
+	svc := setupTestService(t, repo, 0)
+
+	created, err := svc.CreateWithNewKeys(context.Background(), []string{"10.0.0.5", " fd00::5 ", "10.0.1.0/24"}, "", false, 0)
+	require.NoError(t, err)
+	expected := []string{"10.0.0.5/32", "fd00::5/128", "10.0.1.0/24"}
+	assert.Equal(t, expected, created.AllowedIps)
+	assert.Equal(t, expected, repo.configs[created.PublicKey].AllowedIps, "Normalized addresses should reach the repository")
+}
+
+func TestCreateWithNewKeys_CollapsesDuplicateAllowedIPs(t *testing.T) {
+	repo := newFakeRepository()
+	svc := setupTestService(t, repo, 0)
+
+	created, err := svc.CreateWithNewKeys(context.Background(), []string{"10.0.0.1/32", "10.0.0.1/32", "10.0.0.1", "fd00::1/128", "10.0.2.0/24", "10.0.2.7/24"}, "", false, 0)
+	require.NoError(t, err)
+	expected := []string{"10.0.0.1/32", "fd00::1/128", "10.0.2.0/24"}
+	assert.Equal(t, expected, created.AllowedIps)
+	assert.Equal(t, expected, repo.configs[created.PublicKey].AllowedIps, "Only the de-duplicated set should reach the repository")
+}
+
+func TestUpdateAllowedIPs_NormalizesBareIPs(t *testing.T) {
+	repo := newFakeRepository()
+	repo.configs["normalizePeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="] = domain.Config{PublicKey: "normalizePeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", AllowedIps: []string{"10.0.0.2/32"}}
+	svc := setupTestService(t, repo, 0)
+
+	require.NoError(t, svc.UpdateAllowedIPs(context.Background(), "normalizePeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", []string{"10.0.0.6", "2001:db8::6"}))
+	assert.Equal(t, []string{"10.0.0.6/32", "2001:db8::6/128"}, repo.configs["normalizePeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="].AllowedIps)
+}
+
+func TestAllowedIPs_RejectedWhenNormalizationDisabled(t *testing.T) {
+	repo := newFakeRepository()
+	repo.configs["strictPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="] = domain.Config{PublicKey: "strictPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", AllowedIps: []string{"10.0.0.2/32"}}
+	svc := setupTestService(t, repo, 0)
+	WithBareIPNormalization(false)(svc)
+
+	_, err := svc.CreateWithNewKeys(context.Background(), []string{"10.0.0.5"}, "", false, 0)
+	assert.ErrorIs(t, err, ErrInvalidAllowedIP)
+	_, err = svc.CreateWithExistingKeys(context.Background(), "strictImportedPeerAAAAAAAAAAAAAAAAAAAAAAAAA=", "", []string{"fd00::5"}, "", 0)
+	assert.ErrorIs(t, err, ErrInvalidAllowedIP)
+	err = svc.UpdateAllowedIPs(context.Background(), "strictPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", []string{"10.0.0.6"})
+	assert.ErrorIs(t, err, ErrInvalidAllowedIP)
+	assert.Equal(t, []string{"10.0.0.2/32"}, repo.configs["strictPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="].AllowedIps, "Rejected update must not change the peer")
+	assert.Len(t, repo.configs, 1, "Rejected creates must not add peers")
+
+	require.NoError(t, svc.UpdateAllowedIPs(context.Background(), "strictPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", []string{"10.0.0.6/32"}), "Explicit prefixes are still accepted")
+}
+
+func TestAllowedIPs_RejectsGarbage(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+
+	for _, entry := range []string{"not-an-ip", "10.0.0.300", "10.0.0.1/33", ""} {
+		_, err := svc.CreateWithNewKeys(context.Background(), []string{entry}, "", false, 0)
+		assert.ErrorIs(t, err, ErrInvalidAllowedIP, "entry %q", entry)
+	}
+}
+
+func TestAllowedIPs_RestrictedToPermittedRanges(t *testing.T) {
+	const peerKey = "rangedPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	repo := newFakeRepository()
+	repo.configs[peerKey] = domain.Config{PublicKey: peerKey, AllowedIps: []string{"10.8.0.2/32"}}
+	svc := setupTestService(t, repo, 0)
+	WithAllowedIPRanges([]netip.Prefix{netip.MustParsePrefix("10.8.0.0/24"), netip.MustParsePrefix("fd00::/64")})(svc)
+
+	t.Run("In_range_accepted", func(t *testing.T) {
+		created, err := svc.CreateWithNewKeys(context.Background(), []string{"10.8.0.5", "10.8.0.128/25", "fd00::5/128"}, "", false, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.8.0.5/32", "10.8.0.128/25", "fd00::5/128"}, created.AllowedIps)
+		require.NoError(t, svc.UpdateAllowedIPs(context.Background(), peerKey, []string{"10.8.0.0/24"}), "The range itself is contained")
+	})
+
+	t.Run("Out_of_range_rejected", func(t *testing.T) {
+		before := len(repo.configs)
+		for _, entry := range []string{"0.0.0.0/0", "10.9.0.1/32", "10.8.0.0/16", "fd01::1/128"} {
+			_, err := svc.CreateWithNewKeys(context.Background(), []string{"10.8.0.6/32", entry}, "", false, 0)
+			assert.ErrorIs(t, err, ErrAllowedIPOutOfRange, "entry %q", entry)
+			assert.Equal(t, domain.CategoryInvalid, domain.CategoryOf(err), "entry %q should map to 422", entry)
+		}
+		assert.Len(t, repo.configs, before, "Rejected creates must not add peers")
+
+		err := svc.UpdateAllowedIPs(context.Background(), peerKey, []string{"192.168.1.0/24"})
+		assert.ErrorIs(t, err, ErrAllowedIPOutOfRange)
+		assert.Equal(t, []string{"10.8.0.0/24"}, repo.configs[peerKey].AllowedIps, "Rejected update must not change the peer")
+
+		_, err = svc.ApplyDesiredState(context.Background(), []domain.DesiredPeer{{PublicKey: peerKey, AllowedIps: []string{"172.16.0.1/32"}}}, false)
+		assert.ErrorIs(t, err, ErrAllowedIPOutOfRange)
+	})
+}
+
+func TestAllowedIPs_ValidationTable(t *testing.T) {
+	const peerKey = "validatedPeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	testCases := []struct {
+		name  string
+		ips   []string
+		valid bool
+	}{
+		{name: "IPv4_prefix", ips: []string{"10.0.0.1/32", "192.168.0.0/16"}, valid: true},
+		{name: "IPv6_prefix", ips: []string{"fd00::1/128", "2001:db8::/32"}, valid: true},
+		{name: "Mixed_families", ips: []string{"10.0.0.1/32", "fd00::1/128"}, valid: true},
+		{name: "Empty_list_clears", ips: []string{}, valid: true},
+		{name: "IPv4_prefix_too_long", ips: []string{"10.0.0.1/33"}, valid: false},
+		{name: "IPv4_octet_out_of_range", ips: []string{"300.1.1.1/32"}, valid: false},
+		{name: "IPv6_prefix_too_long", ips: []string{"fd00::1/129"}, valid: false},
+		{name: "IPv6_malformed", ips: []string{"fd00:::1/128"}, valid: false},
+		{name: "One_bad_entry_among_good", ips: []string{"10.0.0.1/32", "10.0.0.2/40"}, valid: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			repo.configs[peerKey] = domain.Config{PublicKey: peerKey, AllowedIps: []string{"10.9.9.9/32"}}
+			svc := setupTestService(t, repo, 0)
+
+			updateErr := svc.UpdateAllowedIPs(context.Background(), peerKey, tc.ips)
+			if !tc.valid {
+				require.ErrorIs(t, updateErr, ErrInvalidAllowedIP)
+				assert.Contains(t, updateErr.Error(), tc.ips[len(tc.ips)-1], "The offending entry should be named")
+				assert.Equal(t, []string{"10.9.9.9/32"}, repo.configs[peerKey].AllowedIps, "The repository must not be called")
+				_, createErr := svc.CreateWithNewKeys(context.Background(), tc.ips, "", false, 0)
+				assert.ErrorIs(t, createErr, ErrInvalidAllowedIP)
+				return
+			}
+			require.NoError(t, updateErr)
+			assert.Equal(t, tc.ips, repo.configs[peerKey].AllowedIps)
+		})
+	}
+}
+
+func TestFindByAllowedIP(t *testing.T) {
+	repo := newFakeRepository()
+	repo.configs["hostPeer"] = domain.Config{PublicKey: "hostPeer", AllowedIps: []string{"10.0.0.5/32", "fd00::5/128"}}
+	repo.configs["subnetPeer"] = domain.Config{PublicKey: "subnetPeer", AllowedIps: []string{"10.1.0.0/24"}}
+	repo.configs["overlapPeer"] = domain.Config{PublicKey: "overlapPeer", AllowedIps: []string{"10.1.0.128/25"}}
+	svc := setupTestService(t, repo, 0)
+
+	t.Run("Single_match", func(t *testing.T) {
+		found, err := svc.FindByAllowedIP(context.Background(), "10.0.0.5")
+		require.NoError(t, err)
+		assert.Equal(t, "hostPeer", found.PublicKey)
+
+		found, err = svc.FindByAllowedIP(context.Background(), "10.1.0.7")
+		require.NoError(t, err)
+		assert.Equal(t, "subnetPeer", found.PublicKey, "Containment in a wider prefix should match")
+	})
+
+	t.Run("No_match", func(t *testing.T) {
+		_, err := svc.FindByAllowedIP(context.Background(), "10.2.0.1")
+		assert.ErrorIs(t, err, repository.ErrPeerNotFound)
+	})
+
+	t.Run("Ambiguous_match", func(t *testing.T) {
+		_, err := svc.FindByAllowedIP(context.Background(), "10.1.0.200")
+		assert.ErrorIs(t, err, ErrAmbiguousAllowedIP)
+		assert.Contains(t, err.Error(), "overlapPeer")
+		assert.Contains(t, err.Error(), "subnetPeer")
+	})
+
+	t.Run("Invalid_address", func(t *testing.T) {
+		_, err := svc.FindByAllowedIP(context.Background(), "10.0.0.0/24")
+		assert.ErrorIs(t, err, ErrInvalidAllowedIP)
+	})
+}
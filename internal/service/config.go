@@ -5,29 +5,134 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
 	"os/exec"
+	"slices"
+	"sort"
 	"strconv" // Added for MTU
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
 	"wgMicro_api/internal/domain"
 	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/metrics"
 	"wgMicro_api/internal/repository"
 )
 
 // DefaultKeyGenTimeout is used if no timeout is specified for CLIENT key generation.
 const DefaultKeyGenTimeoutService = 5 * time.Second // Renamed to avoid conflict if config also has one
 
+// ErrInvalidPrivateKey is returned when a supplied private key is rejected by 'wg pubkey',
+// i.e. it is not a correctly encoded WireGuard key.
+var ErrInvalidPrivateKey = domain.NewCategorizedError(domain.CategoryMalformed, "private key is not a valid WireGuard key")
+
+// ErrInvalidPublicKey is returned when a public key is not a base64-encoded 32-byte WireGuard key.
+// It is checked before the repository is touched, so 'wg' never sees a malformed key.
+var ErrInvalidPublicKey = domain.NewCategorizedError(domain.CategoryMalformed, "public key is not a valid WireGuard key")
+
+// ErrKeyPairMismatch is returned when an imported private key does not belong to the supplied public key.
+var ErrKeyPairMismatch = domain.NewCategorizedError(domain.CategoryInvalid, "private key does not match public key")
+
+// ErrPeerAlreadyExists is returned when importing a peer whose public key is already configured.
+var ErrPeerAlreadyExists = domain.NewCategorizedError(domain.CategoryConflict, "peer already exists")
+
+// ErrRotationConflict is returned when a rotation's expected public key no longer matches the peer's current key.
+var ErrRotationConflict = domain.NewCategorizedError(domain.CategoryConflict, "peer key changed since it was read")
+
+// ErrRotationKeyMismatch is returned when a rotation names one peer to rotate and expects another key.
+var ErrRotationKeyMismatch = domain.NewCategorizedError(domain.CategoryInvalid, "expectedPublicKey differs from the key to rotate")
+
 // ConfigService encapsulates business logic for managing WireGuard peer configurations.
 type ConfigService struct {
 	repo                   repository.Repo
-	serverBasePublicKey    string        // Public key of THIS server's WireGuard interface
-	serverBaseEndpoint     string        // External endpoint of THIS server (host:port) for client configs
-	clientKeyGenTimeout    time.Duration // Timeout for client key generation commands ('wg genkey', 'wg pubkey')
-	clientConfigDNSServers string        // DNS servers for client .conf files (from app config)
-	clientConfigMTU        int           // MTU for client .conf files (from app config, 0 means omit)
+	serverBasePublicKey    string                   // Public key of THIS server's WireGuard interface
+	serverBaseEndpoint     string                   // External endpoint of THIS server (host:port) for client configs; guarded by endpointMu
+	endpointMu             sync.RWMutex             // Guards serverBaseEndpoint, which SetListenPort changes
+	clientKeyGenTimeout    time.Duration            // Timeout for client key generation commands ('wg genkey', 'wg pubkey')
+	clientConfigDNSServers string                   // DNS servers for client .conf files (from app config)
+	clientConfigMTU        int                      // MTU for client .conf files (from app config, 0 means omit)
+	clientKeepalive        int                      // PersistentKeepalive for client .conf files of peers without one (0 means omit)
+	maxClientDNS           int                      // Maximum entries of a requested client DNS list; 0 means unlimited
+	runner                 repository.CommandRunner // Executes 'wg' utilities for key operations
+	keyGen                 KeyGenerator             // Produces client key pairs and preshared keys
+	metadata               repository.MetadataStore // Sidecar peer metadata (name, description, creation time)
+	exportMaxBytes         int64                    // Size cap for peer exports; 0 means unlimited
+	requireIPPrefix        bool                     // Reject bare AllowedIPs addresses instead of normalizing them
+	allowedRanges          []netip.Prefix           // Networks requested AllowedIPs must lie in; empty allows any
+	addressPool            netip.Prefix             // Pool for peers created without AllowedIPs; invalid (zero) disables allocation
+	reservedAddrs          []netip.Addr             // Pool addresses never allocated (server interface addresses)
+	allocMu                sync.Mutex               // Serializes allocate-and-create so two peers never get the same address
+	rotateMu               sync.Mutex               // Serializes rotations so a key is never rotated into two new peers
+	hideTraffic            bool                     // Strip per-peer byte counters from returned configs
+	checkClientKeys        bool                     // Reject malformed or weak client private keys in BuildClientConfig
+	onlineThreshold        time.Duration            // Maximum handshake age for a peer to count as online
+}
+
+// Option customizes a ConfigService created by NewConfigService.
+type Option func(*ConfigService)
+
+// WithKeyGenerator overrides the default CLI-backed key generator.
+func WithKeyGenerator(kg KeyGenerator) Option {
+	return func(s *ConfigService) {
+		if kg != nil {
+			s.keyGen = kg
+		}
+	}
+}
+
+// WithCommandRunner overrides the runner of 'wg' key utilities, e.g. to restrict their environment.
+// It also backs the default CLI key generator unless WithKeyGenerator is given.
+func WithCommandRunner(runner repository.CommandRunner) Option {
+	return func(s *ConfigService) {
+		if runner != nil {
+			s.runner = runner
+		}
+	}
+}
+
+// WithMetadataStore overrides the default in-memory peer metadata store.
+func WithMetadataStore(store repository.MetadataStore) Option {
+	return func(s *ConfigService) {
+		if store != nil {
+			s.metadata = store
+		}
+	}
+}
+
+// WithClientDefaultKeepalive sets the PersistentKeepalive written to client configs
+// when the server-side peer has none. The server peer itself is not changed.
+func WithClientDefaultKeepalive(seconds int) Option {
+	return func(s *ConfigService) {
+		s.clientKeepalive = seconds
+	}
+}
+
+// WithTrafficStats controls whether returned peer configs carry receiveBytes/transmitBytes.
+// Exposed by default; privacy-focused deployments can turn the counters off.
+func WithTrafficStats(expose bool) Option {
+	return func(s *ConfigService) {
+		s.hideTraffic = !expose
+	}
+}
+
+// WithClientKeyCheck makes BuildClientConfig reject client private keys failing CheckPrivateKey,
+// e.g. the all-zero key, instead of writing them into a .conf that cannot work.
+func WithClientKeyCheck(enabled bool) Option {
+	return func(s *ConfigService) {
+		s.checkClientKeys = enabled
+	}
+}
+
+// WithOnlineThreshold overrides domain.DefaultOnlineThreshold. Non-positive values are ignored.
+func WithOnlineThreshold(d time.Duration) Option {
+	return func(s *ConfigService) {
+		if d > 0 {
+			s.onlineThreshold = d
+		}
+	}
 }
 
 // NewConfigService creates a new instance of ConfigService.
@@ -38,6 +143,7 @@ func NewConfigService(
 	clientKeyGenCmdTimeout time.Duration, // Timeout for 'wg genkey', 'wg pubkey' for client keys
 	dnsServersForClient string, // DNS servers for client .conf files
 	mtuForClient int, // MTU for client .conf files
+	opts ...Option,
 ) *ConfigService {
 	if repo == nil {
 		logger.Logger.Fatal("Repository cannot be nil for ConfigService")
@@ -60,6 +166,18 @@ func NewConfigService(
 		clientKeyGenTimeout:    clientKeyGenCmdTimeout,
 		clientConfigDNSServers: dnsServersForClient,
 		clientConfigMTU:        mtuForClient, // Store MTU
+		runner:                 repository.ExecRunner{},
+		onlineThreshold:        domain.DefaultOnlineThreshold,
+		maxClientDNS:           domain.DefaultMaxClientDNS,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.keyGen == nil {
+		s.keyGen = NewCLIKeyGenerator(s.runner, s.clientKeyGenTimeout)
+	}
+	if s.metadata == nil {
+		s.metadata, _ = repository.NewFileMetadataStore("") // In-memory store never fails
 	}
 
 	logger.Logger.Info("ConfigService initialized",
@@ -68,6 +186,7 @@ func NewConfigService(
 		zap.Duration("clientKeyGenTimeout", s.clientKeyGenTimeout),
 		zap.String("clientConfigDNSServers", s.clientConfigDNSServers),
 		zap.Int("clientConfigMTU", s.clientConfigMTU), // Log MTU
+		zap.String("keyGenerator", fmt.Sprintf("%T", s.keyGen)),
 	)
 	return s
 }
@@ -81,23 +200,209 @@ func min(a, b int) int {
 }
 
 // GetAll retrieves all peer configurations.
-func (s *ConfigService) GetAll() ([]domain.Config, error) {
-	configs, err := s.repo.ListConfigs()
+func (s *ConfigService) GetAll(ctx context.Context) ([]domain.Config, error) {
+	configs, err := s.repo.ListConfigs(ctx)
 	if err != nil {
 		logger.Logger.Error("Service: Failed to get all configs from repository", zap.Error(err))
 		return nil, err
 	}
+	for i := range configs {
+		s.prepareConfig(&configs[i])
+	}
 	logger.Logger.Debug("Service: Successfully retrieved all configs", zap.Int("count", len(configs)))
 	return configs, nil
 }
 
+// FindByNamePrefix returns peers whose metadata name starts with prefix, compared case-insensitively.
+// Peers without a stored name never match.
+func (s *ConfigService) FindByNamePrefix(ctx context.Context, prefix string) ([]domain.Config, error) {
+	configs, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = strings.ToLower(prefix)
+	matches := make([]domain.Config, 0)
+	for _, cfg := range configs {
+		if cfg.Metadata != nil && cfg.Metadata.Name != "" && strings.HasPrefix(strings.ToLower(cfg.Metadata.Name), prefix) {
+			matches = append(matches, cfg)
+		}
+	}
+	logger.Logger.Debug("Service: Found peers by name prefix", zap.String("prefix", prefix), zap.Int("count", len(matches)))
+	return matches, nil
+}
+
+// FindByTags returns peers whose metadata carries every tag in tags.
+// Peers without stored tags never match a non-empty tag list.
+func (s *ConfigService) FindByTags(ctx context.Context, tags []string) ([]domain.Config, error) {
+	configs, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]domain.Config, 0)
+	for _, cfg := range configs {
+		if cfg.Metadata != nil && cfg.Metadata.HasTags(tags) {
+			matches = append(matches, cfg)
+		}
+	}
+	logger.Logger.Debug("Service: Found peers by tags", zap.Strings("tags", tags), zap.Int("count", len(matches)))
+	return matches, nil
+}
+
+// ListCreatedBetween returns peers whose metadata createdAt lies in [after, before), oldest first.
+// A zero bound leaves that side of the window open. Peers without a recorded createdAt never match.
+func (s *ConfigService) ListCreatedBetween(ctx context.Context, after, before time.Time) ([]domain.Config, error) {
+	configs, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]domain.Config, 0)
+	for _, cfg := range configs {
+		if cfg.Metadata == nil || cfg.Metadata.CreatedAt.IsZero() {
+			continue
+		}
+		created := cfg.Metadata.CreatedAt
+		if (!after.IsZero() && created.Before(after)) || (!before.IsZero() && !created.Before(before)) {
+			continue
+		}
+		matches = append(matches, cfg)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Metadata.CreatedAt.Before(matches[j].Metadata.CreatedAt)
+	})
+	logger.Logger.Debug("Service: Found peers created in window",
+		zap.Time("after", after), zap.Time("before", before), zap.Int("count", len(matches)))
+	return matches, nil
+}
+
+// ListStale returns peers that never completed a handshake or whose latest handshake
+// is older than olderThan. A non-positive olderThan returns only never-connected peers.
+func (s *ConfigService) ListStale(ctx context.Context, olderThan time.Duration) ([]domain.Config, error) {
+	configs, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		logger.Logger.Error("Service: Failed to list configs for stale peer lookup", zap.Error(err))
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan).Unix()
+	stale := make([]domain.Config, 0)
+	for _, cfg := range configs {
+		if cfg.LatestHandshake == 0 || (olderThan > 0 && cfg.LatestHandshake < cutoff) {
+			s.stripTraffic(&cfg)
+			stale = append(stale, cfg)
+		}
+	}
+	logger.Logger.Debug("Service: Found stale peers",
+		zap.Duration("olderThan", olderThan),
+		zap.Int("total", len(configs)),
+		zap.Int("stale", len(stale)))
+	return stale, nil
+}
+
+// onlineCutoff is the oldest latest-handshake Unix time for which a peer still counts as online.
+func (s *ConfigService) onlineCutoff() int64 {
+	return time.Now().Add(-s.onlineThreshold).Unix()
+}
+
+// Summary counts peers by connection state from a single ListConfigs pass: online peers completed
+// a handshake within the online threshold, offline peers did earlier, and the rest never did.
+func (s *ConfigService) Summary(ctx context.Context) (*domain.PeerSummary, error) {
+	configs, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		logger.Logger.Error("Service: Failed to list configs for peer summary", zap.Error(err))
+		return nil, err
+	}
+
+	cutoff := s.onlineCutoff()
+	summary := &domain.PeerSummary{Total: len(configs)}
+	for _, cfg := range configs {
+		switch {
+		case cfg.LatestHandshake == 0:
+			summary.NeverConnected++
+		case cfg.LatestHandshake >= cutoff:
+			summary.Online++
+		default:
+			summary.Offline++
+		}
+	}
+	return summary, nil
+}
+
+// PeerStats returns one peer's latest handshake, byte counters and online state, without key material.
+// Like TopTalkers, it returns ErrTrafficStatsHidden when per-peer byte counters are not exposed.
+func (s *ConfigService) PeerStats(ctx context.Context, publicKey string) (*domain.PeerStats, error) {
+	if s.hideTraffic {
+		return nil, ErrTrafficStatsHidden
+	}
+	cfg, err := s.Get(ctx, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &domain.PeerStats{
+		PublicKey:     cfg.PublicKey,
+		ReceiveBytes:  cfg.ReceiveBytes,
+		TransmitBytes: cfg.TransmitBytes,
+	}
+	if cfg.LatestHandshake > 0 {
+		handshake := time.Unix(cfg.LatestHandshake, 0).UTC()
+		stats.LatestHandshake = &handshake
+		stats.Online = cfg.LatestHandshake >= s.onlineCutoff()
+	}
+	return stats, nil
+}
+
+// PruneInactive deletes peers that never completed a handshake and were created more than olderThan ago.
+// With requireZeroTraffic, peers that have received or transmitted any bytes are kept as well.
+// Peers without a recorded createdAt are never pruned, since their age is unknown.
+// A failed deletion is reported in the result and does not stop the remaining ones.
+func (s *ConfigService) PruneInactive(ctx context.Context, olderThan time.Duration, requireZeroTraffic bool) (*domain.PruneInactiveResponse, error) {
+	configs, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		logger.Logger.Error("Service: Failed to list configs for pruning inactive peers", zap.Error(err))
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	result := &domain.PruneInactiveResponse{Pruned: make([]string, 0)}
+	for _, cfg := range configs {
+		if cfg.LatestHandshake != 0 || (requireZeroTraffic && (cfg.ReceiveBytes != 0 || cfg.TransmitBytes != 0)) {
+			continue
+		}
+		md, ok := s.metadata.Get(cfg.PublicKey)
+		if !ok || md.CreatedAt.IsZero() || !md.CreatedAt.Before(cutoff) {
+			continue
+		}
+		if err := s.Delete(ctx, cfg.PublicKey); err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[cfg.PublicKey] = err.Error()
+			continue
+		}
+		result.Pruned = append(result.Pruned, cfg.PublicKey)
+	}
+	sort.Strings(result.Pruned)
+	logger.Logger.Info("Service: Pruned inactive peers",
+		zap.Duration("olderThan", olderThan),
+		zap.Bool("requireZeroTraffic", requireZeroTraffic),
+		zap.Int("pruned", len(result.Pruned)),
+		zap.Int("failed", len(result.Failed)))
+	return result, nil
+}
+
 // Get retrieves a single peer's configuration by its public key.
-func (s *ConfigService) Get(publicKey string) (*domain.Config, error) {
+func (s *ConfigService) Get(ctx context.Context, publicKey string) (*domain.Config, error) {
 	if publicKey == "" {
 		logger.Logger.Warn("Service: Get config called with empty public key")
 		return nil, errors.New("public key cannot be empty for Get operation")
 	}
-	config, err := s.repo.GetConfig(publicKey)
+	if err := validatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	config, err := s.repo.GetConfig(ctx, publicKey)
 	if err != nil {
 		if errors.Is(err, repository.ErrPeerNotFound) {
 			logger.Logger.Info("Service: Peer not found in repository", zap.String("publicKey", publicKey))
@@ -106,20 +411,63 @@ func (s *ConfigService) Get(publicKey string) (*domain.Config, error) {
 		}
 		return nil, err
 	}
+	s.prepareConfig(config)
 	logger.Logger.Debug("Service: Successfully retrieved config by public key", zap.String("publicKey", publicKey))
 	return config, nil
 }
 
+// GetByPeerID retrieves a single peer's configuration by its PeerID, matched case-insensitively.
+// It returns ErrInvalidPeerID for a malformed ID and repository.ErrPeerNotFound if no peer has it.
+func (s *ConfigService) GetByPeerID(ctx context.Context, peerID string) (*domain.Config, error) {
+	peerID, err := normalizePeerID(peerID)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		logger.Logger.Error("Service: Failed to list configs for peer ID lookup", zap.Error(err))
+		return nil, err
+	}
+	for _, cfg := range configs {
+		if id, err := PeerID(cfg.PublicKey); err == nil && id == peerID {
+			s.prepareConfig(&cfg)
+			return &cfg, nil
+		}
+	}
+	logger.Logger.Info("Service: No peer has the requested peer ID", zap.String("peerId", peerID))
+	return nil, repository.ErrPeerNotFound
+}
+
 // CreateWithNewKeys generates a new key pair, creates the peer, and returns its configuration including the private key.
-func (s *ConfigService) CreateWithNewKeys(allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+// If generatePSK is set and no presharedKey is given, a preshared key is generated too and returned in PreSharedKey.
+func (s *ConfigService) CreateWithNewKeys(ctx context.Context, allowedIPs []string, presharedKey string, generatePSK bool, persistentKeepalive int) (*domain.Config, error) {
 	if len(allowedIPs) == 0 {
 		logger.Logger.Info("Service: Creating new peer with empty AllowedIPs. This might be acceptable depending on WG configuration.")
 	}
+	allowedIPs, err := s.normalizeAllowedIPs(allowedIPs)
+	if err != nil {
+		return nil, err
+	}
 
 	newPrivKey, newPubKey, err := s.generateKeyPair() // Uses s.clientKeyGenTimeout
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate key pair for new peer: %w", err)
 	}
+	if generatePSK && presharedKey == "" {
+		if presharedKey, err = s.GenPresharedKey(); err != nil {
+			return nil, fmt.Errorf("failed to generate preshared key for new peer: %w", err)
+		}
+	}
+
+	var assigned string
+	if len(allowedIPs) == 0 && s.addressPool.IsValid() {
+		s.allocMu.Lock()
+		defer s.allocMu.Unlock()
+		if assigned, err = s.allocateAddress(ctx); err != nil {
+			return nil, err
+		}
+		allowedIPs = []string{assigned}
+	}
 
 	newPeerCfg := domain.Config{
 		PublicKey:           newPubKey,
@@ -127,6 +475,7 @@ func (s *ConfigService) CreateWithNewKeys(allowedIPs []string, presharedKey stri
 		AllowedIps:          allowedIPs,
 		PreSharedKey:        presharedKey,
 		PersistentKeepalive: persistentKeepalive,
+		AssignedAddress:     assigned,
 	}
 
 	repoPeerCfg := domain.Config{
@@ -135,22 +484,98 @@ func (s *ConfigService) CreateWithNewKeys(allowedIPs []string, presharedKey stri
 		PreSharedKey:        presharedKey,
 		PersistentKeepalive: persistentKeepalive,
 	}
-	if err := s.repo.CreateConfig(repoPeerCfg); err != nil {
+	if err := s.repo.CreateConfig(ctx, repoPeerCfg); err != nil {
 		return nil, fmt.Errorf("failed to add new peer %s to WireGuard: %w", newPubKey, err)
 	}
 
+	newPeerCfg.PeerID, _ = PeerID(newPubKey)
+	newPeerCfg.Metadata = s.storeMetadata(newPubKey, domain.PeerMetadata{CreatedAt: time.Now().UTC()})
+	newPeerCfg.Server = s.ServerIdentity()
 	logger.Logger.Info("Service: Successfully created new peer with generated keys.",
 		zap.String("newPublicKey", newPeerCfg.PublicKey))
 	return &newPeerCfg, nil
 }
 
+// CreateWithExistingKeys creates a peer with an existing public key, e.g. one migrated from another server.
+// If privateKey is given, it must match publicKey; it is returned in the result for immediate
+// client config generation but is never passed to the repository.
+func (s *ConfigService) CreateWithExistingKeys(ctx context.Context, publicKey, privateKey string, allowedIPs []string, presharedKey string, persistentKeepalive int) (*domain.Config, error) {
+	if publicKey == "" {
+		return nil, errors.New("public key is required for importing a peer")
+	}
+	if err := validatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	allowedIPs, err := s.normalizeAllowedIPs(allowedIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	if privateKey != "" {
+		matches, err := s.VerifyKeyPair(publicKey, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			logger.Logger.Warn("Service: Rejected peer import with mismatched key pair", zap.String("publicKey", publicKey))
+			return nil, ErrKeyPairMismatch
+		}
+	}
+
+	exists, err := s.peerExists(ctx, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check whether peer %s exists: %w", publicKey, err)
+	}
+	if exists {
+		return nil, fmt.Errorf("cannot import peer %s: %w", publicKey, ErrPeerAlreadyExists)
+	}
+
+	var assigned string
+	if len(allowedIPs) == 0 && s.addressPool.IsValid() {
+		s.allocMu.Lock()
+		defer s.allocMu.Unlock()
+		if assigned, err = s.allocateAddress(ctx); err != nil {
+			return nil, err
+		}
+		allowedIPs = []string{assigned}
+	}
+
+	repoPeerCfg := domain.Config{
+		PublicKey:           publicKey,
+		AllowedIps:          allowedIPs,
+		PreSharedKey:        presharedKey,
+		PersistentKeepalive: persistentKeepalive,
+	}
+	if err := s.repo.CreateConfig(ctx, repoPeerCfg); err != nil {
+		return nil, fmt.Errorf("failed to add imported peer %s to WireGuard: %w", publicKey, err)
+	}
+
+	createdCfg := repoPeerCfg
+	createdCfg.PrivateKey = privateKey // Transient: returned to the caller only
+	createdCfg.AssignedAddress = assigned
+	createdCfg.PeerID, _ = PeerID(publicKey)
+	createdCfg.Metadata = s.storeMetadata(publicKey, domain.PeerMetadata{CreatedAt: time.Now().UTC()})
+	createdCfg.Server = s.ServerIdentity()
+	logger.Logger.Info("Service: Successfully imported peer with existing keys.",
+		zap.String("publicKey", publicKey),
+		zap.Bool("privateKeyProvided", privateKey != ""))
+	return &createdCfg, nil
+}
+
 // UpdateAllowedIPs updates the allowed IPs for an existing peer.
-func (s *ConfigService) UpdateAllowedIPs(publicKey string, ips []string) error {
+func (s *ConfigService) UpdateAllowedIPs(ctx context.Context, publicKey string, ips []string) error {
 	if publicKey == "" {
 		logger.Logger.Warn("Service: UpdateAllowedIPs called with empty public key")
 		return errors.New("public key is required for updating allowed IPs")
 	}
-	err := s.repo.UpdateAllowedIPs(publicKey, ips)
+	if err := validatePublicKey(publicKey); err != nil {
+		return err
+	}
+	ips, err := s.normalizeAllowedIPs(ips)
+	if err != nil {
+		return err
+	}
+	err = s.repo.UpdateAllowedIPs(ctx, publicKey, ips)
 	if err != nil {
 		logger.Logger.Error("Service: Failed to update allowed IPs in repository",
 			zap.String("publicKey", publicKey),
@@ -163,24 +588,182 @@ func (s *ConfigService) UpdateAllowedIPs(publicKey string, ips []string) error {
 }
 
 // Delete removes a peer.
-func (s *ConfigService) Delete(publicKey string) error {
+func (s *ConfigService) Delete(ctx context.Context, publicKey string) error {
 	if publicKey == "" {
 		logger.Logger.Warn("Service: Delete config called with empty public key")
 		return errors.New("public key is required for deleting a peer")
 	}
-	err := s.repo.DeleteConfig(publicKey)
+	if err := validatePublicKey(publicKey); err != nil {
+		return err
+	}
+	err := s.repo.DeleteConfig(ctx, publicKey)
 	if err != nil {
 		logger.Logger.Error("Service: Failed to delete config in repository", zap.String("publicKey", publicKey), zap.Error(err))
 		return err
 	}
+	s.forgetMetadata(publicKey)
 	logger.Logger.Info("Service: Successfully deleted config", zap.String("publicKey", publicKey))
 	return nil
 }
 
+// DeleteVerbose removes a peer and reports whether it existed beforehand and whether it is gone afterwards.
+// Existence is checked via the repository before and after the removal, since 'wg set ... remove'
+// succeeds silently for unknown peers.
+func (s *ConfigService) DeleteVerbose(ctx context.Context, publicKey string) (*domain.DeleteConfigResponse, error) {
+	if publicKey == "" {
+		logger.Logger.Warn("Service: DeleteVerbose called with empty public key")
+		return nil, errors.New("public key is required for deleting a peer")
+	}
+	if err := validatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	existed, err := s.peerExists(ctx, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check peer %s before deletion: %w", publicKey, err)
+	}
+
+	if err := s.repo.DeleteConfig(ctx, publicKey); err != nil {
+		logger.Logger.Error("Service: Failed to delete config in repository", zap.String("publicKey", publicKey), zap.Error(err))
+		return nil, err
+	}
+
+	stillExists, err := s.peerExists(ctx, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check peer %s after deletion: %w", publicKey, err)
+	}
+
+	result := &domain.DeleteConfigResponse{Deleted: existed && !stillExists, Existed: existed}
+	if !stillExists {
+		s.forgetMetadata(publicKey)
+	}
+	logger.Logger.Info("Service: Verbose delete finished",
+		zap.String("publicKey", publicKey),
+		zap.Bool("existed", result.Existed),
+		zap.Bool("deleted", result.Deleted))
+	return result, nil
+}
+
+// SetPeerMetadata sets the name and description of a peer in the metadata store,
+// keeping its creation time. The peer itself is not checked against the repository.
+func (s *ConfigService) SetPeerMetadata(publicKey, name, description string) (*domain.PeerMetadata, error) {
+	if publicKey == "" {
+		return nil, errors.New("public key is required for setting peer metadata")
+	}
+	md, ok := s.metadata.Get(publicKey)
+	if !ok {
+		md.CreatedAt = time.Now().UTC()
+	}
+	md.Name = name
+	md.Description = description
+	if err := s.metadata.Set(publicKey, md); err != nil {
+		logger.Logger.Error("Service: Failed to store peer metadata", zap.String("publicKey", publicKey), zap.Error(err))
+		return nil, fmt.Errorf("failed to store metadata for peer %s: %w", publicKey, err)
+	}
+	return &md, nil
+}
+
+// SetPeerTags replaces the tags of a peer in the metadata store, keeping its other metadata.
+// Tags are trimmed, and empty and repeated tags are dropped. The peer itself is not checked against the repository.
+func (s *ConfigService) SetPeerTags(publicKey string, tags []string) (*domain.PeerMetadata, error) {
+	if publicKey == "" {
+		return nil, errors.New("public key is required for setting peer tags")
+	}
+	md, ok := s.metadata.Get(publicKey)
+	if !ok {
+		md.CreatedAt = time.Now().UTC()
+	}
+	md.Tags = normalizeTags(tags)
+	if err := s.metadata.Set(publicKey, md); err != nil {
+		logger.Logger.Error("Service: Failed to store peer tags", zap.String("publicKey", publicKey), zap.Error(err))
+		return nil, fmt.Errorf("failed to store tags for peer %s: %w", publicKey, err)
+	}
+	return &md, nil
+}
+
+// normalizeTags trims tags and drops empty and repeated ones, keeping the first occurrence's position.
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// prepareConfig readies a config read from the repository for a response:
+// it sets the peer ID, attaches metadata and strips traffic counters if they are not exposed.
+func (s *ConfigService) prepareConfig(cfg *domain.Config) {
+	cfg.PeerID, _ = PeerID(cfg.PublicKey)
+	s.attachMetadata(cfg)
+	s.stripTraffic(cfg)
+}
+
+// stripTraffic clears the byte counters when traffic stats are hidden; omitempty drops them from JSON.
+func (s *ConfigService) stripTraffic(cfg *domain.Config) {
+	if s.hideTraffic {
+		cfg.ReceiveBytes = 0
+		cfg.TransmitBytes = 0
+	}
+}
+
+// attachMetadata sets cfg.Metadata from the metadata store, if an entry exists.
+func (s *ConfigService) attachMetadata(cfg *domain.Config) {
+	if md, ok := s.metadata.Get(cfg.PublicKey); ok {
+		cfg.Metadata = &md
+	}
+}
+
+// storeMetadata saves md for publicKey and returns it for the response.
+// A store failure is only logged: the peer already exists on the interface and must not be reported as failed.
+func (s *ConfigService) storeMetadata(publicKey string, md domain.PeerMetadata) *domain.PeerMetadata {
+	if err := s.metadata.Set(publicKey, md); err != nil {
+		logger.Logger.Warn("Service: Failed to store peer metadata", zap.String("publicKey", publicKey), zap.Error(err))
+		return nil
+	}
+	return &md
+}
+
+// ServerIdentity returns the server public key and endpoint written into client configs,
+// as carried by create and rotate responses.
+func (s *ConfigService) ServerIdentity() *domain.ServerIdentity {
+	return &domain.ServerIdentity{PublicKey: s.serverBasePublicKey, Endpoint: s.serverEndpoint()}
+}
+
+// forgetMetadata removes the metadata of a deleted peer, logging failures.
+func (s *ConfigService) forgetMetadata(publicKey string) {
+	if err := s.metadata.Delete(publicKey); err != nil {
+		logger.Logger.Warn("Service: Failed to delete peer metadata", zap.String("publicKey", publicKey), zap.Error(err))
+	}
+}
+
+// peerExists reports whether the repository currently knows the peer.
+// ErrPeerNotFound is translated to false; any other repository error is returned.
+func (s *ConfigService) peerExists(ctx context.Context, publicKey string) (bool, error) {
+	_, err := s.repo.GetConfig(ctx, publicKey)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, repository.ErrPeerNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
 // BuildClientConfig generates the .conf file content for a client.
 // peerCfg: Peer configuration from the server (usually from 'wg show dump').
 // clientPrivateKey: Client's private key, provided by the external application.
 func (s *ConfigService) BuildClientConfig(peerCfg *domain.Config, clientPrivateKey string) (string, error) {
+	return s.BuildClientConfigWithOptions(peerCfg, clientPrivateKey, domain.ClientConfigOptions{})
+}
+
+// BuildClientConfigWithOptions is BuildClientConfig with per-file overrides from opts.
+func (s *ConfigService) BuildClientConfigWithOptions(peerCfg *domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error) {
 	if peerCfg == nil {
 		return "", errors.New("peer configuration cannot be nil for BuildClientConfig")
 	}
@@ -191,41 +774,59 @@ func (s *ConfigService) BuildClientConfig(peerCfg *domain.Config, clientPrivateK
 	if peerCfg.PublicKey == "" {
 		return "", errors.New("peer public key is missing from peerCfg, cannot build client config")
 	}
+	if s.checkClientKeys {
+		if err := CheckPrivateKey(clientPrivateKey); err != nil {
+			logger.Logger.Warn("Service: Rejected client private key for .conf generation",
+				zap.String("peerPublicKey", peerCfg.PublicKey), zap.Error(err))
+			return "", err
+		}
+	}
+
+	routes, err := clientRoutes(opts.AllowedIPs)
+	if err != nil {
+		return "", err
+	}
+	if opts.DNS != nil {
+		if err := s.validateClientDNS(*opts.DNS); err != nil {
+			return "", err
+		}
+	}
 
 	var b strings.Builder
 
 	b.WriteString("[Interface]\n")
 	b.WriteString(fmt.Sprintf("PrivateKey = %s\n", clientPrivateKey))
 	if len(peerCfg.AllowedIps) > 0 {
-		clientAddress := peerCfg.AllowedIps[0] // Usually the first IP server allows for this peer
-		// Ensure the client address has /32 mask (single host)
-		if !strings.Contains(clientAddress, "/") {
-			clientAddress += "/32"
-		} else if strings.HasSuffix(clientAddress, "/24") {
-			// Change /24 to /32 for client interface
-			clientAddress = strings.Replace(clientAddress, "/24", "/32", 1)
+		// Emit every address the server allows for this peer, so dual-stack clients get both IPv4 and IPv6.
+		clientAddresses := make([]string, 0, len(peerCfg.AllowedIps))
+		for _, allowedIP := range peerCfg.AllowedIps {
+			if addr := clientInterfaceAddress(allowedIP); addr != "" {
+				clientAddresses = append(clientAddresses, addr)
+			}
 		}
-		b.WriteString(fmt.Sprintf("Address = %s\n", clientAddress))
+		b.WriteString(fmt.Sprintf("Address = %s\n", strings.Join(clientAddresses, ", ")))
 	} else {
 		logger.Logger.Info("Service: Building client config for peer with no server-side AllowedIPs. Client Address field will be omitted.",
 			zap.String("peerPublicKey", peerCfg.PublicKey))
 	}
 
-	if len(s.clientConfigDNSServers) > 0 {
-		b.WriteString(fmt.Sprintf("DNS = %s\n", s.clientConfigDNSServers))
+	resolved := s.resolveClientSettings(peerCfg, opts)
+	if dns := resolved.DNS.Value; len(dns) > 0 {
+		b.WriteString(fmt.Sprintf("DNS = %s\n", dns))
 	}
 
-	// Add MTU if it's configured and greater than 0
-	if s.clientConfigMTU > 0 {
-		b.WriteString(fmt.Sprintf("MTU = %s\n", strconv.Itoa(s.clientConfigMTU)))
+	// Add MTU if it's overridden or configured, or else known from the interface
+	mtu := resolved.MTU.Value
+	if mtu > 0 {
+		b.WriteString(fmt.Sprintf("MTU = %s\n", strconv.Itoa(mtu)))
 	}
 
 	b.WriteString("\n")
 	b.WriteString("[Peer]\n")
 	b.WriteString(fmt.Sprintf("PublicKey = %s\n", s.serverBasePublicKey))
 
-	if s.serverBaseEndpoint != "" {
-		b.WriteString(fmt.Sprintf("Endpoint = %s\n", s.serverBaseEndpoint))
+	if resolved.Endpoint.Value != "" {
+		b.WriteString(fmt.Sprintf("Endpoint = %s\n", resolved.Endpoint.Value))
 	} else {
 		logger.Logger.Warn("Service: Server endpoint is not configured. Client .conf file will be missing the Endpoint field.",
 			zap.String("peerPublicKey", peerCfg.PublicKey))
@@ -235,83 +836,288 @@ func (s *ConfigService) BuildClientConfig(peerCfg *domain.Config, clientPrivateK
 		b.WriteString(fmt.Sprintf("PresharedKey = %s\n", peerCfg.PreSharedKey))
 	}
 
-	b.WriteString("AllowedIPs = 0.0.0.0/0, ::/0\n") // Route all traffic through VPN
+	b.WriteString(fmt.Sprintf("AllowedIPs = %s\n", strings.Join(routes, ", ")))
 
-	if peerCfg.PersistentKeepalive > 0 {
-		b.WriteString(fmt.Sprintf("PersistentKeepalive = %d\n", peerCfg.PersistentKeepalive))
+	if keepalive := resolved.PersistentKeepalive.Value; keepalive > 0 {
+		b.WriteString(fmt.Sprintf("PersistentKeepalive = %d\n", keepalive))
 	}
 
 	logger.Logger.Info("Service: Successfully built client config content using provided client private key.",
 		zap.String("peerPublicKey", peerCfg.PublicKey),
-		zap.Int("mtuAdded", s.clientConfigMTU)) // Log MTU value that was (or wasn't if 0) added
+		zap.Int("mtuAdded", mtu)) // Log MTU value that was (or wasn't if 0) added
 	return b.String(), nil
 }
 
-// generateKeyPair generates a new key pair (private/public).
+// fullTunnelRoutes are the client AllowedIPs routing all traffic through the VPN.
+var fullTunnelRoutes = []string{"0.0.0.0/0", "::/0"}
+
+// clientRoutes returns the [Peer] AllowedIPs of a client config: the requested prefixes for split
+// tunneling, or fullTunnelRoutes when none are requested.
+func clientRoutes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return fullTunnelRoutes, nil
+	}
+	routes := make([]string, 0, len(requested))
+	for _, raw := range requested {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%w: client route %q is not a CIDR prefix", ErrInvalidAllowedIP, raw)
+		}
+		routes = append(routes, prefix.String())
+	}
+	return routes, nil
+}
+
+// PreviewClientConfig builds the .conf a client would get once a peer with peer's values is registered,
+// without reading or changing the repository. AllowedIPs and keys are validated as on creation.
+func (s *ConfigService) PreviewClientConfig(peer domain.Config, clientPrivateKey string, opts domain.ClientConfigOptions) (string, error) {
+	if !domain.IsValidKey(peer.PublicKey) {
+		return "", domain.ErrInvalidKeyFormat
+	}
+	if peer.PreSharedKey != "" && !domain.IsValidKey(peer.PreSharedKey) {
+		return "", domain.ErrInvalidKeyFormat
+	}
+	ips, err := s.normalizeAllowedIPs(peer.AllowedIps)
+	if err != nil {
+		return "", err
+	}
+	peer.AllowedIps = ips
+	return s.BuildClientConfigWithOptions(&peer, clientPrivateKey, opts)
+}
+
+// ExplainClientConfig reports the DNS, MTU, endpoint and keepalive a client .conf for peerCfg built with opts
+// would get, and where each value comes from.
+func (s *ConfigService) ExplainClientConfig(peerCfg domain.Config, opts domain.ClientConfigOptions) *domain.ClientConfigExplanation {
+	resolved := s.resolveClientSettings(&peerCfg, opts)
+	return &resolved
+}
+
+// resolveClientSettings picks the defaulted client .conf values in order of precedence:
+// a per-file override, then the peer's own keepalive, then the configured value, then the interface MTU.
+func (s *ConfigService) resolveClientSettings(peerCfg *domain.Config, opts domain.ClientConfigOptions) domain.ClientConfigExplanation {
+	resolved := domain.ClientConfigExplanation{
+		DNS:      domain.ExplainedString{Value: s.clientConfigDNSServers, Source: domain.SourceServerDefault},
+		Endpoint: domain.ExplainedString{Value: s.serverEndpoint(), Source: domain.SourceServerDefault},
+	}
+	if opts.DNS != nil {
+		resolved.DNS = domain.ExplainedString{Value: *opts.DNS, Source: domain.SourceRequest}
+	}
+
+	if opts.MTU != nil {
+		resolved.MTU = domain.ExplainedInt{Value: *opts.MTU, Source: domain.SourceRequest}
+	} else if s.clientConfigMTU > 0 {
+		resolved.MTU = domain.ExplainedInt{Value: s.clientConfigMTU, Source: domain.SourceServerDefault}
+	} else if mtu := s.interfaceMTU(); mtu > 0 {
+		resolved.MTU = domain.ExplainedInt{Value: mtu, Source: domain.SourceInterfaceFallback}
+	} else {
+		resolved.MTU = domain.ExplainedInt{Source: domain.SourceServerDefault}
+	}
+
+	switch {
+	case opts.PersistentKeepalive != nil: // A per-file override wins over both
+		resolved.PersistentKeepalive = domain.ExplainedInt{Value: *opts.PersistentKeepalive, Source: domain.SourceRequest}
+	case peerCfg.PersistentKeepalive > 0: // The peer's own value wins over the configured default
+		resolved.PersistentKeepalive = domain.ExplainedInt{Value: peerCfg.PersistentKeepalive, Source: domain.SourcePeer}
+	default:
+		resolved.PersistentKeepalive = domain.ExplainedInt{Value: s.clientKeepalive, Source: domain.SourceServerDefault}
+	}
+	return resolved
+}
+
+// interfaceMTU returns the live interface MTU when the repository can read it, used for client configs
+// when no MTU is configured. Reading is best-effort; 0 means omit the MTU line.
+func (s *ConfigService) interfaceMTU() int {
+	reader, ok := s.repo.(repository.MTUReader)
+	if !ok {
+		return 0
+	}
+	mtu, err := reader.InterfaceMTU()
+	if err != nil {
+		logger.Logger.Debug("Service: Could not read interface MTU, omitting MTU from client config", zap.Error(err))
+		return 0
+	}
+	return mtu
+}
+
+// clientInterfaceAddress converts a server-side AllowedIP entry into an address for the client's [Interface].
+// Entries without a mask get a single-host mask (/32 for IPv4, /128 for IPv6); a /24 is narrowed to /32.
+func clientInterfaceAddress(allowedIP string) string {
+	addr := strings.TrimSpace(allowedIP)
+	if addr == "" {
+		return ""
+	}
+	if !strings.Contains(addr, "/") {
+		if strings.Contains(addr, ":") {
+			return addr + "/128"
+		}
+		return addr + "/32"
+	}
+	if strings.HasSuffix(addr, "/24") {
+		// Change /24 to /32 for client interface
+		return strings.Replace(addr, "/24", "/32", 1)
+	}
+	return addr
+}
+
+// generateKeyPair generates a new key pair (private/public) using the configured KeyGenerator.
 func (s *ConfigService) generateKeyPair() (privKey, pubKey string, err error) {
-	logger.Logger.Debug("Service: Generating new key pair for a client", zap.Duration("timeout", s.clientKeyGenTimeout))
+	privKey, pubKey, err = s.keyGen.GenerateKeyPair()
+	if err != nil {
+		return "", "", err
+	}
+	if privKey == "" || pubKey == "" {
+		logger.Logger.Error("Service: Key generator produced an empty key for client.")
+		return "", "", errors.New("key generator produced an empty key")
+	}
+	return privKey, pubKey, nil
+}
+
+// validatePublicKey returns ErrInvalidPublicKey unless publicKey is a well-formed WireGuard key.
+func validatePublicKey(publicKey string) error {
+	if !domain.IsValidKey(publicKey) {
+		logger.Logger.Warn("Service: Rejected malformed public key", zap.String("publicKey", publicKey))
+		return fmt.Errorf("%w: %q", ErrInvalidPublicKey, publicKey)
+	}
+	return nil
+}
+
+// GenPresharedKey generates a new preshared key with the configured KeyGenerator
+// ('wg genpsk' for the CLI backend, bounded by the key generation timeout).
+func (s *ConfigService) GenPresharedKey() (string, error) {
+	psk, err := s.keyGen.GeneratePSK()
+	if err != nil {
+		return "", err
+	}
+	if psk == "" {
+		logger.Logger.Error("Service: Key generator produced an empty preshared key.")
+		return "", errors.New("key generator produced an empty preshared key")
+	}
+	return psk, nil
+}
+
+// VerifyKeyPair reports whether privateKey corresponds to publicKey.
+// The public key is derived from the private key via 'wg pubkey' and compared with the supplied one.
+// The private key is never logged; only the outcome of the comparison is.
+func (s *ConfigService) VerifyKeyPair(publicKey, privateKey string) (bool, error) {
+	if publicKey == "" || privateKey == "" {
+		logger.Logger.Warn("Service: VerifyKeyPair called with an empty key")
+		return false, errors.New("both public and private keys are required for key verification")
+	}
+
+	derivedPubKey, err := s.derivePublicKey(privateKey)
+	if err != nil {
+		return false, err
+	}
+
+	matches := derivedPubKey == publicKey
+	logger.Logger.Info("Service: Verified client key pair",
+		zap.String("publicKey", publicKey),
+		zap.Bool("matches", matches))
+	return matches, nil
+}
+
+// derivePublicKey derives a public key from privateKey using 'wg pubkey'.
+// The private key is passed via stdin and is never included in logs or returned errors.
+func (s *ConfigService) derivePublicKey(privateKey string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.clientKeyGenTimeout)
 	defer cancel()
 
-	genCmd := exec.CommandContext(ctx, "wg", "genkey")
-	privKeyBytes, err := genCmd.Output()
+	stdout, stderr, err := s.runner.Run(ctx, privateKey, "wg", "pubkey")
 	if ctx.Err() == context.DeadlineExceeded {
-		logger.Logger.Error("Service: Timeout during 'wg genkey' for client key.")
-		return "", "", fmt.Errorf("wg genkey timed out: %w", repository.ErrWgTimeout)
+		logger.Logger.Error("Service: Timeout during 'wg pubkey' for key derivation.")
+		return "", fmt.Errorf("wg pubkey timed out: %w", repository.ErrWgTimeout)
 	}
 	if err != nil {
 		var exitError *exec.ExitError
-		errMsg := fmt.Sprintf("wg genkey command failed: %s", err.Error())
 		if errors.As(err, &exitError) {
-			errMsg = fmt.Sprintf("wg genkey command failed with exit code %d: %s. Stderr: %s", exitError.ExitCode(), err.Error(), string(exitError.Stderr))
+			// 'wg pubkey' exits non-zero when the input is not a valid key.
+			logger.Logger.Warn("Service: 'wg pubkey' rejected the supplied private key",
+				zap.Int("exitCode", exitError.ExitCode()), zap.String("stderr", strings.TrimSpace(string(stderr))))
+			return "", ErrInvalidPrivateKey
 		}
-		logger.Logger.Error("Service: Failed to generate client private key", zap.String("details", errMsg), zap.Error(err))
-		return "", "", errors.New(errMsg)
+		logger.Logger.Error("Service: Failed to execute 'wg pubkey' for key derivation", zap.Error(err))
+		return "", fmt.Errorf("wg pubkey command failed: %w", err)
 	}
-	privKey = strings.TrimSpace(string(privKeyBytes))
-	if privKey == "" {
-		logger.Logger.Error("Service: 'wg genkey' produced an empty private key for client.")
-		return "", "", errors.New("wg genkey produced empty private key")
+
+	pubKey := strings.TrimSpace(string(stdout))
+	if pubKey == "" {
+		logger.Logger.Error("Service: 'wg pubkey' produced an empty public key during key derivation.")
+		return "", errors.New("wg pubkey produced empty public key")
 	}
+	return pubKey, nil
+}
 
-	pubCmd := exec.CommandContext(ctx, "wg", "pubkey")
-	pubCmd.Stdin = strings.NewReader(privKey)
-	pubKeyBytes, err := pubCmd.Output()
-	if ctx.Err() == context.DeadlineExceeded {
-		logger.Logger.Error("Service: Timeout during 'wg pubkey' for client key.")
-		return "", "", fmt.Errorf("wg pubkey timed out: %w", repository.ErrWgTimeout)
+// RotatePresharedKey replaces an existing peer's preshared key with a newly generated one and returns it.
+// AllowedIPs and keepalive are kept. The key is sensitive and never logged.
+func (s *ConfigService) RotatePresharedKey(ctx context.Context, publicKey string) (string, error) {
+	if publicKey == "" {
+		logger.Logger.Warn("Service: RotatePresharedKey called with empty public key")
+		return "", errors.New("public key cannot be empty for preshared key rotation")
 	}
+	if err := validatePublicKey(publicKey); err != nil {
+		return "", err
+	}
+	cfg, err := s.repo.GetConfig(ctx, publicKey)
 	if err != nil {
-		var exitError *exec.ExitError
-		errMsg := fmt.Sprintf("wg pubkey command failed: %s", err.Error())
-		if errors.As(err, &exitError) {
-			errMsg = fmt.Sprintf("wg pubkey command failed with exit code %d: %s. Stderr: %s", exitError.ExitCode(), err.Error(), string(exitError.Stderr))
-		}
-		logger.Logger.Error("Service: Failed to generate client public key from private key", zap.String("details", errMsg), zap.Error(err))
-		return "", "", errors.New(errMsg)
+		return "", fmt.Errorf("cannot rotate preshared key for peer %s: %w", publicKey, err)
 	}
-	pubKey = strings.TrimSpace(string(pubKeyBytes))
-	if pubKey == "" {
-		logger.Logger.Error("Service: 'wg pubkey' produced an empty public key for client.")
-		return "", "", errors.New("wg pubkey produced empty public key")
+	psk, err := s.GenPresharedKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate preshared key for peer %s: %w", publicKey, err)
+	}
+	err = s.repo.CreateConfig(ctx, domain.Config{
+		PublicKey:           publicKey,
+		AllowedIps:          cfg.AllowedIps,
+		PreSharedKey:        psk,
+		PersistentKeepalive: cfg.PersistentKeepalive,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to apply new preshared key for peer %s: %w", publicKey, err)
 	}
+	logger.Logger.Info("Service: Rotated preshared key", zap.String("publicKey", publicKey)) // DO NOT log the key
+	return psk, nil
+}
 
-	logger.Logger.Info("Service: Successfully generated new client key pair.")
-	return privKey, pubKey, nil
+// RotatePeerKey rotates keys for an existing peer, carrying its metadata over unchanged.
+func (s *ConfigService) RotatePeerKey(ctx context.Context, oldPublicKey string) (*domain.Config, error) {
+	return s.RotatePeerKeyWithOptions(ctx, oldPublicKey, domain.RotateOptions{})
 }
 
-// RotatePeerKey rotates keys for an existing peer.
-func (s *ConfigService) RotatePeerKey(oldPublicKey string) (*domain.Config, error) {
+// RotatePeerKeyWithOptions rotates keys for an existing peer. The old peer's metadata moves to
+// the new public key (with opts.Name replacing the name, if set) and the old entry is cleared.
+// Each call is counted in the rotation metrics; any returned error counts as a failure.
+// Rotations run one at a time from reading the old peer to deleting it, so of several concurrent
+// rotations of a key exactly one succeeds; the others find the key gone.
+func (s *ConfigService) RotatePeerKeyWithOptions(ctx context.Context, oldPublicKey string, opts domain.RotateOptions) (_ *domain.Config, err error) {
+	metrics.RotationsTotal.Inc()
+	defer func() {
+		if err != nil {
+			metrics.RotationFailuresTotal.Inc()
+		}
+	}()
+
 	if oldPublicKey == "" {
 		logger.Logger.Warn("Service: RotatePeerKey called with empty old public key")
 		return nil, errors.New("old public key cannot be empty for key rotation")
 	}
+	if err := validatePublicKey(oldPublicKey); err != nil {
+		return nil, err
+	}
+	if opts.ExpectedPublicKey != "" && opts.ExpectedPublicKey != oldPublicKey {
+		return nil, fmt.Errorf("%w: rotating %s, expected %s", ErrRotationKeyMismatch, oldPublicKey, opts.ExpectedPublicKey)
+	}
 	logger.Logger.Info("Service: Attempting to rotate peer key", zap.String("oldPublicKey", oldPublicKey))
 
-	oldCfg, err := s.repo.GetConfig(oldPublicKey)
+	s.rotateMu.Lock()
+	defer s.rotateMu.Unlock()
+	oldCfg, err := s.repo.GetConfig(ctx, oldPublicKey)
 	if err != nil {
 		logger.Logger.Error("Service (Rotate): Failed to get old peer config", zap.String("oldPublicKey", oldPublicKey), zap.Error(err))
 		if errors.Is(err, repository.ErrPeerNotFound) {
+			if opts.ExpectedPublicKey != "" {
+				// The caller saw this key, so it was rotated or deleted in the meantime.
+				return nil, fmt.Errorf("%w: peer %s no longer has this key", ErrRotationConflict, oldPublicKey)
+			}
 			return nil, fmt.Errorf("cannot rotate key for peer %s: %w", oldPublicKey, repository.ErrPeerNotFound)
 		}
 		return nil, fmt.Errorf("failed to retrieve config for peer %s before rotation: %w", oldPublicKey, err)
@@ -339,7 +1145,8 @@ func (s *ConfigService) RotatePeerKey(oldPublicKey string) (*domain.Config, erro
 		PreSharedKey:        oldCfg.PreSharedKey,
 		PersistentKeepalive: oldCfg.PersistentKeepalive,
 	}
-	if err := s.repo.CreateConfig(repoPeerCfgForCreate); err != nil {
+	// The new key must be live before the old one is removed, so the write skips any batching window.
+	if err := s.repo.CreateConfig(repository.ImmediateWrite(ctx), repoPeerCfgForCreate); err != nil {
 		logger.Logger.Error("Service (Rotate): Failed to create new peer config with rotated keys",
 			zap.String("newPublicKey", newPubKey),
 			zap.Error(err))
@@ -350,9 +1157,27 @@ func (s *ConfigService) RotatePeerKey(oldPublicKey string) (*domain.Config, erro
 		zap.String("oldPublicKey", oldPublicKey),
 		zap.String("newPublicKey", newPubKey))
 
+	// The new peer is live from here on, so its metadata must follow even if removing the old peer fails.
+	md, ok := s.metadata.Get(oldPublicKey)
+	if !ok {
+		md.CreatedAt = time.Now().UTC()
+	}
+	if opts.Name != "" {
+		md.Name = opts.Name
+	}
+	rotatedAt := time.Now().UTC()
+	md.RotationCount++
+	md.LastRotatedAt = &rotatedAt
+	newPeerDomainCfg.PeerID, _ = PeerID(newPubKey)
+	newPeerDomainCfg.Metadata = s.storeMetadata(newPubKey, md)
+	newPeerDomainCfg.Server = s.ServerIdentity()
+	s.forgetMetadata(oldPublicKey)
+
 	logger.Logger.Debug("Service (Rotate): About to call repo.DeleteConfig with key", zap.String("keyForDelete", oldPublicKey))
 
-	if err := s.repo.DeleteConfig(oldPublicKey); err != nil {
+	// With the new peer live, finish the rotation even if the caller goes away; only the timeout applies.
+	if err := s.repo.DeleteConfig(context.WithoutCancel(ctx), oldPublicKey); err != nil {
+		metrics.RotationOldPeerDeleteFailuresTotal.Inc()
 		logger.Logger.Error("CRITICAL (Rotate): New peer config applied, but FAILED TO DELETE OLD PEER CONFIG. Manual cleanup may be needed.",
 			zap.String("oldPublicKey", oldPublicKey),
 			zap.String("newPublicKey", newPubKey),
@@ -2,12 +2,17 @@
 package service
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"strconv" // Added for MTU tests
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap" // For logger in tests
@@ -15,6 +20,7 @@ import (
 
 	"wgMicro_api/internal/domain"
 	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/metrics"
 	"wgMicro_api/internal/repository" // For mock repository and its errors
 )
 
@@ -49,7 +55,7 @@ func newFakeRepository() *fakeRepository {
 	}
 }
 
-func (r *fakeRepository) ListConfigs() ([]domain.Config, error) {
+func (r *fakeRepository) ListConfigs(ctx context.Context) ([]domain.Config, error) {
 	if r.ListConfigsError != nil {
 		return nil, r.ListConfigsError
 	}
@@ -60,7 +66,11 @@ func (r *fakeRepository) ListConfigs() ([]domain.Config, error) {
 	return list, nil
 }
 
-func (r *fakeRepository) GetConfig(publicKey string) (*domain.Config, error) {
+func (r *fakeRepository) GetInterfaceInfo(ctx context.Context) (*domain.InterfaceInfo, error) {
+	return &domain.InterfaceInfo{PublicKey: "testServiceServerPubKey", ListenPort: 12345}, nil
+}
+
+func (r *fakeRepository) GetConfig(ctx context.Context, publicKey string) (*domain.Config, error) {
 	if r.GetConfigFunc != nil { // Если кастомная функция задана, вызываем ее
 		return r.GetConfigFunc(publicKey)
 	}
@@ -75,7 +85,7 @@ func (r *fakeRepository) GetConfig(publicKey string) (*domain.Config, error) {
 	return &cfg, nil
 }
 
-func (r *fakeRepository) CreateConfig(cfg domain.Config) error {
+func (r *fakeRepository) CreateConfig(ctx context.Context, cfg domain.Config) error {
 	if r.CreateConfigFunc != nil { // <--- Используем CreateConfigFunc
 		return r.CreateConfigFunc(cfg)
 	}
@@ -103,7 +113,7 @@ func (r *fakeRepository) CreateConfig(cfg domain.Config) error {
 }
 
 // internal/service/config_test.go
-func (r *fakeRepository) UpdateAllowedIPs(publicKey string, allowedIps []string) error {
+func (r *fakeRepository) UpdateAllowedIPs(ctx context.Context, publicKey string, allowedIps []string) error {
 	if r.UpdateAllowedIPsFunc != nil { // Вызываем функцию из поля, если она задана
 		return r.UpdateAllowedIPsFunc(publicKey, allowedIps)
 	}
@@ -120,7 +130,7 @@ func (r *fakeRepository) UpdateAllowedIPs(publicKey string, allowedIps []string)
 	return nil
 }
 
-func (r *fakeRepository) DeleteConfig(publicKey string) error {
+func (r *fakeRepository) DeleteConfig(ctx context.Context, publicKey string) error {
 	if r.DeleteFunc != nil { // Если кастомная функция задана, вызываем ее
 		return r.DeleteFunc(publicKey)
 	}
@@ -138,6 +148,40 @@ func (r *fakeRepository) DeleteConfig(publicKey string) error {
 	return nil
 }
 
+// fakeRunner is a stub repository.CommandRunner for service tests.
+// RunFunc answers each command; every invocation is recorded in calls as "name arg1 arg2 ...".
+type fakeRunner struct {
+	RunFunc func(stdin, name string, args ...string) ([]byte, []byte, error)
+	calls   []string
+}
+
+// Ensure fakeRunner implements repository.CommandRunner
+var _ repository.CommandRunner = &fakeRunner{}
+
+func (f *fakeRunner) Run(_ context.Context, stdin string, name string, args ...string) ([]byte, []byte, error) {
+	f.calls = append(f.calls, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+	if f.RunFunc != nil {
+		return f.RunFunc(stdin, name, args...)
+	}
+	return nil, nil, fmt.Errorf("fakeRunner: unexpected command %s %v", name, args)
+}
+
+// pubkeyRunner returns a fakeRunner that answers 'wg pubkey' using the given private->public key table.
+func pubkeyRunner(t *testing.T, pairs map[string]string) *fakeRunner {
+	t.Helper()
+	return &fakeRunner{
+		RunFunc: func(stdin, name string, args ...string) ([]byte, []byte, error) {
+			require.Equal(t, "wg", name)
+			require.Equal(t, []string{"pubkey"}, args)
+			pub, ok := pairs[stdin]
+			if !ok {
+				return nil, nil, fmt.Errorf("fakeRunner: unknown private key")
+			}
+			return []byte(pub + "\n"), nil, nil
+		},
+	}
+}
+
 // setupTestService initializes ConfigService with a mock repository and test config values.
 func setupTestService(t *testing.T, repo repository.Repo, clientMTU int) *ConfigService { // Added clientMTU
 	t.Helper()
@@ -158,7 +202,8 @@ func setupTestService(t *testing.T, repo repository.Repo, clientMTU int) *Config
 		testServerExternalEndpoint,
 		testClientKeyGenCmdTimeout,
 		testDnsServersForClient,
-		clientMTU, // Pass MTU
+		clientMTU,                              // Pass MTU
+		WithKeyGenerator(NativeKeyGenerator{}), // Keeps key-generating tests independent of installed WireGuard tools
 	)
 	return svc
 }
@@ -249,6 +294,214 @@ func TestBuildClientConfig_Service(t *testing.T) {
 	assert.Error(t, err, "BuildClientConfig should return error if peerCfg.PublicKey is empty")
 }
 
+func TestBuildClientConfig_DualStackAddress(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+
+	clientPeerConfig := &domain.Config{
+		PublicKey:  "dualStackPeerPubKey",
+		AllowedIps: []string{"10.10.0.3/32", "fd00::3/128"},
+	}
+
+	out, err := svc.BuildClientConfig(clientPeerConfig, "dualStackPeerPrivKey")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Address = 10.10.0.3/32, fd00::3/128\n", "Address line should list both IPv4 and IPv6 addresses")
+	assert.Equal(t, 1, strings.Count(out, "Address = "), "There should be exactly one Address line")
+}
+
+func TestBuildClientConfig_DefaultKeepalive(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+	WithClientDefaultKeepalive(25)(svc)
+
+	t.Run("Default_applies_without_peer_value", func(t *testing.T) {
+		peerCfg := &domain.Config{PublicKey: "noKeepalivePeer", AllowedIps: []string{"10.10.0.4/32"}}
+		out, err := svc.BuildClientConfig(peerCfg, "noKeepalivePrivKey")
+		require.NoError(t, err)
+		assert.Contains(t, out, "PersistentKeepalive = 25\n")
+		assert.Zero(t, peerCfg.PersistentKeepalive, "The server-side peer must not be changed")
+	})
+
+	t.Run("Peer_value_overrides_default", func(t *testing.T) {
+		peerCfg := &domain.Config{PublicKey: "keepalivePeer", AllowedIps: []string{"10.10.0.5/32"}, PersistentKeepalive: 10}
+		out, err := svc.BuildClientConfig(peerCfg, "keepalivePrivKey")
+		require.NoError(t, err)
+		assert.Contains(t, out, "PersistentKeepalive = 10\n")
+		assert.Equal(t, 1, strings.Count(out, "PersistentKeepalive"))
+	})
+}
+
+func TestBuildClientConfigWithOptions_KeepaliveOverride(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+	peerCfg := &domain.Config{PublicKey: "overridePeer", AllowedIps: []string{"10.10.0.7/32"}, PersistentKeepalive: 10}
+	keepalive := func(v int) *int { return &v }
+
+	t.Run("Default_mirrors_server_peer", func(t *testing.T) {
+		out, err := svc.BuildClientConfigWithOptions(peerCfg, "overridePrivKey", domain.ClientConfigOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, out, "PersistentKeepalive = 10\n")
+	})
+
+	t.Run("Override_replaces_peer_value", func(t *testing.T) {
+		offPeer := &domain.Config{PublicKey: "natPeer", AllowedIps: []string{"10.10.0.8/32"}} // Keepalive off server-side
+		out, err := svc.BuildClientConfigWithOptions(offPeer, "natPrivKey", domain.ClientConfigOptions{PersistentKeepalive: keepalive(25)})
+		require.NoError(t, err)
+		assert.Contains(t, out, "PersistentKeepalive = 25\n")
+		assert.Zero(t, offPeer.PersistentKeepalive, "The server-side peer must not be changed")
+	})
+
+	t.Run("Zero_override_omits_keepalive", func(t *testing.T) {
+		out, err := svc.BuildClientConfigWithOptions(peerCfg, "overridePrivKey", domain.ClientConfigOptions{PersistentKeepalive: keepalive(0)})
+		require.NoError(t, err)
+		assert.NotContains(t, out, "PersistentKeepalive")
+	})
+}
+
+func TestBuildClientConfigWithOptions_SplitTunnel(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+	peerCfg := &domain.Config{PublicKey: "splitPeer", AllowedIps: []string{"10.8.0.7/32"}}
+
+	t.Run("Default_full_tunnel", func(t *testing.T) {
+		out, err := svc.BuildClientConfigWithOptions(peerCfg, "splitPrivKey", domain.ClientConfigOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, out, "[Peer]\n")
+		assert.Contains(t, out, "AllowedIPs = 0.0.0.0/0, ::/0\n")
+	})
+
+	t.Run("Custom_subnet", func(t *testing.T) {
+		out, err := svc.BuildClientConfigWithOptions(peerCfg, "splitPrivKey", domain.ClientConfigOptions{AllowedIPs: []string{"10.8.0.0/24"}})
+		require.NoError(t, err)
+		assert.Contains(t, out, "AllowedIPs = 10.8.0.0/24\n")
+		assert.NotContains(t, out, "0.0.0.0/0")
+		assert.Contains(t, out, "Address = 10.8.0.7/32\n", "The client's own address is unaffected")
+
+		out, err = svc.BuildClientConfigWithOptions(peerCfg, "splitPrivKey", domain.ClientConfigOptions{AllowedIPs: []string{"10.8.0.0/24", " fd00::/64 "}})
+		require.NoError(t, err)
+		assert.Contains(t, out, "AllowedIPs = 10.8.0.0/24, fd00::/64\n")
+	})
+
+	t.Run("Invalid_entry_rejected", func(t *testing.T) {
+		for _, entry := range []string{"10.8.0.1", "not-a-cidr", "10.8.0.0/33"} {
+			_, err := svc.BuildClientConfigWithOptions(peerCfg, "splitPrivKey", domain.ClientConfigOptions{AllowedIPs: []string{entry}})
+			assert.ErrorIs(t, err, ErrInvalidAllowedIP, "entry %q", entry)
+		}
+	})
+}
+
+// mtuRepo adds repository.MTUReader to the fake repository.
+type mtuRepo struct {
+	*fakeRepository
+	mtu int
+	err error
+}
+
+func (r *mtuRepo) InterfaceMTU() (int, error) { return r.mtu, r.err }
+
+func TestBuildClientConfig_InterfaceMTUFallback(t *testing.T) {
+	peerCfg := &domain.Config{PublicKey: "mtuPeerPubKey", AllowedIps: []string{"10.10.0.6/32"}}
+
+	testCases := []struct {
+		name       string
+		repo       repository.Repo
+		configured int
+		expected   string // Expected MTU line, empty if none
+	}{
+		{name: "Interface_MTU_used_when_unset", repo: &mtuRepo{fakeRepository: newFakeRepository(), mtu: 1420}, expected: "MTU = 1420\n"},
+		{name: "Configured_MTU_overrides_interface", repo: &mtuRepo{fakeRepository: newFakeRepository(), mtu: 1420}, configured: 1380, expected: "MTU = 1380\n"},
+		{name: "Read_failure_omits_MTU", repo: &mtuRepo{fakeRepository: newFakeRepository(), err: errors.New("ip: not found")}},
+		{name: "Repository_without_reader_omits_MTU", repo: newFakeRepository()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := setupTestService(t, tc.repo, tc.configured)
+			out, err := svc.BuildClientConfig(peerCfg, "mtuPeerPrivKey")
+			require.NoError(t, err)
+			if tc.expected == "" {
+				assert.NotContains(t, out, "MTU =")
+			} else {
+				assert.Contains(t, out, tc.expected)
+				assert.Equal(t, 1, strings.Count(out, "MTU ="))
+			}
+		})
+	}
+}
+
+func TestExplainClientConfig_Sources(t *testing.T) {
+	dns, mtu, keepalive := "9.9.9.9", 1280, 15
+	interfaceRepo := func() repository.Repo { return &mtuRepo{fakeRepository: newFakeRepository(), mtu: 1420} }
+
+	testCases := []struct {
+		name       string
+		repo       repository.Repo
+		configured int // CLIENT_CONFIG_MTU
+		peer       domain.Config
+		opts       domain.ClientConfigOptions
+		expected   domain.ClientConfigExplanation
+	}{
+		{
+			name: "Server_defaults_and_interface_MTU",
+			repo: interfaceRepo(),
+			expected: domain.ClientConfigExplanation{
+				DNS:                 domain.ExplainedString{Value: "1.1.1.1", Source: domain.SourceServerDefault},
+				MTU:                 domain.ExplainedInt{Value: 1420, Source: domain.SourceInterfaceFallback},
+				Endpoint:            domain.ExplainedString{Value: "test-service.example.com:12345", Source: domain.SourceServerDefault},
+				PersistentKeepalive: domain.ExplainedInt{Value: 0, Source: domain.SourceServerDefault},
+			},
+		},
+		{
+			name:       "Configured_MTU_and_peer_keepalive",
+			repo:       interfaceRepo(),
+			configured: 1380,
+			peer:       domain.Config{PersistentKeepalive: 25},
+			expected: domain.ClientConfigExplanation{
+				DNS:                 domain.ExplainedString{Value: "1.1.1.1", Source: domain.SourceServerDefault},
+				MTU:                 domain.ExplainedInt{Value: 1380, Source: domain.SourceServerDefault},
+				Endpoint:            domain.ExplainedString{Value: "test-service.example.com:12345", Source: domain.SourceServerDefault},
+				PersistentKeepalive: domain.ExplainedInt{Value: 25, Source: domain.SourcePeer},
+			},
+		},
+		{
+			name:       "Request_overrides_win",
+			repo:       interfaceRepo(),
+			configured: 1380,
+			peer:       domain.Config{PersistentKeepalive: 25},
+			opts:       domain.ClientConfigOptions{DNS: &dns, MTU: &mtu, PersistentKeepalive: &keepalive},
+			expected: domain.ClientConfigExplanation{
+				DNS:                 domain.ExplainedString{Value: "9.9.9.9", Source: domain.SourceRequest},
+				MTU:                 domain.ExplainedInt{Value: 1280, Source: domain.SourceRequest},
+				Endpoint:            domain.ExplainedString{Value: "test-service.example.com:12345", Source: domain.SourceServerDefault},
+				PersistentKeepalive: domain.ExplainedInt{Value: 15, Source: domain.SourceRequest},
+			},
+		},
+		{
+			name: "No_MTU_anywhere",
+			repo: newFakeRepository(),
+			expected: domain.ClientConfigExplanation{
+				DNS:                 domain.ExplainedString{Value: "1.1.1.1", Source: domain.SourceServerDefault},
+				MTU:                 domain.ExplainedInt{Value: 0, Source: domain.SourceServerDefault},
+				Endpoint:            domain.ExplainedString{Value: "test-service.example.com:12345", Source: domain.SourceServerDefault},
+				PersistentKeepalive: domain.ExplainedInt{Value: 0, Source: domain.SourceServerDefault},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := setupTestService(t, tc.repo, tc.configured)
+			tc.peer.PublicKey = "explainPeerPubKey"
+			assert.Equal(t, tc.expected, *svc.ExplainClientConfig(tc.peer, tc.opts))
+
+			// The explanation must describe the file that is actually built.
+			out, err := svc.BuildClientConfigWithOptions(&tc.peer, "explainPeerPrivKey", tc.opts)
+			require.NoError(t, err)
+			if tc.expected.MTU.Value > 0 {
+				assert.Contains(t, out, fmt.Sprintf("MTU = %d\n", tc.expected.MTU.Value))
+			}
+			if tc.expected.PersistentKeepalive.Value > 0 {
+				assert.Contains(t, out, fmt.Sprintf("PersistentKeepalive = %d\n", tc.expected.PersistentKeepalive.Value))
+			}
+			assert.Contains(t, out, "DNS = "+tc.expected.DNS.Value+"\n")
+		})
+	}
+}
+
 func TestCreateWithNewKeys_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	// MTU value doesn't affect CreateWithNewKeys logic directly, so passing 0 or any valid value.
@@ -258,7 +511,7 @@ func TestCreateWithNewKeys_Service(t *testing.T) {
 	psk := "newServicePeerPSK"
 	keepalive := 33
 
-	createdCfg, err := svc.CreateWithNewKeys(allowedIPs, psk, keepalive)
+	createdCfg, err := svc.CreateWithNewKeys(context.Background(), allowedIPs, psk, false, keepalive)
 	require.NoError(t, err, "CreateWithNewKeys should not return an error")
 	require.NotNil(t, createdCfg, "Returned config should not be nil")
 
@@ -268,18 +521,40 @@ func TestCreateWithNewKeys_Service(t *testing.T) {
 	assert.Equal(t, psk, createdCfg.PreSharedKey, "PreSharedKey should match input")
 	assert.Equal(t, keepalive, createdCfg.PersistentKeepalive, "PersistentKeepalive should match input")
 
-	repoCfg, repoErr := mockRepo.GetConfig(createdCfg.PublicKey)
+	repoCfg, repoErr := mockRepo.GetConfig(context.Background(), createdCfg.PublicKey)
 	require.NoError(t, repoErr, "Peer should be findable in repository after creation")
 	require.NotNil(t, repoCfg, "Config from repo should not be nil")
 	assert.Equal(t, createdCfg.PublicKey, repoCfg.PublicKey)
 	assert.Empty(t, repoCfg.PrivateKey, "Repository should not store the client's private key")
 }
 
+func TestCreateAndRotate_IncludeServerIdentity_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+	want := &domain.ServerIdentity{PublicKey: "testServiceServerPubKey", Endpoint: "test-service.example.com:12345"}
+
+	created, err := svc.CreateWithNewKeys(context.Background(), []string{"10.20.1.2/32"}, "", false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, want, created.Server)
+
+	imported, err := svc.CreateWithExistingKeys(context.Background(), "importedServerIdentityKeyAAAAAAAAAAAAAAAAAA=", "", []string{"10.20.1.3/32"}, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, want, imported.Server)
+
+	rotated, err := svc.RotatePeerKey(context.Background(), created.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, want, rotated.Server)
+
+	fetched, err := svc.Get(context.Background(), rotated.PublicKey)
+	require.NoError(t, err)
+	assert.Nil(t, fetched.Server, "Only create and rotate responses carry the server identity")
+}
+
 func TestRotatePeerKey_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU not directly relevant here
 
-	oldPeerKey := "peerToRotateServicePubKey"
+	oldPeerKey := "peerToRotateServicePubKeyAAAAAAAAAAAAAAAAAA="
 	oldPeer := domain.Config{
 		PublicKey:           oldPeerKey,
 		AllowedIps:          []string{"10.30.0.1/32"},
@@ -288,7 +563,7 @@ func TestRotatePeerKey_Service(t *testing.T) {
 	}
 	mockRepo.configs[oldPeerKey] = oldPeer // Pre-populate the repo
 
-	rotatedCfg, err := svc.RotatePeerKey(oldPeerKey)
+	rotatedCfg, err := svc.RotatePeerKey(context.Background(), oldPeerKey)
 	require.NoError(t, err, "RotatePeerKey should not return an error")
 	require.NotNil(t, rotatedCfg, "Returned rotated config should not be nil")
 
@@ -298,24 +573,203 @@ func TestRotatePeerKey_Service(t *testing.T) {
 	assert.Equal(t, oldPeer.PreSharedKey, rotatedCfg.PreSharedKey, "PreSharedKey should be preserved")
 	assert.Equal(t, oldPeer.PersistentKeepalive, rotatedCfg.PersistentKeepalive, "PersistentKeepalive should be preserved")
 
-	_, err = mockRepo.GetConfig(oldPeerKey)
+	_, err = mockRepo.GetConfig(context.Background(), oldPeerKey)
 	assert.ErrorIs(t, err, repository.ErrPeerNotFound, "Old peer should be deleted from repository")
 
-	newRepoCfg, err := mockRepo.GetConfig(rotatedCfg.PublicKey)
+	newRepoCfg, err := mockRepo.GetConfig(context.Background(), rotatedCfg.PublicKey)
 	require.NoError(t, err, "New peer should be findable in repository")
 	require.NotNil(t, newRepoCfg)
 	assert.Empty(t, newRepoCfg.PrivateKey, "Repository should not store the new client's private key")
 }
 
+func TestListStale_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	now := time.Now()
+	mockRepo.configs["neverConnected"] = domain.Config{PublicKey: "neverConnected", LatestHandshake: 0}
+	mockRepo.configs["agedOut"] = domain.Config{PublicKey: "agedOut", LatestHandshake: now.Add(-10 * 24 * time.Hour).Unix()}
+	mockRepo.configs["recent"] = domain.Config{PublicKey: "recent", LatestHandshake: now.Add(-2 * time.Hour).Unix()}
+
+	stalePublicKeys := func(configs []domain.Config) []string {
+		keys := make([]string, 0, len(configs))
+		for _, cfg := range configs {
+			keys = append(keys, cfg.PublicKey)
+		}
+		return keys
+	}
+
+	stale, err := svc.ListStale(context.Background(), 7*24*time.Hour)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"neverConnected", "agedOut"}, stalePublicKeys(stale))
+
+	stale, err = svc.ListStale(context.Background(), time.Hour)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"neverConnected", "agedOut", "recent"}, stalePublicKeys(stale))
+
+	stale, err = svc.ListStale(context.Background(), 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"neverConnected"}, stalePublicKeys(stale), "Zero olderThan should only return never-connected peers")
+}
+
+func TestSummary_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	now := time.Now()
+	for key, handshake := range map[string]int64{
+		"onlineJustNow":   now.Unix(),
+		"onlineRecent":    now.Add(-2 * time.Minute).Unix(),
+		"offlineAnHour":   now.Add(-time.Hour).Unix(),
+		"offlineLastWeek": now.Add(-7 * 24 * time.Hour).Unix(),
+		"offlineMinutes":  now.Add(-5 * time.Minute).Unix(),
+		"neverConnected":  0,
+	} {
+		mockRepo.configs[key] = domain.Config{PublicKey: key, LatestHandshake: handshake}
+	}
+
+	summary, err := svc.Summary(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerSummary{Total: 6, Online: 2, Offline: 3, NeverConnected: 1}, *summary)
+
+	WithOnlineThreshold(10 * time.Minute)(svc)
+	summary, err = svc.Summary(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerSummary{Total: 6, Online: 3, Offline: 2, NeverConnected: 1}, *summary, "The threshold should be configurable")
+
+	mockRepo.ListConfigsError = errors.New("list failed")
+	_, err = svc.Summary(context.Background())
+	assert.Error(t, err)
+}
+
+func TestPeerStats_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	online := "onlinePeerStatsKeyAAAAAAAAAAAAAAAAAAAAAAAAA="
+	offline := "offlinePeerStatsKeyAAAAAAAAAAAAAAAAAAAAAAAA="
+	never := "neverPeerStatsKeyAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	recent := time.Now().Add(-time.Minute).Unix()
+	mockRepo.configs[online] = domain.Config{PublicKey: online, PreSharedKey: "secretPSK", LatestHandshake: recent, ReceiveBytes: 1024, TransmitBytes: 2048}
+	mockRepo.configs[offline] = domain.Config{PublicKey: offline, LatestHandshake: time.Now().Add(-time.Hour).Unix()}
+	mockRepo.configs[never] = domain.Config{PublicKey: never}
+
+	stats, err := svc.PeerStats(context.Background(), online)
+	require.NoError(t, err)
+	assert.True(t, stats.Online)
+	require.NotNil(t, stats.LatestHandshake)
+	assert.Equal(t, recent, stats.LatestHandshake.Unix())
+	assert.Equal(t, uint64(1024), stats.ReceiveBytes)
+	assert.Equal(t, uint64(2048), stats.TransmitBytes)
+
+	stats, err = svc.PeerStats(context.Background(), offline)
+	require.NoError(t, err)
+	assert.False(t, stats.Online, "Online uses the same threshold as Summary")
+	WithOnlineThreshold(2 * time.Hour)(svc)
+	stats, err = svc.PeerStats(context.Background(), offline)
+	require.NoError(t, err)
+	assert.True(t, stats.Online, "The threshold should be configurable")
+
+	stats, err = svc.PeerStats(context.Background(), never)
+	require.NoError(t, err)
+	assert.False(t, stats.Online)
+	assert.Nil(t, stats.LatestHandshake)
+
+	_, err = svc.PeerStats(context.Background(), "missingPeerStatsKeyAAAAAAAAAAAAAAAAAAAAAAAA=")
+	assert.ErrorIs(t, err, repository.ErrPeerNotFound)
+
+	WithTrafficStats(false)(svc)
+	_, err = svc.PeerStats(context.Background(), online)
+	assert.ErrorIs(t, err, ErrTrafficStatsHidden, "Hidden traffic is refused, not reported as zero")
+}
+
+func TestPublicKeyValidation_Service(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	testCases := []struct {
+		name  string
+		key   string
+		valid bool
+	}{
+		{name: "Too_short", key: "c2hvcnQ=", valid: false},
+		{name: "Too_long", key: base64.StdEncoding.EncodeToString(make([]byte, 33)), valid: false},
+		{name: "Not_base64", key: "not base64 at all, but forty-four chars long!", valid: false},
+		{name: "Correct", key: valid, valid: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := newFakeRepository()
+			mockRepo.configs[tc.key] = domain.Config{PublicKey: tc.key, AllowedIps: []string{"10.0.0.2/32"}}
+			svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+			ctx := context.Background()
+
+			_, getErr := svc.Get(ctx, tc.key)
+			updateErr := svc.UpdateAllowedIPs(ctx, tc.key, []string{"10.0.0.3/32"})
+			_, rotateErr := svc.RotatePeerKey(ctx, tc.key)
+			deleteErr := svc.Delete(ctx, tc.key)
+			for op, err := range map[string]error{"Get": getErr, "UpdateAllowedIPs": updateErr, "RotatePeerKey": rotateErr, "Delete": deleteErr} {
+				if tc.valid {
+					assert.NotErrorIs(t, err, ErrInvalidPublicKey, op)
+				} else {
+					assert.ErrorIs(t, err, ErrInvalidPublicKey, op)
+				}
+			}
+			if !tc.valid {
+				assert.Equal(t, []string{"10.0.0.2/32"}, mockRepo.configs[tc.key].AllowedIps, "The repository must not be touched")
+			}
+		})
+	}
+}
+
+func TestPruneInactive_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	seed := func(cfg domain.Config, createdAt time.Time) {
+		mockRepo.configs[cfg.PublicKey] = cfg
+		if !createdAt.IsZero() {
+			require.NoError(t, svc.metadata.Set(cfg.PublicKey, domain.PeerMetadata{CreatedAt: createdAt}))
+		}
+	}
+	seed(domain.Config{PublicKey: "inactiveOldAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, old)
+	seed(domain.Config{PublicKey: "inactiveOldTooAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, old)
+	seed(domain.Config{PublicKey: "handshaked", LatestHandshake: old.Unix()}, old)
+	seed(domain.Config{PublicKey: "trafficOnlyAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", ReceiveBytes: 148}, old)
+	seed(domain.Config{PublicKey: "inactiveNew"}, time.Now().Add(-time.Hour))
+	seed(domain.Config{PublicKey: "unknownAge"}, time.Time{})
+
+	result, err := svc.PruneInactive(context.Background(), 30*24*time.Hour, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"inactiveOldAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "inactiveOldTooAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, result.Pruned)
+	assert.Empty(t, result.Failed)
+	assert.NotContains(t, mockRepo.configs, "inactiveOldAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	_, hasMetadata := svc.metadata.Get("inactiveOldAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	assert.False(t, hasMetadata, "Pruned peers should lose their metadata")
+	for _, kept := range []string{"handshaked", "trafficOnlyAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "inactiveNew", "unknownAge"} {
+		assert.Contains(t, mockRepo.configs, kept)
+	}
+
+	result, err = svc.PruneInactive(context.Background(), 30*24*time.Hour, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"trafficOnlyAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, result.Pruned, "Without requireZeroTraffic, traffic alone does not keep a peer")
+
+	seed(domain.Config{PublicKey: "undeletableAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, old)
+	mockRepo.DeleteConfigError = errors.New("wg set failed")
+	result, err = svc.PruneInactive(context.Background(), 30*24*time.Hour, true)
+	require.NoError(t, err)
+	assert.Empty(t, result.Pruned)
+	assert.Contains(t, result.Failed["undeletableAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="], "wg set failed")
+}
+
 // TestGet_NotFound tests fetching a non-existent peer from the service.
 func TestGet_NotFound(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	nonExistentKey := "someNonExistentKey"
+	nonExistentKey := "someNonExistentKeyAAAAAAAAAAAAAAAAAAAAAAAAA="
 	mockRepo.GetConfigError = repository.ErrPeerNotFound
 
-	config, err := svc.Get(nonExistentKey)
+	config, err := svc.Get(context.Background(), nonExistentKey)
 
 	require.Error(t, err, "Expected an error when getting a non-existent peer")
 	assert.Nil(t, config, "Expected config to be nil on error")
@@ -333,7 +787,7 @@ func TestCreateWithNewKeys_RepoError(t *testing.T) {
 	simulatedRepoErrorMessage := "repository failed to create config"
 	mockRepo.CreateConfigError = errors.New(simulatedRepoErrorMessage)
 
-	createdCfg, err := svc.CreateWithNewKeys(allowedIPs, psk, keepalive)
+	createdCfg, err := svc.CreateWithNewKeys(context.Background(), allowedIPs, psk, false, keepalive)
 
 	require.Error(t, err, "Expected an error when repository fails to create config")
 	assert.Nil(t, createdCfg, "Returned config should be nil on repository error")
@@ -344,7 +798,7 @@ func TestUpdateAllowedIPs_Success_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	targetPublicKey := "peerToUpdateIPsInService"
+	targetPublicKey := "peerToUpdateIPsInServiceAAAAAAAAAAAAAAAAAAA="
 	newIPs := []string{"192.168.200.1/32", "fd00::100/128"}
 	mockRepo.configs[targetPublicKey] = domain.Config{PublicKey: targetPublicKey, AllowedIps: []string{"10.0.0.1/32"}}
 
@@ -362,10 +816,10 @@ func TestUpdateAllowedIPs_Success_Service(t *testing.T) {
 		return nil
 	}
 
-	err := svc.UpdateAllowedIPs(targetPublicKey, newIPs)
+	err := svc.UpdateAllowedIPs(context.Background(), targetPublicKey, newIPs)
 	require.NoError(t, err)
 	assert.True(t, repoUpdateCalled)
-	updatedPeerConfig, _ := mockRepo.GetConfig(targetPublicKey)
+	updatedPeerConfig, _ := mockRepo.GetConfig(context.Background(), targetPublicKey)
 	require.NotNil(t, updatedPeerConfig)
 	assert.Equal(t, newIPs, updatedPeerConfig.AllowedIps)
 }
@@ -374,7 +828,7 @@ func TestUpdateAllowedIPs_NotFound_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	nonExistentPeerKey := "nonExistentPeerForUpdateService"
+	nonExistentPeerKey := "nonExistentPeerForUpdateServiceAAAAAAAAAAAA="
 	newIPs := []string{"172.16.10.1/32"}
 
 	repoUpdateCalled := false
@@ -384,7 +838,7 @@ func TestUpdateAllowedIPs_NotFound_Service(t *testing.T) {
 		return repository.ErrPeerNotFound
 	}
 
-	err := svc.UpdateAllowedIPs(nonExistentPeerKey, newIPs)
+	err := svc.UpdateAllowedIPs(context.Background(), nonExistentPeerKey, newIPs)
 	require.Error(t, err)
 	assert.True(t, repoUpdateCalled)
 	assert.ErrorIs(t, err, repository.ErrPeerNotFound)
@@ -394,7 +848,7 @@ func TestUpdateAllowedIPs_RepoError_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	targetPeerKey := "peerForUpdateRepoError"
+	targetPeerKey := "peerForUpdateRepoErrorAAAAAAAAAAAAAAAAAAAAA="
 	newIPs := []string{"172.16.20.1/32"}
 	simulatedRepoError := fmt.Errorf("simulated generic repository error during IP update")
 	mockRepo.configs[targetPeerKey] = domain.Config{PublicKey: targetPeerKey}
@@ -406,7 +860,7 @@ func TestUpdateAllowedIPs_RepoError_Service(t *testing.T) {
 		return simulatedRepoError
 	}
 
-	err := svc.UpdateAllowedIPs(targetPeerKey, newIPs)
+	err := svc.UpdateAllowedIPs(context.Background(), targetPeerKey, newIPs)
 	require.Error(t, err)
 	assert.True(t, repoUpdateCalled)
 	assert.Equal(t, simulatedRepoError, err)
@@ -416,7 +870,7 @@ func TestDelete_Success_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	targetPublicKey := "peerToDeleteInService"
+	targetPublicKey := "peerToDeleteInServiceAAAAAAAAAAAAAAAAAAAAAA="
 	mockRepo.configs[targetPublicKey] = domain.Config{PublicKey: targetPublicKey, AllowedIps: []string{"10.0.100.1/32"}}
 
 	repoDeleteCalled := false
@@ -431,10 +885,10 @@ func TestDelete_Success_Service(t *testing.T) {
 		return nil
 	}
 
-	err := svc.Delete(targetPublicKey)
+	err := svc.Delete(context.Background(), targetPublicKey)
 	require.NoError(t, err)
 	assert.True(t, repoDeleteCalled)
-	_, getErr := mockRepo.GetConfig(targetPublicKey)
+	_, getErr := mockRepo.GetConfig(context.Background(), targetPublicKey)
 	assert.ErrorIs(t, getErr, repository.ErrPeerNotFound)
 }
 
@@ -442,7 +896,7 @@ func TestDelete_RepoError_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	targetPeerKey := "peerForDeleteRepoError"
+	targetPeerKey := "peerForDeleteRepoErrorAAAAAAAAAAAAAAAAAAAAA="
 	simulatedRepoError := fmt.Errorf("simulated generic repository error during deletion")
 
 	repoDeleteCalled := false
@@ -452,17 +906,57 @@ func TestDelete_RepoError_Service(t *testing.T) {
 		return simulatedRepoError
 	}
 
-	err := svc.Delete(targetPeerKey)
+	err := svc.Delete(context.Background(), targetPeerKey)
 	require.Error(t, err)
 	assert.True(t, repoDeleteCalled)
 	assert.Equal(t, simulatedRepoError, err)
 }
 
+func TestDeleteVerbose_ExistingPeer_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	targetPublicKey := "peerToDeleteVerboselyAAAAAAAAAAAAAAAAAAAAAA="
+	mockRepo.configs[targetPublicKey] = domain.Config{PublicKey: targetPublicKey, AllowedIps: []string{"10.0.100.2/32"}}
+
+	result, err := svc.DeleteVerbose(context.Background(), targetPublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, &domain.DeleteConfigResponse{Deleted: true, Existed: true}, result)
+	_, getErr := mockRepo.GetConfig(context.Background(), targetPublicKey)
+	assert.ErrorIs(t, getErr, repository.ErrPeerNotFound)
+}
+
+func TestDeleteVerbose_NonExistingPeer_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	// The fake repository mirrors 'wg set ... remove' and does not fail for unknown peers.
+	result, err := svc.DeleteVerbose(context.Background(), "peerThatNeverExistedAAAAAAAAAAAAAAAAAAAAAAA=")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.DeleteConfigResponse{Deleted: false, Existed: false}, result)
+}
+
+func TestDeleteVerbose_LookupError_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	simulatedErr := errors.New("simulated wg show failure")
+	mockRepo.GetConfigError = simulatedErr
+	mockRepo.DeleteFunc = func(key string) error {
+		t.Errorf("DeleteConfig should not be called when the existence check fails")
+		return nil
+	}
+
+	_, err := svc.DeleteVerbose(context.Background(), "somePeerAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, simulatedErr)
+}
+
 func TestRotatePeerKey_PeerNotFound_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	nonExistentOldPublicKey := "nonExistentPeerForRotationService"
+	nonExistentOldPublicKey := "nonExistentPeerForRotationServiceAAAAAAAAAA="
 	repoGetCalled := false
 	mockRepo.GetConfigFunc = func(key string) (*domain.Config, error) {
 		repoGetCalled = true
@@ -478,7 +972,7 @@ func TestRotatePeerKey_PeerNotFound_Service(t *testing.T) {
 		return nil
 	}
 
-	rotatedCfg, err := svc.RotatePeerKey(nonExistentOldPublicKey)
+	rotatedCfg, err := svc.RotatePeerKey(context.Background(), nonExistentOldPublicKey)
 	require.Error(t, err)
 	assert.Nil(t, rotatedCfg)
 	assert.True(t, repoGetCalled)
@@ -486,11 +980,293 @@ func TestRotatePeerKey_PeerNotFound_Service(t *testing.T) {
 	assert.Contains(t, err.Error(), fmt.Sprintf("cannot rotate key for peer %s", nonExistentOldPublicKey))
 }
 
+func TestRotatePeerKey_OldPeerDeleteFailedMetric_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	oldPublicKey := "oldPeerKeyForDeleteMetricAAAAAAAAAAAAAAAAAA="
+	mockRepo.configs[oldPublicKey] = domain.Config{PublicKey: oldPublicKey, AllowedIps: []string{"10.0.0.9/32"}}
+	mockRepo.DeleteFunc = func(string) error { return errors.New("simulated delete failure") }
+
+	rotationsBefore := testutil.ToFloat64(metrics.RotationsTotal)
+	failuresBefore := testutil.ToFloat64(metrics.RotationFailuresTotal)
+	deleteFailuresBefore := testutil.ToFloat64(metrics.RotationOldPeerDeleteFailuresTotal)
+
+	rotatedCfg, err := svc.RotatePeerKey(context.Background(), oldPublicKey)
+	require.Error(t, err)
+	require.NotNil(t, rotatedCfg, "New config is still returned when only the old peer deletion fails")
+
+	assert.Equal(t, rotationsBefore+1, testutil.ToFloat64(metrics.RotationsTotal))
+	assert.Equal(t, failuresBefore+1, testutil.ToFloat64(metrics.RotationFailuresTotal))
+	assert.Equal(t, deleteFailuresBefore+1, testutil.ToFloat64(metrics.RotationOldPeerDeleteFailuresTotal))
+}
+
+func TestRotatePeerKey_MetadataFollowsNewKey_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	oldPublicKey := "oldPeerKeyWithMetadataAAAAAAAAAAAAAAAAAAAAA="
+	mockRepo.configs[oldPublicKey] = domain.Config{PublicKey: oldPublicKey, AllowedIps: []string{"10.0.0.10/32"}}
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, svc.metadata.Set(oldPublicKey, domain.PeerMetadata{Name: "alice", Description: "laptop", CreatedAt: createdAt}))
+
+	rotated, err := svc.RotatePeerKey(context.Background(), oldPublicKey)
+	require.NoError(t, err)
+	require.NotNil(t, rotated.Metadata)
+	require.NotNil(t, rotated.Metadata.LastRotatedAt)
+	expected := domain.PeerMetadata{Name: "alice", Description: "laptop", CreatedAt: createdAt, RotationCount: 1, LastRotatedAt: rotated.Metadata.LastRotatedAt}
+	assert.Equal(t, expected, *rotated.Metadata, "Metadata should be carried over, with the rotation recorded")
+
+	stored, ok := svc.metadata.Get(rotated.PublicKey)
+	require.True(t, ok, "Metadata should be stored under the new key")
+	assert.Equal(t, expected, stored)
+	_, ok = svc.metadata.Get(oldPublicKey)
+	assert.False(t, ok, "Old metadata entry should be cleared")
+
+	renamed, err := svc.RotatePeerKeyWithOptions(context.Background(), rotated.PublicKey, domain.RotateOptions{Name: "alice-phone"})
+	require.NoError(t, err)
+	stored, ok = svc.metadata.Get(renamed.PublicKey)
+	require.True(t, ok)
+	assert.Equal(t, "alice-phone", stored.Name, "Name from rotate options should replace the old name")
+	assert.Equal(t, "laptop", stored.Description)
+	assert.Equal(t, createdAt, stored.CreatedAt, "CreatedAt should survive repeated rotations")
+
+	fetched, err := svc.Get(context.Background(), renamed.PublicKey)
+	require.NoError(t, err)
+	require.NotNil(t, fetched.Metadata)
+	assert.Equal(t, "alice-phone", fetched.Metadata.Name)
+}
+
+func TestRotatePeerKey_RotationHistory_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	key := "peerWithRotationHistoryAAAAAAAAAAAAAAAAAAAA="
+	mockRepo.configs[key] = domain.Config{PublicKey: key, AllowedIps: []string{"10.0.0.11/32"}}
+	created, err := svc.Get(context.Background(), key)
+	require.NoError(t, err)
+	if created.Metadata != nil {
+		assert.Zero(t, created.Metadata.RotationCount)
+		assert.Nil(t, created.Metadata.LastRotatedAt, "A peer that was never rotated has no lastRotatedAt")
+	}
+
+	var previous time.Time
+	for i := 1; i <= 3; i++ {
+		before := time.Now().UTC()
+		rotated, err := svc.RotatePeerKey(context.Background(), key)
+		require.NoError(t, err)
+		key = rotated.PublicKey
+
+		fetched, err := svc.Get(context.Background(), key)
+		require.NoError(t, err)
+		require.NotNil(t, fetched.Metadata)
+		assert.Equal(t, i, fetched.Metadata.RotationCount, "Each rotation should increment the count")
+		require.NotNil(t, fetched.Metadata.LastRotatedAt)
+		last := *fetched.Metadata.LastRotatedAt
+		assert.False(t, last.Before(before), "lastRotatedAt should be set to the rotation time")
+		assert.False(t, last.Before(previous), "lastRotatedAt should move forward across rotations")
+		previous = last
+	}
+}
+
+func TestRotatePeerKey_ExpectedPublicKey_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	current := "currentPeerKeyForCASAAAAAAAAAAAAAAAAAAAAAAA="
+	mockRepo.configs[current] = domain.Config{PublicKey: current, AllowedIps: []string{"10.0.0.11/32"}}
+
+	t.Run("Mismatched_body_rejected_without_changes", func(t *testing.T) {
+		rotated, err := svc.RotatePeerKeyWithOptions(context.Background(), current, domain.RotateOptions{ExpectedPublicKey: "staleKey"})
+		require.Error(t, err)
+		assert.Nil(t, rotated)
+		assert.ErrorIs(t, err, ErrRotationKeyMismatch)
+		assert.Equal(t, domain.CategoryInvalid, domain.CategoryOf(err))
+		require.Len(t, mockRepo.configs, 1)
+		assert.Contains(t, mockRepo.configs, current, "The peer must be left untouched")
+	})
+
+	t.Run("Match_proceeds", func(t *testing.T) {
+		rotated, err := svc.RotatePeerKeyWithOptions(context.Background(), current, domain.RotateOptions{ExpectedPublicKey: current})
+		require.NoError(t, err)
+		assert.NotEqual(t, current, rotated.PublicKey)
+		assert.NotContains(t, mockRepo.configs, current)
+		assert.Contains(t, mockRepo.configs, rotated.PublicKey)
+	})
+}
+
+func TestRotatePeerKey_ExpectedPublicKey_LostRace(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	seen := "racedPeerKeyForCASAAAAAAAAAAAAAAAAAAAAAAAAA="
+	mockRepo.configs[seen] = domain.Config{PublicKey: seen, AllowedIps: []string{"10.0.0.12/32"}}
+	mockRepo.GetConfigFunc = func(publicKey string) (*domain.Config, error) {
+		cfg, ok := mockRepo.configs[publicKey]
+		time.Sleep(5 * time.Millisecond) // Gives a concurrent rotation time to read the same peer
+		if !ok {
+			return nil, repository.ErrPeerNotFound
+		}
+		return &cfg, nil
+	}
+
+	// Several clients read the key and rotate it at the same time.
+	const rotations = 8
+	var wg sync.WaitGroup
+	results := make([]*domain.Config, rotations)
+	errs := make([]error, rotations)
+	for i := 0; i < rotations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = svc.RotatePeerKeyWithOptions(context.Background(), seen, domain.RotateOptions{ExpectedPublicKey: seen})
+		}(i)
+	}
+	wg.Wait()
+
+	var winner *domain.Config
+	for i, err := range errs {
+		if err == nil {
+			require.Nil(t, winner, "Exactly one concurrent rotation may succeed")
+			winner = results[i]
+			continue
+		}
+		assert.Nil(t, results[i])
+		assert.ErrorIs(t, err, ErrRotationConflict, "A lost race should be a conflict, not a missing peer")
+		assert.NotErrorIs(t, err, repository.ErrPeerNotFound)
+	}
+	require.NotNil(t, winner, "One concurrent rotation must succeed")
+	require.Len(t, mockRepo.configs, 1, "The losing rotations must not create peers")
+	assert.Contains(t, mockRepo.configs, winner.PublicKey)
+
+	_, err := svc.RotatePeerKeyWithOptions(context.Background(), seen, domain.RotateOptions{})
+	assert.ErrorIs(t, err, repository.ErrPeerNotFound, "Without an expectation a missing peer stays 404")
+}
+
+func TestListCreatedBetween_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 12, 0, 0, 0, time.UTC) }
+	for key, created := range map[string]time.Time{"mayFirstKey": day(1), "mayTenthKey": day(10), "mayFifthKey": day(5), "mayTwentiethKey": day(20)} {
+		mockRepo.configs[key] = domain.Config{PublicKey: key, AllowedIps: []string{"10.0.0.30/32"}}
+		require.NoError(t, svc.metadata.Set(key, domain.PeerMetadata{CreatedAt: created}))
+	}
+	mockRepo.configs["noMetadataKey"] = domain.Config{PublicKey: "noMetadataKey"}
+
+	keys := func(configs []domain.Config) []string {
+		out := make([]string, 0, len(configs))
+		for _, cfg := range configs {
+			out = append(out, cfg.PublicKey)
+		}
+		return out
+	}
+
+	matches, err := svc.ListCreatedBetween(context.Background(), day(5), day(20))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mayFifthKey", "mayTenthKey"}, keys(matches), "Window is [after, before), oldest first")
+
+	matches, err = svc.ListCreatedBetween(context.Background(), day(11), time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mayTwentiethKey"}, keys(matches), "A zero bound leaves the window open")
+
+	matches, err = svc.ListCreatedBetween(context.Background(), day(21), day(30))
+	require.NoError(t, err)
+	assert.NotNil(t, matches, "An empty window should yield an empty list, not nil")
+	assert.Empty(t, matches)
+}
+
+func TestFindByNamePrefix_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	for key, name := range map[string]string{"aliceLaptopKey": "Alice-laptop", "alicePhoneKey": "alice-phone", "bobKey": "Bob", "unnamedKey": ""} {
+		mockRepo.configs[key] = domain.Config{PublicKey: key, AllowedIps: []string{"10.0.0.20/32"}}
+		if name != "" {
+			require.NoError(t, svc.metadata.Set(key, domain.PeerMetadata{Name: name}))
+		}
+	}
+
+	matches, err := svc.FindByNamePrefix(context.Background(), "ALICE")
+	require.NoError(t, err)
+	keys := make([]string, 0, len(matches))
+	for _, cfg := range matches {
+		keys = append(keys, cfg.PublicKey)
+		require.NotNil(t, cfg.Metadata, "Matches should carry their metadata")
+	}
+	assert.ElementsMatch(t, []string{"aliceLaptopKey", "alicePhoneKey"}, keys)
+
+	matches, err = svc.FindByNamePrefix(context.Background(), "carol")
+	require.NoError(t, err)
+	assert.NotNil(t, matches, "No match should yield an empty list, not nil")
+	assert.Empty(t, matches)
+
+	mockRepo.ListConfigsError = errors.New("list failed")
+	_, err = svc.FindByNamePrefix(context.Background(), "alice")
+	assert.Error(t, err)
+}
+
+func TestFindByTags_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
+
+	for key, tags := range map[string][]string{
+		"opsStagingKey": {"env:staging", "team:ops"},
+		"opsProdKey":    {"env:prod", "team:ops"},
+		"devStagingKey": {"env:staging", "team:dev"},
+		"untaggedKey":   nil,
+	} {
+		mockRepo.configs[key] = domain.Config{PublicKey: key, AllowedIps: []string{"10.0.0.21/32"}}
+		if tags != nil {
+			_, err := svc.SetPeerTags(key, tags)
+			require.NoError(t, err)
+		}
+	}
+	keys := func(configs []domain.Config) []string {
+		out := make([]string, 0, len(configs))
+		for _, cfg := range configs {
+			out = append(out, cfg.PublicKey)
+		}
+		return out
+	}
+
+	matches, err := svc.FindByTags(context.Background(), []string{"env:staging"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"opsStagingKey", "devStagingKey"}, keys(matches))
+
+	matches, err = svc.FindByTags(context.Background(), []string{"env:staging", "team:ops"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"opsStagingKey"}, keys(matches), "Every tag must be present")
+
+	matches, err = svc.FindByTags(context.Background(), []string{"env:qa"})
+	require.NoError(t, err)
+	assert.NotNil(t, matches, "No match should yield an empty list, not nil")
+	assert.Empty(t, matches)
+}
+
+func TestSetPeerTags_Service(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0) // MTU irrelevant
+
+	_, err := svc.SetPeerMetadata("taggedKey", "alice", "laptop")
+	require.NoError(t, err)
+	md, err := svc.SetPeerTags("taggedKey", []string{" team:ops ", "", "env:staging", "team:ops"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team:ops", "env:staging"}, md.Tags, "Tags should be trimmed and deduplicated")
+	assert.Equal(t, "alice", md.Name, "Setting tags should keep the other metadata")
+
+	md, err = svc.SetPeerMetadata("taggedKey", "alice-renamed", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team:ops", "env:staging"}, md.Tags, "Renaming should keep the tags")
+
+	_, err = svc.SetPeerTags("", []string{"team:ops"})
+	assert.Error(t, err)
+}
+
 func TestRotatePeerKey_CreateNewPeerError_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	oldPublicKey := "oldPeerKeyForCreateError"
+	oldPublicKey := "oldPeerKeyForCreateErrorAAAAAAAAAAAAAAAAAAA="
 	oldPeerConfig := domain.Config{PublicKey: oldPublicKey, AllowedIps: []string{"10.0.0.1/32"}, PreSharedKey: "psk1", PersistentKeepalive: 25}
 	mockRepo.configs[oldPublicKey] = oldPeerConfig
 
@@ -514,7 +1290,7 @@ func TestRotatePeerKey_CreateNewPeerError_Service(t *testing.T) {
 		return nil
 	}
 
-	rotatedCfg, err := svc.RotatePeerKey(oldPublicKey)
+	rotatedCfg, err := svc.RotatePeerKey(context.Background(), oldPublicKey)
 	require.Error(t, err)
 	assert.Nil(t, rotatedCfg)
 	assert.True(t, repoGetCalled)
@@ -522,7 +1298,7 @@ func TestRotatePeerKey_CreateNewPeerError_Service(t *testing.T) {
 	assert.False(t, repoDeleteCalled)
 	assert.Contains(t, err.Error(), simulatedCreateErrorMessage)
 	assert.Contains(t, err.Error(), fmt.Sprintf("failed to apply new configuration for rotated peer %s", oldPublicKey))
-	_, getErr := mockRepo.GetConfig(oldPublicKey)
+	_, getErr := mockRepo.GetConfig(context.Background(), oldPublicKey)
 	assert.NoError(t, getErr)
 }
 
@@ -530,7 +1306,7 @@ func TestRotatePeerKey_DeleteOldPeerError_Service(t *testing.T) {
 	mockRepo := newFakeRepository()
 	svc := setupTestService(t, mockRepo, 0) // MTU irrelevant
 
-	oldPublicKey := "oldPeerKeyForDeleteError"
+	oldPublicKey := "oldPeerKeyForDeleteErrorAAAAAAAAAAAAAAAAAAA="
 	oldPeerConfig := domain.Config{PublicKey: oldPublicKey, AllowedIps: []string{"10.0.0.2/32"}, PreSharedKey: "psk2", PersistentKeepalive: 22}
 	mockRepo.configs[oldPublicKey] = oldPeerConfig
 
@@ -568,7 +1344,7 @@ func TestRotatePeerKey_DeleteOldPeerError_Service(t *testing.T) {
 		return errors.New(simulatedDeleteErrorMessage)
 	}
 
-	rotatedCfg, err := svc.RotatePeerKey(oldPublicKey)
+	rotatedCfg, err := svc.RotatePeerKey(context.Background(), oldPublicKey)
 	require.Error(t, err)
 	require.NotNil(t, rotatedCfg) // New config IS returned
 
@@ -581,8 +1357,79 @@ func TestRotatePeerKey_DeleteOldPeerError_Service(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to delete old peer")
 	assert.Contains(t, err.Error(), "new peer configuration is still valid and returned")
 
-	_, getOldErr := mockRepo.GetConfig(oldPublicKey) // Should still exist
+	_, getOldErr := mockRepo.GetConfig(context.Background(), oldPublicKey) // Should still exist
 	assert.NoError(t, getOldErr, "Old peer should still exist in repo if its deletion failed")
-	_, getNewErr := mockRepo.GetConfig(generatedNewPublicKey) // New peer should exist
+	_, getNewErr := mockRepo.GetConfig(context.Background(), generatedNewPublicKey) // New peer should exist
 	assert.NoError(t, getNewErr, "New peer should exist in repo")
 }
+
+func TestVerifyKeyPair_Matching_Service(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+	runner := pubkeyRunner(t, map[string]string{"clientPrivKeyForVerify": "clientPubKeyForVerify"})
+	svc.runner = runner
+
+	matches, err := svc.VerifyKeyPair("clientPubKeyForVerify", "clientPrivKeyForVerify")
+	require.NoError(t, err)
+	assert.True(t, matches, "Derived public key should match the supplied one")
+	assert.Equal(t, []string{"wg pubkey"}, runner.calls)
+}
+
+func TestVerifyKeyPair_Mismatched_Service(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+	svc.runner = pubkeyRunner(t, map[string]string{"clientPrivKeyForVerify": "clientPubKeyForVerify"})
+
+	matches, err := svc.VerifyKeyPair("someOtherRegisteredPubKey", "clientPrivKeyForVerify")
+	require.NoError(t, err)
+	assert.False(t, matches, "Derived public key should not match a different public key")
+}
+
+func TestVerifyKeyPair_EmptyKeys_Service(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+	svc.runner = &fakeRunner{
+		RunFunc: func(stdin, name string, args ...string) ([]byte, []byte, error) {
+			t.Errorf("runner should not be called when keys are missing")
+			return nil, nil, nil
+		},
+	}
+
+	_, err := svc.VerifyKeyPair("", "clientPrivKeyForVerify")
+	assert.Error(t, err)
+	_, err = svc.VerifyKeyPair("clientPubKeyForVerify", "")
+	assert.Error(t, err)
+}
+
+func TestCreateWithExistingKeys_MatchingPair_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0)
+	svc.runner = pubkeyRunner(t, map[string]string{"migratedPrivKey": "migratedPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="})
+
+	created, err := svc.CreateWithExistingKeys(context.Background(), "migratedPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "migratedPrivKey", []string{"10.60.0.2/32"}, "", 25)
+	require.NoError(t, err)
+	assert.Equal(t, "migratedPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", created.PublicKey)
+	assert.Equal(t, "migratedPrivKey", created.PrivateKey, "Private key should be returned for immediate config generation")
+
+	repoCfg, err := mockRepo.GetConfig(context.Background(), "migratedPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	require.NoError(t, err)
+	assert.Empty(t, repoCfg.PrivateKey, "Repository should never receive the private key")
+	assert.Equal(t, []string{"10.60.0.2/32"}, repoCfg.AllowedIps)
+}
+
+func TestCreateWithExistingKeys_MismatchedPair_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0)
+	svc.runner = pubkeyRunner(t, map[string]string{"migratedPrivKey": "migratedPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="})
+
+	_, err := svc.CreateWithExistingKeys(context.Background(), "someoneElsesPubKeyAAAAAAAAAAAAAAAAAAAAAAAAA=", "migratedPrivKey", []string{"10.60.0.3/32"}, "", 0)
+	assert.ErrorIs(t, err, ErrKeyPairMismatch)
+	assert.Empty(t, mockRepo.configs, "Nothing should be created for a mismatched pair")
+}
+
+func TestCreateWithExistingKeys_AlreadyExists_Service(t *testing.T) {
+	mockRepo := newFakeRepository()
+	svc := setupTestService(t, mockRepo, 0)
+	mockRepo.configs["existingPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="] = domain.Config{PublicKey: "existingPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", AllowedIps: []string{"10.60.0.4/32"}}
+
+	_, err := svc.CreateWithExistingKeys(context.Background(), "existingPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "", []string{"10.60.0.5/32"}, "", 0)
+	assert.ErrorIs(t, err, ErrPeerAlreadyExists)
+	assert.Equal(t, []string{"10.60.0.4/32"}, mockRepo.configs["existingPubKeyAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="].AllowedIps, "Existing peer must not be overwritten")
+}
@@ -0,0 +1,63 @@
+// internal/service/dns.go
+package service
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"wgMicro_api/internal/domain"
+)
+
+// ErrInvalidClientDNS is returned when a requested client DNS list has too many or malformed entries.
+var ErrInvalidClientDNS = domain.NewCategorizedError(domain.CategoryMalformed, "invalid client DNS list")
+
+// WithMaxClientDNS overrides domain.DefaultMaxClientDNS. 0 removes the limit; entries are still validated.
+func WithMaxClientDNS(n int) Option {
+	return func(s *ConfigService) {
+		s.maxClientDNS = n
+	}
+}
+
+// validateClientDNS checks a comma-separated DNS list requested for a client config: at most
+// s.maxClientDNS entries, each an IP address or a host name (WireGuard treats names as search domains).
+// The empty list, which leaves DNS out, is valid.
+func (s *ConfigService) validateClientDNS(list string) error {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	entries := strings.Split(list, ",")
+	if s.maxClientDNS > 0 && len(entries) > s.maxClientDNS {
+		return fmt.Errorf("%w: %d entries exceed the limit of %d", ErrInvalidClientDNS, len(entries), s.maxClientDNS)
+	}
+	for _, raw := range entries {
+		entry := strings.TrimSpace(raw)
+		if _, err := netip.ParseAddr(entry); err == nil {
+			continue
+		}
+		if !isHostName(entry) {
+			return fmt.Errorf("%w: %q is neither an IP address nor a host name", ErrInvalidClientDNS, raw)
+		}
+	}
+	return nil
+}
+
+// isHostName reports whether name is a valid DNS host name: dot-separated labels of 1-63 letters,
+// digits and hyphens, not starting or ending with a hyphen, 253 characters at most.
+func isHostName(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+// internal/service/dns_test.go
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+)
+
+func TestBuildClientConfigWithOptions_DNSLimit(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+	peerCfg := &domain.Config{PublicKey: "dnsPeer", AllowedIps: []string{"10.8.0.9/32"}}
+	build := func(dns string) (string, error) {
+		return svc.BuildClientConfigWithOptions(peerCfg, "dnsPrivKey", domain.ClientConfigOptions{DNS: &dns})
+	}
+
+	t.Run("At_limit_accepted", func(t *testing.T) {
+		out, err := build("1.1.1.1, 2606:4700:4700::1111, 10.8.0.1, corp.example.com")
+		require.NoError(t, err)
+		assert.Contains(t, out, "DNS = 1.1.1.1, 2606:4700:4700::1111, 10.8.0.1, corp.example.com\n")
+	})
+
+	t.Run("Over_limit_rejected", func(t *testing.T) {
+		_, err := build("1.1.1.1,1.0.0.1,8.8.8.8,8.8.4.4,9.9.9.9")
+		assert.ErrorIs(t, err, ErrInvalidClientDNS)
+		assert.Equal(t, domain.CategoryMalformed, domain.CategoryOf(err))
+	})
+
+	t.Run("Malformed_entry_rejected", func(t *testing.T) {
+		for _, dns := range []string{"1.1.1.1,", "1.1.1.1; rm -rf /", "-bad.example.com", "under_score.example", "1.1.1.1\nPostUp = evil"} {
+			_, err := build(dns)
+			assert.ErrorIs(t, err, ErrInvalidClientDNS, "dns %q", dns)
+		}
+	})
+
+	t.Run("Empty_list_omits_DNS", func(t *testing.T) {
+		out, err := build("")
+		require.NoError(t, err)
+		assert.NotContains(t, out, "DNS =")
+	})
+
+	t.Run("Limit_disabled", func(t *testing.T) {
+		unlimited := setupTestService(t, newFakeRepository(), 0)
+		WithMaxClientDNS(0)(unlimited)
+		dns := "1.1.1.1,1.0.0.1,8.8.8.8,8.8.4.4,9.9.9.9"
+		_, err := unlimited.BuildClientConfigWithOptions(peerCfg, "dnsPrivKey", domain.ClientConfigOptions{DNS: &dns})
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+// internal/service/export.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// exportHeader opens every export document.
+const exportHeader = "# WireGuard peers exported by wgMicro_api\n"
+
+// errExportCapReached stops peer iteration once the export size cap is hit.
+var errExportCapReached = errors.New("export size cap reached")
+
+// WithExportMaxBytes caps the size of /configs/export documents. Zero or negative means unlimited.
+func WithExportMaxBytes(maxBytes int64) Option {
+	return func(s *ConfigService) {
+		s.exportMaxBytes = maxBytes
+	}
+}
+
+// ExportPeers writes all peers to w as server-side WireGuard [Peer] blocks, one peer at a time.
+// Repositories implementing repository.ConfigIterator are streamed without building a peer slice.
+// If the configured size cap is reached, the export stops before the peer that would exceed it,
+// a truncation notice is written and a warning is logged; this is not an error.
+func (s *ConfigService) ExportPeers(ctx context.Context, w io.Writer) (domain.ExportStats, error) {
+	var stats domain.ExportStats
+	write := func(text string) error {
+		n, err := io.WriteString(w, text)
+		stats.Bytes += int64(n)
+		return err
+	}
+
+	// The header is written lazily so that a failing 'wg show' leaves w untouched
+	// and the caller can still report a proper error status.
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		return write(exportHeader)
+	}
+
+	writePeer := func(cfg domain.Config) error {
+		if err := start(); err != nil {
+			return err
+		}
+		s.attachMetadata(&cfg)
+		block := formatPeerBlock(cfg)
+		if s.exportMaxBytes > 0 && stats.Bytes+int64(len(block)) > s.exportMaxBytes {
+			return errExportCapReached
+		}
+		if err := write(block); err != nil {
+			return err
+		}
+		stats.Peers++
+		return nil
+	}
+
+	var err error
+	if it, ok := s.repo.(repository.ConfigIterator); ok {
+		err = it.IterConfigs(ctx, writePeer)
+	} else {
+		var configs []domain.Config
+		configs, err = s.repo.ListConfigs(ctx)
+		for i := 0; err == nil && i < len(configs); i++ {
+			err = writePeer(configs[i])
+		}
+	}
+	if err == nil {
+		err = start() // Empty peer set: still emit the header
+	}
+	if errors.Is(err, errExportCapReached) {
+		stats.Truncated = true
+		logger.Logger.Warn("Service: Peer export reached the size cap and was truncated",
+			zap.Int64("maxBytes", s.exportMaxBytes),
+			zap.Int("peersWritten", stats.Peers))
+		err = write(fmt.Sprintf("\n# Export truncated after %d peers: size limit of %d bytes reached.\n", stats.Peers, s.exportMaxBytes))
+	}
+	if err != nil {
+		logger.Logger.Error("Service: Peer export failed", zap.Int("peersWritten", stats.Peers), zap.Error(err))
+		return stats, err
+	}
+
+	logger.Logger.Info("Service: Exported peers",
+		zap.Int("peers", stats.Peers),
+		zap.Int64("bytes", stats.Bytes),
+		zap.Bool("truncated", stats.Truncated))
+	return stats, nil
+}
+
+// formatPeerBlock renders cfg as a server-side [Peer] section, preceded by a blank line.
+// The metadata name, if any, is written as a comment.
+func formatPeerBlock(cfg domain.Config) string {
+	var b strings.Builder
+	b.WriteString("\n[Peer]\n")
+	if cfg.Metadata != nil && cfg.Metadata.Name != "" {
+		b.WriteString("# Name = " + commentSafe(cfg.Metadata.Name) + "\n")
+	}
+	b.WriteString("PublicKey = " + cfg.PublicKey + "\n")
+	if cfg.PreSharedKey != "" {
+		b.WriteString("PresharedKey = " + cfg.PreSharedKey + "\n")
+	}
+	if len(cfg.AllowedIps) > 0 {
+		b.WriteString("AllowedIPs = " + strings.Join(cfg.AllowedIps, ", ") + "\n")
+	}
+	if cfg.Endpoint != "" {
+		b.WriteString("Endpoint = " + cfg.Endpoint + "\n")
+	}
+	if cfg.PersistentKeepalive > 0 {
+		b.WriteString(fmt.Sprintf("PersistentKeepalive = %d\n", cfg.PersistentKeepalive))
+	}
+	return b.String()
+}
+
+// commentSafe replaces control characters in s with spaces, so a user-supplied value written
+// as a comment cannot end the comment line and inject configuration.
+func commentSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,101 @@
+// internal/service/export_test.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/repository"
+)
+
+// syntheticPeers fills repo with n peers with distinct keys and addresses.
+func syntheticPeers(data map[string]domain.Config, n int) {
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("exportPeer%05d", i)
+		data[key] = domain.Config{
+			PublicKey:           key,
+			AllowedIps:          []string{fmt.Sprintf("10.%d.%d.%d/32", i/65536, (i/256)%256, i%256)},
+			PersistentKeepalive: 25,
+		}
+	}
+}
+
+func TestExportPeers_StreamsLargeSet(t *testing.T) {
+	const peerCount = 5000
+
+	// Run against a repository that supports streaming and one that only lists.
+	iterRepo := repository.NewFakeWGRepository()
+	syntheticPeers(iterRepo.Data, peerCount)
+	listRepo := newFakeRepository()
+	syntheticPeers(listRepo.configs, peerCount)
+
+	for name, repo := range map[string]repository.Repo{"iterator": iterRepo, "list": listRepo} {
+		t.Run(name, func(t *testing.T) {
+			svc := setupTestService(t, repo, 0)
+			var out strings.Builder
+
+			stats, err := svc.ExportPeers(context.Background(), &out)
+			require.NoError(t, err)
+			assert.Equal(t, peerCount, stats.Peers)
+			assert.False(t, stats.Truncated)
+			assert.Equal(t, int64(out.Len()), stats.Bytes)
+
+			doc := out.String()
+			assert.True(t, strings.HasPrefix(doc, exportHeader))
+			assert.Equal(t, peerCount, strings.Count(doc, "[Peer]\n"))
+			lines := make(map[string]bool)
+			for _, line := range strings.Split(doc, "\n") {
+				lines[line] = true
+			}
+			for i := 0; i < peerCount; i++ {
+				require.True(t, lines[fmt.Sprintf("PublicKey = exportPeer%05d", i)], "Peer %d missing from export", i)
+			}
+			assert.Contains(t, doc, "AllowedIPs = 10.0.19.135/32\n") // Peer 4999
+		})
+	}
+}
+
+func TestExportPeers_SizeCap(t *testing.T) {
+	repo := repository.NewFakeWGRepository()
+	syntheticPeers(repo.Data, 100)
+	svc := setupTestService(t, repo, 0)
+	WithExportMaxBytes(1024)(svc)
+
+	var out strings.Builder
+	stats, err := svc.ExportPeers(context.Background(), &out)
+	require.NoError(t, err, "Hitting the cap is not an error")
+	assert.True(t, stats.Truncated)
+	assert.Greater(t, stats.Peers, 0)
+	assert.Less(t, stats.Peers, 100)
+	assert.Equal(t, stats.Peers, strings.Count(out.String(), "[Peer]\n"), "Only whole blocks should be written")
+	assert.Contains(t, out.String(), "# Export truncated after")
+}
+
+func TestExportPeers_RepositoryErrorWritesNothing(t *testing.T) {
+	repo := newFakeRepository()
+	repo.ListConfigsError = repository.ErrWgTimeout
+	svc := setupTestService(t, repo, 0)
+
+	var out strings.Builder
+	_, err := svc.ExportPeers(context.Background(), &out)
+	assert.ErrorIs(t, err, repository.ErrWgTimeout)
+	assert.Empty(t, out.String(), "Nothing should be written before the peer list is available")
+}
+
+func TestFormatPeerBlock_NameCannotInjectConfig(t *testing.T) {
+	cfg := domain.Config{
+		PublicKey: "exportedPeer",
+		Metadata:  &domain.PeerMetadata{Name: "laptop\r\n[Peer]\nPublicKey = injectedPeer\nAllowedIPs = 0.0.0.0/0"},
+	}
+
+	block := formatPeerBlock(cfg)
+	assert.Equal(t, 1, strings.Count(block, "[Peer]\n"), "The name must not start another section")
+	assert.NotContains(t, block, "\nPublicKey = injectedPeer")
+	assert.Contains(t, block, "# Name = laptop  [Peer] PublicKey = injectedPeer AllowedIPs = 0.0.0.0/0\n")
+}
@@ -0,0 +1,88 @@
+// internal/service/key.go
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+
+	"wgMicro_api/internal/domain"
+)
+
+// ErrWeakPrivateKey is returned when a supplied private key is well-formed but unusable,
+// such as the all-zero key, so a tunnel built from it would silently fail.
+var ErrWeakPrivateKey = domain.NewCategorizedError(domain.CategoryInvalid, "private key is weak or degenerate")
+
+// fingerprintBytes is how many leading bytes of the SHA-256 digest make up a fingerprint.
+const fingerprintBytes = 8
+
+// KeyFingerprint returns a short human-friendly fingerprint of a WireGuard public key:
+// the first 8 bytes of the SHA-256 of the decoded key, hex-encoded in colon-separated groups of 4,
+// e.g. "3f2a:9c01:b7e4:5d68". It returns domain.ErrInvalidKeyFormat for malformed keys.
+func KeyFingerprint(publicKey string) (string, error) {
+	raw, err := domain.ParseKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	digest := hex.EncodeToString(sum[:fingerprintBytes])
+
+	groups := make([]string, 0, len(digest)/4)
+	for i := 0; i < len(digest); i += 4 {
+		groups = append(groups, digest[i:i+4])
+	}
+	return strings.Join(groups, ":"), nil
+}
+
+// peerIDBytes is how many leading bytes of the SHA-256 digest make up a peer ID; 10 bytes are 16 base32 characters.
+const peerIDBytes = 10
+
+// peerIDEncoding is lowercase base32 without padding, so IDs are URL-safe and case-insensitive.
+var peerIDEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// ErrInvalidPeerID is returned when a peer ID is not 16 base32 characters.
+var ErrInvalidPeerID = domain.NewCategorizedError(domain.CategoryMalformed, "peer ID must be 16 base32 characters")
+
+// PeerID returns the stable, URL-safe identifier of a peer: the first 10 bytes of the SHA-256
+// of the decoded public key, base32-encoded in lowercase, e.g. "h4zcuwakognvtzdi".
+// It returns domain.ErrInvalidKeyFormat for malformed keys.
+func PeerID(publicKey string) (string, error) {
+	raw, err := domain.ParseKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return peerIDEncoding.EncodeToString(sum[:peerIDBytes]), nil
+}
+
+// normalizePeerID lowercases id and checks that it decodes to a peer ID.
+func normalizePeerID(id string) (string, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if _, err := peerIDEncoding.DecodeString(id); err != nil || len(id) != peerIDEncoding.EncodedLen(peerIDBytes) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPeerID, id)
+	}
+	return id, nil
+}
+
+// CheckPrivateKey verifies that privateKey is usable for a client config: it must decode to
+// 32 bytes (ErrInvalidPrivateKey otherwise), must not be all zeros, and must yield a non-degenerate
+// public key when derived in-process (ErrWeakPrivateKey otherwise). The key is never logged.
+func CheckPrivateKey(privateKey string) error {
+	raw, err := domain.ParseKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	var zero [domain.KeyLen]byte
+	if [domain.KeyLen]byte(raw) == zero {
+		return fmt.Errorf("%w: key is all zeros", ErrWeakPrivateKey)
+	}
+	// X25519 rejects scalars whose public key is the all-zero point.
+	if _, err := curve25519.X25519(raw, curve25519.Basepoint); err != nil {
+		return fmt.Errorf("%w: %v", ErrWeakPrivateKey, err)
+	}
+	return nil
+}
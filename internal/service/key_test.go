@@ -0,0 +1,130 @@
+// internal/service/key_test.go
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/repository"
+)
+
+func TestKeyFingerprint_Deterministic(t *testing.T) {
+	raw := make([]byte, domain.KeyLen)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+	key := base64.StdEncoding.EncodeToString(raw)
+	sum := sha256.Sum256(raw)
+	expected := strings.Join([]string{
+		hex.EncodeToString(sum[0:2]), hex.EncodeToString(sum[2:4]),
+		hex.EncodeToString(sum[4:6]), hex.EncodeToString(sum[6:8]),
+	}, ":")
+
+	first, err := KeyFingerprint(key)
+	require.NoError(t, err)
+	assert.Equal(t, expected, first)
+	assert.Len(t, first, 19, "Four groups of four hex digits and three separators")
+
+	second, err := KeyFingerprint(key)
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "Fingerprint must be stable")
+
+	other, err := KeyFingerprint("mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg=")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other, "Different keys should have different fingerprints")
+}
+
+func TestKeyFingerprint_RejectsMalformedKeys(t *testing.T) {
+	for _, key := range []string{
+		"",
+		"not-base64!!",
+		base64.StdEncoding.EncodeToString([]byte("too short")),
+		base64.StdEncoding.EncodeToString(make([]byte, domain.KeyLen+1)),
+	} {
+		_, err := KeyFingerprint(key)
+		assert.ErrorIs(t, err, domain.ErrInvalidKeyFormat, "key %q", key)
+	}
+}
+
+func TestPeerID_StableAndResolvable(t *testing.T) {
+	key := "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg="
+	raw, err := domain.ParseKey(key)
+	require.NoError(t, err)
+	sum := sha256.Sum256(raw)
+
+	id, err := PeerID(key)
+	require.NoError(t, err)
+	assert.Equal(t, strings.ToLower(base32.StdEncoding.EncodeToString(sum[:10])), id)
+	assert.Len(t, id, 16)
+	again, err := PeerID(key)
+	require.NoError(t, err)
+	assert.Equal(t, id, again, "Peer ID must be stable")
+
+	_, err = PeerID("not-a-key")
+	assert.ErrorIs(t, err, domain.ErrInvalidKeyFormat)
+
+	repo := newFakeRepository()
+	other := base64.StdEncoding.EncodeToString(make([]byte, domain.KeyLen))
+	repo.configs[key] = domain.Config{PublicKey: key, AllowedIps: []string{"10.0.0.2/32"}}
+	repo.configs[other] = domain.Config{PublicKey: other, AllowedIps: []string{"10.0.0.3/32"}}
+	svc := setupTestService(t, repo, 0)
+
+	found, err := svc.GetByPeerID(context.Background(), strings.ToUpper(id))
+	require.NoError(t, err)
+	assert.Equal(t, key, found.PublicKey, "Lookup is case-insensitive")
+	assert.Equal(t, id, found.PeerID)
+
+	all, err := svc.GetAll(context.Background())
+	require.NoError(t, err)
+	for _, cfg := range all {
+		expected, _ := PeerID(cfg.PublicKey)
+		assert.Equal(t, expected, cfg.PeerID, "List responses carry the peer ID")
+	}
+
+	_, err = svc.GetByPeerID(context.Background(), "aaaaaaaaaaaaaaaa")
+	assert.ErrorIs(t, err, repository.ErrPeerNotFound)
+	for _, bad := range []string{"", "short", "h4zcuwakognvtzd!", "h4zcuwakognvtzdia"} {
+		_, err = svc.GetByPeerID(context.Background(), bad)
+		assert.ErrorIs(t, err, ErrInvalidPeerID, "id %q", bad)
+	}
+}
+
+func TestBuildClientConfig_ClientKeyCheck(t *testing.T) {
+	svc := setupTestService(t, newFakeRepository(), 0)
+	WithClientKeyCheck(true)(svc)
+	peer := &domain.Config{PublicKey: "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg=", AllowedIps: []string{"10.0.0.2/32"}}
+	zeroKey := base64.StdEncoding.EncodeToString(make([]byte, domain.KeyLen))
+
+	t.Run("Zero_key_rejected", func(t *testing.T) {
+		_, err := svc.BuildClientConfig(peer, zeroKey)
+		assert.ErrorIs(t, err, ErrWeakPrivateKey)
+	})
+
+	t.Run("Malformed_key_rejected", func(t *testing.T) {
+		_, err := svc.BuildClientConfig(peer, "bm90IGEga2V5")
+		assert.ErrorIs(t, err, ErrInvalidPrivateKey)
+	})
+
+	t.Run("Valid_key_accepted", func(t *testing.T) {
+		privKey, _, err := NativeKeyGenerator{}.GenerateKeyPair()
+		require.NoError(t, err)
+		out, err := svc.BuildClientConfig(peer, privKey)
+		require.NoError(t, err)
+		assert.Contains(t, out, "PrivateKey = "+privKey)
+	})
+
+	t.Run("Disabled_check_accepts_zero_key", func(t *testing.T) {
+		unchecked := setupTestService(t, newFakeRepository(), 0)
+		_, err := unchecked.BuildClientConfig(peer, zeroKey)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,156 @@
+// internal/service/keygen.go
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/curve25519"
+
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// Supported values for the KEYGEN_BACKEND setting.
+const (
+	KeyGenBackendCLI    = "cli"    // Shell out to 'wg genkey', 'wg pubkey' and 'wg genpsk'
+	KeyGenBackendNative = "native" // Generate keys in-process using curve25519
+)
+
+// wgKeyLen is the length in bytes of WireGuard private, public and preshared keys.
+const wgKeyLen = 32
+
+// KeyGenerator produces WireGuard key material for client peers.
+// Keys are returned base64-encoded, exactly as the 'wg' utility prints them.
+type KeyGenerator interface {
+	// GenerateKeyPair returns a new private key and its matching public key.
+	GenerateKeyPair() (privKey, pubKey string, err error)
+	// GeneratePSK returns a new preshared key.
+	GeneratePSK() (string, error)
+}
+
+// NewKeyGenerator returns the KeyGenerator for the given backend name.
+// An empty backend selects the CLI implementation.
+func NewKeyGenerator(backend string, runner repository.CommandRunner, timeout time.Duration) (KeyGenerator, error) {
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "", KeyGenBackendCLI:
+		return NewCLIKeyGenerator(runner, timeout), nil
+	case KeyGenBackendNative:
+		return NativeKeyGenerator{}, nil
+	default:
+		return nil, fmt.Errorf("unknown key generation backend %q (expected %q or %q)", backend, KeyGenBackendCLI, KeyGenBackendNative)
+	}
+}
+
+// CLIKeyGenerator generates keys with the 'wg' command-line utility.
+type CLIKeyGenerator struct {
+	runner  repository.CommandRunner
+	timeout time.Duration // Timeout shared by the commands of a single generation call
+}
+
+// NewCLIKeyGenerator creates a CLIKeyGenerator. A nil runner falls back to repository.ExecRunner,
+// and a non-positive timeout falls back to DefaultKeyGenTimeoutService.
+func NewCLIKeyGenerator(runner repository.CommandRunner, timeout time.Duration) *CLIKeyGenerator {
+	if runner == nil {
+		runner = repository.ExecRunner{}
+	}
+	if timeout <= 0 {
+		timeout = DefaultKeyGenTimeoutService
+	}
+	return &CLIKeyGenerator{runner: runner, timeout: timeout}
+}
+
+// GenerateKeyPair runs 'wg genkey' followed by 'wg pubkey'.
+func (g *CLIKeyGenerator) GenerateKeyPair() (privKey, pubKey string, err error) {
+	logger.Logger.Debug("Service: Generating new key pair for a client", zap.Duration("timeout", g.timeout))
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	privKey, err = g.run(ctx, "", "genkey")
+	if err != nil {
+		logger.Logger.Error("Service: Failed to generate client private key", zap.Error(err))
+		return "", "", err
+	}
+
+	pubKey, err = g.run(ctx, privKey, "pubkey")
+	if err != nil {
+		logger.Logger.Error("Service: Failed to generate client public key from private key", zap.Error(err))
+		return "", "", err
+	}
+
+	logger.Logger.Info("Service: Successfully generated new client key pair.")
+	return privKey, pubKey, nil
+}
+
+// GeneratePSK runs 'wg genpsk'.
+func (g *CLIKeyGenerator) GeneratePSK() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	psk, err := g.run(ctx, "", "genpsk")
+	if err != nil {
+		logger.Logger.Error("Service: Failed to generate preshared key", zap.Error(err))
+		return "", err
+	}
+	return psk, nil
+}
+
+// run executes 'wg <subcommand>' and returns its trimmed output.
+// Timeouts are reported as wrapped repository.ErrWgTimeout.
+func (g *CLIKeyGenerator) run(ctx context.Context, stdin, subcommand string) (string, error) {
+	stdout, stderr, err := g.runner.Run(ctx, stdin, "wg", subcommand)
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("wg %s timed out: %w", subcommand, repository.ErrWgTimeout)
+	}
+	if err != nil {
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) {
+			return "", fmt.Errorf("wg %s command failed with exit code %d: %s. Stderr: %s", subcommand, exitError.ExitCode(), err.Error(), strings.TrimSpace(string(stderr)))
+		}
+		return "", fmt.Errorf("wg %s command failed: %s", subcommand, err.Error())
+	}
+	out := strings.TrimSpace(string(stdout))
+	if out == "" {
+		return "", fmt.Errorf("wg %s produced empty output", subcommand)
+	}
+	return out, nil
+}
+
+// NativeKeyGenerator generates keys in-process, without requiring WireGuard tools.
+// The output is interchangeable with that of 'wg genkey', 'wg pubkey' and 'wg genpsk'.
+type NativeKeyGenerator struct{}
+
+// GenerateKeyPair creates a clamped Curve25519 private key and derives its public key.
+func (NativeKeyGenerator) GenerateKeyPair() (privKey, pubKey string, err error) {
+	var priv [wgKeyLen]byte
+	if _, err := rand.Read(priv[:]); err != nil {
+		return "", "", fmt.Errorf("failed to read random bytes for private key: %w", err)
+	}
+	// Clamp the scalar the same way 'wg genkey' does.
+	priv[0] &= 248
+	priv[31] &= 127
+	priv[31] |= 64
+
+	pub, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to derive public key: %w", err)
+	}
+	logger.Logger.Info("Service: Successfully generated new client key pair (native).")
+	return base64.StdEncoding.EncodeToString(priv[:]), base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// GeneratePSK creates 32 random bytes, as 'wg genpsk' does.
+func (NativeKeyGenerator) GeneratePSK() (string, error) {
+	var psk [wgKeyLen]byte
+	if _, err := rand.Read(psk[:]); err != nil {
+		return "", fmt.Errorf("failed to read random bytes for preshared key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(psk[:]), nil
+}
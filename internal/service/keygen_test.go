@@ -0,0 +1,217 @@
+// internal/service/keygen_test.go
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/crypto/curve25519"
+
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// decodeWgKey decodes a base64 WireGuard key and checks its length.
+func decodeWgKey(t *testing.T, key string) []byte {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(key)
+	require.NoError(t, err, "Key should be valid base64: %q", key)
+	require.Len(t, raw, wgKeyLen, "Key should decode to 32 bytes")
+	return raw
+}
+
+func TestNativeKeyGenerator_GenerateKeyPair(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	kg := NativeKeyGenerator{}
+
+	privKey, pubKey, err := kg.GenerateKeyPair()
+	require.NoError(t, err)
+
+	priv := decodeWgKey(t, privKey)
+	pub := decodeWgKey(t, pubKey)
+
+	// Private key must be clamped like 'wg genkey' output.
+	assert.Zero(t, priv[0]&7, "Low 3 bits of the first byte must be cleared")
+	assert.Zero(t, priv[31]&128, "High bit of the last byte must be cleared")
+	assert.NotZero(t, priv[31]&64, "Second-highest bit of the last byte must be set")
+
+	expectedPub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	require.NoError(t, err)
+	assert.Equal(t, expectedPub, pub, "Public key must be derived from the private key")
+
+	otherPriv, otherPub, err := kg.GenerateKeyPair()
+	require.NoError(t, err)
+	assert.NotEqual(t, privKey, otherPriv, "Consecutive private keys must differ")
+	assert.NotEqual(t, pubKey, otherPub, "Consecutive public keys must differ")
+}
+
+func TestNativeKeyGenerator_GeneratePSK(t *testing.T) {
+	kg := NativeKeyGenerator{}
+
+	psk, err := kg.GeneratePSK()
+	require.NoError(t, err)
+	decodeWgKey(t, psk)
+
+	otherPSK, err := kg.GeneratePSK()
+	require.NoError(t, err)
+	assert.NotEqual(t, psk, otherPSK, "Consecutive preshared keys must differ")
+}
+
+func TestCLIKeyGenerator_GenerateKeyPair(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	runner := &fakeRunner{
+		RunFunc: func(stdin, name string, args ...string) ([]byte, []byte, error) {
+			switch args[0] {
+			case "genkey":
+				return []byte("cliPrivKey\n"), nil, nil
+			case "pubkey":
+				assert.Equal(t, "cliPrivKey", stdin, "Private key should be piped to 'wg pubkey'")
+				return []byte("cliPubKey\n"), nil, nil
+			}
+			return nil, nil, errors.New("unexpected subcommand")
+		},
+	}
+	kg := NewCLIKeyGenerator(runner, time.Second)
+
+	privKey, pubKey, err := kg.GenerateKeyPair()
+	require.NoError(t, err)
+	assert.Equal(t, "cliPrivKey", privKey)
+	assert.Equal(t, "cliPubKey", pubKey)
+	assert.Equal(t, []string{"wg genkey", "wg pubkey"}, runner.calls)
+}
+
+func TestCLIKeyGenerator_GenerateKeyPair_CommandError(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	runner := &fakeRunner{
+		RunFunc: func(stdin, name string, args ...string) ([]byte, []byte, error) {
+			return nil, nil, errors.New("simulated exec failure")
+		},
+	}
+	kg := NewCLIKeyGenerator(runner, time.Second)
+
+	_, _, err := kg.GenerateKeyPair()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wg genkey command failed")
+	assert.Equal(t, []string{"wg genkey"}, runner.calls, "'wg pubkey' must not run after a failed 'wg genkey'")
+}
+
+func TestCLIKeyGenerator_GeneratePSK(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	runner := &fakeRunner{
+		RunFunc: func(stdin, name string, args ...string) ([]byte, []byte, error) {
+			return []byte("cliPSK\n"), nil, nil
+		},
+	}
+	kg := NewCLIKeyGenerator(runner, time.Second)
+
+	psk, err := kg.GeneratePSK()
+	require.NoError(t, err)
+	assert.Equal(t, "cliPSK", psk)
+	assert.Equal(t, []string{"wg genpsk"}, runner.calls)
+}
+
+func TestNewConfigService_WithCommandRunner(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	runner := &fakeRunner{
+		RunFunc: func(stdin, name string, args ...string) ([]byte, []byte, error) {
+			if args[0] == "genkey" {
+				return []byte("envPrivKey\n"), nil, nil
+			}
+			return []byte("envPubKey\n"), nil, nil
+		},
+	}
+	svc := NewConfigService(newFakeRepository(), "serverPubKey", "vpn.example.com:51820", time.Second, "", 0, WithCommandRunner(runner))
+
+	_, pubKey, err := svc.keyGen.GenerateKeyPair()
+	require.NoError(t, err)
+	assert.Equal(t, "envPubKey", pubKey)
+	assert.Equal(t, []string{"wg genkey", "wg pubkey"}, runner.calls, "The default CLI key generator should use the injected runner")
+
+	derived, err := svc.derivePublicKey("envPrivKey")
+	require.NoError(t, err)
+	assert.Equal(t, "envPubKey", derived)
+	assert.Len(t, runner.calls, 3, "Key derivation should use the injected runner too")
+}
+
+func TestNewKeyGenerator_Backends(t *testing.T) {
+	kg, err := NewKeyGenerator(KeyGenBackendNative, nil, time.Second)
+	require.NoError(t, err)
+	assert.IsType(t, NativeKeyGenerator{}, kg)
+
+	kg, err = NewKeyGenerator(KeyGenBackendCLI, repository.ExecRunner{}, time.Second)
+	require.NoError(t, err)
+	assert.IsType(t, &CLIKeyGenerator{}, kg)
+
+	kg, err = NewKeyGenerator("", nil, time.Second)
+	require.NoError(t, err)
+	assert.IsType(t, &CLIKeyGenerator{}, kg, "Empty backend should default to CLI")
+
+	_, err = NewKeyGenerator("openssl", nil, time.Second)
+	assert.Error(t, err)
+}
+
+func TestGenPresharedKey_Service(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	output := "stubPSK\n"
+	runner := &fakeRunner{
+		RunFunc: func(stdin, name string, args ...string) ([]byte, []byte, error) {
+			switch args[0] {
+			case "genkey":
+				return []byte("stubPrivKey\n"), nil, nil
+			case "pubkey":
+				return []byte("stubPubKey\n"), nil, nil
+			}
+			return []byte(output), nil, nil
+		},
+	}
+	repo := newFakeRepository()
+	svc := NewConfigService(repo, "serverPubKey", "vpn.example.com:51820", time.Second, "", 0, WithCommandRunner(runner))
+
+	psk, err := svc.GenPresharedKey()
+	require.NoError(t, err)
+	assert.Equal(t, "stubPSK", psk)
+	assert.Equal(t, []string{"wg genpsk"}, runner.calls)
+
+	t.Run("Generated_when_requested", func(t *testing.T) {
+		created, err := svc.CreateWithNewKeys(context.Background(), []string{"10.30.0.2/32"}, "", true, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "stubPSK", created.PreSharedKey)
+		stored, err := repo.GetConfig(context.Background(), created.PublicKey)
+		require.NoError(t, err)
+		assert.Equal(t, "stubPSK", stored.PreSharedKey, "The generated key should be applied to the peer")
+	})
+
+	t.Run("Supplied_key_wins", func(t *testing.T) {
+		delete(repo.configs, "stubPubKey")
+		runner.calls = nil
+		created, err := svc.CreateWithNewKeys(context.Background(), []string{"10.30.0.3/32"}, "clientPSK", true, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "clientPSK", created.PreSharedKey)
+		assert.NotContains(t, runner.calls, "wg genpsk")
+	})
+
+	t.Run("Empty_output_is_an_error", func(t *testing.T) {
+		output = "\n"
+		_, err := svc.GenPresharedKey()
+		assert.Error(t, err)
+
+		delete(repo.configs, "stubPubKey")
+		_, err = svc.CreateWithNewKeys(context.Background(), []string{"10.30.0.4/32"}, "", true, 0)
+		assert.Error(t, err)
+		assert.Empty(t, repo.configs, "No peer should be created without the requested preshared key")
+	})
+
+	t.Run("Command_error", func(t *testing.T) {
+		runner.RunFunc = func(stdin, name string, args ...string) ([]byte, []byte, error) {
+			return nil, []byte("wg: not permitted"), errors.New("exit status 1")
+		}
+		_, err := svc.GenPresharedKey()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,102 @@
+// internal/service/metadata_reconcile.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// PruneOrphanedMetadata deletes metadata entries whose peer no longer exists on the interface,
+// e.g. after peers were removed with 'wg' directly, and returns their public keys, sorted.
+// Stores that cannot list their keys are left alone.
+func (s *ConfigService) PruneOrphanedMetadata(ctx context.Context) ([]string, error) {
+	lister, ok := s.metadata.(repository.MetadataLister)
+	if !ok {
+		return []string{}, nil
+	}
+	// Take the keys before listing peers: a peer created meanwhile gets its metadata only after it
+	// exists on the interface, so it is either absent from keys or present in the peer list.
+	keys := lister.Keys()
+
+	configs, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers for metadata pruning: %w", err)
+	}
+	live := make(map[string]struct{}, len(configs))
+	for _, cfg := range configs {
+		live[cfg.PublicKey] = struct{}{}
+	}
+
+	pruned := []string{}
+	for _, key := range keys {
+		if _, ok := live[key]; ok {
+			continue
+		}
+		if err := s.metadata.Delete(key); err != nil {
+			return pruned, fmt.Errorf("failed to delete orphaned metadata for %s: %w", key, err)
+		}
+		pruned = append(pruned, key)
+	}
+	sort.Strings(pruned)
+	return pruned, nil
+}
+
+// MetadataReconciler periodically prunes orphaned peer metadata.
+type MetadataReconciler struct {
+	svc       *ConfigService
+	interval  time.Duration
+	newTicker func(time.Duration) (<-chan time.Time, func()) // Tick source, replaceable in tests
+}
+
+// NewMetadataReconciler creates a reconciler pruning svc's metadata every interval. Call Run to start it.
+func NewMetadataReconciler(svc *ConfigService, interval time.Duration) *MetadataReconciler {
+	if svc == nil {
+		logger.Logger.Fatal("ConfigService cannot be nil for MetadataReconciler")
+	}
+	return &MetadataReconciler{
+		svc:      svc,
+		interval: interval,
+		newTicker: func(d time.Duration) (<-chan time.Time, func()) {
+			t := time.NewTicker(d)
+			return t.C, t.Stop
+		},
+	}
+}
+
+// Run prunes orphaned metadata on every interval tick until ctx is done.
+func (r *MetadataReconciler) Run(ctx context.Context) {
+	logger.Logger.Info("Metadata reconciler started", zap.Duration("interval", r.interval))
+	ticks, stop := r.newTicker(r.interval)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Logger.Info("Metadata reconciler stopped")
+			return
+		case <-ticks:
+			r.reconcile(ctx)
+		}
+	}
+}
+
+// reconcile runs one pruning pass and logs a summary; failures are retried on the next tick.
+func (r *MetadataReconciler) reconcile(ctx context.Context) {
+	pruned, err := r.svc.PruneOrphanedMetadata(ctx)
+	if err != nil {
+		logger.Logger.Warn("Metadata reconcile failed", zap.Strings("pruned", pruned), zap.Error(err))
+		return
+	}
+	if len(pruned) == 0 {
+		logger.Logger.Debug("Metadata reconcile found no orphans")
+		return
+	}
+	logger.Logger.Info("Metadata reconcile pruned orphaned entries", zap.Int("count", len(pruned)), zap.Strings("publicKeys", pruned))
+}
@@ -0,0 +1,71 @@
+// internal/service/metadata_reconcile_test.go
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+)
+
+func TestPruneOrphanedMetadata(t *testing.T) {
+	repo := newFakeRepository()
+	svc := setupTestService(t, repo, 0)
+
+	repo.configs["livePeer"] = domain.Config{PublicKey: "livePeer"}
+	for _, key := range []string{"livePeer", "goneB", "goneA"} {
+		require.NoError(t, svc.metadata.Set(key, domain.PeerMetadata{Name: key}))
+	}
+
+	pruned, err := svc.PruneOrphanedMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"goneA", "goneB"}, pruned)
+	_, ok := svc.metadata.Get("livePeer")
+	assert.True(t, ok, "Metadata of existing peers must be kept")
+
+	repo.ListConfigsError = assert.AnError
+	require.NoError(t, svc.metadata.Set("goneC", domain.PeerMetadata{}))
+	_, err = svc.PruneOrphanedMetadata(context.Background())
+	assert.Error(t, err)
+	_, ok = svc.metadata.Get("goneC")
+	assert.True(t, ok, "Nothing may be pruned when peers cannot be listed")
+}
+
+func TestMetadataReconciler_TickPrunesAndStops(t *testing.T) {
+	repo := newFakeRepository()
+	svc := setupTestService(t, repo, 0)
+	repo.configs["livePeer"] = domain.Config{PublicKey: "livePeer"}
+	require.NoError(t, svc.metadata.Set("livePeer", domain.PeerMetadata{Name: "live"}))
+	require.NoError(t, svc.metadata.Set("orphanPeer", domain.PeerMetadata{Name: "orphan"}))
+
+	ticks := make(chan time.Time)
+	stopped := false
+	r := NewMetadataReconciler(svc, time.Hour)
+	r.newTicker = func(time.Duration) (<-chan time.Time, func()) { return ticks, func() { stopped = true } }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	ticks <- time.Now() // Blocks until Run receives the tick
+	ticks <- time.Now() // A second tick is only received once the first pass has finished
+	_, ok := svc.metadata.Get("orphanPeer")
+	assert.False(t, ok, "The orphan should be pruned on a tick")
+	_, ok = svc.metadata.Get("livePeer")
+	assert.True(t, ok)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+	assert.True(t, stopped, "The ticker should be stopped on shutdown")
+}
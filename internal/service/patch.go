@@ -0,0 +1,240 @@
+// internal/service/patch.go
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// ErrInvalidPatch is returned for a JSON Patch that cannot be applied: an unknown operation or path,
+// an index out of range, or a value of the wrong type.
+var ErrInvalidPatch = domain.NewCategorizedError(domain.CategoryMalformed, "invalid JSON patch")
+
+// ErrReadOnlyField is returned when a JSON Patch targets a field that cannot be changed in place,
+// such as the public key or the traffic counters.
+var ErrReadOnlyField = domain.NewCategorizedError(domain.CategoryInvalid, "field is read-only")
+
+// ErrPatchTestFailed is returned when a "test" operation of a JSON Patch does not match the peer.
+var ErrPatchTestFailed = domain.NewCategorizedError(domain.CategoryConflict, "JSON patch test failed")
+
+// ErrEmptyAllowedIPsUnconfirmed is returned when a JSON Patch would remove all of a peer's AllowedIPs
+// without the caller confirming it. The request lacks its ?confirm=true parameter, so it is malformed
+// (400) rather than invalid, like the other endpoints that require confirmation.
+var ErrEmptyAllowedIPsUnconfirmed = domain.NewCategorizedError(domain.CategoryMalformed,
+	"the patch removes all of the peer's AllowedIPs; repeat the request with ?confirm=true to clear them")
+
+// readOnlyPatchFields are the JSON fields of domain.Config that a patch may not touch.
+var readOnlyPatchFields = map[string]bool{
+	"publicKey": true, "peerId": true, "privateKey": true, "endpoint": true,
+	"latestHandshake": true, "receiveBytes": true, "transmitBytes": true,
+	"metadata": true, "assignedAddress": true, "server": true, "allowedIpsMore": true,
+}
+
+// patchablePeer holds the settings of a peer that a JSON Patch may change.
+type patchablePeer struct {
+	AllowedIps          []string
+	PreSharedKey        string
+	PersistentKeepalive int
+}
+
+// PatchPeer applies an RFC 6902 JSON Patch to the mutable settings of an existing peer
+// (allowedIps, presharedKey, persistentKeepalive) and writes the result with a single repository update.
+// The patch is all-or-nothing: if any operation fails, the peer is left unchanged. The peer is read and
+// written under the repository's write lock, so a "test" operation holds until the write.
+// A result without AllowedIPs is refused with ErrEmptyAllowedIPsUnconfirmed unless allowEmptyAllowedIPs is set.
+func (s *ConfigService) PatchPeer(ctx context.Context, publicKey string, ops []domain.PatchOperation, allowEmptyAllowedIPs bool) (*domain.Config, error) {
+	if err := validatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%w: the patch has no operations", ErrInvalidPatch)
+	}
+	updater, ok := s.repo.(repository.PeerUpdater)
+	if !ok {
+		return nil, errors.New("the repository cannot update peers in place")
+	}
+
+	var current *domain.Config
+	err := updater.UpdatePeer(ctx, publicKey, func(cfg *domain.Config) error {
+		peer := patchablePeer{
+			AllowedIps:          slices.Clone(cfg.AllowedIps),
+			PreSharedKey:        cfg.PreSharedKey,
+			PersistentKeepalive: cfg.PersistentKeepalive,
+		}
+		for i, op := range ops {
+			if err := applyPatchOperation(&peer, op); err != nil {
+				return fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+			}
+		}
+
+		allowedIps, err := s.normalizeAllowedIPs(peer.AllowedIps)
+		if err != nil {
+			return err
+		}
+		if len(allowedIps) == 0 && len(cfg.AllowedIps) > 0 && !allowEmptyAllowedIPs {
+			return ErrEmptyAllowedIPsUnconfirmed
+		}
+
+		cfg.AllowedIps = allowedIps
+		cfg.PreSharedKey = peer.PreSharedKey
+		cfg.PersistentKeepalive = peer.PersistentKeepalive
+		patched := *cfg
+		current = &patched
+		return nil
+	})
+	if err != nil {
+		if current != nil { // The patch applied, so the repository write failed
+			logger.Logger.Error("Service: Failed to apply peer patch", zap.String("publicKey", publicKey), zap.Error(err))
+		}
+		return nil, err
+	}
+
+	s.prepareConfig(current)
+	logger.Logger.Info("Service: Patched peer", zap.String("publicKey", publicKey), zap.Int("operations", len(ops)))
+	return current, nil
+}
+
+// applyPatchOperation applies one JSON Patch operation to peer.
+func applyPatchOperation(peer *patchablePeer, op domain.PatchOperation) error {
+	switch op.Op {
+	case "add", "remove", "replace", "test":
+	default:
+		return fmt.Errorf("%w: unsupported op %q (use add, remove, replace or test)", ErrInvalidPatch, op.Op)
+	}
+	tokens, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return err
+	}
+	if readOnlyPatchFields[tokens[0]] {
+		return fmt.Errorf("%w: %s", ErrReadOnlyField, tokens[0])
+	}
+
+	switch tokens[0] {
+	case "allowedIps":
+		return patchAllowedIPs(&peer.AllowedIps, op, tokens[1:])
+	case "presharedKey":
+		if len(tokens) > 1 {
+			return fmt.Errorf("%w: %s has no members", ErrInvalidPatch, op.Path)
+		}
+		return patchScalar(&peer.PreSharedKey, op, "", func(psk string) error {
+			if !domain.IsValidKey(psk) {
+				return errors.New("not a base64-encoded 32-byte key")
+			}
+			return nil
+		})
+	case "persistentKeepalive":
+		if len(tokens) > 1 {
+			return fmt.Errorf("%w: %s has no members", ErrInvalidPatch, op.Path)
+		}
+		return patchScalar(&peer.PersistentKeepalive, op, 0, func(seconds int) error {
+			if seconds < 0 || seconds > 65535 {
+				return errors.New("must be between 0 and 65535")
+			}
+			return nil
+		})
+	}
+	return fmt.Errorf("%w: unknown field %q", ErrInvalidPatch, tokens[0])
+}
+
+// parseJSONPointer splits an RFC 6901 pointer such as "/allowedIps/0" into its unescaped tokens.
+func parseJSONPointer(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") || path == "/" {
+		return nil, fmt.Errorf("%w: path %q must point to a field, e.g. /allowedIps", ErrInvalidPatch, path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// patchScalar applies op to a single-valued field; remove resets it to zero.
+func patchScalar[T comparable](field *T, op domain.PatchOperation, zero T, validate func(T) error) error {
+	if op.Op == "remove" {
+		*field = zero
+		return nil
+	}
+	var value T
+	if err := json.Unmarshal(op.Value, &value); err != nil {
+		return fmt.Errorf("%w: value for %s has the wrong type", ErrInvalidPatch, op.Path)
+	}
+	if op.Op == "test" {
+		if *field != value {
+			return ErrPatchTestFailed
+		}
+		return nil
+	}
+	if err := validate(value); err != nil {
+		return fmt.Errorf("%w: value for %s: %v", ErrInvalidPatch, op.Path, err)
+	}
+	*field = value
+	return nil
+}
+
+// patchAllowedIPs applies op to the whole list (no index) or to one entry ("/<index>", or "/-" to append).
+func patchAllowedIPs(ips *[]string, op domain.PatchOperation, index []string) error {
+	if len(index) == 0 {
+		if op.Op == "remove" {
+			*ips = []string{}
+			return nil
+		}
+		var list []string
+		if err := json.Unmarshal(op.Value, &list); err != nil || list == nil {
+			return fmt.Errorf("%w: value for %s must be a list of CIDR strings", ErrInvalidPatch, op.Path)
+		}
+		if op.Op == "test" {
+			if !slices.Equal(*ips, list) {
+				return ErrPatchTestFailed
+			}
+			return nil
+		}
+		*ips = list
+		return nil
+	}
+	if len(index) > 1 {
+		return fmt.Errorf("%w: %s has no members", ErrInvalidPatch, op.Path)
+	}
+
+	pos := len(*ips)
+	if index[0] != "-" || op.Op != "add" {
+		n, err := strconv.Atoi(index[0])
+		limit := len(*ips) - 1
+		if op.Op == "add" {
+			limit = len(*ips) // add may insert right after the last entry
+		}
+		if err != nil || n < 0 || n > limit || strconv.Itoa(n) != index[0] {
+			return fmt.Errorf("%w: %s is not an index of the %d AllowedIPs entries", ErrInvalidPatch, op.Path, len(*ips))
+		}
+		pos = n
+	}
+	if op.Op == "remove" {
+		*ips = slices.Delete(*ips, pos, pos+1)
+		return nil
+	}
+	var entry string
+	if err := json.Unmarshal(op.Value, &entry); err != nil {
+		return fmt.Errorf("%w: value for %s must be a CIDR string", ErrInvalidPatch, op.Path)
+	}
+	switch op.Op {
+	case "test":
+		if (*ips)[pos] != entry {
+			return ErrPatchTestFailed
+		}
+	case "replace":
+		(*ips)[pos] = entry
+	case "add":
+		*ips = slices.Insert(*ips, pos, entry)
+	}
+	return nil
+}
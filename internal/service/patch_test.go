@@ -0,0 +1,125 @@
+// internal/service/patch_test.go
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/repository"
+)
+
+func TestPatchPeer(t *testing.T) {
+	const key = "mK0477z4M24qLMVu2aSNwJjgCR97FPbyxsZ3+gx/NWg="
+	const psk = "BDFTfugHHNOHfPC3B4NSGfRmNE4zs+ZXM2ikT8//RUU="
+	setup := func(t *testing.T) (*ConfigService, *repository.FakeWGRepository) {
+		repo := repository.NewFakeWGRepository()
+		repo.Data[key] = domain.Config{PublicKey: key, AllowedIps: []string{"10.0.0.2/32"}, PreSharedKey: psk,
+			PersistentKeepalive: 25, Endpoint: "192.0.2.1:51820", ReceiveBytes: 10}
+		return setupTestService(t, repo, 0), repo
+	}
+	op := func(operation, path string, value any) domain.PatchOperation {
+		raw, _ := json.Marshal(value)
+		return domain.PatchOperation{Op: operation, Path: path, Value: raw}
+	}
+
+	t.Run("Replace_keepalive", func(t *testing.T) {
+		svc, repo := setup(t)
+		patched, err := svc.PatchPeer(context.Background(), key, []domain.PatchOperation{op("replace", "/persistentKeepalive", 15)}, false)
+		require.NoError(t, err)
+		assert.Equal(t, 15, patched.PersistentKeepalive)
+		assert.Equal(t, 15, repo.Data[key].PersistentKeepalive)
+		assert.Equal(t, []string{"10.0.0.2/32"}, repo.Data[key].AllowedIps, "Unpatched settings are kept")
+		assert.Equal(t, psk, repo.Data[key].PreSharedKey)
+		assert.Equal(t, "192.0.2.1:51820", patched.Endpoint)
+	})
+
+	t.Run("Add_to_allowedIps", func(t *testing.T) {
+		svc, repo := setup(t)
+		_, err := svc.PatchPeer(context.Background(), key, []domain.PatchOperation{
+			op("add", "/allowedIps/-", "10.0.1.0/24"),
+			op("add", "/allowedIps/0", "fd00::2"),
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"fd00::2/128", "10.0.0.2/32", "10.0.1.0/24"}, repo.Data[key].AllowedIps,
+			"Entries are inserted in place and bare addresses normalized")
+	})
+
+	t.Run("Remove_turns_settings_off", func(t *testing.T) {
+		svc, repo := setup(t)
+		_, err := svc.PatchPeer(context.Background(), key, []domain.PatchOperation{
+			op("remove", "/presharedKey", nil),
+			op("remove", "/persistentKeepalive", nil),
+		}, false)
+		require.NoError(t, err)
+		assert.Empty(t, repo.Data[key].PreSharedKey)
+		assert.Zero(t, repo.Data[key].PersistentKeepalive)
+	})
+
+	t.Run("Read_only_fields_rejected", func(t *testing.T) {
+		svc, repo := setup(t)
+		for _, path := range []string{"/publicKey", "/receiveBytes", "/endpoint", "/peerId"} {
+			_, err := svc.PatchPeer(context.Background(), key, []domain.PatchOperation{
+				op("replace", "/persistentKeepalive", 5),
+				op("replace", path, "x"),
+			}, false)
+			assert.ErrorIs(t, err, ErrReadOnlyField, path)
+			assert.Equal(t, domain.CategoryInvalid, domain.CategoryOf(err))
+		}
+		assert.Equal(t, 25, repo.Data[key].PersistentKeepalive, "A rejected patch changes nothing")
+	})
+
+	t.Run("Invalid_patches", func(t *testing.T) {
+		svc, _ := setup(t)
+		for name, ops := range map[string][]domain.PatchOperation{
+			"empty":         {},
+			"unknown op":    {op("move", "/allowedIps", nil)},
+			"unknown field": {op("replace", "/mtu", 1420)},
+			"bad index":     {op("replace", "/allowedIps/3", "10.0.0.9/32")},
+			"bad type":      {op("replace", "/persistentKeepalive", "25")},
+			"bad keepalive": {op("replace", "/persistentKeepalive", 70000)},
+			"bad key":       {op("replace", "/presharedKey", "short")},
+			"bad CIDR":      {op("add", "/allowedIps/-", "10.0.0.300/32")},
+		} {
+			_, err := svc.PatchPeer(context.Background(), key, ops, false)
+			assert.Equal(t, domain.CategoryMalformed, domain.CategoryOf(err), name)
+		}
+	})
+
+	t.Run("Test_operation", func(t *testing.T) {
+		svc, repo := setup(t)
+		_, err := svc.PatchPeer(context.Background(), key, []domain.PatchOperation{
+			op("test", "/allowedIps", []string{"10.0.0.9/32"}),
+			op("replace", "/allowedIps", []string{"10.0.0.3/32"}),
+		}, false)
+		assert.ErrorIs(t, err, ErrPatchTestFailed)
+		assert.Equal(t, []string{"10.0.0.2/32"}, repo.Data[key].AllowedIps)
+
+		_, err = svc.PatchPeer(context.Background(), key, []domain.PatchOperation{
+			op("test", "/persistentKeepalive", 25),
+			op("replace", "/allowedIps", []string{"10.0.0.3/32"}),
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.3/32"}, repo.Data[key].AllowedIps)
+	})
+
+	t.Run("Emptying_allowedIps_needs_confirmation", func(t *testing.T) {
+		svc, repo := setup(t)
+		clearAll := []domain.PatchOperation{op("remove", "/allowedIps/0", nil)}
+		_, err := svc.PatchPeer(context.Background(), key, clearAll, false)
+		assert.ErrorIs(t, err, ErrEmptyAllowedIPsUnconfirmed)
+		_, err = svc.PatchPeer(context.Background(), key, clearAll, true)
+		require.NoError(t, err)
+		assert.Empty(t, repo.Data[key].AllowedIps)
+	})
+
+	t.Run("Missing_peer", func(t *testing.T) {
+		svc, _ := setup(t)
+		_, err := svc.PatchPeer(context.Background(), psk, []domain.PatchOperation{op("replace", "/persistentKeepalive", 5)}, false)
+		assert.ErrorIs(t, err, repository.ErrPeerNotFound)
+	})
+}
@@ -0,0 +1,74 @@
+// internal/service/pool.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// ErrAddressPoolExhausted is returned when a peer needs an address from the pool but none is free.
+var ErrAddressPoolExhausted = domain.NewCategorizedError(domain.CategoryConflict, "address pool exhausted")
+
+// WithAddressPool enables address allocation for peers created without AllowedIPs:
+// such a peer gets the lowest free host address of pool as a /32 (or /128).
+// The pool's network address, the IPv4 broadcast address and reserved (e.g. the server's own
+// interface addresses) are never handed out.
+func WithAddressPool(pool netip.Prefix, reserved ...netip.Addr) Option {
+	return func(s *ConfigService) {
+		s.addressPool = pool.Masked()
+		s.reservedAddrs = reserved
+	}
+}
+
+// allocateAddress returns the lowest host address of the pool not covered by any existing peer's
+// AllowedIPs, as a host prefix string. The caller must hold s.allocMu until the peer is created.
+func (s *ConfigService) allocateAddress(ctx context.Context) (string, error) {
+	configs, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list peers for address allocation: %w", err)
+	}
+	var used []netip.Prefix
+	for _, cfg := range configs {
+		for _, raw := range cfg.AllowedIps {
+			if prefix, err := netip.ParsePrefix(raw); err == nil && prefix.Overlaps(s.addressPool) {
+				used = append(used, prefix.Masked())
+			}
+		}
+	}
+
+	for addr := s.addressPool.Addr().Next(); addr.IsValid() && s.addressPool.Contains(addr); addr = addr.Next() {
+		if addr.Is4() && s.addressPool.Bits() < 31 && !s.addressPool.Contains(addr.Next()) {
+			break // IPv4 broadcast address
+		}
+		if s.addressTaken(addr, used) {
+			continue
+		}
+		allocated := netip.PrefixFrom(addr, addr.BitLen()).String()
+		logger.Logger.Info("Service: Allocated peer address from pool",
+			zap.String("pool", s.addressPool.String()), zap.String("address", allocated))
+		return allocated, nil
+	}
+	logger.Logger.Warn("Service: Address pool exhausted", zap.String("pool", s.addressPool.String()), zap.Int("peers", len(configs)))
+	return "", fmt.Errorf("%w: no free address in %s", ErrAddressPoolExhausted, s.addressPool)
+}
+
+// addressTaken reports whether addr is reserved or inside one of the used prefixes.
+func (s *ConfigService) addressTaken(addr netip.Addr, used []netip.Prefix) bool {
+	for _, r := range s.reservedAddrs {
+		if r == addr {
+			return true
+		}
+	}
+	for _, prefix := range used {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+// internal/service/pool_test.go
+package service
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+)
+
+func TestCreateWithNewKeys_AssignsAddressFromPool(t *testing.T) {
+	repo := newFakeRepository()
+	repo.configs["existingPeer"] = domain.Config{PublicKey: "existingPeer", AllowedIps: []string{"10.8.0.2/32"}}
+	svc := setupTestService(t, repo, 0)
+	WithAddressPool(netip.MustParsePrefix("10.8.0.0/24"), netip.MustParseAddr("10.8.0.1"))(svc)
+
+	created, err := svc.CreateWithNewKeys(context.Background(), nil, "", false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.8.0.3/32", created.AssignedAddress, "Network, reserved server and used addresses should be skipped")
+	assert.Equal(t, []string{created.AssignedAddress}, created.AllowedIps)
+	assert.Equal(t, []string{"10.8.0.3/32"}, repo.configs[created.PublicKey].AllowedIps)
+
+	next, err := svc.CreateWithNewKeys(context.Background(), nil, "", false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.8.0.4/32", next.AssignedAddress, "Each peer should get its own address")
+
+	explicit, err := svc.CreateWithNewKeys(context.Background(), []string{"10.9.0.5/32"}, "", false, 0)
+	require.NoError(t, err)
+	assert.Empty(t, explicit.AssignedAddress, "Peers with explicit AllowedIPs are not allocated an address")
+}
+
+func TestCreateWithNewKeys_PoolExhausted(t *testing.T) {
+	repo := newFakeRepository()
+	repo.configs["onlyPeer"] = domain.Config{PublicKey: "onlyPeer", AllowedIps: []string{"10.8.1.2/32"}}
+	svc := setupTestService(t, repo, 0)
+	WithAddressPool(netip.MustParsePrefix("10.8.1.0/30"), netip.MustParseAddr("10.8.1.1"))(svc) // .1 server, .2 used, .3 broadcast
+
+	_, err := svc.CreateWithNewKeys(context.Background(), nil, "", false, 0)
+	assert.ErrorIs(t, err, ErrAddressPoolExhausted)
+	assert.Len(t, repo.configs, 1, "No peer should be created when the pool is exhausted")
+}
+
+func TestCreateWithNewKeys_FillsTinyPool(t *testing.T) {
+	repo := newFakeRepository()
+	svc := setupTestService(t, repo, 0)
+	WithAddressPool(netip.MustParsePrefix("10.8.2.0/30"))(svc) // Hosts .1 and .2
+
+	var assigned []string
+	for i := 0; i < 2; i++ {
+		created, err := svc.CreateWithNewKeys(context.Background(), nil, "", false, 0)
+		require.NoError(t, err)
+		assigned = append(assigned, created.AssignedAddress)
+	}
+	assert.Equal(t, []string{"10.8.2.1/32", "10.8.2.2/32"}, assigned, "Addresses should be handed out lowest first")
+
+	_, err := svc.CreateWithNewKeys(context.Background(), nil, "", false, 0)
+	assert.ErrorIs(t, err, ErrAddressPoolExhausted)
+	assert.Len(t, repo.configs, 2)
+
+	for key, cfg := range repo.configs {
+		if cfg.AllowedIps[0] == "10.8.2.1/32" {
+			delete(repo.configs, key)
+		}
+	}
+	created, err := svc.CreateWithNewKeys(context.Background(), nil, "", false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.8.2.1/32", created.AssignedAddress, "A freed address should be reused")
+}
@@ -0,0 +1,251 @@
+// internal/service/reconcile.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// ErrInvalidDesiredState is returned when a desired-state document is malformed,
+// e.g. it contains an entry without a public key, the same key twice or peers with overlapping AllowedIPs.
+var ErrInvalidDesiredState = domain.NewCategorizedError(domain.CategoryInvalid, "invalid desired state")
+
+// reconcilePlan is the set of changes needed to move the interface to a desired state.
+type reconcilePlan struct {
+	create    []domain.Config // New peers, fully specified
+	update    []domain.Config // Existing peers with their merged desired configuration
+	delete    []string        // Public keys of peers to remove (only when pruning)
+	unchanged []string        // Public keys of peers already in the desired state
+}
+
+// planReconcile computes the changes needed to turn current into desired.
+// Peers missing from desired are only scheduled for deletion when prune is set.
+// The result lists are sorted by public key for stable output.
+func planReconcile(current []domain.Config, desired []domain.DesiredPeer, prune bool) (*reconcilePlan, error) {
+	currentByKey := make(map[string]domain.Config, len(current))
+	for _, cfg := range current {
+		currentByKey[cfg.PublicKey] = cfg
+	}
+
+	plan := &reconcilePlan{}
+	desiredKeys := make(map[string]struct{}, len(desired))
+	for i, peer := range desired {
+		if peer.PublicKey == "" {
+			return nil, fmt.Errorf("%w: entry %d has no publicKey", ErrInvalidDesiredState, i)
+		}
+		if _, dup := desiredKeys[peer.PublicKey]; dup {
+			return nil, fmt.Errorf("%w: publicKey %s appears more than once", ErrInvalidDesiredState, peer.PublicKey)
+		}
+		desiredKeys[peer.PublicKey] = struct{}{}
+	}
+	if conflicts := allowedIPConflicts(desired); len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: overlapping AllowedIPs: %s", ErrInvalidDesiredState, strings.Join(conflicts, "; "))
+	}
+
+	for _, peer := range desired {
+		existing, ok := currentByKey[peer.PublicKey]
+		if !ok {
+			plan.create = append(plan.create, domain.Config{
+				PublicKey:           peer.PublicKey,
+				AllowedIps:          nonNilStrings(peer.AllowedIps),
+				PreSharedKey:        peer.PresharedKey,
+				PersistentKeepalive: peer.Keepalive,
+			})
+			continue
+		}
+
+		merged := existing
+		merged.AllowedIps = nonNilStrings(peer.AllowedIps)
+		if peer.PresharedKey != "" {
+			merged.PreSharedKey = peer.PresharedKey
+		}
+		if peer.Keepalive > 0 {
+			merged.PersistentKeepalive = peer.Keepalive
+		}
+		if sameStringSet(existing.AllowedIps, merged.AllowedIps) &&
+			existing.PreSharedKey == merged.PreSharedKey &&
+			existing.PersistentKeepalive == merged.PersistentKeepalive {
+			plan.unchanged = append(plan.unchanged, peer.PublicKey)
+			continue
+		}
+		plan.update = append(plan.update, merged)
+	}
+
+	if prune {
+		for key := range currentByKey {
+			if _, ok := desiredKeys[key]; !ok {
+				plan.delete = append(plan.delete, key)
+			}
+		}
+	}
+
+	sort.Slice(plan.create, func(i, j int) bool { return plan.create[i].PublicKey < plan.create[j].PublicKey })
+	sort.Slice(plan.update, func(i, j int) bool { return plan.update[i].PublicKey < plan.update[j].PublicKey })
+	sort.Strings(plan.delete)
+	sort.Strings(plan.unchanged)
+	return plan, nil
+}
+
+// PlanDesiredState computes the changes ApplyDesiredState would make for desired and prune,
+// without mutating anything.
+func (s *ConfigService) PlanDesiredState(ctx context.Context, desired []domain.DesiredPeer, prune bool) (*domain.ReconcilePlan, error) {
+	current, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		logger.Logger.Error("Service: Failed to list configs for plan", zap.Error(err))
+		return nil, err
+	}
+
+	plan, err := planReconcile(current, desired, prune)
+	if err == nil {
+		err = s.checkDesiredRanges(desired)
+	}
+	if err != nil {
+		logger.Logger.Warn("Service: Rejected desired state document", zap.Error(err))
+		return nil, err
+	}
+
+	result := &domain.ReconcilePlan{
+		Create: make([]string, 0, len(plan.create)),
+		Update: make([]string, 0, len(plan.update)),
+		Delete: nonNilStrings(plan.delete),
+	}
+	for _, cfg := range plan.create {
+		result.Create = append(result.Create, cfg.PublicKey)
+	}
+	for _, cfg := range plan.update {
+		result.Update = append(result.Update, cfg.PublicKey)
+	}
+	logger.Logger.Info("Service: Planned desired state",
+		zap.Bool("prune", prune),
+		zap.Int("create", len(result.Create)),
+		zap.Int("update", len(result.Update)),
+		zap.Int("delete", len(result.Delete)))
+	return result, nil
+}
+
+// ApplyDesiredState reconciles the interface with the desired peer set:
+// missing peers are created, changed peers are updated and, if prune is set,
+// peers absent from desired are deleted. Deletions run first so that freed
+// addresses can be reused by created peers.
+// On a repository error the changes made so far are kept and the error names the failing peer.
+func (s *ConfigService) ApplyDesiredState(ctx context.Context, desired []domain.DesiredPeer, prune bool) (*domain.ApplyResult, error) {
+	current, err := s.repo.ListConfigs(ctx)
+	if err != nil {
+		logger.Logger.Error("Service: Failed to list configs for apply", zap.Error(err))
+		return nil, err
+	}
+
+	plan, err := planReconcile(current, desired, prune)
+	if err == nil {
+		err = s.checkDesiredRanges(desired)
+	}
+	if err != nil {
+		logger.Logger.Warn("Service: Rejected desired state document", zap.Error(err))
+		return nil, err
+	}
+
+	result := &domain.ApplyResult{
+		Created:   []string{},
+		Updated:   []string{},
+		Deleted:   []string{},
+		Unchanged: nonNilStrings(plan.unchanged),
+	}
+
+	for _, key := range plan.delete {
+		if err := s.repo.DeleteConfig(ctx, key); err != nil {
+			return nil, fmt.Errorf("apply: failed to delete peer %s (after %d deletions): %w", key, len(result.Deleted), err)
+		}
+		result.Deleted = append(result.Deleted, key)
+	}
+	for _, cfg := range plan.update {
+		if err := s.repo.CreateConfig(ctx, cfg); err != nil {
+			return nil, fmt.Errorf("apply: failed to update peer %s: %w", cfg.PublicKey, err)
+		}
+		result.Updated = append(result.Updated, cfg.PublicKey)
+	}
+	for _, cfg := range plan.create {
+		if err := s.repo.CreateConfig(ctx, cfg); err != nil {
+			return nil, fmt.Errorf("apply: failed to create peer %s: %w", cfg.PublicKey, err)
+		}
+		result.Created = append(result.Created, cfg.PublicKey)
+	}
+
+	logger.Logger.Info("Service: Applied desired state",
+		zap.Bool("prune", prune),
+		zap.Int("created", len(result.Created)),
+		zap.Int("updated", len(result.Updated)),
+		zap.Int("deleted", len(result.Deleted)),
+		zap.Int("unchanged", len(result.Unchanged)))
+	return result, nil
+}
+
+// allowedIPConflicts describes every pair of AllowedIPs entries of different desired peers that overlap,
+// e.g. the same /32 or a /32 inside another peer's /24, since 'wg' would silently move the range to
+// whichever peer is set last. Entries that do not parse are left for the repository to reject.
+func allowedIPConflicts(desired []domain.DesiredPeer) []string {
+	type claim struct {
+		prefix netip.Prefix
+		entry  string
+		owner  string
+	}
+	var claims []claim
+	for _, peer := range desired {
+		for _, raw := range peer.AllowedIps {
+			entry := strings.TrimSpace(raw)
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				addr, addrErr := netip.ParseAddr(entry)
+				if addrErr != nil {
+					continue
+				}
+				prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			claims = append(claims, claim{prefix: prefix.Masked(), entry: entry, owner: peer.PublicKey})
+		}
+	}
+
+	var conflicts []string
+	for i := range claims {
+		for j := i + 1; j < len(claims); j++ {
+			a, b := claims[i], claims[j]
+			if a.owner != b.owner && a.prefix.Overlaps(b.prefix) {
+				conflicts = append(conflicts, fmt.Sprintf("%s (%s) and %s (%s)", a.entry, a.owner, b.entry, b.owner))
+			}
+		}
+	}
+	return conflicts
+}
+
+// sameStringSet reports whether a and b contain the same elements, ignoring order.
+func sameStringSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, v := range a {
+		counts[v]++
+	}
+	for _, v := range b {
+		counts[v]--
+		if counts[v] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// nonNilStrings returns s, or an empty slice if s is nil, so JSON output shows [] instead of null.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
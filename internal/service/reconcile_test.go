@@ -0,0 +1,135 @@
+// internal/service/reconcile_test.go
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+)
+
+// seedReconcileRepo returns a fake repository holding three peers used by the reconcile tests.
+func seedReconcileRepo() *fakeRepository {
+	repo := newFakeRepository()
+	repo.configs["peerUnchanged"] = domain.Config{PublicKey: "peerUnchanged", AllowedIps: []string{"10.50.0.2/32"}, PersistentKeepalive: 25}
+	repo.configs["peerChanged"] = domain.Config{PublicKey: "peerChanged", AllowedIps: []string{"10.50.0.3/32"}, PreSharedKey: "keptPSK"}
+	repo.configs["peerExtra"] = domain.Config{PublicKey: "peerExtra", AllowedIps: []string{"10.50.0.4/32"}}
+	return repo
+}
+
+// reconcileDesired is the desired state matching seedReconcileRepo, except for one change and one new peer.
+var reconcileDesired = []domain.DesiredPeer{
+	{PublicKey: "peerUnchanged", AllowedIps: []string{"10.50.0.2/32"}}, // Zero keepalive keeps the existing 25
+	{PublicKey: "peerChanged", AllowedIps: []string{"10.50.0.3/32", "fd00::3/128"}},
+	{PublicKey: "peerNew", AllowedIps: []string{"10.50.0.5/32"}, PresharedKey: "newPSK", Keepalive: 15},
+}
+
+func TestApplyDesiredState_CreateUpdateUnchanged(t *testing.T) {
+	repo := seedReconcileRepo()
+	svc := setupTestService(t, repo, 0)
+
+	result, err := svc.ApplyDesiredState(context.Background(), reconcileDesired, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"peerNew"}, result.Created)
+	assert.Equal(t, []string{"peerChanged"}, result.Updated)
+	assert.Equal(t, []string{"peerUnchanged"}, result.Unchanged)
+	assert.Empty(t, result.Deleted, "Nothing should be deleted without prune")
+
+	created := repo.configs["peerNew"]
+	assert.Equal(t, []string{"10.50.0.5/32"}, created.AllowedIps)
+	assert.Equal(t, "newPSK", created.PreSharedKey)
+	assert.Equal(t, 15, created.PersistentKeepalive)
+
+	updated := repo.configs["peerChanged"]
+	assert.Equal(t, []string{"10.50.0.3/32", "fd00::3/128"}, updated.AllowedIps)
+	assert.Equal(t, "keptPSK", updated.PreSharedKey, "Empty presharedKey should keep the existing one")
+
+	assert.Equal(t, 25, repo.configs["peerUnchanged"].PersistentKeepalive)
+	assert.Contains(t, repo.configs, "peerExtra", "Peer outside the document should survive without prune")
+}
+
+func TestApplyDesiredState_Prune(t *testing.T) {
+	repo := seedReconcileRepo()
+	svc := setupTestService(t, repo, 0)
+
+	result, err := svc.ApplyDesiredState(context.Background(), reconcileDesired, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"peerExtra"}, result.Deleted)
+	assert.NotContains(t, repo.configs, "peerExtra")
+	assert.Len(t, repo.configs, 3)
+}
+
+func TestApplyDesiredState_InvalidDocument(t *testing.T) {
+	repo := seedReconcileRepo()
+	svc := setupTestService(t, repo, 0)
+
+	_, err := svc.ApplyDesiredState(context.Background(), []domain.DesiredPeer{{PublicKey: "a"}, {PublicKey: "a"}}, true)
+	assert.ErrorIs(t, err, ErrInvalidDesiredState, "Duplicate keys should be rejected")
+
+	_, err = svc.ApplyDesiredState(context.Background(), []domain.DesiredPeer{{AllowedIps: []string{"10.0.0.1/32"}}}, true)
+	assert.ErrorIs(t, err, ErrInvalidDesiredState, "Missing publicKey should be rejected")
+
+	assert.Len(t, repo.configs, 3, "Invalid documents must not change anything")
+}
+
+func TestApplyDesiredState_OverlappingAllowedIPs(t *testing.T) {
+	repo := seedReconcileRepo()
+	svc := setupTestService(t, repo, 0)
+	conflicting := []domain.DesiredPeer{
+		{PublicKey: "peerUnchanged", AllowedIps: []string{"10.50.0.2/32"}},
+		{PublicKey: "peerNew", AllowedIps: []string{"10.50.0.9/32", "10.50.0.2/32"}}, // Claims peerUnchanged's /32
+		{PublicKey: "peerOther", AllowedIps: []string{"10.50.1.7"}},
+	}
+
+	_, err := svc.PlanDesiredState(context.Background(), conflicting, true)
+	require.ErrorIs(t, err, ErrInvalidDesiredState)
+	assert.Contains(t, err.Error(), "10.50.0.2/32 (peerUnchanged) and 10.50.0.2/32 (peerNew)", "The conflict should name both peers")
+
+	_, err = svc.ApplyDesiredState(context.Background(), conflicting, true)
+	require.ErrorIs(t, err, ErrInvalidDesiredState)
+	assert.Len(t, repo.configs, 3, "A conflicting document must be rejected before any mutation")
+	assert.NotContains(t, repo.configs, "peerNew")
+	assert.Contains(t, repo.configs, "peerExtra", "Prune must not have run")
+
+	t.Run("Subnet_containing_another_peer_address", func(t *testing.T) {
+		_, err := svc.PlanDesiredState(context.Background(), []domain.DesiredPeer{
+			{PublicKey: "peerA", AllowedIps: []string{"10.60.0.0/24"}},
+			{PublicKey: "peerB", AllowedIps: []string{"10.60.0.8/32"}},
+		}, false)
+		assert.ErrorIs(t, err, ErrInvalidDesiredState)
+	})
+
+	t.Run("Disjoint_ranges_are_accepted", func(t *testing.T) {
+		_, err := svc.PlanDesiredState(context.Background(), []domain.DesiredPeer{
+			{PublicKey: "peerA", AllowedIps: []string{"10.60.0.0/25"}},
+			{PublicKey: "peerB", AllowedIps: []string{"10.60.0.128/25", "fd00::b/128"}},
+		}, false)
+		assert.NoError(t, err)
+	})
+}
+
+func TestPlanDesiredState_MatchesApplyWithoutMutating(t *testing.T) {
+	for _, prune := range []bool{false, true} {
+		planRepo := seedReconcileRepo()
+		planSvc := setupTestService(t, planRepo, 0)
+		before := make(map[string]domain.Config, len(planRepo.configs))
+		for k, v := range planRepo.configs {
+			before[k] = v
+		}
+
+		plan, err := planSvc.PlanDesiredState(context.Background(), reconcileDesired, prune)
+		require.NoError(t, err)
+		assert.Equal(t, before, planRepo.configs, "Plan must not change the repository (prune=%v)", prune)
+
+		applyRepo := seedReconcileRepo()
+		applied, err := setupTestService(t, applyRepo, 0).ApplyDesiredState(context.Background(), reconcileDesired, prune)
+		require.NoError(t, err)
+
+		assert.Equal(t, applied.Created, plan.Create, "prune=%v", prune)
+		assert.Equal(t, applied.Updated, plan.Update, "prune=%v", prune)
+		assert.Equal(t, applied.Deleted, plan.Delete, "prune=%v", prune)
+	}
+}
@@ -0,0 +1,124 @@
+// internal/service/server.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// ErrInvalidListenPort is returned when a requested interface listen port is outside 1-65535.
+var ErrInvalidListenPort = domain.NewCategorizedError(domain.CategoryInvalid, "listen port must be between 1 and 65535")
+
+// SetListenPort changes the interface's listen port and makes client configs use it as the endpoint port.
+// It returns the endpoint client configs are generated with from now on.
+func (s *ConfigService) SetListenPort(ctx context.Context, port int) (string, error) {
+	if port < 1 || port > 65535 {
+		return "", fmt.Errorf("%w: got %d", ErrInvalidListenPort, port)
+	}
+	setter, ok := s.repo.(repository.ListenPortSetter)
+	if !ok {
+		return "", errors.New("the repository cannot change the listen port")
+	}
+	if err := setter.SetListenPort(ctx, port); err != nil {
+		logger.Logger.Error("Service: Failed to change listen port", zap.Int("listenPort", port), zap.Error(err))
+		return "", err
+	}
+
+	s.endpointMu.Lock()
+	defer s.endpointMu.Unlock()
+	if s.serverBaseEndpoint != "" {
+		host, _, err := net.SplitHostPort(s.serverBaseEndpoint)
+		if err != nil {
+			host = s.serverBaseEndpoint // Endpoint configured without a port, possibly a bracketed IPv6 literal
+		}
+		s.serverBaseEndpoint = domain.JoinEndpoint(host, strconv.Itoa(port))
+	}
+	logger.Logger.Warn("Service: Listen port changed; new client configs use the new endpoint",
+		zap.Int("listenPort", port), zap.String("endpoint", s.serverBaseEndpoint))
+	return s.serverBaseEndpoint, nil
+}
+
+// serverEndpoint returns the endpoint written into client configs.
+func (s *ConfigService) serverEndpoint() string {
+	s.endpointMu.RLock()
+	defer s.endpointMu.RUnlock()
+	return s.serverBaseEndpoint
+}
+
+// DetectAddressDrift compares the configured interface addresses with the live ones reported by lister.
+// If lister is nil or fails, the result has Checked=false. A detected drift is logged as a warning,
+// since client configs generated from a drifted configuration will be wrong.
+func DetectAddressDrift(configured []string, lister repository.AddressLister) domain.AddressDrift {
+	result := domain.AddressDrift{Configured: append([]string{}, configured...)}
+
+	if lister == nil {
+		result.Error = "live interface addresses are not available from the repository"
+		logger.Logger.Info("Address drift check skipped: repository cannot report interface addresses")
+		return result
+	}
+
+	live, err := lister.InterfaceAddresses()
+	if err != nil {
+		result.Error = err.Error()
+		logger.Logger.Warn("Address drift check skipped: failed to read live interface addresses", zap.Error(err))
+		return result
+	}
+
+	result.Checked = true
+	result.Live = live
+	result.Missing = addressDifference(configured, live)
+	result.Unexpected = addressDifference(live, configured)
+	result.InSync = len(result.Missing) == 0 && len(result.Unexpected) == 0
+
+	if result.InSync {
+		logger.Logger.Info("Interface addresses match the configuration", zap.Strings("addresses", live))
+	} else {
+		logger.Logger.Warn("!!! INTERFACE ADDRESS DRIFT DETECTED: configured addresses do not match the live interface. Generated client configs may be wrong. !!!",
+			zap.Strings("configured", configured),
+			zap.Strings("live", live),
+			zap.Strings("missing", result.Missing),
+			zap.Strings("unexpected", result.Unexpected))
+	}
+	return result
+}
+
+// addressDifference returns the addresses of a that are not in b, sorted.
+// Addresses are compared in canonical CIDR form when they parse, so "fd00::1/64" equals "FD00:0::1/64".
+func addressDifference(a, b []string) []string {
+	inB := make(map[string]struct{}, len(b))
+	for _, addr := range b {
+		inB[canonicalAddress(addr)] = struct{}{}
+	}
+	var diff []string
+	for _, addr := range a {
+		if _, ok := inB[canonicalAddress(addr)]; !ok {
+			diff = append(diff, strings.TrimSpace(addr))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// canonicalAddress normalizes an interface address for comparison.
+func canonicalAddress(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if prefix, err := netip.ParsePrefix(addr); err == nil {
+		return prefix.String()
+	}
+	if ip, err := netip.ParseAddr(addr); err == nil {
+		return netip.PrefixFrom(ip, ip.BitLen()).String()
+	}
+	return addr
+}
@@ -0,0 +1,98 @@
+// internal/service/server_test.go
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"wgMicro_api/internal/logger"
+)
+
+// stubAddressLister is a repository.AddressLister returning fixed addresses.
+type stubAddressLister struct {
+	addrs []string
+	err   error
+}
+
+func (s stubAddressLister) InterfaceAddresses() ([]string, error) {
+	return s.addrs, s.err
+}
+
+// portSettingRepository is a fakeRepository that also implements repository.ListenPortSetter.
+type portSettingRepository struct {
+	*fakeRepository
+	port int
+}
+
+func (r *portSettingRepository) SetListenPort(ctx context.Context, port int) error {
+	r.port = port
+	return nil
+}
+
+func TestSetListenPort_Endpoint(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+
+	for _, tc := range []struct {
+		configured, expected string
+	}{
+		{"vpn.example.com:51820", "vpn.example.com:51821"},
+		{"vpn.example.com", "vpn.example.com:51821"},
+		{"[2001:db8::1]:51820", "[2001:db8::1]:51821"},
+		{"[2001:db8::1]", "[2001:db8::1]:51821"}, // Bracketed IPv6 without a port must not be bracketed twice
+		{"", ""},
+	} {
+		repo := &portSettingRepository{fakeRepository: newFakeRepository()}
+		svc := NewConfigService(repo, "testServiceServerPubKey", tc.configured, time.Second, "", 0)
+		endpoint, err := svc.SetListenPort(context.Background(), 51821)
+		require.NoError(t, err, tc.configured)
+		assert.Equal(t, tc.expected, endpoint, tc.configured)
+		assert.Equal(t, 51821, repo.port)
+	}
+}
+
+func TestDetectAddressDrift_Matching(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+
+	configured := []string{"10.8.0.1/24", "fd00::1/64"}
+	// Same set in a different order and a non-canonical IPv6 spelling.
+	lister := stubAddressLister{addrs: []string{"FD00:0::1/64", "10.8.0.1/24"}}
+
+	drift := DetectAddressDrift(configured, lister)
+	assert.True(t, drift.Checked)
+	assert.True(t, drift.InSync, "Addresses should be considered in sync")
+	assert.Empty(t, drift.Missing)
+	assert.Empty(t, drift.Unexpected)
+	assert.Equal(t, configured, drift.Configured)
+}
+
+func TestDetectAddressDrift_Drifting(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+
+	configured := []string{"10.8.0.1/24", "fd00::1/64"}
+	lister := stubAddressLister{addrs: []string{"10.9.0.1/24", "fd00::1/64"}}
+
+	drift := DetectAddressDrift(configured, lister)
+	assert.True(t, drift.Checked)
+	assert.False(t, drift.InSync, "Drift should be detected")
+	assert.Equal(t, []string{"10.8.0.1/24"}, drift.Missing)
+	assert.Equal(t, []string{"10.9.0.1/24"}, drift.Unexpected)
+}
+
+func TestDetectAddressDrift_LiveUnavailable(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+
+	drift := DetectAddressDrift([]string{"10.8.0.1/24"}, stubAddressLister{err: errors.New("ip: command not found")})
+	assert.False(t, drift.Checked)
+	assert.False(t, drift.InSync)
+	assert.Contains(t, drift.Error, "command not found")
+
+	drift = DetectAddressDrift([]string{"10.8.0.1/24"}, nil)
+	assert.False(t, drift.Checked)
+	assert.NotEmpty(t, drift.Error)
+}
@@ -0,0 +1,140 @@
+// internal/service/stats.go
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+	"wgMicro_api/internal/repository"
+)
+
+// StatsCollector periodically aggregates peer statistics from the repository and caches the result,
+// so reads of /stats do not each run 'wg show dump'. It also records its own failures.
+type StatsCollector struct {
+	repo     repository.Repo
+	interval time.Duration
+	now      func() time.Time // Clock, replaceable in tests
+
+	mu     sync.RWMutex
+	stats  domain.InterfaceStats
+	hasRun bool                      // True once stats holds a successful snapshot
+	prev   map[string]trafficCounter // Per-peer counters of the latest snapshot, for deltas
+	status domain.CollectorStatus
+}
+
+// NewStatsCollector creates a collector polling repo every interval. Call Run to start it.
+func NewStatsCollector(repo repository.Repo, interval time.Duration) *StatsCollector {
+	if repo == nil {
+		logger.Logger.Fatal("Repository cannot be nil for StatsCollector")
+	}
+	return &StatsCollector{
+		repo:     repo,
+		interval: interval,
+		now:      time.Now,
+		status:   domain.CollectorStatus{IntervalSeconds: int(interval / time.Second)},
+	}
+}
+
+// Run collects immediately and then on every interval tick until ctx is done.
+func (c *StatsCollector) Run(ctx context.Context) {
+	logger.Logger.Info("Stats collector started", zap.Duration("interval", c.interval))
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	_ = c.Collect(ctx) // Failures are recorded in the status and logged
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Logger.Info("Stats collector stopped")
+			return
+		case <-ticker.C:
+			_ = c.Collect(ctx)
+		}
+	}
+}
+
+// Collect takes one snapshot. On failure the previous snapshot is kept and the error is recorded.
+func (c *StatsCollector) Collect(ctx context.Context) error {
+	configs, err := c.repo.ListConfigs(ctx)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.status.LastError = err.Error()
+		c.status.LastErrorAt = &now
+		c.status.ConsecutiveFailures++
+		logger.Logger.Warn("Stats collection failed",
+			zap.Int("consecutiveFailures", c.status.ConsecutiveFailures),
+			zap.Error(err))
+		return err
+	}
+
+	snapshot := domain.InterfaceStats{PeerCount: len(configs), CollectedAt: now}
+	counters := make(map[string]trafficCounter, len(configs))
+	for _, cfg := range configs {
+		snapshot.ReceiveBytes += cfg.ReceiveBytes
+		snapshot.TransmitBytes += cfg.TransmitBytes
+		counters[cfg.PublicKey] = trafficCounter{rx: cfg.ReceiveBytes, tx: cfg.TransmitBytes}
+	}
+	if c.hasRun {
+		since := c.stats.CollectedAt
+		snapshot.DeltaSince = &since
+		snapshot.Peers = make([]domain.PeerTrafficDelta, 0, len(configs))
+		for _, cfg := range configs {
+			old := c.prev[cfg.PublicKey] // Zero for peers added since: their counters started at 0
+			delta := domain.PeerTrafficDelta{
+				PublicKey: cfg.PublicKey,
+				RxDelta:   counterDelta(old.rx, cfg.ReceiveBytes),
+				TxDelta:   counterDelta(old.tx, cfg.TransmitBytes),
+			}
+			snapshot.RxDelta += delta.RxDelta
+			snapshot.TxDelta += delta.TxDelta
+			snapshot.Peers = append(snapshot.Peers, delta)
+		}
+		sort.Slice(snapshot.Peers, func(i, j int) bool { return snapshot.Peers[i].PublicKey < snapshot.Peers[j].PublicKey })
+	}
+	c.prev = counters
+	c.stats = snapshot
+	c.hasRun = true
+	c.status.LastSuccessAt = &now
+	c.status.ConsecutiveFailures = 0
+	logger.Logger.Debug("Stats collected", zap.Int("peerCount", snapshot.PeerCount))
+	return nil
+}
+
+// Stats returns the latest successful snapshot and whether one exists yet.
+func (c *StatsCollector) Stats() (domain.InterfaceStats, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats, c.hasRun
+}
+
+// Status reports the collector's last success, last error and whether its data is stale.
+func (c *StatsCollector) Status() domain.CollectorStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status := c.status
+	status.Stale = status.LastSuccessAt == nil || c.now().Sub(*status.LastSuccessAt) > 2*c.interval
+	return status
+}
+
+// trafficCounter holds a peer's cumulative byte counters from one snapshot.
+type trafficCounter struct {
+	rx, tx uint64
+}
+
+// counterDelta returns the growth of a cumulative counter. A smaller current value means the
+// counter was reset, so everything counted since the reset, i.e. cur, is the delta.
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
@@ -0,0 +1,114 @@
+// internal/service/stats_test.go
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+func TestStatsCollector_RecordsFailuresAndRecovery(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	repo := newFakeRepository()
+	repo.configs["statsPeerA"] = domain.Config{PublicKey: "statsPeerA", ReceiveBytes: 100, TransmitBytes: 10}
+	repo.configs["statsPeerB"] = domain.Config{PublicKey: "statsPeerB", ReceiveBytes: 50, TransmitBytes: 5}
+
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	collector := NewStatsCollector(repo, 30*time.Second)
+	collector.now = func() time.Time { return clock }
+
+	require.NoError(t, collector.Collect(context.Background()))
+	stats, ok := collector.Stats()
+	require.True(t, ok)
+	assert.Equal(t, domain.InterfaceStats{PeerCount: 2, ReceiveBytes: 150, TransmitBytes: 15, CollectedAt: clock}, stats)
+	assert.False(t, collector.Status().Stale)
+
+	// Force repeated failures: the last error surfaces and the old snapshot is kept.
+	repo.ListConfigsError = errors.New("simulated wg show failure")
+	for i := 0; i < 3; i++ {
+		clock = clock.Add(30 * time.Second)
+		assert.Error(t, collector.Collect(context.Background()))
+	}
+	status := collector.Status()
+	assert.Equal(t, "simulated wg show failure", status.LastError)
+	assert.Equal(t, 3, status.ConsecutiveFailures)
+	require.NotNil(t, status.LastErrorAt)
+	assert.Equal(t, clock, *status.LastErrorAt)
+	require.NotNil(t, status.LastSuccessAt)
+	assert.Equal(t, clock.Add(-90*time.Second), *status.LastSuccessAt)
+	assert.True(t, status.Stale, "No success within two intervals should mark the data stale")
+	stats, _ = collector.Stats()
+	assert.Equal(t, 2, stats.PeerCount, "Failed collections keep the previous snapshot")
+
+	repo.ListConfigsError = nil
+	clock = clock.Add(30 * time.Second)
+	require.NoError(t, collector.Collect(context.Background()))
+	status = collector.Status()
+	assert.Zero(t, status.ConsecutiveFailures)
+	assert.False(t, status.Stale)
+	assert.Equal(t, "simulated wg show failure", status.LastError, "The last error stays visible after recovery")
+}
+
+func TestStatsCollector_NoDataBeforeFirstSuccess(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	repo := newFakeRepository()
+	repo.ListConfigsError = errors.New("interface down")
+	collector := NewStatsCollector(repo, time.Second)
+
+	assert.Error(t, collector.Collect(context.Background()))
+	_, ok := collector.Stats()
+	assert.False(t, ok)
+	status := collector.Status()
+	assert.Nil(t, status.LastSuccessAt)
+	assert.True(t, status.Stale)
+	assert.Equal(t, "interface down", status.LastError)
+}
+
+func TestStatsCollector_Deltas(t *testing.T) {
+	logger.Logger = zaptest.NewLogger(t)
+	repo := newFakeRepository()
+	repo.configs["deltaPeerA"] = domain.Config{PublicKey: "deltaPeerA", ReceiveBytes: 1000, TransmitBytes: 100}
+
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	first := clock
+	collector := NewStatsCollector(repo, 30*time.Second)
+	collector.now = func() time.Time { return clock }
+
+	require.NoError(t, collector.Collect(context.Background()))
+	stats, _ := collector.Stats()
+	assert.Nil(t, stats.DeltaSince, "A single sample has nothing to compare against")
+	assert.Zero(t, stats.RxDelta)
+	assert.Empty(t, stats.Peers)
+
+	t.Run("Normal_increase", func(t *testing.T) {
+		repo.configs["deltaPeerA"] = domain.Config{PublicKey: "deltaPeerA", ReceiveBytes: 1500, TransmitBytes: 130}
+		clock = clock.Add(30 * time.Second)
+		require.NoError(t, collector.Collect(context.Background()))
+
+		stats, _ := collector.Stats()
+		require.NotNil(t, stats.DeltaSince)
+		assert.Equal(t, first, *stats.DeltaSince)
+		assert.Equal(t, uint64(500), stats.RxDelta)
+		assert.Equal(t, uint64(30), stats.TxDelta)
+		assert.Equal(t, []domain.PeerTrafficDelta{{PublicKey: "deltaPeerA", RxDelta: 500, TxDelta: 30}}, stats.Peers)
+	})
+
+	t.Run("Counter_reset_reports_new_value", func(t *testing.T) {
+		repo.configs["deltaPeerA"] = domain.Config{PublicKey: "deltaPeerA", ReceiveBytes: 40, TransmitBytes: 7}
+		clock = clock.Add(30 * time.Second)
+		require.NoError(t, collector.Collect(context.Background()))
+
+		stats, _ := collector.Stats()
+		assert.Equal(t, uint64(40), stats.RxDelta)
+		assert.Equal(t, uint64(7), stats.TxDelta)
+		assert.Equal(t, []domain.PeerTrafficDelta{{PublicKey: "deltaPeerA", RxDelta: 40, TxDelta: 7}}, stats.Peers)
+	})
+}
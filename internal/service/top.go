@@ -0,0 +1,63 @@
+// internal/service/top.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"wgMicro_api/internal/domain"
+	"wgMicro_api/internal/logger"
+)
+
+// Traffic directions accepted by TopTalkers.
+const (
+	TopByReceive  = "rx" // Bytes received from the peer
+	TopByTransmit = "tx" // Bytes sent to the peer
+)
+
+// ErrInvalidTopOrder is returned by TopTalkers for a direction other than TopByReceive or TopByTransmit.
+var ErrInvalidTopOrder = domain.NewCategorizedError(domain.CategoryMalformed, "invalid traffic direction")
+
+// ErrTrafficStatsHidden is returned by TopTalkers and PeerStats when per-peer byte counters are not exposed (EXPOSE_TRAFFIC_STATS=false).
+var ErrTrafficStatsHidden = domain.NewCategorizedError(domain.CategoryForbidden, "per-peer traffic statistics are not exposed")
+
+// TopTalkers returns at most limit peers with the most bytes in direction by, busiest first.
+// Peers with equal counters are ordered by public key. A non-positive limit returns every peer.
+func (s *ConfigService) TopTalkers(ctx context.Context, by string, limit int) ([]domain.Config, error) {
+	if by != TopByReceive && by != TopByTransmit {
+		return nil, fmt.Errorf("%w: %q (use %s or %s)", ErrInvalidTopOrder, by, TopByReceive, TopByTransmit)
+	}
+	if s.hideTraffic {
+		return nil, ErrTrafficStatsHidden
+	}
+	configs, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sortByTraffic(configs, by)
+	if limit > 0 && len(configs) > limit {
+		configs = configs[:limit]
+	}
+	logger.Logger.Debug("Service: Listed top talkers", zap.String("by", by), zap.Int("limit", limit), zap.Int("count", len(configs)))
+	return configs, nil
+}
+
+// sortByTraffic orders configs by their byte counter in direction by, highest first, then by public key.
+func sortByTraffic(configs []domain.Config, by string) {
+	bytes := func(cfg domain.Config) uint64 {
+		if by == TopByTransmit {
+			return cfg.TransmitBytes
+		}
+		return cfg.ReceiveBytes
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if a, b := bytes(configs[i]), bytes(configs[j]); a != b {
+			return a > b
+		}
+		return configs[i].PublicKey < configs[j].PublicKey
+	})
+}
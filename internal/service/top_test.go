@@ -0,0 +1,47 @@
+// internal/service/top_test.go
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wgMicro_api/internal/domain"
+)
+
+func TestTopTalkers(t *testing.T) {
+	repo := newFakeRepository()
+	svc := setupTestService(t, repo, 0)
+	for key, counters := range map[string][2]uint64{
+		"peerA": {500, 10},
+		"peerB": {9000, 20},
+		"peerC": {100, 7000},
+		"peerD": {500, 300},
+		"idle":  {0, 0},
+	} {
+		repo.configs[key] = domain.Config{PublicKey: key, ReceiveBytes: counters[0], TransmitBytes: counters[1]}
+	}
+	top := func(by string, limit int) []string {
+		configs, err := svc.TopTalkers(context.Background(), by, limit)
+		require.NoError(t, err)
+		keys := make([]string, 0, len(configs))
+		for _, cfg := range configs {
+			keys = append(keys, cfg.PublicKey)
+		}
+		return keys
+	}
+
+	assert.Equal(t, []string{"peerB", "peerA", "peerD"}, top(TopByReceive, 3), "Equal counters are ordered by public key")
+	assert.Equal(t, []string{"peerC", "peerD"}, top(TopByTransmit, 2))
+	assert.Equal(t, []string{"peerC", "peerD", "peerB", "peerA", "idle"}, top(TopByTransmit, 10), "A limit above the peer count returns every peer")
+	assert.Len(t, top(TopByReceive, 0), 5)
+
+	_, err := svc.TopTalkers(context.Background(), "total", 10)
+	assert.ErrorIs(t, err, ErrInvalidTopOrder)
+
+	WithTrafficStats(false)(svc)
+	_, err = svc.TopTalkers(context.Background(), TopByReceive, 10)
+	assert.ErrorIs(t, err, ErrTrafficStatsHidden)
+}